@@ -34,6 +34,14 @@ func SetupRoutes(logger *zap.Logger) *gin.Engine {
 			auth.POST("/logout", middleware.AuthMiddleware(), authHandler.Logout)
 		}
 
+		// Realtime event stream; the token may also be passed as ?token= for WebSocket clients
+		realtimeHandler := handlers.NewRealtimeHandler()
+		api.GET("/ws", middleware.TokenFromQuery(), middleware.AuthMiddleware(), realtimeHandler.Connect)
+
+		// Payment gateway webhooks are signed by the gateway instead of authenticated
+		paymentHandler := handlers.NewPaymentHandler()
+		api.POST("/payments/webhook", paymentHandler.Webhook)
+
 		// Protected routes
 		protected := api.Group("")
 		protected.Use(middleware.AuthMiddleware())
@@ -45,16 +53,57 @@ func SetupRoutes(logger *zap.Logger) *gin.Engine {
 
 			// Trip routes (customer)
 			tripHandler := handlers.NewTripHandler()
+			scheduledTripHandler := handlers.NewScheduledTripHandler()
+			ratingHandler := handlers.NewRatingHandler()
 			trips := protected.Group("/trips")
 			trips.Use(middleware.RequireUserType("customer"))
 			{
 				trips.POST("/estimate-fare", tripHandler.EstimateFare) // Public fare estimation
 				trips.POST("", tripHandler.CreateTrip)
 				trips.GET("/active", tripHandler.GetActiveTrip)
+				trips.GET("/scheduled", scheduledTripHandler.ListScheduledTrips)
 				trips.GET("/history", tripHandler.GetTripHistory)
 				trips.GET("/:id", tripHandler.GetTripByID)
 				trips.PUT("/:id", tripHandler.UpdateTrip)
+				trips.GET("/:id/cancellation-fee", tripHandler.GetCancellationFee)
 				trips.POST("/:id/cancel", tripHandler.CancelTrip)
+				trips.POST("/:id/tip", tripHandler.TipTrip)
+				trips.POST("/:id/rating", ratingHandler.RateTrip)
+			}
+
+			// Both parties to a trip can follow its timeline and see its receipt
+			protected.GET("/trips/:id/timeline", middleware.RequireUserType("customer", "driver"), tripHandler.GetTripTimeline)
+			protected.GET("/trips/:id/receipt", middleware.RequireUserType("customer", "driver"), tripHandler.GetTripReceipt)
+			protected.GET("/trips/:id/payment", middleware.RequireUserType("customer", "driver"), paymentHandler.GetTripPayment)
+			waypointHandler := handlers.NewWaypointHandler()
+			protected.GET("/trips/:id/waypoints/:waypointId/proof", middleware.RequireUserType("customer", "driver"), waypointHandler.GetProof)
+			protected.GET("/ratings/tags", middleware.RequireUserType("customer", "driver"), ratingHandler.GetTags)
+
+			// In-trip chat between the customer and the assigned driver
+			chatHandler := handlers.NewChatHandler()
+			protected.GET("/trips/:id/messages", middleware.RequireUserType("customer", "driver"), chatHandler.ListMessages)
+			protected.POST("/trips/:id/messages", middleware.RequireUserType("customer", "driver"), chatHandler.SendMessage)
+			protected.POST("/trips/:id/messages/read", middleware.RequireUserType("customer", "driver"), chatHandler.MarkRead)
+			protected.GET("/chat/quick-replies", middleware.RequireUserType("customer", "driver"), chatHandler.GetQuickReplies)
+
+			// Saved cards (customer)
+			paymentMethods := protected.Group("/payment-methods")
+			paymentMethods.Use(middleware.RequireUserType("customer"))
+			{
+				paymentMethods.GET("", paymentHandler.ListPaymentMethods)
+				paymentMethods.POST("", paymentHandler.AddPaymentMethod)
+				paymentMethods.PUT("/:id/default", paymentHandler.SetDefaultPaymentMethod)
+				paymentMethods.DELETE("/:id", paymentHandler.DeletePaymentMethod)
+			}
+
+			// Wallet (customer)
+			walletHandler := handlers.NewWalletHandler()
+			wallet := protected.Group("/wallet")
+			wallet.Use(middleware.RequireUserType("customer"))
+			{
+				wallet.GET("", walletHandler.GetWallet)
+				wallet.GET("/transactions", walletHandler.ListTransactions)
+				wallet.POST("/top-ups", walletHandler.TopUp)
 			}
 
 			// Job routes (driver)
@@ -63,11 +112,34 @@ func SetupRoutes(logger *zap.Logger) *gin.Engine {
 			jobs.Use(middleware.RequireUserType("driver"))
 			{
 				jobs.GET("/available", jobHandler.GetAvailableJobs)
+				jobs.GET("/scheduled", scheduledTripHandler.ListScheduledJobs)
+				jobs.POST("/:id/pre-accept", scheduledTripHandler.PreAcceptJob)
+				jobs.DELETE("/:id/pre-accept", scheduledTripHandler.ReleaseJob)
 				jobs.POST("/:id/accept", jobHandler.AcceptJob)
 				jobs.POST("/:id/reject", jobHandler.RejectJob)
 				jobs.GET("/active", jobHandler.GetActiveJob)
 				jobs.GET("/history", jobHandler.GetJobHistory)
 				jobs.PUT("/:id/status", jobHandler.UpdateJobStatus)
+				jobs.POST("/:id/cancel", jobHandler.CancelJob)
+				jobs.POST("/:id/rating", ratingHandler.RateJob)
+				jobs.POST("/:id/waypoints/:waypointId/arrive", waypointHandler.ArriveAtWaypoint)
+				jobs.POST("/:id/waypoints/:waypointId/complete", waypointHandler.CompleteWaypoint)
+				jobs.POST("/:id/waypoints/:waypointId/fail", waypointHandler.FailWaypoint)
+			}
+
+			// Driver availability routes (driver)
+			driverHandler := handlers.NewDriverHandler()
+			complianceHandler := handlers.NewDriverComplianceHandler()
+			drivers := protected.Group("/drivers")
+			drivers.Use(middleware.RequireUserType("driver"))
+			{
+				drivers.GET("/availability", driverHandler.GetAvailability)
+				drivers.PUT("/availability", driverHandler.UpdateAvailability)
+				drivers.POST("/heartbeat", driverHandler.Heartbeat)
+				drivers.GET("/compliance", complianceHandler.GetCompliance)
+				drivers.PUT("/vehicle", complianceHandler.SaveVehicle)
+				drivers.POST("/documents", complianceHandler.UploadDocument)
+				drivers.GET("/documents/:id/file", complianceHandler.GetDocumentFile)
 			}
 
 			// Children routes (parent)
@@ -87,7 +159,9 @@ func SetupRoutes(logger *zap.Logger) *gin.Engine {
 			buses := protected.Group("/buses")
 			{
 				buses.GET("/child/:childId", busHandler.GetBusByChildID)
+				buses.GET("/child/:childId/stops", middleware.RequireUserType("parent"), busHandler.GetChildStops)
 				buses.GET("/:id/track", busHandler.TrackBus)
+				buses.GET("/:id/stops", busHandler.GetBusStops)
 			}
 
 			// Notification routes
@@ -100,13 +174,55 @@ func SetupRoutes(logger *zap.Logger) *gin.Engine {
 				notifications.PUT("/settings", notificationHandler.UpdateSettings)
 			}
 
-			// Earnings routes (driver)
+			// Route and timetable management, pricing, promo codes, earnings corrections,
+			// refunds, wallet credits, driver document reviews, low-rated drivers and trip
+			// chats for support (admin)
+			routeHandler := handlers.NewRouteHandler()
 			earningsHandler := handlers.NewEarningsHandler()
+			pricingHandler := handlers.NewPricingHandler()
+			promoHandler := handlers.NewPromoHandler()
+			admin := protected.Group("/admin")
+			admin.Use(middleware.RequireUserType("admin"))
+			{
+				admin.GET("/routes", routeHandler.ListRoutes)
+				admin.POST("/routes", routeHandler.CreateRoute)
+				admin.GET("/routes/:id", routeHandler.GetRoute)
+				admin.PUT("/routes/:id", routeHandler.UpdateRoute)
+				admin.DELETE("/routes/:id", routeHandler.DeleteRoute)
+				admin.POST("/routes/:id/stops", routeHandler.AddStop)
+				admin.PUT("/routes/:id/stops/:stopId", routeHandler.UpdateStop)
+				admin.DELETE("/routes/:id/stops/:stopId", routeHandler.DeleteStop)
+				admin.POST("/routes/:id/schedules", routeHandler.AddSchedule)
+				admin.PUT("/routes/:id/schedules/:scheduleId", routeHandler.UpdateSchedule)
+				admin.DELETE("/routes/:id/schedules/:scheduleId", routeHandler.DeleteSchedule)
+				admin.GET("/fare-tables", pricingHandler.ListFareTables)
+				admin.POST("/fare-tables", pricingHandler.CreateFareTable)
+				admin.GET("/fare-tables/:id", pricingHandler.GetFareTable)
+				admin.GET("/promo-codes", promoHandler.ListPromoCodes)
+				admin.POST("/promo-codes", promoHandler.CreatePromoCode)
+				admin.POST("/promo-codes/:id/deactivate", promoHandler.DeactivatePromoCode)
+				admin.POST("/earnings/:id/adjustments", earningsHandler.AdjustEarning)
+				admin.POST("/payouts/:id/retry", earningsHandler.RetryPayout)
+				admin.POST("/trips/:id/refunds", paymentHandler.RefundTripPayment)
+				admin.GET("/trips/:id/messages", chatHandler.ListMessagesForSupport)
+				admin.POST("/customers/:id/wallet/credits", walletHandler.CreditWallet)
+				admin.GET("/driver-documents", complianceHandler.ListDocuments)
+				admin.GET("/driver-documents/:id/file", complianceHandler.GetDocumentFileForReview)
+				admin.POST("/driver-documents/:id/approve", complianceHandler.ApproveDocument)
+				admin.POST("/driver-documents/:id/reject", complianceHandler.RejectDocument)
+				admin.GET("/drivers/:id/compliance", complianceHandler.GetDriverCompliance)
+				admin.GET("/drivers/flagged", ratingHandler.ListFlaggedDrivers)
+			}
+
+			// Earnings routes (driver)
 			earnings := protected.Group("/earnings")
 			earnings.Use(middleware.RequireUserType("driver"))
 			{
 				earnings.GET("/summary", earningsHandler.GetSummary)
 				earnings.GET("/history", earningsHandler.GetHistory)
+				earnings.GET("/statements", earningsHandler.ListStatements)
+				earnings.GET("/statements/current", earningsHandler.GetCurrentStatement)
+				earnings.GET("/statements/:id", earningsHandler.GetStatement)
 			}
 		}
 	}
@@ -9,7 +9,9 @@ import (
 	"github.com/telemoz/backend/internal/database"
 	"github.com/telemoz/backend/internal/jobs"
 	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/repositories"
 	"github.com/telemoz/backend/internal/services"
+	"github.com/telemoz/backend/pkg/traccar"
 	"go.uber.org/zap"
 )
 
@@ -44,15 +46,44 @@ func main() {
 	if err := database.Migrate(
 		&models.User{},
 		&models.Trip{},
+		&models.SurgeZone{},
+		&models.FareTable{},
 		&models.Job{},
+		&models.TripEvent{},
+		&models.TripTrackPoint{},
+		&models.TripReceipt{},
 		&models.Child{},
 		&models.Bus{},
 		&models.BusLocation{},
+		&models.Route{},
+		&models.RouteStop{},
+		&models.Schedule{},
+		&models.ScheduleStopTime{},
+		&models.BusStopVisit{},
+		&models.BusStopAlert{},
 		&models.Notification{},
 		&models.NotificationSettings{},
 		&models.DriverEarning{},
 		&models.RefreshToken{},
 		&models.DriverAvailability{},
+		&models.JobOffer{},
+		&models.SettlementPeriod{},
+		&models.PayoutStatement{},
+		&models.PaymentMethod{},
+		&models.Payment{},
+		&models.PaymentRefund{},
+		&models.PaymentWebhookEvent{},
+		&models.PromoCode{},
+		&models.PromoRedemption{},
+		&models.Wallet{},
+		&models.WalletTransaction{},
+		&models.TripCancellation{},
+		&models.TripWaypoint{},
+		&models.TripPackage{},
+		&models.Vehicle{},
+		&models.DriverDocument{},
+		&models.TripRating{},
+		&models.TripMessage{},
 	); err != nil {
 		logger.Fatal("Failed to run migrations", zap.Error(err))
 	}
@@ -65,6 +96,40 @@ func main() {
 	// Start background job for expiring trips
 	jobs.StartTripExpirationJob(tripService)
 
+	// Start background job for offering jobs to drivers in waves
+	jobs.StartDispatchJob(services.NewDispatchService())
+
+	// Start background job for reminding about and dispatching scheduled trips
+	jobs.StartScheduledTripJob(services.NewScheduledTripService(), config.AppConfig.Scheduling.Interval)
+
+	// Start background job for taking drivers with stale heartbeats offline
+	jobs.StartDriverSweeperJob(
+		services.NewDriverAvailabilityService(repositories.NewDriverAvailabilityRepository()),
+		config.AppConfig.Dispatch.HeartbeatTimeout,
+	)
+
+	// Start background job for recomputing surge multipliers
+	if config.AppConfig.Surge.Enabled {
+		jobs.StartSurgeJob(services.NewSurgeService(), config.AppConfig.Surge.Interval)
+	}
+
+	// Start background job for settling weekly driver payouts
+	jobs.StartSettlementJob(services.NewPayoutService())
+
+	// Start background job for warning drivers about expiring documents
+	jobs.StartDocumentExpiryJob(services.NewDriverComplianceService())
+
+	// Start streaming bus positions from Traccar
+	if config.AppConfig.Traccar.IngestionEnabled {
+		jobs.StartTraccarIngestionJob(jobs.NewTraccarIngestionWorker(
+			traccar.NewWebSocketClient,
+			repositories.NewBusRepository(),
+			services.NewBusService(),
+			config.AppConfig.Traccar.ReconnectMinBackoff,
+			config.AppConfig.Traccar.ReconnectMaxBackoff,
+		))
+	}
+
 	// Setup routes
 	router := api.SetupRoutes(logger)
 
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"sort"
 	"strconv"
 	"time"
 
@@ -9,15 +10,31 @@ import (
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	JWT      JWTConfig
-	Traccar  TraccarConfig
-	Maps     MapsConfig
-	SMS      SMSConfig
-	Voice    VoiceConfig
-	Firebase FirebaseConfig
-	CORS     CORSConfig
+	Server     ServerConfig
+	Database   DatabaseConfig
+	JWT        JWTConfig
+	Traccar    TraccarConfig
+	Maps       MapsConfig
+	SMS        SMSConfig
+	Voice      VoiceConfig
+	Firebase   FirebaseConfig
+	CORS       CORSConfig
+	Dispatch   DispatchConfig
+	BusStops   BusStopConfig
+	Commission CommissionConfig
+	Payout     PayoutConfig
+	Surge      SurgeConfig
+	Routing    RoutingConfig
+	Quote      QuoteConfig
+	Meter      MeterConfig
+	Payment    PaymentConfig
+	Wallet     WalletConfig
+	Cancel     CancellationConfig
+	Scheduling SchedulingConfig
+	Storage    StorageConfig
+	Delivery   DeliveryConfig
+	Compliance ComplianceConfig
+	Rating     RatingConfig
 }
 
 type ServerConfig struct {
@@ -45,6 +62,11 @@ type TraccarConfig struct {
 	URL      string
 	Username string
 	Password string
+
+	// Position ingestion from the Traccar WebSocket
+	IngestionEnabled    bool
+	ReconnectMinBackoff time.Duration
+	ReconnectMaxBackoff time.Duration
 }
 
 type MapsConfig struct {
@@ -73,6 +95,193 @@ type CORSConfig struct {
 	AllowedOrigins []string
 }
 
+type DispatchConfig struct {
+	WaveSize       int
+	MaxWaves       int
+	OfferTimeout   time.Duration
+	MaxRadiusKm    float64
+	LocationMaxAge time.Duration
+	// Drivers who haven't sent a heartbeat for this long are taken offline
+	HeartbeatTimeout time.Duration
+	// Each star below five a driver's rating falls counts as this many extra km when
+	// ranking drivers; zero ranks by distance alone
+	RatingWeightKm float64
+}
+
+type BusStopConfig struct {
+	// Parents are alerted once the bus comes within this distance of their stop
+	NearbyDistanceMeters float64
+	// Open visits older than this are abandoned, e.g. when a tracker goes silent at a stop
+	VisitTimeout time.Duration
+	// Timetable times are wall-clock times in this location
+	ScheduleTimezone string
+
+	// ETA estimation
+	ETASpeedWindow     time.Duration
+	ETADefaultSpeedKmh float64
+	ETARoadFactor      float64
+	ETADefaultDwell    time.Duration
+}
+
+type CommissionConfig struct {
+	// Share of a trip's fare kept by the platform, in percent
+	PlatformFeePercent float64
+	// Per service type overrides of PlatformFeePercent
+	ServiceFeePercent map[string]float64
+	// Share of tips kept by the platform, in percent
+	TipFeePercent float64
+	// Largest tip a customer can give on a trip
+	TipMax float64
+}
+
+type PayoutConfig struct {
+	// "fake" records transfers in memory; anything else marks payouts as paid for manual transfer
+	Provider string
+	Currency string
+	// Settlement periods are weeks starting on this day, e.g. "monday"
+	WeekStart string
+	// A period is settled this long after it ends, leaving time for late adjustments
+	SettlementDelay time.Duration
+}
+
+type SurgeConfig struct {
+	Enabled bool
+	// Geohash length of the cells demand and supply are counted in
+	CellPrecision int
+	// Trips that started searching within this window count as demand
+	Window time.Duration
+	// How often multipliers are recomputed
+	Interval time.Duration
+	// Surge starts once there are more than Threshold searching trips per available driver
+	Threshold float64
+	// How much the multiplier rises per searching trip per driver above Threshold
+	Sensitivity float64
+	// Cells with fewer searching trips than this never surge
+	MinDemand     int
+	MaxMultiplier float64
+	// Weight of the newly computed multiplier against the previous one, from 0 to 1
+	Smoothing float64
+}
+
+type RoutingConfig struct {
+	// "google" uses the Maps distance matrix; anything else always estimates distances
+	Provider string
+	// Longest a fare estimate waits for the provider before estimating instead
+	Timeout time.Duration
+	// Routes are cached per origin/destination pair, rounded to about 10m
+	CacheTTL  time.Duration
+	CacheSize int
+	// Estimated road distance is the straight-line distance times RoadFactor, driven at
+	// FallbackSpeedKmh
+	RoadFactor       float64
+	FallbackSpeedKmh float64
+}
+
+type QuoteConfig struct {
+	// How long a fare quote can be booked after it was estimated
+	TTL time.Duration
+	// Key quotes are signed with; defaults to the JWT secret
+	SigningKey string
+	// A trip may start or end this far from the quoted points and still use the quote
+	RouteToleranceMeters float64
+}
+
+type MeterConfig struct {
+	// Track points less accurate than this are ignored
+	MaxAccuracyMeters float64
+	// Points implying a faster jump from the previous one are GPS glitches and ignored
+	MaxSpeedKmh float64
+	// Movements shorter than this are GPS jitter; the vehicle is treated as standing
+	MinMoveMeters float64
+	// Time spent moving slower than this counts as waiting
+	WaitingSpeedKmh float64
+	// The final fare stays within this percentage of the upfront fare
+	FareTolerancePercent float64
+}
+
+type PaymentConfig struct {
+	// "fake" charges test cards in memory; anything else disables card payments
+	Gateway  string
+	Currency string
+	// Secret the gateway signs webhooks with
+	WebhookSecret string
+	// Card holds exceed the upfront fare by this percentage so a higher metered fare
+	// can still be captured
+	HoldMarginPercent float64
+}
+
+type WalletConfig struct {
+	// Top-ups are charged to a saved card and must be within these amounts
+	MinTopUp float64
+	MaxTopUp float64
+	// Top-ups may not take a balance above this
+	MaxBalance float64
+}
+
+type CancellationConfig struct {
+	// Customers cancel for free this long after a driver accepts; later, or once the
+	// driver has arrived, they pay Fee
+	FreeWindow time.Duration
+	Fee        float64
+	// Drivers can report a no-show once they have waited this long at the pickup;
+	// the customer then pays NoShowFee
+	NoShowWait time.Duration
+	NoShowFee  float64
+}
+
+type SchedulingConfig struct {
+	// Scheduled pickups must be at least MinLead and at most MaxLead ahead
+	MinLead time.Duration
+	MaxLead time.Duration
+	// Dispatch starts this long before the pickup; customers and pre-accepted drivers
+	// are reminded ReminderBefore it
+	DispatchBefore time.Duration
+	ReminderBefore time.Duration
+	// A driver's scheduled trips must be at least this far apart, on top of their
+	// estimated durations
+	ConflictBuffer time.Duration
+	// How often due reminders and dispatches are looked for
+	Interval time.Duration
+}
+
+type StorageConfig struct {
+	// Uploaded files such as proof-of-delivery photos are kept under Dir
+	Dir string
+	// Uploads larger than this many bytes are refused
+	MaxUploadBytes int
+}
+
+type DeliveryConfig struct {
+	// Packages heavier than a tier's weight pay its surcharge; the heaviest tier a
+	// package exceeds applies. Sorted by weight.
+	WeightSurcharges []WeightTier
+}
+
+// WeightTier is a delivery surcharge for packages over OverKg
+type WeightTier struct {
+	OverKg    float64
+	Surcharge float64
+}
+
+type ComplianceConfig struct {
+	// Document types a driver needs approved and unexpired, besides a registered vehicle,
+	// to be offered jobs
+	RequiredDocuments []string
+	// Drivers are warned this long before a document expires
+	ExpiryWarning time.Duration
+}
+
+type RatingConfig struct {
+	// Trips can be rated for this long after they are completed
+	Window time.Duration
+	// Averages are taken over a user's most recent RollingCount ratings
+	RollingCount int
+	// Drivers averaging below FlagThreshold over at least FlagMinRatings ratings are
+	// flagged for review
+	FlagThreshold  float64
+	FlagMinRatings int
+}
+
 var AppConfig *Config
 
 func Load() error {
@@ -81,6 +290,30 @@ func Load() error {
 
 	accessExpiry, _ := time.ParseDuration(getEnv("JWT_ACCESS_EXPIRY", "15m"))
 	refreshExpiry, _ := time.ParseDuration(getEnv("JWT_REFRESH_EXPIRY", "168h"))
+	offerTimeout, _ := time.ParseDuration(getEnv("DISPATCH_OFFER_TIMEOUT", "20s"))
+	locationMaxAge, _ := time.ParseDuration(getEnv("DISPATCH_LOCATION_MAX_AGE", "5m"))
+	heartbeatTimeout, _ := time.ParseDuration(getEnv("DRIVER_HEARTBEAT_TIMEOUT", "2m"))
+	traccarMinBackoff, _ := time.ParseDuration(getEnv("TRACCAR_RECONNECT_MIN_BACKOFF", "1s"))
+	traccarMaxBackoff, _ := time.ParseDuration(getEnv("TRACCAR_RECONNECT_MAX_BACKOFF", "1m"))
+	stopVisitTimeout, _ := time.ParseDuration(getEnv("BUS_STOP_VISIT_TIMEOUT", "2h"))
+	etaSpeedWindow, _ := time.ParseDuration(getEnv("BUS_ETA_SPEED_WINDOW", "5m"))
+	etaDefaultDwell, _ := time.ParseDuration(getEnv("BUS_ETA_DEFAULT_DWELL", "45s"))
+	settlementDelay, _ := time.ParseDuration(getEnv("PAYOUT_SETTLEMENT_DELAY", "24h"))
+	surgeWindow, _ := time.ParseDuration(getEnv("SURGE_WINDOW", "10m"))
+	surgeInterval, _ := time.ParseDuration(getEnv("SURGE_INTERVAL", "1m"))
+	routingTimeout, _ := time.ParseDuration(getEnv("ROUTING_TIMEOUT", "2s"))
+	routingCacheTTL, _ := time.ParseDuration(getEnv("ROUTING_CACHE_TTL", "15m"))
+	quoteTTL, _ := time.ParseDuration(getEnv("QUOTE_TTL", "5m"))
+	cancelFreeWindow, _ := time.ParseDuration(getEnv("CANCELLATION_FREE_WINDOW", "2m"))
+	noShowWait, _ := time.ParseDuration(getEnv("CANCELLATION_NO_SHOW_WAIT", "5m"))
+	scheduleMinLead, _ := time.ParseDuration(getEnv("SCHEDULED_TRIP_MIN_LEAD", "30m"))
+	scheduleMaxLead, _ := time.ParseDuration(getEnv("SCHEDULED_TRIP_MAX_LEAD", "168h"))
+	scheduleDispatchBefore, _ := time.ParseDuration(getEnv("SCHEDULED_TRIP_DISPATCH_BEFORE", "15m"))
+	scheduleReminderBefore, _ := time.ParseDuration(getEnv("SCHEDULED_TRIP_REMINDER_BEFORE", "1h"))
+	scheduleConflictBuffer, _ := time.ParseDuration(getEnv("SCHEDULED_TRIP_CONFLICT_BUFFER", "15m"))
+	scheduleInterval, _ := time.ParseDuration(getEnv("SCHEDULED_TRIP_INTERVAL", "1m"))
+	documentExpiryWarning, _ := time.ParseDuration(getEnv("DRIVER_DOCUMENT_EXPIRY_WARNING", "720h"))
+	ratingWindow, _ := time.ParseDuration(getEnv("RATING_WINDOW", "72h"))
 
 	AppConfig = &Config{
 		Server: ServerConfig{
@@ -105,6 +338,10 @@ func Load() error {
 			URL:      getEnv("TRACCAR_URL", "http://localhost:8082"),
 			Username: getEnv("TRACCAR_USERNAME", "admin"),
 			Password: getEnv("TRACCAR_PASSWORD", "admin"),
+
+			IngestionEnabled:    getEnvAsBool("TRACCAR_INGESTION_ENABLED", true),
+			ReconnectMinBackoff: traccarMinBackoff,
+			ReconnectMaxBackoff: traccarMaxBackoff,
 		},
 		Maps: MapsConfig{
 			APIKey: getEnv("GOOGLE_MAPS_API_KEY", ""),
@@ -127,6 +364,109 @@ func Load() error {
 		CORS: CORSConfig{
 			AllowedOrigins: parseStringSlice(getEnv("CORS_ALLOWED_ORIGINS", "http://localhost:3000,http://localhost:19006")),
 		},
+		Dispatch: DispatchConfig{
+			WaveSize:         getEnvAsInt("DISPATCH_WAVE_SIZE", 3),
+			MaxWaves:         getEnvAsInt("DISPATCH_MAX_WAVES", 3),
+			OfferTimeout:     offerTimeout,
+			MaxRadiusKm:      getEnvAsFloat("DISPATCH_MAX_RADIUS_KM", 10),
+			LocationMaxAge:   locationMaxAge,
+			HeartbeatTimeout: heartbeatTimeout,
+			RatingWeightKm:   getEnvAsFloat("DISPATCH_RATING_WEIGHT_KM", 0),
+		},
+		BusStops: BusStopConfig{
+			NearbyDistanceMeters: getEnvAsFloat("BUS_NEARBY_DISTANCE_METERS", 500),
+			VisitTimeout:         stopVisitTimeout,
+			ScheduleTimezone:     getEnv("BUS_SCHEDULE_TIMEZONE", "UTC"),
+			ETASpeedWindow:       etaSpeedWindow,
+			ETADefaultSpeedKmh:   getEnvAsFloat("BUS_ETA_DEFAULT_SPEED_KMH", 25),
+			ETARoadFactor:        getEnvAsFloat("BUS_ETA_ROAD_FACTOR", 1.3),
+			ETADefaultDwell:      etaDefaultDwell,
+		},
+		Commission: CommissionConfig{
+			PlatformFeePercent: getEnvAsFloat("COMMISSION_PLATFORM_FEE_PERCENT", 20),
+			ServiceFeePercent:  parseFloatMap(getEnv("COMMISSION_SERVICE_FEE_PERCENT", "")),
+			TipFeePercent:      getEnvAsFloat("COMMISSION_TIP_FEE_PERCENT", 0),
+			TipMax:             getEnvAsFloat("COMMISSION_TIP_MAX", 100),
+		},
+		Payout: PayoutConfig{
+			Provider:        getEnv("PAYOUT_PROVIDER", "manual"),
+			Currency:        getEnv("PAYOUT_CURRENCY", "USD"),
+			WeekStart:       getEnv("PAYOUT_WEEK_START", "monday"),
+			SettlementDelay: settlementDelay,
+		},
+		Surge: SurgeConfig{
+			Enabled:       getEnvAsBool("SURGE_ENABLED", true),
+			CellPrecision: getEnvAsInt("SURGE_CELL_PRECISION", 6),
+			Window:        surgeWindow,
+			Interval:      surgeInterval,
+			Threshold:     getEnvAsFloat("SURGE_THRESHOLD", 1),
+			Sensitivity:   getEnvAsFloat("SURGE_SENSITIVITY", 0.5),
+			MinDemand:     getEnvAsInt("SURGE_MIN_DEMAND", 3),
+			MaxMultiplier: getEnvAsFloat("SURGE_MAX_MULTIPLIER", 2.5),
+			Smoothing:     getEnvAsFloat("SURGE_SMOOTHING", 0.5),
+		},
+		Routing: RoutingConfig{
+			Provider:         getEnv("ROUTING_PROVIDER", "google"),
+			Timeout:          routingTimeout,
+			CacheTTL:         routingCacheTTL,
+			CacheSize:        getEnvAsInt("ROUTING_CACHE_SIZE", 10000),
+			RoadFactor:       getEnvAsFloat("ROUTING_ROAD_FACTOR", 1.3),
+			FallbackSpeedKmh: getEnvAsFloat("ROUTING_FALLBACK_SPEED_KMH", 40),
+		},
+		Quote: QuoteConfig{
+			TTL:                  quoteTTL,
+			SigningKey:           getEnv("QUOTE_SIGNING_KEY", getEnv("JWT_SECRET", "change-me-in-production")),
+			RouteToleranceMeters: getEnvAsFloat("QUOTE_ROUTE_TOLERANCE_METERS", 50),
+		},
+		Meter: MeterConfig{
+			MaxAccuracyMeters:    getEnvAsFloat("METER_MAX_ACCURACY_METERS", 50),
+			MaxSpeedKmh:          getEnvAsFloat("METER_MAX_SPEED_KMH", 160),
+			MinMoveMeters:        getEnvAsFloat("METER_MIN_MOVE_METERS", 10),
+			WaitingSpeedKmh:      getEnvAsFloat("METER_WAITING_SPEED_KMH", 5),
+			FareTolerancePercent: getEnvAsFloat("METER_FARE_TOLERANCE_PERCENT", 20),
+		},
+		Payment: PaymentConfig{
+			Gateway:           getEnv("PAYMENT_GATEWAY", "none"),
+			Currency:          getEnv("PAYMENT_CURRENCY", "USD"),
+			WebhookSecret:     getEnv("PAYMENT_WEBHOOK_SECRET", ""),
+			HoldMarginPercent: getEnvAsFloat("PAYMENT_HOLD_MARGIN_PERCENT", 20),
+		},
+		Wallet: WalletConfig{
+			MinTopUp:   getEnvAsFloat("WALLET_MIN_TOP_UP", 5),
+			MaxTopUp:   getEnvAsFloat("WALLET_MAX_TOP_UP", 500),
+			MaxBalance: getEnvAsFloat("WALLET_MAX_BALANCE", 1000),
+		},
+		Cancel: CancellationConfig{
+			FreeWindow: cancelFreeWindow,
+			Fee:        getEnvAsFloat("CANCELLATION_FEE", 5),
+			NoShowWait: noShowWait,
+			NoShowFee:  getEnvAsFloat("CANCELLATION_NO_SHOW_FEE", 5),
+		},
+		Storage: StorageConfig{
+			Dir:            getEnv("STORAGE_DIR", "./uploads"),
+			MaxUploadBytes: getEnvAsInt("STORAGE_MAX_UPLOAD_BYTES", 5<<20),
+		},
+		Scheduling: SchedulingConfig{
+			MinLead:        scheduleMinLead,
+			MaxLead:        scheduleMaxLead,
+			DispatchBefore: scheduleDispatchBefore,
+			ReminderBefore: scheduleReminderBefore,
+			ConflictBuffer: scheduleConflictBuffer,
+			Interval:       scheduleInterval,
+		},
+		Delivery: DeliveryConfig{
+			WeightSurcharges: parseWeightTiers(getEnv("DELIVERY_WEIGHT_SURCHARGES", "5:2,15:5,30:10,100:25")),
+		},
+		Compliance: ComplianceConfig{
+			RequiredDocuments: parseStringSlice(getEnv("DRIVER_REQUIRED_DOCUMENTS", "license,insurance,registration")),
+			ExpiryWarning:     documentExpiryWarning,
+		},
+		Rating: RatingConfig{
+			Window:         ratingWindow,
+			RollingCount:   getEnvAsInt("RATING_ROLLING_COUNT", 100),
+			FlagThreshold:  getEnvAsFloat("RATING_FLAG_THRESHOLD", 4.2),
+			FlagMinRatings: getEnvAsInt("RATING_FLAG_MIN_RATINGS", 10),
+		},
 	}
 
 	return nil
@@ -147,6 +487,22 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvAsBool(key string, defaultValue bool) bool {
+	valueStr := getEnv(key, "")
+	if value, err := strconv.ParseBool(valueStr); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	valueStr := getEnv(key, "")
+	if value, err := strconv.ParseFloat(valueStr, 64); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
 func parseStringSlice(value string) []string {
 	if value == "" {
 		return []string{}
@@ -160,6 +516,37 @@ func parseStringSlice(value string) []string {
 	return result
 }
 
+// parseFloatMap parses "key:value" pairs separated by commas, e.g. "delivery:15,taxi:20".
+// Malformed pairs are skipped.
+func parseFloatMap(value string) map[string]float64 {
+	result := make(map[string]float64)
+	for _, pair := range parseStringSlice(value) {
+		parts := splitString(pair, ":")
+		if len(parts) != 2 {
+			continue
+		}
+		if number, err := strconv.ParseFloat(trimString(parts[1]), 64); err == nil {
+			result[trimString(parts[0])] = number
+		}
+	}
+	return result
+}
+
+// parseWeightTiers parses "kg:surcharge" pairs, e.g. "5:2,15:5", sorted by weight.
+// Malformed pairs are skipped.
+func parseWeightTiers(value string) []WeightTier {
+	tiers := []WeightTier{}
+	for weight, surcharge := range parseFloatMap(value) {
+		if overKg, err := strconv.ParseFloat(weight, 64); err == nil {
+			tiers = append(tiers, WeightTier{OverKg: overKg, Surcharge: surcharge})
+		}
+	}
+	sort.Slice(tiers, func(i, j int) bool {
+		return tiers[i].OverKg < tiers[j].OverKg
+	})
+	return tiers
+}
+
 func splitString(s, sep string) []string {
 	result := []string{}
 	current := ""
@@ -1,21 +1,20 @@
 package config
 
-// PricingConfig holds pricing configuration for different service types
+// PricingConfig holds the fallback prices of a service type, used until a fare table
+// has been created for it
 type PricingConfig struct {
-	BaseFare        float64
-	PerKmRate       float64
-	MinimumFare     float64
-	SurgeMultiplier float64 // For future surge pricing
+	BaseFare    float64
+	PerKmRate   float64
+	MinimumFare float64
 }
 
 // GetPricingForService returns pricing config for a service type
 func GetPricingForService(serviceType string) PricingConfig {
 	// Default pricing (taxi)
 	config := PricingConfig{
-		BaseFare:        2.0, // $2 base fare
-		PerKmRate:       4.0, // $4 per kilometer
-		MinimumFare:     5.0, // $5 minimum
-		SurgeMultiplier: 1.0, // No surge by default
+		BaseFare:    2.0, // $2 base fare
+		PerKmRate:   4.0, // $4 per kilometer
+		MinimumFare: 5.0, // $5 minimum
 	}
 
 	// Customize pricing by service type
@@ -24,7 +23,7 @@ func GetPricingForService(serviceType string) PricingConfig {
 		config.BaseFare = 1.5
 		config.PerKmRate = 3.5
 		config.MinimumFare = 4.0
-	case "bus":
+	case "school_bus":
 		config.BaseFare = 1.0
 		config.PerKmRate = 2.0
 		config.MinimumFare = 3.0
@@ -30,5 +30,8 @@ type UserResponse struct {
 	Name      string  `json:"name"`
 	UserType  string  `json:"user_type"`
 	AvatarURL *string `json:"avatar_url,omitempty"`
+	// Rating is the average of the user's recent ratings, omitted until they are rated
+	Rating      *float64 `json:"rating,omitempty"`
+	RatingCount int      `json:"rating_count"`
 }
 
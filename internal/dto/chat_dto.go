@@ -0,0 +1,41 @@
+package dto
+
+// SendMessageRequest is a chat message with either free text or the code of a quick reply
+type SendMessageRequest struct {
+	Text       string `json:"text,omitempty" binding:"max=1000"`
+	QuickReply string `json:"quick_reply,omitempty"`
+}
+
+type TripMessageResponse struct {
+	ID         string  `json:"id"`
+	TripID     string  `json:"trip_id"`
+	SenderID   string  `json:"sender_id"`
+	SenderType string  `json:"sender_type"`
+	Kind       string  `json:"kind"`
+	QuickReply *string `json:"quick_reply,omitempty"`
+	Body       string  `json:"body"`
+	ReadAt     *string `json:"read_at,omitempty"`
+	CreatedAt  string  `json:"created_at"`
+}
+
+// TripChatResponse is a page of a trip's chat, newest message first
+type TripChatResponse struct {
+	TripID string `json:"trip_id"`
+	// ReadOnly is set once the trip is no longer under way; the thread is kept but
+	// new messages are refused
+	ReadOnly bool                  `json:"read_only"`
+	Unread   int64                 `json:"unread"`
+	Messages []TripMessageResponse `json:"messages"`
+}
+
+// QuickReply is a canned chat message
+type QuickReply struct {
+	Code string `json:"code"`
+	Text string `json:"text"`
+}
+
+type ChatReadResponse struct {
+	TripID string `json:"trip_id"`
+	Read   int64  `json:"read"`
+	ReadAt string `json:"read_at"`
+}
@@ -0,0 +1,85 @@
+package dto
+
+type UpdateAvailabilityRequest struct {
+	IsAvailable *bool `json:"is_available" binding:"required"`
+	// ServiceTypes must be among those the driver's vehicle is registered for
+	ServiceTypes []string `json:"service_types,omitempty" binding:"omitempty,dive,oneof=delivery taxi school_bus"`
+}
+
+type HeartbeatRequest struct {
+	Latitude  *float64 `json:"latitude" binding:"required"`
+	Longitude *float64 `json:"longitude" binding:"required"`
+	Accuracy  *float64 `json:"accuracy,omitempty"`
+	Speed     *float64 `json:"speed,omitempty"`
+	Heading   *float64 `json:"heading,omitempty"`
+}
+
+type DriverAvailabilityResponse struct {
+	DriverID          string    `json:"driver_id"`
+	IsAvailable       bool      `json:"is_available"`
+	ServiceTypes      []string  `json:"service_types"`
+	VehicleClass      string    `json:"vehicle_class"`
+	LastActiveAt      string    `json:"last_active_at"`
+	Location          *Location `json:"location,omitempty"`
+	LocationUpdatedAt *string   `json:"location_updated_at,omitempty"`
+}
+
+// VehicleRequest registers the driver's vehicle, or updates it
+type VehicleRequest struct {
+	PlateNumber  string   `json:"plate_number" binding:"required,max=20"`
+	Make         string   `json:"make" binding:"required,max=50"`
+	Model        string   `json:"model" binding:"required,max=50"`
+	Color        string   `json:"color" binding:"required,max=30"`
+	Year         int      `json:"year" binding:"required,min=1980,max=2100"`
+	VehicleClass string   `json:"vehicle_class" binding:"required,oneof=bike car van truck"`
+	Seats        int      `json:"seats,omitempty" binding:"min=0,max=60"`
+	ServiceTypes []string `json:"service_types" binding:"required,min=1,dive,oneof=delivery taxi school_bus"`
+}
+
+type VehicleResponse struct {
+	ID           string   `json:"id"`
+	PlateNumber  string   `json:"plate_number"`
+	Make         string   `json:"make"`
+	Model        string   `json:"model"`
+	Color        string   `json:"color"`
+	Year         int      `json:"year"`
+	VehicleClass string   `json:"vehicle_class"`
+	Seats        int      `json:"seats"`
+	ServiceTypes []string `json:"service_types"`
+}
+
+// UploadDocumentRequest is the form uploading a driver document; the scan is the
+// "file" part
+type UploadDocumentRequest struct {
+	Type string `form:"type" binding:"required,oneof=license insurance registration"`
+	// ExpiresAt is the last day the document is valid, as YYYY-MM-DD
+	ExpiresAt string `form:"expires_at" binding:"required"`
+}
+
+type DriverDocumentResponse struct {
+	ID              string  `json:"id"`
+	DriverID        string  `json:"driver_id"`
+	DriverName      string  `json:"driver_name,omitempty"`
+	Type            string  `json:"type"`
+	Status          string  `json:"status"`
+	ExpiresAt       string  `json:"expires_at"`
+	Expired         bool    `json:"expired"`
+	RejectionReason *string `json:"rejection_reason,omitempty"`
+	ReviewedAt      *string `json:"reviewed_at,omitempty"`
+	FileURL         string  `json:"file_url"`
+	CreatedAt       string  `json:"created_at"`
+}
+
+// RejectDocumentRequest tells the driver why their document was rejected
+type RejectDocumentRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+// DriverComplianceResponse is whether a driver may take jobs and, if not, why
+type DriverComplianceResponse struct {
+	DriverID  string                   `json:"driver_id"`
+	Compliant bool                     `json:"compliant"`
+	Issues    []string                 `json:"issues"`
+	Vehicle   *VehicleResponse         `json:"vehicle,omitempty"`
+	Documents []DriverDocumentResponse `json:"documents"`
+}
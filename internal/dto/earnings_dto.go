@@ -0,0 +1,51 @@
+package dto
+
+type TipRequest struct {
+	Amount float64 `json:"amount" binding:"required,gt=0"`
+}
+
+// EarningAdjustmentRequest corrects a driver earning; Amount is added to the
+// driver's earnings and may be negative
+type EarningAdjustmentRequest struct {
+	Amount float64 `json:"amount" binding:"required"`
+	Reason string  `json:"reason" binding:"required"`
+}
+
+type PayoutStatementResponse struct {
+	// ID is empty for the preview of the period still in progress
+	ID                 string  `json:"id,omitempty"`
+	PeriodStart        string  `json:"period_start"`
+	PeriodEnd          string  `json:"period_end"`
+	ExpectedPayoutDate string  `json:"expected_payout_date"`
+	Earnings           float64 `json:"earnings"`
+	CashCollected      float64 `json:"cash_collected"`
+	NetPayout          float64 `json:"net_payout"`
+	Currency           string  `json:"currency"`
+	Status             string  `json:"status"`
+	ProviderReference  *string `json:"provider_reference,omitempty"`
+	FailureReason      *string `json:"failure_reason,omitempty"`
+	PaidAt             *string `json:"paid_at,omitempty"`
+}
+
+type StatementEntry struct {
+	Date        string  `json:"date"`
+	Type        string  `json:"type"`
+	JobID       string  `json:"job_id"`
+	GrossAmount float64 `json:"gross_amount"`
+	Commission  float64 `json:"commission"`
+	Amount      float64 `json:"amount"`
+	Reason      *string `json:"reason,omitempty"`
+}
+
+type StatementCashTrip struct {
+	TripID string  `json:"trip_id"`
+	Date   string  `json:"date"`
+	Fare   float64 `json:"fare"`
+}
+
+// PayoutStatementDetailResponse is a statement with the ledger entries and cash trips it adds up
+type PayoutStatementDetailResponse struct {
+	PayoutStatementResponse
+	Entries   []StatementEntry    `json:"entries"`
+	CashTrips []StatementCashTrip `json:"cash_trips"`
+}
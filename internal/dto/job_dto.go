@@ -11,10 +11,28 @@ type JobResponse struct {
 	EstimatedEarnings *float64 `json:"estimated_earnings,omitempty"`
 	Distance        *float64  `json:"distance,omitempty"`
 	CustomerID      string    `json:"customer_id"`
+	// CustomerRating is the average of the customer's recent ratings from drivers
+	CustomerRating      *float64 `json:"customer_rating,omitempty"`
+	CustomerRatingCount int      `json:"customer_rating_count"`
+	DistanceToPickup *float64 `json:"distance_to_pickup_km,omitempty"`
+	OfferExpiresAt  *string   `json:"offer_expires_at,omitempty"`
+	ScheduledAt     *string   `json:"scheduled_at,omitempty"`
+	Waypoints       []WaypointResponse `json:"waypoints,omitempty"`
+	// Package is what a delivery carries
+	Package         *PackageResponse   `json:"package,omitempty"`
 	CreatedAt       string    `json:"created_at"`
 }
 
+// CancelJobRequest is a driver giving up a job they accepted; the trip is offered to
+// other drivers again
+type CancelJobRequest struct {
+	Reason string `json:"reason" binding:"required,oneof=customer_unreachable customer_requested vehicle_problem unsafe_pickup wrong_pickup other"`
+	Note   string `json:"note,omitempty" binding:"max=500"`
+}
+
 type UpdateJobStatusRequest struct {
-	Status string `json:"status" binding:"required,oneof=accepted rejected in_progress completed"`
+	Status   string    `json:"status" binding:"required,oneof=arrived in_progress completed no_show"`
+	Location *Location `json:"location,omitempty"`
+	Note     string    `json:"note,omitempty"`
 }
 
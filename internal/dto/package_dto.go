@@ -0,0 +1,25 @@
+package dto
+
+// PackageRequest describes what a delivery carries
+type PackageRequest struct {
+	WeightKg      float64 `json:"weight_kg" binding:"required,gt=0,max=2000"`
+	LengthCm      float64 `json:"length_cm" binding:"required,gt=0,max=1000"`
+	WidthCm       float64 `json:"width_cm" binding:"required,gt=0,max=1000"`
+	HeightCm      float64 `json:"height_cm" binding:"required,gt=0,max=1000"`
+	Category      string  `json:"category" binding:"required,oneof=documents food parcel electronics furniture other"`
+	DeclaredValue float64 `json:"declared_value,omitempty" binding:"gte=0"`
+	Fragile       bool    `json:"fragile,omitempty"`
+}
+
+type PackageResponse struct {
+	WeightKg      float64 `json:"weight_kg"`
+	LengthCm      float64 `json:"length_cm"`
+	WidthCm       float64 `json:"width_cm"`
+	HeightCm      float64 `json:"height_cm"`
+	Category      string  `json:"category"`
+	DeclaredValue float64 `json:"declared_value"`
+	Fragile       bool    `json:"fragile"`
+	// VehicleClass is the smallest vehicle the package fits in
+	VehicleClass    string  `json:"vehicle_class"`
+	WeightSurcharge float64 `json:"weight_surcharge"`
+}
@@ -0,0 +1,50 @@
+package dto
+
+// AddPaymentMethodRequest saves a card; Token is created by the gateway's client SDK
+type AddPaymentMethodRequest struct {
+	Token string `json:"token" binding:"required"`
+	// Default makes the card the one trips are charged to; a customer's first card
+	// is always the default
+	Default bool `json:"default"`
+}
+
+type PaymentMethodResponse struct {
+	ID        string `json:"id"`
+	Brand     string `json:"brand"`
+	Last4     string `json:"last4"`
+	ExpMonth  int    `json:"exp_month"`
+	ExpYear   int    `json:"exp_year"`
+	IsDefault bool   `json:"is_default"`
+	CreatedAt string `json:"created_at"`
+}
+
+// RefundRequest refunds part or all of a trip's captured payment
+type RefundRequest struct {
+	Amount float64 `json:"amount" binding:"required,gt=0"`
+	Reason string  `json:"reason" binding:"required"`
+}
+
+type PaymentResponse struct {
+	ID               string           `json:"id"`
+	TripID           string           `json:"trip_id"`
+	PaymentMethodID  string           `json:"payment_method_id"`
+	Status           string           `json:"status"`
+	AuthorizedAmount float64          `json:"authorized_amount"`
+	CapturedAmount   float64          `json:"captured_amount"`
+	RefundedAmount   float64          `json:"refunded_amount"`
+	Currency         string           `json:"currency"`
+	FailureReason    *string          `json:"failure_reason,omitempty"`
+	AuthorizedAt     *string          `json:"authorized_at,omitempty"`
+	CapturedAt       *string          `json:"captured_at,omitempty"`
+	VoidedAt         *string          `json:"voided_at,omitempty"`
+	Refunds          []RefundResponse `json:"refunds"`
+}
+
+type RefundResponse struct {
+	ID            string  `json:"id"`
+	Amount        float64 `json:"amount"`
+	Reason        string  `json:"reason"`
+	Status        string  `json:"status"`
+	FailureReason *string `json:"failure_reason,omitempty"`
+	CreatedAt     string  `json:"created_at"`
+}
@@ -0,0 +1,49 @@
+package dto
+
+// FareTableRequest creates a new pricing version for a service type and zone
+type FareTableRequest struct {
+	ServiceType string `json:"service_type" binding:"required,oneof=delivery taxi school_bus"`
+	// Zone is a geohash prefix; empty applies everywhere
+	Zone string `json:"zone,omitempty" binding:"omitempty,max=12,alphanum"`
+	Name string `json:"name,omitempty" binding:"omitempty,max=100"`
+	// EffectiveFrom defaults to now; EffectiveTo to open-ended
+	EffectiveFrom         *string  `json:"effective_from,omitempty" binding:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	EffectiveTo           *string  `json:"effective_to,omitempty" binding:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	Timezone              string   `json:"timezone,omitempty"`
+	BaseFare              *float64 `json:"base_fare" binding:"required,gte=0"`
+	PerKmRate             *float64 `json:"per_km_rate" binding:"required,gte=0"`
+	PerMinuteRate         float64  `json:"per_minute_rate" binding:"gte=0"`
+	MinimumFare           float64  `json:"minimum_fare" binding:"gte=0"`
+	BookingFee            float64  `json:"booking_fee" binding:"gte=0"`
+	WaitingPerMinuteRate  float64  `json:"waiting_per_minute_rate" binding:"gte=0"`
+	FreeWaitingMinutes    float64  `json:"free_waiting_minutes" binding:"gte=0"`
+	NightSurchargePercent float64  `json:"night_surcharge_percent" binding:"gte=0"`
+	NightStart            *string  `json:"night_start,omitempty" binding:"omitempty,datetime=15:04"`
+	NightEnd              *string  `json:"night_end,omitempty" binding:"omitempty,datetime=15:04"`
+	AirportSurcharge      float64  `json:"airport_surcharge" binding:"gte=0"`
+	AirportZones          []string `json:"airport_zones,omitempty" binding:"dive,max=12,alphanum"`
+}
+
+// PromoCodeRequest creates a promo code
+type PromoCodeRequest struct {
+	Code          string   `json:"code" binding:"required,min=3,max=40,alphanum"`
+	Description   string   `json:"description,omitempty"`
+	DiscountType  string   `json:"discount_type" binding:"required,oneof=percentage fixed"`
+	DiscountValue float64  `json:"discount_value" binding:"required,gt=0"`
+	MaxDiscount   *float64 `json:"max_discount,omitempty" binding:"omitempty,gt=0"`
+	// ServiceTypes the code applies to; empty applies to all
+	ServiceTypes []string `json:"service_types,omitempty" binding:"dive,oneof=delivery taxi school_bus"`
+	// StartsAt defaults to now; EndsAt to open-ended
+	StartsAt       *string `json:"starts_at,omitempty" binding:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	EndsAt         *string `json:"ends_at,omitempty" binding:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	MaxRedemptions *int    `json:"max_redemptions,omitempty" binding:"omitempty,gt=0"`
+	// PerUserLimit defaults to 1
+	PerUserLimit *int `json:"per_user_limit,omitempty" binding:"omitempty,gt=0"`
+}
+
+// DiscountLine is a promo code discount taken off a fare
+type DiscountLine struct {
+	PromoCode   string  `json:"promo_code"`
+	Description string  `json:"description,omitempty"`
+	Amount      float64 `json:"amount"`
+}
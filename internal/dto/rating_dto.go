@@ -0,0 +1,28 @@
+package dto
+
+// RateTripRequest is a customer rating their driver, or a driver their customer,
+// after a completed trip
+type RateTripRequest struct {
+	Stars   int      `json:"stars" binding:"required,min=1,max=5"`
+	Tags    []string `json:"tags,omitempty" binding:"max=5"`
+	Comment string   `json:"comment,omitempty" binding:"max=500"`
+}
+
+type RatingResponse struct {
+	ID        string   `json:"id"`
+	TripID    string   `json:"trip_id"`
+	Stars     int      `json:"stars"`
+	Tags      []string `json:"tags,omitempty"`
+	Comment   *string  `json:"comment,omitempty"`
+	CreatedAt string   `json:"created_at"`
+}
+
+// FlaggedDriverResponse is a driver whose rating fell below the review threshold
+type FlaggedDriverResponse struct {
+	DriverID    string   `json:"driver_id"`
+	Name        string   `json:"name"`
+	Email       string   `json:"email"`
+	Rating      *float64 `json:"rating,omitempty"`
+	RatingCount int      `json:"rating_count"`
+	FlaggedAt   string   `json:"flagged_at"`
+}
@@ -0,0 +1,59 @@
+package dto
+
+// TripStatusEvent is pushed to the customer and driver of a trip when its status changes
+type TripStatusEvent struct {
+	TripID   string  `json:"trip_id"`
+	Status   string  `json:"status"`
+	DriverID *string `json:"driver_id,omitempty"`
+}
+
+// TripWaypointEvent is pushed to the customer of a multi-stop trip as the driver
+// reaches, completes or fails a stop
+type TripWaypointEvent struct {
+	TripID     string `json:"trip_id"`
+	WaypointID string `json:"waypoint_id"`
+	Sequence   int    `json:"sequence"`
+	Status     string `json:"status"`
+}
+
+// ChatReadEvent tells the other party of a trip chat that their messages up to
+// ReadAt have been read
+type ChatReadEvent struct {
+	TripID   string `json:"trip_id"`
+	ReaderID string `json:"reader_id"`
+	ReadAt   string `json:"read_at"`
+}
+
+// JobOfferEvent is pushed to a driver when the dispatcher offers them a job
+type JobOfferEvent struct {
+	JobID            string   `json:"job_id"`
+	TripID           string   `json:"trip_id"`
+	ServiceType      string   `json:"service_type"`
+	PickupLocation   Location `json:"pickup_location"`
+	DropoffLocation  Location `json:"dropoff_location"`
+	DistanceToPickup float64  `json:"distance_to_pickup_km"`
+	FareAmount       *float64 `json:"fare_amount,omitempty"`
+	ExpiresAt        string   `json:"expires_at"`
+}
+
+// JobOfferClosedEvent tells a driver an offer they received is no longer available
+type JobOfferClosedEvent struct {
+	JobID string `json:"job_id"`
+}
+
+// DriverLocationEvent is pushed to a customer while their driver is on the way or on the trip
+type DriverLocationEvent struct {
+	TripID   string   `json:"trip_id"`
+	DriverID string   `json:"driver_id"`
+	Location Location `json:"location"`
+	Speed    *float64 `json:"speed,omitempty"`
+	Heading  *float64 `json:"heading,omitempty"`
+}
+
+// BusLocationEvent is pushed to parents whose children ride the bus
+type BusLocationEvent struct {
+	BusID    string   `json:"bus_id"`
+	Location Location `json:"location"`
+	Speed    *float64 `json:"speed,omitempty"`
+	Heading  *float64 `json:"heading,omitempty"`
+}
@@ -0,0 +1,94 @@
+package dto
+
+type CreateRouteRequest struct {
+	Name        string  `json:"name" binding:"required"`
+	Description *string `json:"description,omitempty"`
+	BusID       *string `json:"bus_id,omitempty" binding:"omitempty,uuid"`
+}
+
+type UpdateRouteRequest struct {
+	Name        *string `json:"name,omitempty"`
+	Description *string `json:"description,omitempty"`
+	BusID       *string `json:"bus_id,omitempty" binding:"omitempty,uuid"`
+	IsActive    *bool   `json:"is_active,omitempty"`
+}
+
+type RouteStopRequest struct {
+	Name                 string   `json:"name" binding:"required"`
+	Latitude             *float64 `json:"latitude" binding:"required"`
+	Longitude            *float64 `json:"longitude" binding:"required"`
+	GeofenceRadiusMeters *float64 `json:"geofence_radius_meters,omitempty" binding:"omitempty,gt=0"`
+	Sequence             int      `json:"sequence"`
+}
+
+type ScheduleRequest struct {
+	RunType       string                    `json:"run_type" binding:"required,oneof=morning afternoon"`
+	Weekdays      []string                  `json:"weekdays" binding:"required,min=1,dive,oneof=mon tue wed thu fri sat sun"`
+	DepartureTime string                    `json:"departure_time" binding:"required,datetime=15:04"`
+	StartsOn      *string                   `json:"starts_on,omitempty" binding:"omitempty,datetime=2006-01-02"`
+	EndsOn        *string                   `json:"ends_on,omitempty" binding:"omitempty,datetime=2006-01-02"`
+	IsActive      *bool                     `json:"is_active,omitempty"`
+	StopTimes     []ScheduleStopTimeRequest `json:"stop_times" binding:"dive"`
+}
+
+type ScheduleStopTimeRequest struct {
+	StopID      string `json:"stop_id" binding:"required,uuid"`
+	ArrivalTime string `json:"arrival_time" binding:"required,datetime=15:04"`
+}
+
+// ChildStopsResponse is a child's pickup and drop-off stops with their timetabled arrivals
+type ChildStopsResponse struct {
+	ChildID string          `json:"child_id"`
+	BusID   *string         `json:"bus_id,omitempty"`
+	Pickup  *ChildStopTimes `json:"pickup,omitempty"`
+	Dropoff *ChildStopTimes `json:"dropoff,omitempty"`
+}
+
+type ChildStopTimes struct {
+	StopID      string          `json:"stop_id"`
+	StopName    string          `json:"stop_name"`
+	Location    Location        `json:"location"`
+	RouteID     string          `json:"route_id"`
+	RouteName   string          `json:"route_name"`
+	Runs        []ScheduledStop `json:"runs"`
+	NextArrival *string         `json:"next_arrival,omitempty"`
+}
+
+type ScheduledStop struct {
+	ScheduleID  string   `json:"schedule_id"`
+	RunType     string   `json:"run_type"`
+	Weekdays    []string `json:"weekdays"`
+	ArrivalTime string   `json:"arrival_time"`
+}
+
+// BusTrackResponse is a bus's latest position with its ETA to the stops left on
+// the run under way
+type BusTrackResponse struct {
+	ID        string          `json:"id"`
+	BusID     string          `json:"bus_id"`
+	Latitude  float64         `json:"latitude"`
+	Longitude float64         `json:"longitude"`
+	Accuracy  *float64        `json:"accuracy,omitempty"`
+	Speed     *float64        `json:"speed,omitempty"`
+	Heading   *float64        `json:"heading,omitempty"`
+	Timestamp string          `json:"timestamp"`
+	ETA       *BusRunResponse `json:"eta,omitempty"`
+}
+
+type BusRunResponse struct {
+	ScheduleID string            `json:"schedule_id"`
+	RouteID    string            `json:"route_id"`
+	RunType    string            `json:"run_type"`
+	SpeedKmh   float64           `json:"speed_kmh"`
+	Stops      []StopETAResponse `json:"stops"`
+}
+
+type StopETAResponse struct {
+	StopID           string  `json:"stop_id"`
+	StopName         string  `json:"stop_name"`
+	Sequence         int     `json:"sequence"`
+	DistanceKm       float64 `json:"distance_km"`
+	EtaMinutes       int     `json:"eta_minutes"`
+	EstimatedArrival string  `json:"estimated_arrival"`
+	ScheduledArrival string  `json:"scheduled_arrival,omitempty"`
+}
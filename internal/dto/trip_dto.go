@@ -4,24 +4,52 @@ type CreateTripRequest struct {
 	ServiceType     string   `json:"service_type" binding:"required,oneof=delivery taxi school_bus"`
 	PickupLocation  Location `json:"pickup_location" binding:"required"`
 	DropoffLocation Location `json:"dropoff_location" binding:"required"`
-	PaymentMethod   string   `json:"payment_method,omitempty"`
+	PaymentMethod   string   `json:"payment_method,omitempty" binding:"omitempty,oneof=cash card wallet"`
+	// PaymentMethodID is the saved card a card trip is charged to; defaults to the customer's default card
+	PaymentMethodID string `json:"payment_method_id,omitempty" binding:"omitempty,uuid"`
+	PromoCode       string `json:"promo_code,omitempty"`
+	// QuoteID is the quote_id of a fare estimate; the trip is charged the quoted fare
+	QuoteID string `json:"quote_id,omitempty"`
+	// SurgeMultiplier is the multiplier of the estimate the customer accepted; the trip
+	// is refused if surge has risen above it since. Ignored when QuoteID is set.
+	SurgeMultiplier *float64 `json:"surge_multiplier,omitempty"`
+	// ScheduledAt books the trip ahead for a pickup at this time (RFC3339); drivers are
+	// dispatched shortly before it. Empty for an immediate ride.
+	ScheduledAt string `json:"scheduled_at,omitempty"`
+	// Waypoints makes a multi-stop trip: the stops after the pickup in the order they
+	// are visited. The last one is the drop-off, so dropoff_location can be left out.
+	Waypoints []WaypointRequest `json:"waypoints,omitempty" binding:"omitempty,max=10,dive"`
+	// Package is required for deliveries and not accepted for other service types
+	Package *PackageRequest `json:"package,omitempty"`
 }
 
 type TripResponse struct {
-	ID                string   `json:"id"`
-	CustomerID        string   `json:"customer_id"`
-	DriverID          *string  `json:"driver_id,omitempty"`
-	ServiceType       string   `json:"service_type"`
-	Status            string   `json:"status"`
-	PickupLocation    Location `json:"pickup_location"`
-	DropoffLocation   Location `json:"dropoff_location"`
-	EstimatedDistance *float64 `json:"estimated_distance,omitempty"`
-	EstimatedDuration *int     `json:"estimated_duration,omitempty"`
-	EstimatedArrival  *int64   `json:"estimated_arrival,omitempty"`
-	FareAmount        *float64 `json:"fare_amount,omitempty"`
-	PaymentMethod     string   `json:"payment_method"`
-	CreatedAt         string   `json:"created_at"`
-	UpdatedAt         string   `json:"updated_at"`
+	ID                string        `json:"id"`
+	CustomerID        string        `json:"customer_id"`
+	DriverID          *string       `json:"driver_id,omitempty"`
+	ServiceType       string        `json:"service_type"`
+	Status            string        `json:"status"`
+	PickupLocation    Location      `json:"pickup_location"`
+	DropoffLocation   Location      `json:"dropoff_location"`
+	EstimatedDistance *float64      `json:"estimated_distance,omitempty"`
+	EstimatedDuration *int          `json:"estimated_duration,omitempty"`
+	DistanceMethod    *string       `json:"distance_method,omitempty"`
+	EstimatedArrival  *int64        `json:"estimated_arrival,omitempty"`
+	ScheduledAt       *string       `json:"scheduled_at,omitempty"`
+	FareAmount        *float64      `json:"fare_amount,omitempty"`
+	SurgeMultiplier   float64       `json:"surge_multiplier"`
+	PricingVersionID  *string       `json:"pricing_version_id,omitempty"`
+	PaymentMethod     string        `json:"payment_method"`
+	PaymentMethodID   *string       `json:"payment_method_id,omitempty"`
+	Discount          *DiscountLine `json:"discount,omitempty"`
+	// CancellationFee is charged for late cancellations and no-shows
+	CancellationFee    float64 `json:"cancellation_fee,omitempty"`
+	CancellationReason *string `json:"cancellation_reason,omitempty"`
+	// Waypoints are the stops of a multi-stop trip
+	Waypoints []WaypointResponse `json:"waypoints,omitempty"`
+	Package   *PackageResponse   `json:"package,omitempty"`
+	CreatedAt string             `json:"created_at"`
+	UpdatedAt string             `json:"updated_at"`
 }
 
 type UpdateTripRequest struct {
@@ -31,17 +59,71 @@ type UpdateTripRequest struct {
 	DropoffLocation  *Location `json:"dropoff_location,omitempty"`
 }
 
+// CancelTripRequest is the optional body of a customer cancelling a trip
+type CancelTripRequest struct {
+	Reason string `json:"reason,omitempty" binding:"max=500"`
+}
+
+// CancellationFeeResponse is what cancelling a trip would cost now
+type CancellationFeeResponse struct {
+	Fee float64 `json:"cancellation_fee"`
+	// FreeUntil is when free cancellation ends, while it hasn't
+	FreeUntil *string `json:"free_until,omitempty"`
+}
+
+// TripEventResponse is one entry of a trip's timeline
+type TripEventResponse struct {
+	ID         string    `json:"id"`
+	FromStatus string    `json:"from_status,omitempty"`
+	ToStatus   string    `json:"to_status"`
+	ActorType  string    `json:"actor_type"`
+	ActorID    *string   `json:"actor_id,omitempty"`
+	Location   *Location `json:"location,omitempty"`
+	Note       *string   `json:"note,omitempty"`
+	CreatedAt  string    `json:"created_at"`
+}
+
 // EstimateFareRequest is the request body for fare estimation
 type EstimateFareRequest struct {
 	ServiceType     string   `json:"service_type" binding:"required,oneof=delivery taxi school_bus"`
 	PickupLocation  Location `json:"pickup_location" binding:"required"`
 	DropoffLocation Location `json:"dropoff_location" binding:"required"`
+	PromoCode       string   `json:"promo_code,omitempty"`
+	// Waypoints are the stops of a multi-stop trip after the pickup; the last is the
+	// drop-off. Multi-stop estimates don't come with a quote.
+	Waypoints []Location `json:"waypoints,omitempty" binding:"omitempty,max=10"`
+	// Package adds the delivery's weight surcharge to the estimate
+	Package *PackageRequest `json:"package,omitempty"`
 }
 
 // EstimateFareResponse is the response for fare estimation
 type EstimateFareResponse struct {
-	Distance          float64 `json:"distance_km"`
-	EstimatedDuration float64 `json:"estimated_duration_minutes"`
-	EstimatedFare     float64 `json:"estimated_fare"`
-	ServiceType       string  `json:"service_type"`
+	Distance          float64       `json:"distance_km"`
+	EstimatedDuration float64       `json:"estimated_duration_minutes"`
+	DistanceMethod    string        `json:"distance_method"` // "road" or "straight_line"
+	EstimatedFare     float64       `json:"estimated_fare"`
+	SurgeMultiplier   float64       `json:"surge_multiplier"`
+	Breakdown         FareBreakdown `json:"fare_breakdown"`
+	PricingVersionID  *string       `json:"pricing_version_id,omitempty"`
+	ServiceType       string        `json:"service_type"`
+	// Discount is the promo code's discount; TotalFare is EstimatedFare less it
+	Discount  *DiscountLine `json:"discount,omitempty"`
+	TotalFare float64       `json:"total_fare"`
+	// QuoteID books the estimated fare when passed to trip creation before QuoteExpiresAt
+	QuoteID        string `json:"quote_id"`
+	QuoteExpiresAt string `json:"quote_expires_at"`
+}
+
+// FareBreakdown itemizes a fare
+type FareBreakdown struct {
+	BaseFare         float64 `json:"base_fare"`
+	DistanceFare     float64 `json:"distance_fare"`
+	TimeFare         float64 `json:"time_fare"`
+	SurgeAmount      float64 `json:"surge_amount"`
+	NightSurcharge   float64 `json:"night_surcharge"`
+	MinimumFareTopUp float64 `json:"minimum_fare_top_up"`
+	BookingFee       float64 `json:"booking_fee"`
+	AirportSurcharge float64 `json:"airport_surcharge"`
+	WeightSurcharge  float64 `json:"weight_surcharge"`
+	Total            float64 `json:"total"`
 }
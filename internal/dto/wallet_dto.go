@@ -0,0 +1,31 @@
+package dto
+
+// TopUpRequest adds money to the wallet from a saved card, by default the default card
+type TopUpRequest struct {
+	Amount          float64 `json:"amount" binding:"required,gt=0"`
+	PaymentMethodID string  `json:"payment_method_id,omitempty" binding:"omitempty,uuid"`
+}
+
+// WalletCreditRequest credits a customer's wallet (admin)
+type WalletCreditRequest struct {
+	Amount float64 `json:"amount" binding:"required,gt=0"`
+	Type   string  `json:"type" binding:"required,oneof=refund referral_credit adjustment"`
+	Reason string  `json:"reason" binding:"required"`
+	// TripID is the trip a refund is for, if any
+	TripID string `json:"trip_id,omitempty" binding:"omitempty,uuid"`
+}
+
+type WalletResponse struct {
+	Balance  float64 `json:"balance"`
+	Currency string  `json:"currency"`
+}
+
+type WalletTransactionResponse struct {
+	ID           string  `json:"id"`
+	Type         string  `json:"type"`
+	Amount       float64 `json:"amount"`
+	BalanceAfter float64 `json:"balance_after"`
+	TripID       *string `json:"trip_id,omitempty"`
+	Description  *string `json:"description,omitempty"`
+	CreatedAt    string  `json:"created_at"`
+}
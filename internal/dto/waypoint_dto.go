@@ -0,0 +1,48 @@
+package dto
+
+// WaypointRequest is a stop of a multi-stop trip after the pickup
+type WaypointRequest struct {
+	Location           Location `json:"location" binding:"required"`
+	ContactName        string   `json:"contact_name" binding:"required,max=100"`
+	ContactPhone       string   `json:"contact_phone" binding:"required,max=20"`
+	PackageDescription string   `json:"package_description,omitempty" binding:"max=500"`
+	PackageQuantity    int      `json:"package_quantity,omitempty" binding:"omitempty,min=1,max=100"`
+	Instructions       string   `json:"instructions,omitempty" binding:"max=500"`
+}
+
+type WaypointResponse struct {
+	ID                 string   `json:"id"`
+	Sequence           int      `json:"sequence"`
+	Location           Location `json:"location"`
+	ContactName        string   `json:"contact_name"`
+	ContactPhone       string   `json:"contact_phone"`
+	PackageDescription *string  `json:"package_description,omitempty"`
+	PackageQuantity    int      `json:"package_quantity"`
+	Instructions       *string  `json:"instructions,omitempty"`
+	Status             string   `json:"status"`
+	ArrivedAt          *string  `json:"arrived_at,omitempty"`
+	CompletedAt        *string  `json:"completed_at,omitempty"`
+	FailureReason      *string  `json:"failure_reason,omitempty"`
+	ProofRequired      bool     `json:"proof_required"`
+	ProofType          *string  `json:"proof_type,omitempty"`
+	RecipientName      *string  `json:"recipient_name,omitempty"`
+	// PINLocked means too many wrong PINs were entered; the stop needs a signature or photo
+	PINLocked bool `json:"pin_locked,omitempty"`
+	// ProofURL downloads the signature or photo taken at the stop
+	ProofURL *string `json:"proof_url,omitempty"`
+	// DeliveryPIN is only shown to the customer, who passes it on to the recipient
+	DeliveryPIN *string `json:"delivery_pin,omitempty"`
+}
+
+// CompleteWaypointRequest is the multipart form a driver completes a stop with. A
+// signature or photo is uploaded as the "proof" file; a PIN is read out by the recipient.
+type CompleteWaypointRequest struct {
+	ProofType     string `json:"proof_type,omitempty" form:"proof_type" binding:"omitempty,oneof=signature photo pin"`
+	PIN           string `json:"pin,omitempty" form:"pin" binding:"max=10"`
+	RecipientName string `json:"recipient_name,omitempty" form:"recipient_name" binding:"max=100"`
+}
+
+// FailWaypointRequest is a driver giving up on a stop, e.g. because nobody was there
+type FailWaypointRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
@@ -10,12 +10,14 @@ import (
 )
 
 type BusHandler struct {
-	busService services.BusService
+	busService   services.BusService
+	routeService services.RouteService
 }
 
 func NewBusHandler() *BusHandler {
 	return &BusHandler{
-		busService: services.NewBusService(),
+		busService:   services.NewBusService(),
+		routeService: services.NewRouteService(),
 	}
 }
 
@@ -36,7 +38,31 @@ func (h *BusHandler) GetBusByChildID(c *gin.Context) {
 	utils.SuccessResponse(c, http.StatusOK, bus, "Bus retrieved successfully")
 }
 
-// TrackBus gets bus location and tracking data
+// GetChildStops gets the child's pickup and drop-off stops and their scheduled times
+func (h *BusHandler) GetChildStops(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	parentID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		utils.Unauthorized(c, "Invalid user ID")
+		return
+	}
+
+	childID, err := uuid.Parse(c.Param("childId"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid child ID", nil)
+		return
+	}
+
+	stops, err := h.routeService.GetChildStops(childID, parentID)
+	if err != nil {
+		utils.NotFound(c, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, stops, "Child stops retrieved successfully")
+}
+
+// TrackBus gets the bus location and its ETA to the stops left on the current run
 func (h *BusHandler) TrackBus(c *gin.Context) {
 	busID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
@@ -44,12 +70,28 @@ func (h *BusHandler) TrackBus(c *gin.Context) {
 		return
 	}
 
-	location, err := h.busService.GetBusLocation(busID)
+	tracking, err := h.busService.GetBusTracking(busID)
 	if err != nil {
 		utils.NotFound(c, err.Error())
 		return
 	}
 
-	utils.SuccessResponse(c, http.StatusOK, location, "Bus location retrieved successfully")
+	utils.SuccessResponse(c, http.StatusOK, tracking, "Bus location retrieved successfully")
 }
 
+// GetBusStops lists the stops on a bus route so parents can pick their child's stop
+func (h *BusHandler) GetBusStops(c *gin.Context) {
+	busID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid bus ID", nil)
+		return
+	}
+
+	stops, err := h.busService.GetBusStops(busID)
+	if err != nil {
+		utils.InternalError(c, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, stops, "Bus stops retrieved successfully")
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/dto"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/services"
+	"github.com/telemoz/backend/internal/utils"
+)
+
+type ChatHandler struct {
+	chatService services.ChatService
+}
+
+func NewChatHandler() *ChatHandler {
+	return &ChatHandler{
+		chatService: services.NewChatService(),
+	}
+}
+
+// ListMessages returns a page of the trip's chat for its customer or driver, newest first
+func (h *ChatHandler) ListMessages(c *gin.Context) {
+	userID, tripID, ok := parseChatRequest(c)
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	chat, err := h.chatService.ListMessages(tripID, userID, limit, offset)
+	if err != nil {
+		respondChatError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, chat, "Messages retrieved successfully")
+}
+
+// SendMessage sends a text or quick reply to the other party of the trip
+func (h *ChatHandler) SendMessage(c *gin.Context) {
+	userID, tripID, ok := parseChatRequest(c)
+	if !ok {
+		return
+	}
+
+	var req dto.SendMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "Invalid request data", err.Error())
+		return
+	}
+
+	message, err := h.chatService.SendMessage(tripID, userID, req)
+	if err != nil {
+		respondChatError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, message, "Message sent successfully")
+}
+
+// MarkRead marks the messages the user received on the trip as read
+func (h *ChatHandler) MarkRead(c *gin.Context) {
+	userID, tripID, ok := parseChatRequest(c)
+	if !ok {
+		return
+	}
+
+	receipt, err := h.chatService.MarkRead(tripID, userID)
+	if err != nil {
+		respondChatError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, receipt, "Messages marked as read")
+}
+
+// GetQuickReplies lists the canned messages the current user can send
+func (h *ChatHandler) GetQuickReplies(c *gin.Context) {
+	userType, _ := c.Get("user_type")
+	userTypeStr, _ := userType.(string)
+
+	replies := h.chatService.GetQuickReplies(models.UserType(userTypeStr))
+	utils.SuccessResponse(c, http.StatusOK, replies, "Quick replies retrieved successfully")
+}
+
+// ListMessagesForSupport returns a page of a trip's whole chat history (admin)
+func (h *ChatHandler) ListMessagesForSupport(c *gin.Context) {
+	tripID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid trip ID", nil)
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	chat, err := h.chatService.ListMessagesForSupport(tripID, limit, offset)
+	if err != nil {
+		respondChatError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, chat, "Messages retrieved successfully")
+}
+
+// parseChatRequest reads the user and trip of a chat request, responding with an
+// error when one is invalid
+func parseChatRequest(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		utils.Unauthorized(c, "Invalid user ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	tripID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid trip ID", nil)
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID, tripID, true
+}
+
+func respondChatError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrChatNotFound):
+		utils.NotFound(c, err.Error())
+	case errors.Is(err, services.ErrChatClosed):
+		utils.Conflict(c, err.Error())
+	case errors.Is(err, services.ErrEmptyMessage), errors.Is(err, services.ErrInvalidQuickReply):
+		utils.BadRequest(c, err.Error(), nil)
+	default:
+		utils.InternalError(c, err.Error())
+	}
+}
@@ -50,6 +50,8 @@ func (h *ChildHandler) CreateChild(c *gin.Context) {
 		Name       string    `json:"name" binding:"required"`
 		SchoolName string    `json:"school_name,omitempty"`
 		BusID      *uuid.UUID `json:"bus_id,omitempty"`
+		PickupStopID  *uuid.UUID `json:"pickup_stop_id,omitempty"`
+		DropoffStopID *uuid.UUID `json:"dropoff_stop_id,omitempty"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -57,7 +59,7 @@ func (h *ChildHandler) CreateChild(c *gin.Context) {
 		return
 	}
 
-	child, err := h.childService.CreateChild(parentID, req.Name, req.SchoolName, req.BusID)
+	child, err := h.childService.CreateChild(parentID, req.Name, req.SchoolName, req.BusID, req.PickupStopID, req.DropoffStopID)
 	if err != nil {
 		utils.BadRequest(c, err.Error(), nil)
 		return
@@ -102,6 +104,8 @@ func (h *ChildHandler) UpdateChild(c *gin.Context) {
 		Name       *string    `json:"name,omitempty"`
 		SchoolName *string    `json:"school_name,omitempty"`
 		BusID      *uuid.UUID `json:"bus_id,omitempty"`
+		PickupStopID  *uuid.UUID `json:"pickup_stop_id,omitempty"`
+		DropoffStopID *uuid.UUID `json:"dropoff_stop_id,omitempty"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -109,7 +113,7 @@ func (h *ChildHandler) UpdateChild(c *gin.Context) {
 		return
 	}
 
-	child, err := h.childService.UpdateChild(childID, parentID, req.Name, req.SchoolName, req.BusID)
+	child, err := h.childService.UpdateChild(childID, parentID, req.Name, req.SchoolName, req.BusID, req.PickupStopID, req.DropoffStopID)
 	if err != nil {
 		utils.BadRequest(c, err.Error(), nil)
 		return
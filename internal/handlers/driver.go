@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/dto"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/repositories"
+	"github.com/telemoz/backend/internal/services"
+	"github.com/telemoz/backend/internal/utils"
+)
+
+type DriverHandler struct {
+	availabilityService services.DriverAvailabilityService
+}
+
+func NewDriverHandler() *DriverHandler {
+	return &DriverHandler{
+		availabilityService: services.NewDriverAvailabilityService(repositories.NewDriverAvailabilityRepository()),
+	}
+}
+
+// GetAvailability gets the driver's availability and last known location
+func (h *DriverHandler) GetAvailability(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	driverID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		utils.Unauthorized(c, "Invalid user ID")
+		return
+	}
+
+	availability, err := h.availabilityService.GetAvailability(driverID)
+	if err != nil {
+		// Drivers start offline until they first update their availability
+		utils.SuccessResponse(c, http.StatusOK, dto.DriverAvailabilityResponse{
+			DriverID:     driverID.String(),
+			ServiceTypes: []string{},
+			VehicleClass: string(models.VehicleClassCar),
+		}, "Availability retrieved successfully")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, availabilityToDTO(availability), "Availability retrieved successfully")
+}
+
+// UpdateAvailability toggles the driver online/offline and sets their service types
+func (h *DriverHandler) UpdateAvailability(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	driverID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		utils.Unauthorized(c, "Invalid user ID")
+		return
+	}
+
+	var req dto.UpdateAvailabilityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "Invalid request data", err.Error())
+		return
+	}
+
+	if err := h.availabilityService.UpdateAvailability(driverID, *req.IsAvailable, req.ServiceTypes); err != nil {
+		utils.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	availability, err := h.availabilityService.GetAvailability(driverID)
+	if err != nil {
+		utils.InternalError(c, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, availabilityToDTO(availability), "Availability updated successfully")
+}
+
+// Heartbeat records that the driver is still connected and where they are
+func (h *DriverHandler) Heartbeat(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	driverID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		utils.Unauthorized(c, "Invalid user ID")
+		return
+	}
+
+	var req dto.HeartbeatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "Invalid request data", err.Error())
+		return
+	}
+
+	availability, err := h.availabilityService.RecordHeartbeat(driverID, req)
+	if err != nil {
+		utils.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, availabilityToDTO(availability), "Heartbeat recorded")
+}
+
+func availabilityToDTO(availability *models.DriverAvailability) dto.DriverAvailabilityResponse {
+	response := dto.DriverAvailabilityResponse{
+		DriverID:     availability.DriverID.String(),
+		IsAvailable:  availability.IsAvailable,
+		ServiceTypes: []string(availability.ServiceTypes),
+		VehicleClass: string(availability.VehicleClass),
+		LastActiveAt: availability.LastActiveAt.Format(time.RFC3339),
+	}
+	if response.ServiceTypes == nil {
+		response.ServiceTypes = []string{}
+	}
+
+	if availability.Latitude != nil && availability.Longitude != nil {
+		response.Location = &dto.Location{
+			Latitude:  *availability.Latitude,
+			Longitude: *availability.Longitude,
+			Accuracy:  availability.Accuracy,
+		}
+	}
+	if availability.LocationUpdatedAt != nil {
+		updatedAt := availability.LocationUpdatedAt.Format(time.RFC3339)
+		response.LocationUpdatedAt = &updatedAt
+	}
+
+	return response
+}
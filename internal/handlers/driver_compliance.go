@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/dto"
+	"github.com/telemoz/backend/internal/services"
+	"github.com/telemoz/backend/internal/utils"
+)
+
+type DriverComplianceHandler struct {
+	complianceService services.DriverComplianceService
+}
+
+func NewDriverComplianceHandler() *DriverComplianceHandler {
+	return &DriverComplianceHandler{
+		complianceService: services.NewDriverComplianceService(),
+	}
+}
+
+// GetCompliance returns the driver's vehicle and documents and whether they can take jobs
+func (h *DriverComplianceHandler) GetCompliance(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	driverID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		utils.Unauthorized(c, "Invalid user ID")
+		return
+	}
+
+	compliance, err := h.complianceService.GetCompliance(driverID)
+	if err != nil {
+		utils.InternalError(c, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, compliance, "Compliance retrieved successfully")
+}
+
+// SaveVehicle registers or updates the driver's vehicle
+func (h *DriverComplianceHandler) SaveVehicle(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	driverID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		utils.Unauthorized(c, "Invalid user ID")
+		return
+	}
+
+	var req dto.VehicleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "Invalid request data", err.Error())
+		return
+	}
+
+	vehicle, err := h.complianceService.SaveVehicle(driverID, req)
+	if errors.Is(err, services.ErrPlateNumberTaken) {
+		utils.Conflict(c, err.Error())
+		return
+	}
+	if err != nil {
+		utils.InternalError(c, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, vehicle, "Vehicle saved successfully")
+}
+
+// UploadDocument uploads a license, insurance or registration for review. It takes a
+// multipart form with the scan as the "file" part.
+func (h *DriverComplianceHandler) UploadDocument(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	driverID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		utils.Unauthorized(c, "Invalid user ID")
+		return
+	}
+
+	var req dto.UploadDocumentRequest
+	if err := c.ShouldBind(&req); err != nil {
+		utils.BadRequest(c, "Invalid request data", err.Error())
+		return
+	}
+	file, err := c.FormFile("file")
+	if err != nil {
+		utils.BadRequest(c, "Document file is required", nil)
+		return
+	}
+	content, err := file.Open()
+	if err != nil {
+		utils.BadRequest(c, "Invalid document file", nil)
+		return
+	}
+	defer content.Close()
+
+	document, err := h.complianceService.UploadDocument(driverID, req, services.DocumentUpload{Content: content})
+	if errors.Is(err, services.ErrInvalidDocumentFile) || errors.Is(err, services.ErrInvalidDocumentExpiry) {
+		utils.BadRequest(c, err.Error(), nil)
+		return
+	}
+	if err != nil {
+		utils.InternalError(c, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, document, "Document uploaded successfully")
+}
+
+// GetDocumentFile downloads the scan of one of the driver's documents
+func (h *DriverComplianceHandler) GetDocumentFile(c *gin.Context) {
+	h.sendDocumentFile(c, false)
+}
+
+// GetDocumentFileForReview downloads the scan of any driver's document (admin)
+func (h *DriverComplianceHandler) GetDocumentFileForReview(c *gin.Context) {
+	h.sendDocumentFile(c, true)
+}
+
+// ListDocuments lists driver documents by status, pending by default, for review (admin)
+func (h *DriverComplianceHandler) ListDocuments(c *gin.Context) {
+	status := c.Query("status")
+	if status != "" && status != "pending" && status != "approved" && status != "rejected" {
+		utils.BadRequest(c, "Invalid status", nil)
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	documents, err := h.complianceService.ListDocuments(status, limit, offset)
+	if err != nil {
+		utils.InternalError(c, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, documents, "Documents retrieved successfully")
+}
+
+// ApproveDocument approves a pending driver document (admin)
+func (h *DriverComplianceHandler) ApproveDocument(c *gin.Context) {
+	adminID, documentID, ok := parseDocumentReview(c)
+	if !ok {
+		return
+	}
+
+	document, err := h.complianceService.ApproveDocument(documentID, adminID)
+	if err != nil {
+		respondDocumentReviewError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, document, "Document approved successfully")
+}
+
+// RejectDocument rejects a pending driver document with a reason (admin)
+func (h *DriverComplianceHandler) RejectDocument(c *gin.Context) {
+	adminID, documentID, ok := parseDocumentReview(c)
+	if !ok {
+		return
+	}
+
+	var req dto.RejectDocumentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "Invalid request data", err.Error())
+		return
+	}
+
+	document, err := h.complianceService.RejectDocument(documentID, adminID, req)
+	if err != nil {
+		respondDocumentReviewError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, document, "Document rejected successfully")
+}
+
+// GetDriverCompliance returns a driver's vehicle, documents and compliance (admin)
+func (h *DriverComplianceHandler) GetDriverCompliance(c *gin.Context) {
+	driverID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid driver ID", nil)
+		return
+	}
+
+	compliance, err := h.complianceService.GetCompliance(driverID)
+	if err != nil {
+		utils.InternalError(c, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, compliance, "Compliance retrieved successfully")
+}
+
+func (h *DriverComplianceHandler) sendDocumentFile(c *gin.Context, admin bool) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		utils.Unauthorized(c, "Invalid user ID")
+		return
+	}
+
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid document ID", nil)
+		return
+	}
+
+	file, err := h.complianceService.GetDocumentFile(documentID, userID, admin)
+	if err != nil {
+		utils.NotFound(c, err.Error())
+		return
+	}
+
+	c.Data(http.StatusOK, file.ContentType, file.Content)
+}
+
+// parseDocumentReview reads the admin and document of a review, responding with an
+// error when one is invalid
+func parseDocumentReview(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	userIDStr, _ := c.Get("user_id")
+	adminID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		utils.Unauthorized(c, "Invalid user ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid document ID", nil)
+		return uuid.Nil, uuid.Nil, false
+	}
+	return adminID, documentID, true
+}
+
+func respondDocumentReviewError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrDocumentReviewed):
+		utils.Conflict(c, err.Error())
+	case errors.Is(err, services.ErrDocumentExpired):
+		utils.BadRequest(c, err.Error(), nil)
+	case errors.Is(err, services.ErrDocumentNotFound):
+		utils.NotFound(c, err.Error())
+	default:
+		utils.InternalError(c, err.Error())
+	}
+}
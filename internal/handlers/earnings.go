@@ -1,22 +1,27 @@
 package handlers
 
 import (
+	"bytes"
+	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/dto"
 	"github.com/telemoz/backend/internal/services"
 	"github.com/telemoz/backend/internal/utils"
 )
 
 type EarningsHandler struct {
 	earningsService services.EarningsService
+	payoutService   services.PayoutService
 }
 
 func NewEarningsHandler() *EarningsHandler {
 	return &EarningsHandler{
 		earningsService: services.NewEarningsService(),
+		payoutService:   services.NewPayoutService(),
 	}
 }
 
@@ -59,3 +64,134 @@ func (h *EarningsHandler) GetHistory(c *gin.Context) {
 	utils.SuccessResponse(c, http.StatusOK, earnings, "Earnings history retrieved successfully")
 }
 
+// AdjustEarning appends a correction to a driver earning (admin)
+func (h *EarningsHandler) AdjustEarning(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	adminID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		utils.Unauthorized(c, "Invalid user ID")
+		return
+	}
+
+	earningID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid earning ID", nil)
+		return
+	}
+
+	var req dto.EarningAdjustmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "Invalid request data", err.Error())
+		return
+	}
+
+	adjustment, err := h.earningsService.AdjustEarning(earningID, adminID, req.Amount, req.Reason)
+	if err != nil {
+		utils.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, adjustment, "Earning adjusted successfully")
+}
+
+// ListStatements lists the driver's settled payout statements
+func (h *EarningsHandler) ListStatements(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	driverID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		utils.Unauthorized(c, "Invalid user ID")
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	statements, err := h.payoutService.ListStatements(driverID, limit, offset)
+	if err != nil {
+		utils.InternalError(c, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, statements, "Statements retrieved successfully")
+}
+
+// GetCurrentStatement previews the statement for the week in progress
+func (h *EarningsHandler) GetCurrentStatement(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	driverID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		utils.Unauthorized(c, "Invalid user ID")
+		return
+	}
+
+	statement, err := h.payoutService.CurrentStatement(driverID)
+	if err != nil {
+		utils.InternalError(c, err.Error())
+		return
+	}
+
+	h.respondStatement(c, statement, "current")
+}
+
+// GetStatement gets a payout statement as JSON, or as a download with ?format=csv or ?format=pdf
+func (h *EarningsHandler) GetStatement(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	driverID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		utils.Unauthorized(c, "Invalid user ID")
+		return
+	}
+
+	statementID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid statement ID", nil)
+		return
+	}
+
+	statement, err := h.payoutService.GetStatement(statementID, driverID)
+	if err != nil {
+		utils.NotFound(c, err.Error())
+		return
+	}
+
+	h.respondStatement(c, statement, statement.PeriodStart)
+}
+
+func (h *EarningsHandler) respondStatement(c *gin.Context, statement *dto.PayoutStatementDetailResponse, name string) {
+	filename := fmt.Sprintf("statement-%s", name)
+
+	switch c.DefaultQuery("format", "json") {
+	case "csv":
+		var buf bytes.Buffer
+		if err := services.WriteStatementCSV(&buf, statement); err != nil {
+			utils.InternalError(c, "Failed to render statement")
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".csv"))
+		c.Data(http.StatusOK, "text/csv", buf.Bytes())
+	case "pdf":
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".pdf"))
+		c.Data(http.StatusOK, "application/pdf", services.RenderStatementPDF(statement))
+	case "json":
+		utils.SuccessResponse(c, http.StatusOK, statement, "Statement retrieved successfully")
+	default:
+		utils.BadRequest(c, "Unsupported format, use json, csv or pdf", nil)
+	}
+}
+
+// RetryPayout retries a failed statement payout (admin)
+func (h *EarningsHandler) RetryPayout(c *gin.Context) {
+	statementID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid statement ID", nil)
+		return
+	}
+
+	statement, err := h.payoutService.RetryPayout(statementID)
+	if err != nil {
+		utils.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, statement, "Payout retried")
+}
@@ -21,9 +21,16 @@ func NewJobHandler() *JobHandler {
 	}
 }
 
-// GetAvailableJobs gets available jobs for drivers
+// GetAvailableJobs gets the jobs currently offered to the driver
 func (h *JobHandler) GetAvailableJobs(c *gin.Context) {
-	jobs, err := h.jobService.GetAvailableJobs()
+	userIDStr, _ := c.Get("user_id")
+	driverID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		utils.Unauthorized(c, "Invalid user ID")
+		return
+	}
+
+	jobs, err := h.jobService.GetAvailableJobs(driverID)
 	if err != nil {
 		utils.InternalError(c, err.Error())
 		return
@@ -49,7 +56,7 @@ func (h *JobHandler) AcceptJob(c *gin.Context) {
 
 	job, err := h.jobService.AcceptJob(jobID, driverID)
 	if err != nil {
-		utils.BadRequest(c, err.Error(), nil)
+		respondTripError(c, err)
 		return
 	}
 
@@ -139,12 +146,42 @@ func (h *JobHandler) UpdateJobStatus(c *gin.Context) {
 		return
 	}
 
-	job, err := h.jobService.UpdateJobStatus(jobID, driverID, req.Status)
+	job, err := h.jobService.UpdateJobStatus(jobID, driverID, req)
 	if err != nil {
-		utils.BadRequest(c, err.Error(), nil)
+		respondTripError(c, err)
 		return
 	}
 
 	utils.SuccessResponse(c, http.StatusOK, job, "Job status updated successfully")
 }
 
+
+// CancelJob gives up an accepted job with a reason code; the trip is offered to
+// other drivers again
+func (h *JobHandler) CancelJob(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	driverID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		utils.Unauthorized(c, "Invalid user ID")
+		return
+	}
+
+	jobID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid job ID", nil)
+		return
+	}
+
+	var req dto.CancelJobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "Invalid request data", err.Error())
+		return
+	}
+
+	if err := h.jobService.CancelJob(jobID, driverID, req); err != nil {
+		respondTripError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, nil, "Job cancelled successfully")
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/dto"
+	"github.com/telemoz/backend/internal/services"
+	"github.com/telemoz/backend/internal/utils"
+)
+
+type PaymentHandler struct {
+	paymentService services.PaymentService
+}
+
+func NewPaymentHandler() *PaymentHandler {
+	return &PaymentHandler{
+		paymentService: services.NewPaymentService(),
+	}
+}
+
+// ListPaymentMethods lists the customer's saved cards
+func (h *PaymentHandler) ListPaymentMethods(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	customerID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		utils.Unauthorized(c, "Invalid user ID")
+		return
+	}
+
+	methods, err := h.paymentService.ListPaymentMethods(customerID)
+	if err != nil {
+		utils.InternalError(c, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, methods, "Payment methods retrieved successfully")
+}
+
+// AddPaymentMethod saves a card tokenized with the payment gateway
+func (h *PaymentHandler) AddPaymentMethod(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	customerID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		utils.Unauthorized(c, "Invalid user ID")
+		return
+	}
+
+	var req dto.AddPaymentMethodRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "Invalid request data", err.Error())
+		return
+	}
+
+	method, err := h.paymentService.AddPaymentMethod(customerID, req)
+	if err != nil {
+		utils.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, method, "Payment method added successfully")
+}
+
+// SetDefaultPaymentMethod makes a saved card the one trips are charged to
+func (h *PaymentHandler) SetDefaultPaymentMethod(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	customerID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		utils.Unauthorized(c, "Invalid user ID")
+		return
+	}
+
+	methodID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid payment method ID", nil)
+		return
+	}
+
+	if err := h.paymentService.SetDefaultPaymentMethod(customerID, methodID); err != nil {
+		if errors.Is(err, services.ErrPaymentMethodNotFound) {
+			utils.NotFound(c, err.Error())
+			return
+		}
+		utils.InternalError(c, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, nil, "Default payment method updated successfully")
+}
+
+// DeletePaymentMethod removes a saved card
+func (h *PaymentHandler) DeletePaymentMethod(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	customerID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		utils.Unauthorized(c, "Invalid user ID")
+		return
+	}
+
+	methodID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid payment method ID", nil)
+		return
+	}
+
+	if err := h.paymentService.DeletePaymentMethod(customerID, methodID); err != nil {
+		switch {
+		case errors.Is(err, services.ErrPaymentMethodNotFound):
+			utils.NotFound(c, err.Error())
+		case errors.Is(err, services.ErrPaymentMethodInUse):
+			utils.Conflict(c, err.Error())
+		default:
+			utils.InternalError(c, err.Error())
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, nil, "Payment method deleted successfully")
+}
+
+// GetTripPayment gets the card payment of a trip for its customer or driver
+func (h *PaymentHandler) GetTripPayment(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		utils.Unauthorized(c, "Invalid user ID")
+		return
+	}
+
+	tripID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid trip ID", nil)
+		return
+	}
+
+	tripPayment, err := h.paymentService.GetTripPayment(tripID, userID)
+	if err != nil {
+		utils.NotFound(c, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, tripPayment, "Trip payment retrieved successfully")
+}
+
+// RefundTripPayment refunds part or all of a trip's card payment (admin)
+func (h *PaymentHandler) RefundTripPayment(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	adminID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		utils.Unauthorized(c, "Invalid user ID")
+		return
+	}
+
+	tripID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid trip ID", nil)
+		return
+	}
+
+	var req dto.RefundRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "Invalid request data", err.Error())
+		return
+	}
+
+	tripPayment, err := h.paymentService.RefundTripPayment(tripID, adminID, req)
+	if err != nil {
+		if errors.Is(err, services.ErrPaymentNotFound) {
+			utils.NotFound(c, err.Error())
+			return
+		}
+		utils.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, tripPayment, "Payment refunded successfully")
+}
+
+// Webhook receives charge and refund updates from the payment gateway. It is not
+// authenticated; the gateway signs each request in the X-Payment-Signature header.
+func (h *PaymentHandler) Webhook(c *gin.Context) {
+	payload, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		utils.BadRequest(c, "Invalid request body", nil)
+		return
+	}
+
+	if err := h.paymentService.HandleWebhook(payload, c.GetHeader("X-Payment-Signature")); err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidWebhook):
+			utils.BadRequest(c, err.Error(), nil)
+		case errors.Is(err, services.ErrCardPaymentsDisabled):
+			utils.NotFound(c, err.Error())
+		default:
+			utils.InternalError(c, err.Error())
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, nil, "Webhook processed")
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/dto"
+	"github.com/telemoz/backend/internal/services"
+	"github.com/telemoz/backend/internal/utils"
+)
+
+type PricingHandler struct {
+	fareTableService services.FareTableService
+}
+
+func NewPricingHandler() *PricingHandler {
+	return &PricingHandler{
+		fareTableService: services.NewFareTableService(),
+	}
+}
+
+// ListFareTables lists every pricing version, optionally filtered by ?service_type=
+func (h *PricingHandler) ListFareTables(c *gin.Context) {
+	tables, err := h.fareTableService.ListFareTables(c.Query("service_type"))
+	if err != nil {
+		utils.InternalError(c, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, tables, "Fare tables retrieved successfully")
+}
+
+// CreateFareTable creates a new pricing version
+func (h *PricingHandler) CreateFareTable(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	adminID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		utils.Unauthorized(c, "Invalid user ID")
+		return
+	}
+
+	var req dto.FareTableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "Invalid request data", err.Error())
+		return
+	}
+
+	table, err := h.fareTableService.CreateFareTable(adminID, &req)
+	if err != nil {
+		utils.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, table, "Fare table created successfully")
+}
+
+// GetFareTable gets a pricing version
+func (h *PricingHandler) GetFareTable(c *gin.Context) {
+	tableID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid fare table ID", nil)
+		return
+	}
+
+	table, err := h.fareTableService.GetFareTable(tableID)
+	if err != nil {
+		utils.NotFound(c, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, table, "Fare table retrieved successfully")
+}
@@ -36,11 +36,13 @@ func (h *ProfileHandler) GetProfile(c *gin.Context) {
 	}
 
 	response := dto.UserResponse{
-		ID:        user.ID.String(),
-		Email:     user.Email,
-		Name:      user.Name,
-		UserType:  string(user.UserType),
-		AvatarURL: user.AvatarURL,
+		ID:          user.ID.String(),
+		Email:       user.Email,
+		Name:        user.Name,
+		UserType:    string(user.UserType),
+		AvatarURL:   user.AvatarURL,
+		Rating:      user.RatingAverage,
+		RatingCount: user.RatingCount,
 	}
 	if user.Phone != nil {
 		response.Phone = user.Phone
@@ -96,11 +98,13 @@ func (h *ProfileHandler) UpdateProfile(c *gin.Context) {
 	}
 
 	response := dto.UserResponse{
-		ID:        user.ID.String(),
-		Email:     user.Email,
-		Name:      user.Name,
-		UserType:  string(user.UserType),
-		AvatarURL: user.AvatarURL,
+		ID:          user.ID.String(),
+		Email:       user.Email,
+		Name:        user.Name,
+		UserType:    string(user.UserType),
+		AvatarURL:   user.AvatarURL,
+		Rating:      user.RatingAverage,
+		RatingCount: user.RatingCount,
 	}
 	if user.Phone != nil {
 		response.Phone = user.Phone
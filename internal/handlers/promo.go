@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/dto"
+	"github.com/telemoz/backend/internal/services"
+	"github.com/telemoz/backend/internal/utils"
+)
+
+type PromoHandler struct {
+	promoService services.PromoService
+}
+
+func NewPromoHandler() *PromoHandler {
+	return &PromoHandler{
+		promoService: services.NewPromoService(),
+	}
+}
+
+// ListPromoCodes lists all promo codes (admin)
+func (h *PromoHandler) ListPromoCodes(c *gin.Context) {
+	promos, err := h.promoService.ListPromoCodes()
+	if err != nil {
+		utils.InternalError(c, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, promos, "Promo codes retrieved successfully")
+}
+
+// CreatePromoCode adds a promo code customers can apply to trips (admin)
+func (h *PromoHandler) CreatePromoCode(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	adminID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		utils.Unauthorized(c, "Invalid user ID")
+		return
+	}
+
+	var req dto.PromoCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "Invalid request data", err.Error())
+		return
+	}
+
+	promo, err := h.promoService.CreatePromoCode(adminID, &req)
+	if err != nil {
+		utils.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, promo, "Promo code created successfully")
+}
+
+// DeactivatePromoCode stops a promo code from being applied to new trips (admin)
+func (h *PromoHandler) DeactivatePromoCode(c *gin.Context) {
+	promoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid promo code ID", nil)
+		return
+	}
+
+	promo, err := h.promoService.DeactivatePromoCode(promoID)
+	if err != nil {
+		if errors.Is(err, services.ErrPromoNotFound) {
+			utils.NotFound(c, err.Error())
+			return
+		}
+		utils.InternalError(c, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, promo, "Promo code deactivated successfully")
+}
+
+// isPromoError reports whether err is a promo code the customer cannot use
+func isPromoError(err error) bool {
+	return errors.Is(err, services.ErrPromoNotFound) || errors.Is(err, services.ErrPromoInactive) ||
+		errors.Is(err, services.ErrPromoNotApplicable) || errors.Is(err, services.ErrPromoLimitReached)
+}
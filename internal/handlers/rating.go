@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/dto"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/services"
+	"github.com/telemoz/backend/internal/utils"
+)
+
+type RatingHandler struct {
+	ratingService services.RatingService
+}
+
+func NewRatingHandler() *RatingHandler {
+	return &RatingHandler{
+		ratingService: services.NewRatingService(),
+	}
+}
+
+// RateTrip rates the driver of the customer's completed trip
+func (h *RatingHandler) RateTrip(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	customerID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		utils.Unauthorized(c, "Invalid user ID")
+		return
+	}
+
+	tripID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid trip ID", nil)
+		return
+	}
+
+	var req dto.RateTripRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "Invalid request data", err.Error())
+		return
+	}
+
+	rating, err := h.ratingService.RateTrip(tripID, customerID, req)
+	if err != nil {
+		respondRatingError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, rating, "Rating submitted successfully")
+}
+
+// RateJob rates the customer of a job the driver completed
+func (h *RatingHandler) RateJob(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	driverID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		utils.Unauthorized(c, "Invalid user ID")
+		return
+	}
+
+	jobID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid job ID", nil)
+		return
+	}
+
+	var req dto.RateTripRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "Invalid request data", err.Error())
+		return
+	}
+
+	rating, err := h.ratingService.RateJob(jobID, driverID, req)
+	if err != nil {
+		respondRatingError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, rating, "Rating submitted successfully")
+}
+
+// GetTags lists the tags the current user can attach to a rating
+func (h *RatingHandler) GetTags(c *gin.Context) {
+	userType, _ := c.Get("user_type")
+	userTypeStr, _ := userType.(string)
+
+	tags := h.ratingService.GetTags(models.UserType(userTypeStr))
+	utils.SuccessResponse(c, http.StatusOK, tags, "Rating tags retrieved successfully")
+}
+
+// ListFlaggedDrivers lists drivers whose rating fell below the review threshold (admin)
+func (h *RatingHandler) ListFlaggedDrivers(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	drivers, err := h.ratingService.ListFlaggedDrivers(limit, offset)
+	if err != nil {
+		utils.InternalError(c, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, drivers, "Flagged drivers retrieved successfully")
+}
+
+func respondRatingError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrRatingTripNotFound):
+		utils.NotFound(c, err.Error())
+	case errors.Is(err, services.ErrAlreadyRated):
+		utils.Conflict(c, err.Error())
+	case errors.Is(err, services.ErrTripNotRateable),
+		errors.Is(err, services.ErrRatingWindowClosed),
+		errors.Is(err, services.ErrInvalidRatingTag):
+		utils.BadRequest(c, err.Error(), nil)
+	default:
+		utils.InternalError(c, err.Error())
+	}
+}
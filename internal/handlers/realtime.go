@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/telemoz/backend/internal/config"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/realtime"
+	"github.com/telemoz/backend/internal/services"
+	"github.com/telemoz/backend/internal/utils"
+)
+
+const (
+	wsWriteWait  = 10 * time.Second
+	wsPongWait   = 60 * time.Second
+	wsPingPeriod = (wsPongWait * 9) / 10
+)
+
+type RealtimeHandler struct {
+	childService services.ChildService
+	hub          *realtime.Hub
+	upgrader     websocket.Upgrader
+}
+
+func NewRealtimeHandler() *RealtimeHandler {
+	return &RealtimeHandler{
+		childService: services.NewChildService(),
+		hub:          realtime.DefaultHub,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			CheckOrigin:     checkWebSocketOrigin,
+		},
+	}
+}
+
+// Connect upgrades the request to a WebSocket and streams the user's events.
+// Every user receives their own trip, job and chat events; parents additionally
+// receive live positions of the buses their children ride.
+func (h *RealtimeHandler) Connect(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		utils.Unauthorized(c, "Invalid user ID")
+		return
+	}
+	userType, _ := c.Get("user_type")
+
+	topics := []string{realtime.UserTopic(userID)}
+	if userType == string(models.UserTypeParent) {
+		children, err := h.childService.ListChildren(userID)
+		if err != nil {
+			utils.InternalError(c, err.Error())
+			return
+		}
+		for _, child := range children {
+			if child.BusID != nil {
+				topics = append(topics, realtime.BusTopic(child.BusID))
+			}
+		}
+	}
+
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// Upgrade has already written an error response
+		return
+	}
+
+	sub := h.hub.Subscribe(topics...)
+	go h.readPump(conn, sub)
+	h.writePump(conn, sub)
+}
+
+// readPump discards client messages and closes the subscription once the client goes away
+func (h *RealtimeHandler) readPump(conn *websocket.Conn, sub *realtime.Subscription) {
+	defer sub.Close()
+
+	conn.SetReadLimit(4096)
+	conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+// writePump forwards hub events to the client and keeps the connection alive with pings
+func (h *RealtimeHandler) writePump(conn *websocket.Conn, sub *realtime.Subscription) {
+	ticker := time.NewTicker(wsPingPeriod)
+	defer func() {
+		ticker.Stop()
+		sub.Close()
+		conn.Close()
+	}()
+
+	for {
+		select {
+		case event, ok := <-sub.Events():
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if !ok {
+				conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := conn.WriteJSON(event); err != nil {
+				return
+			}
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// checkWebSocketOrigin accepts native clients, which send no Origin header, and
+// browsers on the configured CORS origins
+func checkWebSocketOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range config.AppConfig.CORS.AllowedOrigins {
+		if origin == allowed {
+			return true
+		}
+	}
+	return false
+}
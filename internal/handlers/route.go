@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/dto"
+	"github.com/telemoz/backend/internal/services"
+	"github.com/telemoz/backend/internal/utils"
+)
+
+type RouteHandler struct {
+	routeService services.RouteService
+}
+
+func NewRouteHandler() *RouteHandler {
+	return &RouteHandler{
+		routeService: services.NewRouteService(),
+	}
+}
+
+// ListRoutes lists all bus routes
+func (h *RouteHandler) ListRoutes(c *gin.Context) {
+	routes, err := h.routeService.ListRoutes()
+	if err != nil {
+		utils.InternalError(c, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, routes, "Routes retrieved successfully")
+}
+
+// CreateRoute creates a bus route
+func (h *RouteHandler) CreateRoute(c *gin.Context) {
+	var req dto.CreateRouteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "Invalid request data", err.Error())
+		return
+	}
+
+	route, err := h.routeService.CreateRoute(&req)
+	if err != nil {
+		utils.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, route, "Route created successfully")
+}
+
+// GetRoute gets a route with its stops and schedules
+func (h *RouteHandler) GetRoute(c *gin.Context) {
+	routeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid route ID", nil)
+		return
+	}
+
+	route, err := h.routeService.GetRoute(routeID)
+	if err != nil {
+		utils.NotFound(c, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, route, "Route retrieved successfully")
+}
+
+// UpdateRoute updates a route's details and assigned bus
+func (h *RouteHandler) UpdateRoute(c *gin.Context) {
+	routeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid route ID", nil)
+		return
+	}
+
+	var req dto.UpdateRouteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "Invalid request data", err.Error())
+		return
+	}
+
+	route, err := h.routeService.UpdateRoute(routeID, &req)
+	if err != nil {
+		utils.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, route, "Route updated successfully")
+}
+
+// DeleteRoute deletes a route with its stops and schedules
+func (h *RouteHandler) DeleteRoute(c *gin.Context) {
+	routeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid route ID", nil)
+		return
+	}
+
+	if err := h.routeService.DeleteRoute(routeID); err != nil {
+		utils.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, nil, "Route deleted successfully")
+}
+
+// AddStop adds a stop to a route
+func (h *RouteHandler) AddStop(c *gin.Context) {
+	routeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid route ID", nil)
+		return
+	}
+
+	var req dto.RouteStopRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "Invalid request data", err.Error())
+		return
+	}
+
+	stop, err := h.routeService.AddStop(routeID, &req)
+	if err != nil {
+		utils.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, stop, "Stop created successfully")
+}
+
+// UpdateStop updates a stop on a route
+func (h *RouteHandler) UpdateStop(c *gin.Context) {
+	routeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid route ID", nil)
+		return
+	}
+
+	stopID, err := uuid.Parse(c.Param("stopId"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid stop ID", nil)
+		return
+	}
+
+	var req dto.RouteStopRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "Invalid request data", err.Error())
+		return
+	}
+
+	stop, err := h.routeService.UpdateStop(routeID, stopID, &req)
+	if err != nil {
+		utils.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, stop, "Stop updated successfully")
+}
+
+// DeleteStop removes a stop from a route
+func (h *RouteHandler) DeleteStop(c *gin.Context) {
+	routeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid route ID", nil)
+		return
+	}
+
+	stopID, err := uuid.Parse(c.Param("stopId"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid stop ID", nil)
+		return
+	}
+
+	if err := h.routeService.DeleteStop(routeID, stopID); err != nil {
+		utils.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, nil, "Stop deleted successfully")
+}
+
+// AddSchedule adds a timetabled run to a route
+func (h *RouteHandler) AddSchedule(c *gin.Context) {
+	routeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid route ID", nil)
+		return
+	}
+
+	var req dto.ScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "Invalid request data", err.Error())
+		return
+	}
+
+	schedule, err := h.routeService.AddSchedule(routeID, &req)
+	if err != nil {
+		utils.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, schedule, "Schedule created successfully")
+}
+
+// UpdateSchedule replaces a run's calendar and stop times
+func (h *RouteHandler) UpdateSchedule(c *gin.Context) {
+	routeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid route ID", nil)
+		return
+	}
+
+	scheduleID, err := uuid.Parse(c.Param("scheduleId"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid schedule ID", nil)
+		return
+	}
+
+	var req dto.ScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "Invalid request data", err.Error())
+		return
+	}
+
+	schedule, err := h.routeService.UpdateSchedule(routeID, scheduleID, &req)
+	if err != nil {
+		utils.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, schedule, "Schedule updated successfully")
+}
+
+// DeleteSchedule removes a run from a route
+func (h *RouteHandler) DeleteSchedule(c *gin.Context) {
+	routeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid route ID", nil)
+		return
+	}
+
+	scheduleID, err := uuid.Parse(c.Param("scheduleId"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid schedule ID", nil)
+		return
+	}
+
+	if err := h.routeService.DeleteSchedule(routeID, scheduleID); err != nil {
+		utils.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, nil, "Schedule deleted successfully")
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/services"
+	"github.com/telemoz/backend/internal/utils"
+)
+
+type ScheduledTripHandler struct {
+	scheduledTripService services.ScheduledTripService
+}
+
+func NewScheduledTripHandler() *ScheduledTripHandler {
+	return &ScheduledTripHandler{
+		scheduledTripService: services.NewScheduledTripService(),
+	}
+}
+
+// ListScheduledTrips lists the customer's upcoming scheduled trips
+func (h *ScheduledTripHandler) ListScheduledTrips(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	customerID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		utils.Unauthorized(c, "Invalid user ID")
+		return
+	}
+
+	trips, err := h.scheduledTripService.ListCustomerTrips(customerID)
+	if err != nil {
+		utils.InternalError(c, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, trips, "Scheduled trips retrieved successfully")
+}
+
+// ListScheduledJobs lists the scheduled jobs the driver has pre-accepted and the ones
+// still open for their services
+func (h *ScheduledTripHandler) ListScheduledJobs(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	driverID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		utils.Unauthorized(c, "Invalid user ID")
+		return
+	}
+
+	jobs, err := h.scheduledTripService.ListJobs(driverID)
+	if err != nil {
+		utils.InternalError(c, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, jobs, "Scheduled jobs retrieved successfully")
+}
+
+// PreAcceptJob takes a scheduled job ahead of its pickup time
+func (h *ScheduledTripHandler) PreAcceptJob(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	driverID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		utils.Unauthorized(c, "Invalid user ID")
+		return
+	}
+
+	jobID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid job ID", nil)
+		return
+	}
+
+	job, err := h.scheduledTripService.PreAcceptJob(jobID, driverID)
+	if err != nil {
+		respondTripError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, job, "Scheduled job accepted successfully")
+}
+
+// ReleaseJob gives a pre-accepted scheduled job back
+func (h *ScheduledTripHandler) ReleaseJob(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	driverID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		utils.Unauthorized(c, "Invalid user ID")
+		return
+	}
+
+	jobID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid job ID", nil)
+		return
+	}
+
+	if err := h.scheduledTripService.ReleaseJob(jobID, driverID); err != nil {
+		respondTripError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, nil, "Scheduled job released successfully")
+}
+
+// isScheduleError reports whether err is a pickup time trips cannot be booked for
+func isScheduleError(err error) bool {
+	return errors.Is(err, services.ErrInvalidScheduledAt) || errors.Is(err, services.ErrScheduleTooSoon) ||
+		errors.Is(err, services.ErrScheduleTooFar)
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -11,24 +12,33 @@ import (
 )
 
 type TripHandler struct {
-	tripService services.TripService
+	tripService     services.TripService
+	earningsService services.EarningsService
 }
 
 func NewTripHandler() *TripHandler {
 	return &TripHandler{
-		tripService: services.NewTripService(),
+		tripService:     services.NewTripService(),
+		earningsService: services.NewEarningsService(),
 	}
 }
 
 // EstimateFare estimates the fare for a trip
 func (h *TripHandler) EstimateFare(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	customerID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		utils.Unauthorized(c, "Invalid user ID")
+		return
+	}
+
 	var req dto.EstimateFareRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.BadRequest(c, "Invalid request data", nil)
 		return
 	}
 
-	response, err := h.tripService.EstimateFare(req)
+	response, err := h.tripService.EstimateFare(customerID, req)
 	if err != nil {
 		utils.BadRequest(c, err.Error(), nil)
 		return
@@ -53,6 +63,17 @@ func (h *TripHandler) CreateTrip(c *gin.Context) {
 	}
 
 	trip, err := h.tripService.CreateTrip(customerID, req)
+	if errors.Is(err, services.ErrSurgeChanged) || errors.Is(err, services.ErrQuoteExpired) || errors.Is(err, services.ErrQuoteUsed) {
+		utils.Conflict(c, err.Error())
+		return
+	}
+	if errors.Is(err, services.ErrQuoteInvalid) || errors.Is(err, services.ErrQuoteMismatch) ||
+		errors.Is(err, services.ErrCardPaymentsDisabled) || errors.Is(err, services.ErrPaymentMethodRequired) ||
+		errors.Is(err, services.ErrPaymentMethodNotFound) || errors.Is(err, services.ErrInsufficientBalance) ||
+		isPromoError(err) || isScheduleError(err) || isPackageError(err) {
+		utils.BadRequest(c, err.Error(), nil)
+		return
+	}
 	if err != nil {
 		utils.InternalError(c, err.Error())
 		return
@@ -119,6 +140,13 @@ func (h *TripHandler) GetTripByID(c *gin.Context) {
 
 // UpdateTrip updates a trip
 func (h *TripHandler) UpdateTrip(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	customerID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		utils.Unauthorized(c, "Invalid user ID")
+		return
+	}
+
 	tripID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		utils.BadRequest(c, "Invalid trip ID", nil)
@@ -131,16 +159,17 @@ func (h *TripHandler) UpdateTrip(c *gin.Context) {
 		return
 	}
 
-	trip, err := h.tripService.UpdateTrip(tripID, req)
+	trip, err := h.tripService.UpdateTrip(tripID, customerID, req)
 	if err != nil {
-		utils.BadRequest(c, err.Error(), nil)
+		respondTripError(c, err)
 		return
 	}
 
 	utils.SuccessResponse(c, http.StatusOK, trip, "Trip updated successfully")
 }
 
-// CancelTrip cancels a trip
+// CancelTrip cancels a trip, with an optional reason. Late cancellations are charged
+// a fee, returned on the trip.
 func (h *TripHandler) CancelTrip(c *gin.Context) {
 	userIDStr, _ := c.Get("user_id")
 	customerID, err := uuid.Parse(userIDStr.(string))
@@ -155,10 +184,140 @@ func (h *TripHandler) CancelTrip(c *gin.Context) {
 		return
 	}
 
-	if err := h.tripService.CancelTrip(tripID, customerID); err != nil {
+	var req dto.CancelTripRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.BadRequest(c, "Invalid request data", err.Error())
+			return
+		}
+	}
+
+	trip, err := h.tripService.CancelTrip(tripID, customerID, req)
+	if err != nil {
+		respondTripError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, trip, "Trip cancelled successfully")
+}
+
+// GetCancellationFee tells the customer what cancelling the trip would cost now
+func (h *TripHandler) GetCancellationFee(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	customerID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		utils.Unauthorized(c, "Invalid user ID")
+		return
+	}
+
+	tripID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid trip ID", nil)
+		return
+	}
+
+	fee, err := h.tripService.GetCancellationFee(tripID, customerID)
+	if err != nil {
+		respondTripError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, fee, "Cancellation fee retrieved successfully")
+}
+
+// GetTripTimeline gets the status history of a trip for its customer or driver
+func (h *TripHandler) GetTripTimeline(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		utils.Unauthorized(c, "Invalid user ID")
+		return
+	}
+
+	tripID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid trip ID", nil)
+		return
+	}
+
+	timeline, err := h.tripService.GetTripTimeline(tripID, userID)
+	if err != nil {
+		utils.NotFound(c, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, timeline, "Trip timeline retrieved successfully")
+}
+
+// TipTrip tips the driver of a completed trip
+func (h *TripHandler) TipTrip(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	customerID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		utils.Unauthorized(c, "Invalid user ID")
+		return
+	}
+
+	tripID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid trip ID", nil)
+		return
+	}
+
+	var req dto.TipRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "Invalid request data", err.Error())
+		return
+	}
+
+	tip, err := h.earningsService.AddTip(tripID, customerID, req.Amount)
+	if err != nil {
 		utils.BadRequest(c, err.Error(), nil)
 		return
 	}
 
-	utils.SuccessResponse(c, http.StatusOK, nil, "Trip cancelled successfully")
+	utils.SuccessResponse(c, http.StatusCreated, tip, "Tip added successfully")
+}
+
+// GetTripReceipt gets the final fare of a completed trip for its customer or driver
+func (h *TripHandler) GetTripReceipt(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		utils.Unauthorized(c, "Invalid user ID")
+		return
+	}
+
+	tripID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid trip ID", nil)
+		return
+	}
+
+	receipt, err := h.tripService.GetTripReceipt(tripID, userID)
+	if err != nil {
+		utils.NotFound(c, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, receipt, "Trip receipt retrieved successfully")
+}
+
+// respondTripError answers rejected or lost-race status changes, including a job
+// another driver accepted first, with 409 and anything else with 400
+func respondTripError(c *gin.Context, err error) {
+	var transitionErr *services.TransitionError
+	if errors.As(err, &transitionErr) || errors.Is(err, services.ErrTripStatusChanged) || errors.Is(err, services.ErrJobTaken) ||
+		errors.Is(err, services.ErrNoShowTooEarly) || errors.Is(err, services.ErrScheduleConflict) ||
+		errors.Is(err, services.ErrWaypointOutOfOrder) || errors.Is(err, services.ErrWaypointsOutstanding) {
+		utils.Conflict(c, err.Error())
+		return
+	}
+	utils.BadRequest(c, err.Error(), nil)
+}
+
+// isPackageError reports whether err is a delivery's package details being refused
+func isPackageError(err error) bool {
+	return errors.Is(err, services.ErrPackageRequired) || errors.Is(err, services.ErrPackageNotAllowed) ||
+		errors.Is(err, services.ErrPackageTooLarge)
 }
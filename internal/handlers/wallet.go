@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/dto"
+	"github.com/telemoz/backend/internal/services"
+	"github.com/telemoz/backend/internal/utils"
+)
+
+type WalletHandler struct {
+	walletService services.WalletService
+}
+
+func NewWalletHandler() *WalletHandler {
+	return &WalletHandler{
+		walletService: services.NewWalletService(),
+	}
+}
+
+// GetWallet gets the customer's wallet balance
+func (h *WalletHandler) GetWallet(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	customerID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		utils.Unauthorized(c, "Invalid user ID")
+		return
+	}
+
+	wallet, err := h.walletService.GetWallet(customerID)
+	if err != nil {
+		utils.InternalError(c, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, wallet, "Wallet retrieved successfully")
+}
+
+// ListTransactions lists the customer's wallet transactions, newest first
+func (h *WalletHandler) ListTransactions(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	customerID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		utils.Unauthorized(c, "Invalid user ID")
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	transactions, err := h.walletService.ListTransactions(customerID, limit, offset)
+	if err != nil {
+		utils.InternalError(c, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, transactions, "Wallet transactions retrieved successfully")
+}
+
+// TopUp charges a saved card and adds the amount to the customer's wallet
+func (h *WalletHandler) TopUp(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	customerID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		utils.Unauthorized(c, "Invalid user ID")
+		return
+	}
+
+	var req dto.TopUpRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "Invalid request data", err.Error())
+		return
+	}
+
+	transaction, err := h.walletService.TopUp(customerID, req)
+	if err != nil {
+		utils.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, transaction, "Wallet topped up successfully")
+}
+
+// CreditWallet adds a refund, referral credit or adjustment to a customer's wallet (admin)
+func (h *WalletHandler) CreditWallet(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	adminID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		utils.Unauthorized(c, "Invalid user ID")
+		return
+	}
+
+	customerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid customer ID", nil)
+		return
+	}
+
+	var req dto.WalletCreditRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "Invalid request data", err.Error())
+		return
+	}
+
+	transaction, err := h.walletService.Credit(customerID, adminID, req)
+	if err != nil {
+		utils.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, transaction, "Wallet credited successfully")
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/dto"
+	"github.com/telemoz/backend/internal/services"
+	"github.com/telemoz/backend/internal/utils"
+)
+
+type WaypointHandler struct {
+	waypointService services.WaypointService
+}
+
+func NewWaypointHandler() *WaypointHandler {
+	return &WaypointHandler{
+		waypointService: services.NewWaypointService(),
+	}
+}
+
+// ArriveAtWaypoint records the driver reaching the next stop of their job
+func (h *WaypointHandler) ArriveAtWaypoint(c *gin.Context) {
+	driverID, jobID, waypointID, ok := parseWaypointRequest(c)
+	if !ok {
+		return
+	}
+
+	waypoint, err := h.waypointService.ArriveAtWaypoint(jobID, waypointID, driverID)
+	if err != nil {
+		respondTripError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, waypoint, "Arrival at stop recorded successfully")
+}
+
+// CompleteWaypoint completes the next stop of the driver's job. It takes a multipart
+// form; a signature or photo is uploaded as the "proof" file.
+func (h *WaypointHandler) CompleteWaypoint(c *gin.Context) {
+	driverID, jobID, waypointID, ok := parseWaypointRequest(c)
+	if !ok {
+		return
+	}
+
+	var req dto.CompleteWaypointRequest
+	if err := c.ShouldBind(&req); err != nil {
+		utils.BadRequest(c, "Invalid request data", err.Error())
+		return
+	}
+
+	var proof *services.ProofImage
+	file, err := c.FormFile("proof")
+	if err == nil {
+		content, err := file.Open()
+		if err != nil {
+			utils.BadRequest(c, "Invalid proof file", nil)
+			return
+		}
+		defer content.Close()
+		proof = &services.ProofImage{Content: content}
+	} else if !errors.Is(err, http.ErrMissingFile) && !errors.Is(err, http.ErrNotMultipart) {
+		utils.BadRequest(c, "Invalid proof file", err.Error())
+		return
+	}
+
+	waypoint, err := h.waypointService.CompleteWaypoint(jobID, waypointID, driverID, req, proof)
+	if err != nil {
+		respondTripError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, waypoint, "Stop completed successfully")
+}
+
+// FailWaypoint records that the next stop of the driver's job couldn't be completed
+func (h *WaypointHandler) FailWaypoint(c *gin.Context) {
+	driverID, jobID, waypointID, ok := parseWaypointRequest(c)
+	if !ok {
+		return
+	}
+
+	var req dto.FailWaypointRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "Invalid request data", err.Error())
+		return
+	}
+
+	waypoint, err := h.waypointService.FailWaypoint(jobID, waypointID, driverID, req)
+	if err != nil {
+		respondTripError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, waypoint, "Stop marked as failed")
+}
+
+// GetProof downloads the signature or photo taken at a stop (customer or assigned driver)
+func (h *WaypointHandler) GetProof(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		utils.Unauthorized(c, "Invalid user ID")
+		return
+	}
+
+	tripID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid trip ID", nil)
+		return
+	}
+	waypointID, err := uuid.Parse(c.Param("waypointId"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid waypoint ID", nil)
+		return
+	}
+
+	proof, err := h.waypointService.GetProof(tripID, waypointID, userID)
+	if err != nil {
+		utils.NotFound(c, err.Error())
+		return
+	}
+
+	c.Data(http.StatusOK, proof.ContentType, proof.Content)
+}
+
+// parseWaypointRequest reads the driver, job and waypoint of a stop update, responding
+// with an error when one is invalid
+func parseWaypointRequest(c *gin.Context) (uuid.UUID, uuid.UUID, uuid.UUID, bool) {
+	userIDStr, _ := c.Get("user_id")
+	driverID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		utils.Unauthorized(c, "Invalid user ID")
+		return uuid.Nil, uuid.Nil, uuid.Nil, false
+	}
+
+	jobID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid job ID", nil)
+		return uuid.Nil, uuid.Nil, uuid.Nil, false
+	}
+	waypointID, err := uuid.Parse(c.Param("waypointId"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid waypoint ID", nil)
+		return uuid.Nil, uuid.Nil, uuid.Nil, false
+	}
+	return driverID, jobID, waypointID, true
+}
@@ -0,0 +1,23 @@
+package jobs
+
+import (
+	"time"
+
+	"github.com/telemoz/backend/internal/services"
+)
+
+// StartDispatchJob runs a background job that moves searching trips through their dispatch waves
+func StartDispatchJob(dispatchService services.DispatchService) {
+	ticker := time.NewTicker(5 * time.Second) // Run every 5 seconds
+
+	go func() {
+		for range ticker.C {
+			if err := dispatchService.AdvanceDispatch(); err != nil {
+				// Log error but continue
+				println("Error advancing dispatch:", err.Error())
+			}
+		}
+	}()
+
+	println("🚕 Dispatch background job started (runs every 5s)")
+}
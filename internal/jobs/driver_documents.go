@@ -0,0 +1,24 @@
+package jobs
+
+import (
+	"time"
+
+	"github.com/telemoz/backend/internal/services"
+)
+
+// StartDocumentExpiryJob runs a daily background job that warns drivers whose
+// documents are about to expire
+func StartDocumentExpiryJob(complianceService services.DriverComplianceService) {
+	ticker := time.NewTicker(24 * time.Hour) // Run daily
+
+	go func() {
+		for range ticker.C {
+			if err := complianceService.WarnExpiringDocuments(time.Now()); err != nil {
+				// Log error but continue
+				println("Error warning drivers about expiring documents:", err.Error())
+			}
+		}
+	}()
+
+	println("🪪 Driver document expiry background job started (runs every 24h)")
+}
@@ -0,0 +1,26 @@
+package jobs
+
+import (
+	"time"
+
+	"github.com/telemoz/backend/internal/services"
+)
+
+// StartDriverSweeperJob runs a background job that marks drivers offline once their heartbeat goes stale
+func StartDriverSweeperJob(availabilityService services.DriverAvailabilityService, timeout time.Duration) {
+	ticker := time.NewTicker(30 * time.Second) // Run every 30 seconds
+
+	go func() {
+		for range ticker.C {
+			count, err := availabilityService.MarkStaleDriversOffline(timeout)
+			if err != nil {
+				// Log error but continue
+				println("Error sweeping stale drivers:", err.Error())
+			} else if count > 0 {
+				println("✅ Marked", count, "stale drivers offline")
+			}
+		}
+	}()
+
+	println("🕐 Driver heartbeat sweeper started (runs every 30s)")
+}
@@ -6,7 +6,7 @@ import (
 	"github.com/telemoz/backend/internal/services"
 )
 
-// StartTripExpirationJob runs a background job to expire trips that have been searching for >3 minutes
+// StartTripExpirationJob runs a background job to expire trips left searching after every dispatch wave has ended
 func StartTripExpirationJob(tripService services.TripService) {
 	ticker := time.NewTicker(30 * time.Second) // Run every 30 seconds
 
@@ -0,0 +1,27 @@
+package jobs
+
+import (
+	"time"
+
+	"github.com/telemoz/backend/internal/services"
+)
+
+// StartScheduledTripJob runs a background job that sends reminders for upcoming
+// scheduled trips and dispatches them shortly before their pickup time
+func StartScheduledTripJob(scheduledTripService services.ScheduledTripService, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		for range ticker.C {
+			if err := scheduledTripService.ProcessDue(time.Now()); err != nil {
+				// Log error but continue
+				println("Error processing scheduled trips:", err.Error())
+			}
+		}
+	}()
+
+	println("📅 Scheduled trip background job started (runs every", interval.String()+")")
+}
@@ -0,0 +1,24 @@
+package jobs
+
+import (
+	"time"
+
+	"github.com/telemoz/backend/internal/services"
+)
+
+// StartSettlementJob runs a background job that settles last week's driver earnings
+// and pays drivers once the settlement delay has passed
+func StartSettlementJob(payoutService services.PayoutService) {
+	ticker := time.NewTicker(time.Hour) // Run every hour
+
+	go func() {
+		for range ticker.C {
+			if err := payoutService.SettleDuePeriods(time.Now()); err != nil {
+				// Log error but continue
+				println("Error settling driver payouts:", err.Error())
+			}
+		}
+	}()
+
+	println("💸 Payout settlement background job started (runs every 1h)")
+}
@@ -0,0 +1,27 @@
+package jobs
+
+import (
+	"time"
+
+	"github.com/telemoz/backend/internal/services"
+)
+
+// StartSurgeJob runs a background job that recomputes surge multipliers from current
+// demand and supply
+func StartSurgeJob(surgeService services.SurgeService, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		for range ticker.C {
+			if err := surgeService.Recompute(time.Now()); err != nil {
+				// Log error but continue
+				println("Error recomputing surge multipliers:", err.Error())
+			}
+		}
+	}()
+
+	println("📈 Surge pricing background job started (runs every", interval.String()+")")
+}
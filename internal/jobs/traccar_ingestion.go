@@ -0,0 +1,195 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/pkg/traccar"
+	"gorm.io/gorm"
+)
+
+// Traccar reports speed in knots; bus locations are stored in km/h
+const knotsToKmh = 1.852
+
+// How long a device → bus mapping is trusted before it is looked up again
+const busCacheTTL = 5 * time.Minute
+
+// BusLookup resolves the bus a Traccar device is installed in
+type BusLookup interface {
+	FindByTraccarDeviceID(deviceID string) (*models.Bus, error)
+}
+
+// BusLocationRecorder persists a bus position
+type BusLocationRecorder interface {
+	RecordBusLocation(location *models.BusLocation) error
+}
+
+type cachedBus struct {
+	busID     uuid.UUID // uuid.Nil when no bus uses the device
+	expiresAt time.Time
+}
+
+// TraccarIngestionWorker streams positions from the Traccar WebSocket into
+// bus_locations, reconnecting with exponential backoff whenever the socket drops
+type TraccarIngestionWorker struct {
+	newClient  func() *traccar.WebSocketClient
+	buses      BusLookup
+	recorder   BusLocationRecorder
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	mu             sync.Mutex
+	busCache       map[int]cachedBus
+	lastPositionID map[int]int
+}
+
+func NewTraccarIngestionWorker(
+	newClient func() *traccar.WebSocketClient,
+	buses BusLookup,
+	recorder BusLocationRecorder,
+	minBackoff, maxBackoff time.Duration,
+) *TraccarIngestionWorker {
+	return &TraccarIngestionWorker{
+		newClient:      newClient,
+		buses:          buses,
+		recorder:       recorder,
+		minBackoff:     minBackoff,
+		maxBackoff:     maxBackoff,
+		busCache:       make(map[int]cachedBus),
+		lastPositionID: make(map[int]int),
+	}
+}
+
+// StartTraccarIngestionJob runs the ingestion worker in the background for the lifetime of the process
+func StartTraccarIngestionJob(worker *TraccarIngestionWorker) {
+	go worker.Run(context.Background())
+
+	println("📡 Traccar position ingestion started")
+}
+
+// Run keeps a Traccar connection open until ctx is cancelled
+func (w *TraccarIngestionWorker) Run(ctx context.Context) {
+	backoff := w.minBackoff
+
+	for {
+		client := w.newClient()
+		client.OnPosition(func(pos traccar.Position) {
+			if err := w.HandlePosition(pos); err != nil {
+				log.Printf("Traccar ingestion: failed to store position %d of device %d: %v", pos.ID, pos.DeviceID, err)
+			}
+		})
+
+		connectedAt := time.Now()
+		if err := client.Connect(); err != nil {
+			log.Printf("Traccar ingestion: %v", err)
+		} else {
+			select {
+			case <-ctx.Done():
+				client.Close()
+				return
+			case <-client.Done():
+				client.Close()
+			}
+
+			// A connection that stayed up for a while was healthy; start over with a short delay
+			if time.Since(connectedAt) > w.maxBackoff {
+				backoff = w.minBackoff
+			}
+		}
+
+		log.Printf("Traccar ingestion: reconnecting in %s", backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > w.maxBackoff {
+			backoff = w.maxBackoff
+		}
+	}
+}
+
+// HandlePosition maps a Traccar position to its bus and stores it. Positions from
+// devices that are not installed in a bus and positions already stored are skipped.
+func (w *TraccarIngestionWorker) HandlePosition(pos traccar.Position) error {
+	if !w.isNewPosition(pos) {
+		return nil
+	}
+
+	busID, err := w.resolveBus(pos.DeviceID)
+	if err != nil {
+		return err
+	}
+	if busID == uuid.Nil {
+		return nil
+	}
+
+	location := &models.BusLocation{
+		BusID:     busID,
+		Latitude:  pos.Latitude,
+		Longitude: pos.Longitude,
+		Timestamp: parseFixTime(pos.FixTime),
+	}
+	speed := pos.Speed * knotsToKmh
+	location.Speed = &speed
+	heading := pos.Course
+	location.Heading = &heading
+	if pos.Accuracy > 0 {
+		accuracy := pos.Accuracy
+		location.Accuracy = &accuracy
+	}
+
+	return w.recorder.RecordBusLocation(location)
+}
+
+// isNewPosition drops positions Traccar replays, e.g. the latest positions it sends on every reconnect
+func (w *TraccarIngestionWorker) isNewPosition(pos traccar.Position) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if pos.ID > 0 && pos.ID <= w.lastPositionID[pos.DeviceID] {
+		return false
+	}
+	w.lastPositionID[pos.DeviceID] = pos.ID
+	return true
+}
+
+func (w *TraccarIngestionWorker) resolveBus(deviceID int) (uuid.UUID, error) {
+	now := time.Now()
+
+	w.mu.Lock()
+	cached, ok := w.busCache[deviceID]
+	w.mu.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.busID, nil
+	}
+
+	busID := uuid.Nil
+	bus, err := w.buses.FindByTraccarDeviceID(strconv.Itoa(deviceID))
+	if err == nil {
+		busID = bus.ID
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return uuid.Nil, err
+	}
+
+	w.mu.Lock()
+	w.busCache[deviceID] = cachedBus{busID: busID, expiresAt: now.Add(busCacheTTL)}
+	w.mu.Unlock()
+
+	return busID, nil
+}
+
+func parseFixTime(fixTime string) time.Time {
+	if parsed, err := time.Parse(time.RFC3339, fixTime); err == nil {
+		return parsed
+	}
+	return time.Now()
+}
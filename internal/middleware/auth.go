@@ -40,6 +40,24 @@ func AuthMiddleware() gin.HandlerFunc {
 	}
 }
 
+// TokenFromQuery lets clients that cannot set headers on a WebSocket handshake pass
+// the access token as the "token" query parameter. It must run before AuthMiddleware.
+// The token is taken out of the URL so it isn't logged.
+func TokenFromQuery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := c.Request.URL.Query()
+		if token := query.Get("token"); token != "" {
+			if c.GetHeader("Authorization") == "" {
+				c.Request.Header.Set("Authorization", "Bearer "+token)
+			}
+			query.Del("token")
+			c.Request.URL.RawQuery = query.Encode()
+		}
+
+		c.Next()
+	}
+}
+
 func RequireUserType(allowedTypes ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userType, exists := c.Get("user_type")
@@ -11,10 +11,12 @@ func Logger(logger *zap.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
 		path := c.Request.URL.Path
-		query := c.Request.URL.RawQuery
 
 		c.Next()
 
+		// Read once the handlers have run, so credentials TokenFromQuery takes out
+		// of the query aren't logged
+		query := c.Request.URL.RawQuery
 		latency := time.Since(start)
 		logger.Info("HTTP Request",
 			zap.Int("status", c.Writer.Status()),
@@ -21,6 +21,7 @@ type Bus struct {
 	Driver   User           `gorm:"foreignKey:DriverID" json:"driver,omitempty"`
 	Children []Child        `gorm:"foreignKey:BusID" json:"children,omitempty"`
 	Locations []BusLocation `gorm:"foreignKey:BusID" json:"locations,omitempty"`
+	Routes    []Route       `gorm:"foreignKey:BusID" json:"routes,omitempty"`
 }
 
 func (b *Bus) BeforeCreate(tx *gorm.DB) error {
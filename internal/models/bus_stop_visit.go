@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BusStopVisit tracks one approach of a bus to a route stop so that each alert is
+// sent at most once per visit. The visit closes when the bus moves out of nearby range.
+type BusStopVisit struct {
+	ID              uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	BusID           uuid.UUID  `gorm:"type:uuid;not null;index:idx_bus_stop_visit_open" json:"bus_id"`
+	StopID          uuid.UUID  `gorm:"type:uuid;not null;index:idx_bus_stop_visit_open" json:"stop_id"`
+	NearbyAlertedAt *time.Time `json:"nearby_alerted_at,omitempty"`
+	ArrivedAt       *time.Time `json:"arrived_at,omitempty"`
+	DepartedAt      *time.Time `json:"departed_at,omitempty"`
+	ClosedAt        *time.Time `gorm:"index:idx_bus_stop_visit_open" json:"closed_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+func (v *BusStopVisit) BeforeCreate(tx *gorm.DB) error {
+	if v.ID == uuid.Nil {
+		v.ID = uuid.New()
+	}
+	return nil
+}
+
+// BusStopAlert records a nearby alert sent to a parent for one run of a bus through
+// a stop. The unique index makes claiming an alert atomic when ETA and distance
+// checks race each other.
+type BusStopAlert struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_bus_stop_alert_claim" json:"user_id"`
+	StopID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_bus_stop_alert_claim" json:"stop_id"`
+	RunKey    string    `gorm:"type:varchar(100);not null;uniqueIndex:idx_bus_stop_alert_claim" json:"run_key"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (a *BusStopAlert) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
@@ -8,18 +8,22 @@ import (
 )
 
 type Child struct {
-	ID         uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
-	ParentID   uuid.UUID `gorm:"type:uuid;not null;index" json:"parent_id"`
-	Name       string    `gorm:"not null" json:"name"`
-	SchoolName *string   `gorm:"type:varchar(255)" json:"school_name,omitempty"`
-	BusID      *uuid.UUID `gorm:"type:uuid;index" json:"bus_id,omitempty"`
-	AvatarURL  *string   `json:"avatar_url,omitempty"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	ID            uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	ParentID      uuid.UUID  `gorm:"type:uuid;not null;index" json:"parent_id"`
+	Name          string     `gorm:"not null" json:"name"`
+	SchoolName    *string    `gorm:"type:varchar(255)" json:"school_name,omitempty"`
+	BusID         *uuid.UUID `gorm:"type:uuid;index" json:"bus_id,omitempty"`
+	PickupStopID  *uuid.UUID `gorm:"type:uuid;index" json:"pickup_stop_id,omitempty"`
+	DropoffStopID *uuid.UUID `gorm:"type:uuid;index" json:"dropoff_stop_id,omitempty"`
+	AvatarURL     *string    `json:"avatar_url,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
 
 	// Relations
-	Parent User  `gorm:"foreignKey:ParentID" json:"parent,omitempty"`
-	Bus    *Bus  `gorm:"foreignKey:BusID" json:"bus,omitempty"`
+	Parent      User       `gorm:"foreignKey:ParentID" json:"parent,omitempty"`
+	Bus         *Bus       `gorm:"foreignKey:BusID" json:"bus,omitempty"`
+	PickupStop  *RouteStop `gorm:"foreignKey:PickupStopID" json:"pickup_stop,omitempty"`
+	DropoffStop *RouteStop `gorm:"foreignKey:DropoffStopID" json:"dropoff_stop,omitempty"`
 }
 
 func (c *Child) BeforeCreate(tx *gorm.DB) error {
@@ -28,4 +32,3 @@ func (c *Child) BeforeCreate(tx *gorm.DB) error {
 	}
 	return nil
 }
-
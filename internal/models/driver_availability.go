@@ -1,18 +1,97 @@
 package models
 
 import (
+	"database/sql/driver"
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+// StringArray maps a Go string slice to a PostgreSQL text[] column
+type StringArray []string
+
+func (a StringArray) Value() (driver.Value, error) {
+	if a == nil {
+		return "{}", nil
+	}
+	quoted := make([]string, len(a))
+	for i, item := range a {
+		item = strings.ReplaceAll(item, `\`, `\\`)
+		item = strings.ReplaceAll(item, `"`, `\"`)
+		quoted[i] = `"` + item + `"`
+	}
+	return "{" + strings.Join(quoted, ",") + "}", nil
+}
+
+func (a *StringArray) Scan(value interface{}) error {
+	if value == nil {
+		*a = StringArray{}
+		return nil
+	}
+
+	var literal string
+	switch v := value.(type) {
+	case []byte:
+		literal = string(v)
+	case string:
+		literal = v
+	default:
+		return errors.New("unsupported type for StringArray")
+	}
+
+	if len(literal) < 2 || literal[0] != '{' || literal[len(literal)-1] != '}' {
+		return errors.New("invalid array literal")
+	}
+
+	result := StringArray{}
+	body := literal[1 : len(literal)-1]
+	if body == "" {
+		*a = result
+		return nil
+	}
+
+	var current strings.Builder
+	inQuotes := false
+	for i := 0; i < len(body); i++ {
+		char := body[i]
+		switch {
+		case char == '\\' && i+1 < len(body):
+			i++
+			current.WriteByte(body[i])
+		case char == '"':
+			inQuotes = !inQuotes
+		case char == ',' && !inQuotes:
+			result = append(result, current.String())
+			current.Reset()
+		default:
+			current.WriteByte(char)
+		}
+	}
+	result = append(result, current.String())
+
+	*a = result
+	return nil
+}
+
 type DriverAvailability struct {
-	DriverID     uuid.UUID `gorm:"type:uuid;primary_key" json:"driver_id"`
-	IsAvailable  bool      `gorm:"default:false;index" json:"is_available"`
-	ServiceTypes []string  `gorm:"type:text[]" json:"service_types"`
-	LastActiveAt time.Time `json:"last_active_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	DriverID     uuid.UUID   `gorm:"type:uuid;primary_key" json:"driver_id"`
+	IsAvailable  bool        `gorm:"default:false;index" json:"is_available"`
+	ServiceTypes StringArray `gorm:"type:text[]" json:"service_types"`
+	// VehicleClass limits the deliveries the driver is offered to packages that fit
+	VehicleClass VehicleClass `gorm:"type:varchar(10);not null;default:'car'" json:"vehicle_class"`
+	LastActiveAt time.Time    `json:"last_active_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+
+	// Last known position, used to rank drivers for dispatch
+	Latitude          *float64   `gorm:"type:decimal(10,8)" json:"latitude,omitempty"`
+	Longitude         *float64   `gorm:"type:decimal(11,8)" json:"longitude,omitempty"`
+	Accuracy          *float64   `gorm:"type:decimal(10,2)" json:"accuracy,omitempty"`
+	Speed             *float64   `gorm:"type:decimal(10,2)" json:"speed,omitempty"`
+	Heading           *float64   `gorm:"type:decimal(5,2)" json:"heading,omitempty"`
+	LocationUpdatedAt *time.Time `json:"location_updated_at,omitempty"`
 
 	// Relations
 	Driver User `gorm:"foreignKey:DriverID" json:"driver,omitempty"`
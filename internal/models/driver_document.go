@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type DocumentType string
+
+const (
+	DocumentTypeLicense      DocumentType = "license"
+	DocumentTypeInsurance    DocumentType = "insurance"
+	DocumentTypeRegistration DocumentType = "registration"
+)
+
+type DocumentStatus string
+
+const (
+	DocumentStatusPending  DocumentStatus = "pending"
+	DocumentStatusApproved DocumentStatus = "approved"
+	DocumentStatusRejected DocumentStatus = "rejected"
+)
+
+// DriverDocument is a scan of a driver's license, insurance or vehicle registration,
+// reviewed by an admin. Renewing a document uploads a new one; older uploads are kept.
+type DriverDocument struct {
+	ID        uuid.UUID      `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	DriverID  uuid.UUID      `gorm:"type:uuid;not null;index" json:"driver_id"`
+	Type      DocumentType   `gorm:"type:varchar(20);not null;index" json:"type"`
+	Status    DocumentStatus `gorm:"type:varchar(20);not null;default:'pending';index" json:"status"`
+	FileKey   string         `gorm:"type:varchar(255);not null" json:"-"`
+	ExpiresAt time.Time      `gorm:"not null;index" json:"expires_at"`
+
+	RejectionReason *string    `gorm:"type:text" json:"rejection_reason,omitempty"`
+	ReviewedBy      *uuid.UUID `gorm:"type:uuid" json:"reviewed_by,omitempty"`
+	ReviewedAt      *time.Time `json:"reviewed_at,omitempty"`
+	// ExpiryWarnedAt is when the driver was warned the document is about to expire
+	ExpiryWarnedAt *time.Time `json:"expiry_warned_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Relations
+	Driver User `gorm:"foreignKey:DriverID" json:"driver,omitempty"`
+}
+
+func (d *DriverDocument) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	return nil
+}
@@ -7,13 +7,36 @@ import (
 	"gorm.io/gorm"
 )
 
+type EarningType string
+
+const (
+	EarningTypeTrip       EarningType = "trip"
+	EarningTypeTip        EarningType = "tip"
+	EarningTypeAdjustment EarningType = "adjustment"
+	// EarningTypeCancellation compensates the driver out of a customer's cancellation
+	// or no-show fee
+	EarningTypeCancellation EarningType = "cancellation"
+)
+
+// DriverEarning is an entry in a driver's earnings ledger. Entries are never edited;
+// corrections are appended as adjustment entries pointing at the entry they correct.
+// A job has at most one tip entry.
 type DriverEarning struct {
-	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
-	DriverID  uuid.UUID `gorm:"type:uuid;not null;index" json:"driver_id"`
-	JobID     uuid.UUID `gorm:"type:uuid;not null" json:"job_id"`
-	Amount    float64   `gorm:"type:decimal(10,2);not null" json:"amount"`
-	Date      time.Time `gorm:"type:date;not null;index" json:"date"`
-	CreatedAt time.Time `json:"created_at"`
+	ID       uuid.UUID   `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	DriverID uuid.UUID   `gorm:"type:uuid;not null;index" json:"driver_id"`
+	JobID    uuid.UUID   `gorm:"type:uuid;not null;index;uniqueIndex:idx_driver_earnings_job_tip,where:type = 'tip'" json:"job_id"`
+	Type     EarningType `gorm:"type:varchar(20);not null;default:'trip';index;uniqueIndex:idx_driver_earnings_job_tip,where:type = 'tip'" json:"type"`
+	// GrossAmount is the fare, tip or adjustment before commission
+	GrossAmount    float64 `gorm:"type:decimal(10,2);not null;default:0" json:"gross_amount"`
+	CommissionRate float64 `gorm:"type:decimal(5,2);not null;default:0" json:"commission_rate"`
+	Commission     float64 `gorm:"type:decimal(10,2);not null;default:0" json:"commission"`
+	// Amount is what the driver is credited: GrossAmount less Commission
+	Amount    float64    `gorm:"type:decimal(10,2);not null" json:"amount"`
+	Reason    *string    `gorm:"type:text" json:"reason,omitempty"`
+	AdjustsID *uuid.UUID `gorm:"type:uuid" json:"adjusts_id,omitempty"`
+	CreatedBy *uuid.UUID `gorm:"type:uuid" json:"created_by,omitempty"`
+	Date      time.Time  `gorm:"type:date;not null;index" json:"date"`
+	CreatedAt time.Time  `json:"created_at"`
 
 	// Relations
 	Driver User `gorm:"foreignKey:DriverID" json:"driver,omitempty"`
@@ -26,4 +49,3 @@ func (de *DriverEarning) BeforeCreate(tx *gorm.DB) error {
 	}
 	return nil
 }
-
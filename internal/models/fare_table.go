@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// FareTable is one version of the prices of a service type in a zone. Versions are
+// never edited; a price change is a new version effective from a later time.
+type FareTable struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	ServiceType string    `gorm:"type:varchar(20);not null;uniqueIndex:idx_fare_table_version" json:"service_type"`
+	// Zone is the geohash prefix the prices apply in, e.g. a city; empty applies everywhere
+	Zone          string     `gorm:"type:varchar(12);not null;default:'';uniqueIndex:idx_fare_table_version" json:"zone"`
+	Name          string     `gorm:"type:varchar(100)" json:"name"`
+	Version       int        `gorm:"not null;uniqueIndex:idx_fare_table_version" json:"version"`
+	EffectiveFrom time.Time  `gorm:"not null;index" json:"effective_from"`
+	EffectiveTo   *time.Time `json:"effective_to,omitempty"`
+	// Night surcharge hours are wall-clock times in this location
+	Timezone      string  `gorm:"type:varchar(64);not null;default:'UTC'" json:"timezone"`
+	BaseFare      float64 `gorm:"type:decimal(10,2);not null" json:"base_fare"`
+	PerKmRate     float64 `gorm:"type:decimal(10,2);not null" json:"per_km_rate"`
+	PerMinuteRate float64 `gorm:"type:decimal(10,2);not null;default:0" json:"per_minute_rate"`
+	MinimumFare   float64 `gorm:"type:decimal(10,2);not null;default:0" json:"minimum_fare"`
+	BookingFee    float64 `gorm:"type:decimal(10,2);not null;default:0" json:"booking_fee"`
+	// Waiting in traffic or at stops beyond FreeWaitingMinutes costs WaitingPerMinuteRate
+	WaitingPerMinuteRate float64 `gorm:"type:decimal(10,2);not null;default:0" json:"waiting_per_minute_rate"`
+	FreeWaitingMinutes   float64 `gorm:"type:decimal(6,2);not null;default:0" json:"free_waiting_minutes"`
+	// Trips starting between NightStart and NightEnd ("HH:MM", may wrap past midnight)
+	// cost NightSurchargePercent more
+	NightSurchargePercent float64 `gorm:"type:decimal(5,2);not null;default:0" json:"night_surcharge_percent"`
+	NightStart            *string `gorm:"type:varchar(5)" json:"night_start,omitempty"`
+	NightEnd              *string `gorm:"type:varchar(5)" json:"night_end,omitempty"`
+	// Trips picking up or dropping off in one of AirportZones (geohash prefixes) pay
+	// AirportSurcharge once
+	AirportSurcharge float64     `gorm:"type:decimal(10,2);not null;default:0" json:"airport_surcharge"`
+	AirportZones     StringArray `gorm:"type:text[]" json:"airport_zones"`
+	CreatedBy        *uuid.UUID  `gorm:"type:uuid" json:"created_by,omitempty"`
+	CreatedAt        time.Time   `json:"created_at"`
+}
+
+func (f *FareTable) BeforeCreate(tx *gorm.DB) error {
+	if f.ID == uuid.Nil {
+		f.ID = uuid.New()
+	}
+	return nil
+}
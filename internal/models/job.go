@@ -12,9 +12,13 @@ type JobStatus string
 const (
 	JobStatusPending    JobStatus = "pending"
 	JobStatusAccepted   JobStatus = "accepted"
+	JobStatusArrived    JobStatus = "arrived"
 	JobStatusRejected   JobStatus = "rejected"
 	JobStatusInProgress JobStatus = "in_progress"
 	JobStatusCompleted  JobStatus = "completed"
+	JobStatusExpired    JobStatus = "expired"
+	JobStatusCancelled  JobStatus = "cancelled"
+	JobStatusNoShow     JobStatus = "no_show"
 )
 
 type Job struct {
@@ -25,6 +29,8 @@ type Job struct {
 	EstimatedEarnings *float64  `gorm:"type:decimal(10,2)" json:"estimated_earnings,omitempty"`
 	ActualEarnings  *float64   `gorm:"type:decimal(10,2)" json:"actual_earnings,omitempty"`
 	Distance        *float64   `gorm:"type:decimal(10,2)" json:"distance,omitempty"`
+	DispatchWave    int        `gorm:"default:0" json:"dispatch_wave"`
+	WaveEndsAt      *time.Time `gorm:"index" json:"wave_ends_at,omitempty"`
 	AcceptedAt      *time.Time `json:"accepted_at,omitempty"`
 	CompletedAt     *time.Time `json:"completed_at,omitempty"`
 	CreatedAt       time.Time  `json:"created_at"`
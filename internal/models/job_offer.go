@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type JobOfferStatus string
+
+const (
+	JobOfferStatusOffered   JobOfferStatus = "offered"
+	JobOfferStatusAccepted  JobOfferStatus = "accepted"
+	JobOfferStatusDeclined  JobOfferStatus = "declined"
+	JobOfferStatusExpired   JobOfferStatus = "expired"
+	JobOfferStatusWithdrawn JobOfferStatus = "withdrawn"
+)
+
+// JobOffer records a job being offered to a single driver during a dispatch wave
+type JobOffer struct {
+	ID          uuid.UUID      `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	JobID       uuid.UUID      `gorm:"type:uuid;not null;index" json:"job_id"`
+	DriverID    uuid.UUID      `gorm:"type:uuid;not null;index" json:"driver_id"`
+	Wave        int            `gorm:"not null" json:"wave"`
+	DistanceKm  float64        `gorm:"type:decimal(10,2)" json:"distance_km"`
+	Status      JobOfferStatus `gorm:"type:varchar(20);not null;default:'offered';index" json:"status"`
+	ExpiresAt   time.Time      `gorm:"not null;index" json:"expires_at"`
+	RespondedAt *time.Time     `json:"responded_at,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+
+	// Relations
+	Job    Job  `gorm:"foreignKey:JobID" json:"job,omitempty"`
+	Driver User `gorm:"foreignKey:DriverID" json:"driver,omitempty"`
+}
+
+func (o *JobOffer) BeforeCreate(tx *gorm.DB) error {
+	if o.ID == uuid.Nil {
+		o.ID = uuid.New()
+	}
+	return nil
+}
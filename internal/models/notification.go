@@ -60,6 +60,10 @@ type NotificationSettings struct {
 	CreatedAt       time.Time `json:"created_at"`
 	UpdatedAt       time.Time `json:"updated_at"`
 
+	// When set, the nearby alert fires once the bus is expected at the stop within
+	// this many minutes instead of when it comes within the nearby distance
+	BusNearbyMinutes int `gorm:"default:0" json:"bus_nearby_minutes" binding:"gte=0,lte=60"`
+
 	// Relations
 	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
 }
@@ -0,0 +1,123 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// How a trip is paid for (Trip.PaymentMethod)
+const (
+	PaymentTypeCash   = "cash"
+	PaymentTypeCard   = "card"
+	PaymentTypeWallet = "wallet"
+)
+
+// PaymentMethod is a card a customer saved with the payment gateway
+type PaymentMethod struct {
+	ID           uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	CustomerID   uuid.UUID `gorm:"type:uuid;not null;index" json:"customer_id"`
+	GatewayToken string    `gorm:"type:varchar(255);not null" json:"-"`
+	Brand        string    `gorm:"type:varchar(20);not null" json:"brand"`
+	Last4        string    `gorm:"type:varchar(4);not null" json:"last4"`
+	ExpMonth     int       `gorm:"not null" json:"exp_month"`
+	ExpYear      int       `gorm:"not null" json:"exp_year"`
+	IsDefault    bool      `gorm:"not null;default:false" json:"is_default"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+func (m *PaymentMethod) BeforeCreate(tx *gorm.DB) error {
+	if m.ID == uuid.Nil {
+		m.ID = uuid.New()
+	}
+	return nil
+}
+
+type PaymentStatus string
+
+const (
+	PaymentStatusAuthorized        PaymentStatus = "authorized"
+	PaymentStatusCaptured          PaymentStatus = "captured"
+	PaymentStatusVoided            PaymentStatus = "voided"
+	PaymentStatusFailed            PaymentStatus = "failed"
+	PaymentStatusPartiallyRefunded PaymentStatus = "partially_refunded"
+	PaymentStatusRefunded          PaymentStatus = "refunded"
+)
+
+// Payment is the card charge of a trip: held when a driver accepts, captured at the
+// final fare on completion and voided if the trip ends otherwise
+type Payment struct {
+	ID              uuid.UUID     `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	TripID          uuid.UUID     `gorm:"type:uuid;not null;uniqueIndex" json:"trip_id"`
+	CustomerID      uuid.UUID     `gorm:"type:uuid;not null;index" json:"customer_id"`
+	PaymentMethodID uuid.UUID     `gorm:"type:uuid;not null" json:"payment_method_id"`
+	Status          PaymentStatus `gorm:"type:varchar(20);not null;index" json:"status"`
+	// AuthorizedAmount is the hold placed on the card
+	AuthorizedAmount float64 `gorm:"type:decimal(10,2);not null" json:"authorized_amount"`
+	CapturedAmount   float64 `gorm:"type:decimal(10,2);not null;default:0" json:"captured_amount"`
+	RefundedAmount   float64 `gorm:"type:decimal(10,2);not null;default:0" json:"refunded_amount"`
+	Currency         string  `gorm:"type:varchar(3);not null" json:"currency"`
+	// GatewayReference is the gateway's ID for the charge
+	GatewayReference *string    `gorm:"type:varchar(255);uniqueIndex" json:"gateway_reference,omitempty"`
+	FailureReason    *string    `gorm:"type:text" json:"failure_reason,omitempty"`
+	AuthorizedAt     *time.Time `json:"authorized_at,omitempty"`
+	CapturedAt       *time.Time `json:"captured_at,omitempty"`
+	VoidedAt         *time.Time `json:"voided_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+
+	// Relations
+	Refunds []PaymentRefund `gorm:"foreignKey:PaymentID" json:"refunds,omitempty"`
+}
+
+func (p *Payment) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
+
+type RefundStatus string
+
+const (
+	RefundStatusSucceeded RefundStatus = "succeeded"
+	RefundStatusFailed    RefundStatus = "failed"
+)
+
+// PaymentRefund returns part or all of a captured payment to the customer
+type PaymentRefund struct {
+	ID               uuid.UUID    `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	PaymentID        uuid.UUID    `gorm:"type:uuid;not null;index" json:"payment_id"`
+	Amount           float64      `gorm:"type:decimal(10,2);not null" json:"amount"`
+	Reason           string       `gorm:"type:text;not null" json:"reason"`
+	Status           RefundStatus `gorm:"type:varchar(20);not null" json:"status"`
+	GatewayReference *string      `gorm:"type:varchar(255);uniqueIndex" json:"gateway_reference,omitempty"`
+	FailureReason    *string      `gorm:"type:text" json:"failure_reason,omitempty"`
+	CreatedBy        uuid.UUID    `gorm:"type:uuid;not null" json:"created_by"`
+	CreatedAt        time.Time    `json:"created_at"`
+	UpdatedAt        time.Time    `json:"updated_at"`
+}
+
+func (r *PaymentRefund) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// PaymentWebhookEvent records a processed gateway webhook so redeliveries are ignored
+type PaymentWebhookEvent struct {
+	ID         uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	EventID    string    `gorm:"type:varchar(255);not null;uniqueIndex" json:"event_id"`
+	Type       string    `gorm:"type:varchar(50);not null" json:"type"`
+	ReceivedAt time.Time `gorm:"not null" json:"received_at"`
+}
+
+func (e *PaymentWebhookEvent) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SettlementPeriod is a week of driver earnings settled together
+type SettlementPeriod struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	StartDate time.Time  `gorm:"type:date;not null;uniqueIndex" json:"start_date"`
+	EndDate   time.Time  `gorm:"type:date;not null" json:"end_date"`
+	SettledAt *time.Time `json:"settled_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+func (p *SettlementPeriod) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
+
+type PayoutStatus string
+
+const (
+	PayoutStatusPending PayoutStatus = "pending"
+	PayoutStatusPaid    PayoutStatus = "paid"
+	PayoutStatusFailed  PayoutStatus = "failed"
+)
+
+// PayoutStatement is what a driver is owed for a settlement period: their earnings
+// less the cash they collected from customers directly
+type PayoutStatement struct {
+	ID                uuid.UUID    `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	PeriodID          uuid.UUID    `gorm:"type:uuid;not null;uniqueIndex:idx_payout_statement_period_driver" json:"period_id"`
+	DriverID          uuid.UUID    `gorm:"type:uuid;not null;uniqueIndex:idx_payout_statement_period_driver;index" json:"driver_id"`
+	Earnings          float64      `gorm:"type:decimal(10,2);not null" json:"earnings"`
+	CashCollected     float64      `gorm:"type:decimal(10,2);not null" json:"cash_collected"`
+	NetPayout         float64      `gorm:"type:decimal(10,2);not null" json:"net_payout"`
+	Currency          string       `gorm:"type:varchar(3);not null" json:"currency"`
+	Status            PayoutStatus `gorm:"type:varchar(20);not null;default:'pending';index" json:"status"`
+	ProviderReference *string      `gorm:"type:varchar(255)" json:"provider_reference,omitempty"`
+	FailureReason     *string      `gorm:"type:text" json:"failure_reason,omitempty"`
+	PaidAt            *time.Time   `json:"paid_at,omitempty"`
+	CreatedAt         time.Time    `json:"created_at"`
+	UpdatedAt         time.Time    `json:"updated_at"`
+
+	// Relations
+	Period SettlementPeriod `gorm:"foreignKey:PeriodID" json:"period,omitempty"`
+}
+
+func (s *PayoutStatement) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
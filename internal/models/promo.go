@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type PromoDiscountType string
+
+const (
+	PromoDiscountPercentage PromoDiscountType = "percentage"
+	PromoDiscountFixed      PromoDiscountType = "fixed"
+)
+
+// PromoCode is a discount customers apply to trips by entering its code
+type PromoCode struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Code        string    `gorm:"type:varchar(40);not null;uniqueIndex" json:"code"`
+	Description string    `gorm:"type:text" json:"description"`
+	// DiscountValue is a percentage of the fare or a fixed amount, by DiscountType
+	DiscountType  PromoDiscountType `gorm:"type:varchar(20);not null" json:"discount_type"`
+	DiscountValue float64           `gorm:"type:decimal(10,2);not null" json:"discount_value"`
+	MaxDiscount   *float64          `gorm:"type:decimal(10,2)" json:"max_discount,omitempty"`
+	// ServiceTypes the code applies to; empty applies to all
+	ServiceTypes StringArray `gorm:"type:text[]" json:"service_types"`
+	StartsAt     time.Time   `gorm:"not null" json:"starts_at"`
+	EndsAt       *time.Time  `json:"ends_at,omitempty"`
+	// MaxRedemptions caps redemptions across all customers; nil is unlimited
+	MaxRedemptions *int       `json:"max_redemptions,omitempty"`
+	PerUserLimit   int        `gorm:"not null;default:1" json:"per_user_limit"`
+	Active         bool       `gorm:"not null;default:true" json:"active"`
+	CreatedBy      *uuid.UUID `gorm:"type:uuid" json:"created_by,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+func (p *PromoCode) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
+
+type PromoRedemptionStatus string
+
+const (
+	PromoRedemptionRedeemed PromoRedemptionStatus = "redeemed"
+	// PromoRedemptionReleased redemptions belong to trips that never completed and
+	// don't count towards the code's limits
+	PromoRedemptionReleased PromoRedemptionStatus = "released"
+)
+
+// PromoRedemption is a promo code applied to a trip
+type PromoRedemption struct {
+	ID          uuid.UUID             `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	PromoCodeID uuid.UUID             `gorm:"type:uuid;not null;index:idx_promo_redemption_code_customer" json:"promo_code_id"`
+	CustomerID  uuid.UUID             `gorm:"type:uuid;not null;index:idx_promo_redemption_code_customer" json:"customer_id"`
+	TripID      uuid.UUID             `gorm:"type:uuid;not null;uniqueIndex" json:"trip_id"`
+	Discount    float64               `gorm:"type:decimal(10,2);not null" json:"discount"`
+	Status      PromoRedemptionStatus `gorm:"type:varchar(20);not null;default:'redeemed'" json:"status"`
+	CreatedAt   time.Time             `json:"created_at"`
+	UpdatedAt   time.Time             `json:"updated_at"`
+}
+
+func (r *PromoRedemption) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type RunType string
+
+const (
+	RunTypeMorning   RunType = "morning"
+	RunTypeAfternoon RunType = "afternoon"
+)
+
+// Route is a named school bus route operated by a bus
+type Route struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Name        string     `gorm:"type:varchar(255);not null" json:"name"`
+	Description *string    `gorm:"type:text" json:"description,omitempty"`
+	BusID       *uuid.UUID `gorm:"type:uuid;index" json:"bus_id,omitempty"`
+	IsActive    bool       `gorm:"default:true" json:"is_active"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	// Relations
+	Bus       *Bus        `gorm:"foreignKey:BusID" json:"bus,omitempty"`
+	Stops     []RouteStop `gorm:"foreignKey:RouteID" json:"stops,omitempty"`
+	Schedules []Schedule  `gorm:"foreignKey:RouteID" json:"schedules,omitempty"`
+}
+
+func (r *Route) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// RouteStop is a stop on a route. Parents are alerted as the bus approaches,
+// enters and leaves the stop's geofence.
+type RouteStop struct {
+	ID                   uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	RouteID              uuid.UUID `gorm:"type:uuid;not null;index" json:"route_id"`
+	Name                 string    `gorm:"not null" json:"name"`
+	Latitude             float64   `gorm:"type:decimal(10,8);not null" json:"latitude"`
+	Longitude            float64   `gorm:"type:decimal(11,8);not null" json:"longitude"`
+	GeofenceRadiusMeters float64   `gorm:"type:decimal(10,2);not null;default:50" json:"geofence_radius_meters"`
+	Sequence             int       `gorm:"not null;default:0" json:"sequence"`
+	CreatedAt            time.Time `json:"created_at"`
+	UpdatedAt            time.Time `json:"updated_at"`
+
+	// Relations
+	Route *Route `gorm:"foreignKey:RouteID" json:"route,omitempty"`
+}
+
+func (s *RouteStop) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
+
+// Schedule is a timetabled run of a route on a set of weekdays, optionally limited
+// to a date range such as a school term
+type Schedule struct {
+	ID            uuid.UUID   `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	RouteID       uuid.UUID   `gorm:"type:uuid;not null;index" json:"route_id"`
+	RunType       RunType     `gorm:"type:varchar(20);not null" json:"run_type"`
+	Weekdays      StringArray `gorm:"type:text[]" json:"weekdays"`
+	DepartureTime string      `gorm:"type:varchar(5);not null" json:"departure_time"`
+	StartsOn      *time.Time  `gorm:"type:date" json:"starts_on,omitempty"`
+	EndsOn        *time.Time  `gorm:"type:date" json:"ends_on,omitempty"`
+	IsActive      bool        `gorm:"default:true" json:"is_active"`
+	CreatedAt     time.Time   `json:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+
+	// Relations
+	StopTimes []ScheduleStopTime `gorm:"foreignKey:ScheduleID" json:"stop_times,omitempty"`
+}
+
+func (s *Schedule) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
+
+// RunsOn reports whether the schedule operates on the given day
+func (s *Schedule) RunsOn(day time.Time) bool {
+	if !s.IsActive {
+		return false
+	}
+	date := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	if s.StartsOn != nil && date.Before(dateOnly(*s.StartsOn)) {
+		return false
+	}
+	if s.EndsOn != nil && date.After(dateOnly(*s.EndsOn)) {
+		return false
+	}
+
+	weekday := WeekdayCode(day.Weekday())
+	for _, d := range s.Weekdays {
+		if d == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+// ScheduleStopTime is the timetabled arrival of a schedule's run at one stop
+type ScheduleStopTime struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	ScheduleID  uuid.UUID `gorm:"type:uuid;not null;index" json:"schedule_id"`
+	RouteStopID uuid.UUID `gorm:"type:uuid;not null;index" json:"route_stop_id"`
+	ArrivalTime string    `gorm:"type:varchar(5);not null" json:"arrival_time"`
+}
+
+func (t *ScheduleStopTime) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
+
+var weekdayCodes = [...]string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// WeekdayCode returns the three-letter code used in Schedule.Weekdays
+func WeekdayCode(day time.Weekday) string {
+	return weekdayCodes[day]
+}
+
+func dateOnly(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
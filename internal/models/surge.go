@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SurgeZone is the current surge multiplier of a geohash cell for a service type,
+// with the demand and supply it was computed from
+type SurgeZone struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Cell        string    `gorm:"type:varchar(12);not null;uniqueIndex:idx_surge_zone_cell_service" json:"cell"`
+	ServiceType string    `gorm:"type:varchar(20);not null;uniqueIndex:idx_surge_zone_cell_service" json:"service_type"`
+	Demand      int       `gorm:"not null;default:0" json:"demand"`
+	Supply      int       `gorm:"not null;default:0" json:"supply"`
+	Multiplier  float64   `gorm:"type:decimal(4,2);not null;default:1" json:"multiplier"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (z *SurgeZone) BeforeCreate(tx *gorm.DB) error {
+	if z.ID == uuid.Nil {
+		z.ID = uuid.New()
+	}
+	return nil
+}
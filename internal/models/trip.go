@@ -21,11 +21,13 @@ const (
 	TripStatusPending             TripStatus = "pending"
 	TripStatusSearching           TripStatus = "searching"
 	TripStatusAccepted            TripStatus = "accepted"
+	TripStatusArrived             TripStatus = "arrived"
 	TripStatusInProgress          TripStatus = "in_progress"
 	TripStatusCompleted           TripStatus = "completed"
 	TripStatusCancelled           TripStatus = "cancelled"
 	TripStatusExpired             TripStatus = "expired"
 	TripStatusCancelledByCustomer TripStatus = "cancelled_by_customer"
+	TripStatusNoShow              TripStatus = "no_show"
 )
 
 type Trip struct {
@@ -42,16 +44,37 @@ type Trip struct {
 	DropoffAddress    *string     `gorm:"type:text" json:"dropoff_address,omitempty"`
 	EstimatedDistance *float64    `gorm:"type:decimal(10,2)" json:"estimated_distance,omitempty"`
 	EstimatedDuration *int        `gorm:"type:integer" json:"estimated_duration,omitempty"`
+	DistanceMethod    *string     `gorm:"type:varchar(20)" json:"distance_method,omitempty"`
+	QuoteID           *uuid.UUID  `gorm:"type:uuid;uniqueIndex" json:"quote_id,omitempty"`
 	EstimatedArrival  *time.Time  `json:"estimated_arrival,omitempty"`
 	FareAmount        *float64    `gorm:"type:decimal(10,2)" json:"fare_amount,omitempty"`
+	SurgeMultiplier   float64     `gorm:"type:decimal(4,2);not null;default:1" json:"surge_multiplier"`
+	FareTableID       *uuid.UUID  `gorm:"type:uuid" json:"fare_table_id,omitempty"`
 	PaymentMethod     string      `gorm:"type:varchar(20);default:'cash'" json:"payment_method"`
+	PaymentMethodID   *uuid.UUID  `gorm:"type:uuid" json:"payment_method_id,omitempty"`
+	PromoCodeID       *uuid.UUID  `gorm:"type:uuid;index" json:"promo_code_id,omitempty"`
+	PromoCode         *string     `gorm:"type:varchar(40)" json:"promo_code,omitempty"`
+	DiscountAmount    float64     `gorm:"type:decimal(10,2);not null;default:0" json:"discount_amount"` // already taken off FareAmount
 	TraccarDeviceID   *string     `gorm:"type:varchar(255)" json:"traccar_device_id,omitempty"`
 
+	// StartedAt is when the trip went in progress, the start of its metered time
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	// ArrivedAt is when the driver reported arriving at the pickup
+	ArrivedAt *time.Time `json:"arrived_at,omitempty"`
+	// CompletedAt is when the trip was completed; ratings are accepted for a while after
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+
+	// ScheduledAt is the requested pickup time of a trip booked ahead. Such trips stay
+	// pending, optionally with a pre-accepted driver, until they are dispatched.
+	ScheduledAt    *time.Time `gorm:"index" json:"scheduled_at,omitempty"`
+	ReminderSentAt *time.Time `json:"reminder_sent_at,omitempty"`
+
 	// Search tracking
 	SearchStartedAt    *time.Time `json:"search_started_at,omitempty"`
 	SearchEndedAt      *time.Time `json:"search_ended_at,omitempty"`
 	CancelledBy        *uuid.UUID `gorm:"type:uuid" json:"cancelled_by,omitempty"`
 	CancellationReason *string    `gorm:"type:text" json:"cancellation_reason,omitempty"`
+	CancellationFee    float64    `gorm:"type:decimal(10,2);not null;default:0" json:"cancellation_fee"`
 
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
@@ -59,6 +82,10 @@ type Trip struct {
 	// Relations
 	Customer User  `gorm:"foreignKey:CustomerID" json:"customer,omitempty"`
 	Driver   *User `gorm:"foreignKey:DriverID" json:"driver,omitempty"`
+	// Waypoints are the stops of a multi-stop trip, empty for a single drop-off
+	Waypoints []TripWaypoint `gorm:"foreignKey:TripID" json:"waypoints,omitempty"`
+	// Package is what a delivery carries
+	Package *TripPackage `gorm:"foreignKey:TripID" json:"package,omitempty"`
 }
 
 func (t *Trip) BeforeCreate(tx *gorm.DB) error {
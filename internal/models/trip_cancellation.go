@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DriverCancelReason is why a driver gave up a trip they accepted
+type DriverCancelReason string
+
+const (
+	DriverCancelCustomerUnreachable DriverCancelReason = "customer_unreachable"
+	DriverCancelCustomerRequested   DriverCancelReason = "customer_requested"
+	DriverCancelVehicleProblem      DriverCancelReason = "vehicle_problem"
+	DriverCancelUnsafePickup        DriverCancelReason = "unsafe_pickup"
+	DriverCancelWrongPickup         DriverCancelReason = "wrong_pickup"
+	DriverCancelOther               DriverCancelReason = "other"
+)
+
+// TripCancellation records a trip being cancelled by its customer or the system, a
+// customer no-show, or a driver giving the trip up so it is dispatched again. A trip
+// can have several: one per driver who cancelled, and the one that ended it.
+type TripCancellation struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	TripID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"trip_id"`
+	JobID      *uuid.UUID `gorm:"type:uuid" json:"job_id,omitempty"`
+	DriverID   *uuid.UUID `gorm:"type:uuid;index" json:"driver_id,omitempty"` // the driver assigned at the time
+	FromStatus TripStatus `gorm:"type:varchar(30);not null" json:"from_status"`
+	ToStatus   TripStatus `gorm:"type:varchar(30);not null" json:"to_status"`
+	ActorType  TripActor  `gorm:"type:varchar(20);not null" json:"actor_type"`
+	ActorID    *uuid.UUID `gorm:"type:uuid" json:"actor_id,omitempty"`
+	// ReasonCode is set for drivers; Reason is free text from whoever cancelled
+	ReasonCode *DriverCancelReason `gorm:"type:varchar(30)" json:"reason_code,omitempty"`
+	Reason     *string             `gorm:"type:text" json:"reason,omitempty"`
+	// Fee is charged to the customer; DriverCompensation is the driver's share of it
+	Fee                float64   `gorm:"type:decimal(10,2);not null;default:0" json:"fee"`
+	DriverCompensation float64   `gorm:"type:decimal(10,2);not null;default:0" json:"driver_compensation"`
+	CreatedAt          time.Time `json:"created_at"`
+}
+
+func (c *TripCancellation) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
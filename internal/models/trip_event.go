@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type TripActor string
+
+const (
+	TripActorCustomer TripActor = "customer"
+	TripActorDriver   TripActor = "driver"
+	TripActorSystem   TripActor = "system"
+)
+
+// TripEvent is one status transition in a trip's lifecycle
+type TripEvent struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	TripID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"trip_id"`
+	JobID      *uuid.UUID `gorm:"type:uuid" json:"job_id,omitempty"`
+	FromStatus TripStatus `gorm:"type:varchar(30)" json:"from_status,omitempty"`
+	ToStatus   TripStatus `gorm:"type:varchar(30);not null" json:"to_status"`
+	ActorType  TripActor  `gorm:"type:varchar(20);not null" json:"actor_type"`
+	ActorID    *uuid.UUID `gorm:"type:uuid" json:"actor_id,omitempty"`
+	Latitude   *float64   `gorm:"type:decimal(10,8)" json:"latitude,omitempty"`
+	Longitude  *float64   `gorm:"type:decimal(11,8)" json:"longitude,omitempty"`
+	Note       *string    `gorm:"type:text" json:"note,omitempty"`
+	CreatedAt  time.Time  `gorm:"index" json:"created_at"`
+}
+
+func (e *TripEvent) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
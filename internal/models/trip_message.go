@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type MessageKind string
+
+const (
+	MessageKindText       MessageKind = "text"
+	MessageKindQuickReply MessageKind = "quick_reply"
+)
+
+// TripMessage is a chat message between the customer and driver of a trip. Messages
+// are kept after the trip ends so support can review the conversation.
+type TripMessage struct {
+	ID     uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	TripID uuid.UUID `gorm:"type:uuid;not null;index:idx_trip_messages_thread" json:"trip_id"`
+	// DriverID is the driver the customer was talking to, so a driver the trip is
+	// reassigned to doesn't see the earlier conversation
+	DriverID   uuid.UUID   `gorm:"type:uuid;not null;index:idx_trip_messages_thread" json:"driver_id"`
+	SenderID   uuid.UUID   `gorm:"type:uuid;not null" json:"sender_id"`
+	SenderType UserType    `gorm:"type:varchar(20);not null" json:"sender_type"`
+	Kind       MessageKind `gorm:"type:varchar(20);not null;default:'text'" json:"kind"`
+	// QuickReply is the code of the canned reply a quick reply message was sent with
+	QuickReply *string    `gorm:"type:varchar(40)" json:"quick_reply,omitempty"`
+	Body       string     `gorm:"type:text;not null" json:"body"`
+	ReadAt     *time.Time `json:"read_at,omitempty"`
+	CreatedAt  time.Time  `gorm:"index" json:"created_at"`
+}
+
+func (m *TripMessage) BeforeCreate(tx *gorm.DB) error {
+	if m.ID == uuid.Nil {
+		m.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TripTrackPoint is a driver position recorded while a trip is in progress
+type TripTrackPoint struct {
+	ID         uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	TripID     uuid.UUID `gorm:"type:uuid;not null;index:idx_trip_track_point_trip_time" json:"trip_id"`
+	Latitude   float64   `gorm:"type:decimal(10,8);not null" json:"latitude"`
+	Longitude  float64   `gorm:"type:decimal(11,8);not null" json:"longitude"`
+	Accuracy   *float64  `gorm:"type:decimal(8,2)" json:"accuracy,omitempty"`
+	Speed      *float64  `gorm:"type:decimal(8,2)" json:"speed,omitempty"`
+	RecordedAt time.Time `gorm:"not null;index:idx_trip_track_point_trip_time" json:"recorded_at"`
+}
+
+func (p *TripTrackPoint) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
+
+// TripReceipt is the final fare of a completed trip, metered from its track
+type TripReceipt struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	TripID      uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex" json:"trip_id"`
+	FareTableID *uuid.UUID `gorm:"type:uuid" json:"fare_table_id,omitempty"`
+	// DistanceMethod is "gps" when distance was metered from the track, "estimated"
+	// when the track was too sparse and the trip's estimated distance was used
+	DistanceMethod  string  `gorm:"type:varchar(20);not null" json:"distance_method"`
+	DistanceKm      float64 `gorm:"type:decimal(10,2);not null" json:"distance_km"`
+	DurationMinutes float64 `gorm:"type:decimal(10,2);not null" json:"duration_minutes"`
+	WaitingMinutes  float64 `gorm:"type:decimal(10,2);not null" json:"waiting_minutes"`
+
+	BaseFare         float64 `gorm:"type:decimal(10,2);not null" json:"base_fare"`
+	DistanceFare     float64 `gorm:"type:decimal(10,2);not null" json:"distance_fare"`
+	TimeFare         float64 `gorm:"type:decimal(10,2);not null" json:"time_fare"`
+	WaitingCharge    float64 `gorm:"type:decimal(10,2);not null" json:"waiting_charge"`
+	SurgeMultiplier  float64 `gorm:"type:decimal(4,2);not null;default:1" json:"surge_multiplier"`
+	SurgeAmount      float64 `gorm:"type:decimal(10,2);not null" json:"surge_amount"`
+	NightSurcharge   float64 `gorm:"type:decimal(10,2);not null" json:"night_surcharge"`
+	MinimumFareTopUp float64 `gorm:"type:decimal(10,2);not null" json:"minimum_fare_top_up"`
+	BookingFee       float64 `gorm:"type:decimal(10,2);not null" json:"booking_fee"`
+	AirportSurcharge float64 `gorm:"type:decimal(10,2);not null" json:"airport_surcharge"`
+	WeightSurcharge  float64 `gorm:"type:decimal(10,2);not null;default:0" json:"weight_surcharge"`
+	// MeteredFare is the sum of the items above. FinalFare is MeteredFare kept within
+	// the tolerance around UpfrontFare, less the promo code Discount, and is what the
+	// customer pays. UpfrontFare is before any discount.
+	MeteredFare float64   `gorm:"type:decimal(10,2);not null" json:"metered_fare"`
+	UpfrontFare float64   `gorm:"type:decimal(10,2);not null" json:"upfront_fare"`
+	PromoCode   *string   `gorm:"type:varchar(40)" json:"promo_code,omitempty"`
+	Discount    float64   `gorm:"type:decimal(10,2);not null;default:0" json:"discount"`
+	FinalFare   float64   `gorm:"type:decimal(10,2);not null" json:"final_fare"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (r *TripReceipt) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
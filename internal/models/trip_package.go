@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// VehicleClass is the size of vehicle a driver drives, from smallest to largest
+type VehicleClass string
+
+const (
+	VehicleClassBike  VehicleClass = "bike"
+	VehicleClassCar   VehicleClass = "car"
+	VehicleClassVan   VehicleClass = "van"
+	VehicleClassTruck VehicleClass = "truck"
+)
+
+type PackageCategory string
+
+const (
+	PackageCategoryDocuments   PackageCategory = "documents"
+	PackageCategoryFood        PackageCategory = "food"
+	PackageCategoryParcel      PackageCategory = "parcel"
+	PackageCategoryElectronics PackageCategory = "electronics"
+	PackageCategoryFurniture   PackageCategory = "furniture"
+	PackageCategoryOther       PackageCategory = "other"
+)
+
+// TripPackage is what a delivery trip carries. VehicleClass is the smallest vehicle
+// it fits in; only drivers of that class or larger are offered the trip.
+type TripPackage struct {
+	ID            uuid.UUID       `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	TripID        uuid.UUID       `gorm:"type:uuid;not null;uniqueIndex" json:"trip_id"`
+	WeightKg      float64         `gorm:"type:decimal(8,2);not null" json:"weight_kg"`
+	LengthCm      float64         `gorm:"type:decimal(8,2);not null" json:"length_cm"`
+	WidthCm       float64         `gorm:"type:decimal(8,2);not null" json:"width_cm"`
+	HeightCm      float64         `gorm:"type:decimal(8,2);not null" json:"height_cm"`
+	Category      PackageCategory `gorm:"type:varchar(20);not null" json:"category"`
+	DeclaredValue float64         `gorm:"type:decimal(10,2);not null;default:0" json:"declared_value"`
+	Fragile       bool            `gorm:"not null;default:false" json:"fragile"`
+	VehicleClass  VehicleClass    `gorm:"type:varchar(10);not null" json:"vehicle_class"`
+	// WeightSurcharge is the part of the trip's fare charged for the package's weight
+	WeightSurcharge float64   `gorm:"type:decimal(10,2);not null;default:0" json:"weight_surcharge"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+func (p *TripPackage) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
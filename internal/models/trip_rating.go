@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TripRating is one party's rating of the other after a completed trip: customers
+// rate their driver and drivers rate their customer, once each per trip.
+type TripRating struct {
+	ID        uuid.UUID   `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	TripID    uuid.UUID   `gorm:"type:uuid;not null;uniqueIndex:idx_trip_ratings_trip_rater" json:"trip_id"`
+	RaterID   uuid.UUID   `gorm:"type:uuid;not null;uniqueIndex:idx_trip_ratings_trip_rater" json:"rater_id"`
+	RateeID   uuid.UUID   `gorm:"type:uuid;not null;index" json:"ratee_id"`
+	RaterType UserType    `gorm:"type:varchar(20);not null" json:"rater_type"`
+	Stars     int         `gorm:"type:smallint;not null" json:"stars"`
+	Tags      StringArray `gorm:"type:text[]" json:"tags,omitempty"`
+	Comment   *string     `gorm:"type:text" json:"comment,omitempty"`
+	CreatedAt time.Time   `gorm:"index" json:"created_at"`
+}
+
+func (r *TripRating) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type WaypointStatus string
+
+const (
+	WaypointStatusPending   WaypointStatus = "pending"
+	WaypointStatusArrived   WaypointStatus = "arrived"
+	WaypointStatusCompleted WaypointStatus = "completed"
+	// WaypointStatusFailed is a stop the driver could not complete, e.g. because
+	// nobody was there to receive the package
+	WaypointStatusFailed WaypointStatus = "failed"
+)
+
+// ProofOfDeliveryType is how a driver proved a package was handed over
+type ProofOfDeliveryType string
+
+const (
+	ProofOfDeliverySignature ProofOfDeliveryType = "signature"
+	ProofOfDeliveryPhoto     ProofOfDeliveryType = "photo"
+	ProofOfDeliveryPIN       ProofOfDeliveryType = "pin"
+)
+
+// TripWaypoint is one stop of a multi-stop trip after the pickup, visited in Sequence
+// order. The trip's drop-off is its last waypoint.
+type TripWaypoint struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	TripID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_trip_waypoint_sequence" json:"trip_id"`
+	Sequence  int       `gorm:"not null;uniqueIndex:idx_trip_waypoint_sequence" json:"sequence"`
+	Latitude  float64   `gorm:"type:decimal(10,8);not null" json:"latitude"`
+	Longitude float64   `gorm:"type:decimal(11,8);not null" json:"longitude"`
+	Address   *string   `gorm:"type:text" json:"address,omitempty"`
+
+	// The person to meet at the stop
+	ContactName  string `gorm:"type:varchar(100);not null" json:"contact_name"`
+	ContactPhone string `gorm:"type:varchar(20);not null" json:"contact_phone"`
+
+	PackageDescription *string `gorm:"type:text" json:"package_description,omitempty"`
+	PackageQuantity    int     `gorm:"not null;default:1" json:"package_quantity"`
+	Instructions       *string `gorm:"type:text" json:"instructions,omitempty"`
+
+	Status      WaypointStatus `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
+	ArrivedAt   *time.Time     `json:"arrived_at,omitempty"`
+	CompletedAt *time.Time     `json:"completed_at,omitempty"` // completed or failed
+	// FailureReason is set when the stop failed
+	FailureReason *string `gorm:"type:text" json:"failure_reason,omitempty"`
+
+	// Delivery stops can't be completed without proof. DeliveryPIN is shown to the
+	// customer to pass on to the recipient, who reads it out to the driver.
+	ProofRequired bool                 `gorm:"not null;default:false" json:"proof_required"`
+	DeliveryPIN   *string              `gorm:"type:varchar(10)" json:"-"`
+	ProofType     *ProofOfDeliveryType `gorm:"type:varchar(20)" json:"proof_type,omitempty"`
+	// PINAttempts counts the wrong PINs entered at the stop
+	PINAttempts int `gorm:"not null;default:0" json:"-"`
+	// ProofKey is where the signature or photo is stored
+	ProofKey      *string `gorm:"type:varchar(255)" json:"-"`
+	RecipientName *string `gorm:"type:varchar(100)" json:"recipient_name,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (w *TripWaypoint) BeforeCreate(tx *gorm.DB) error {
+	if w.ID == uuid.Nil {
+		w.ID = uuid.New()
+	}
+	return nil
+}
@@ -13,6 +13,7 @@ const (
 	UserTypeCustomer UserType = "customer"
 	UserTypeDriver   UserType = "driver"
 	UserTypeParent   UserType = "parent"
+	UserTypeAdmin    UserType = "admin"
 )
 
 type User struct {
@@ -24,8 +25,17 @@ type User struct {
 	UserType     UserType  `gorm:"type:varchar(20);not null;index" json:"user_type"`
 	AvatarURL    *string   `json:"avatar_url,omitempty"`
 	IsActive     bool      `gorm:"default:true" json:"is_active"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+
+	// RatingAverage is the average of the most recent ratings the user received, nil
+	// until they are first rated
+	RatingAverage *float64 `gorm:"type:decimal(3,2)" json:"rating_average,omitempty"`
+	RatingCount   int      `gorm:"not null;default:0" json:"rating_count"`
+	// RatingFlaggedAt is when a driver's average fell below the review threshold,
+	// cleared once it recovers
+	RatingFlaggedAt *time.Time `gorm:"index" json:"rating_flagged_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 func (u *User) BeforeCreate(tx *gorm.DB) error {
@@ -34,4 +44,3 @@ func (u *User) BeforeCreate(tx *gorm.DB) error {
 	}
 	return nil
 }
-
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Vehicle is the vehicle a driver drives. Its class and service types limit the jobs
+// the driver is offered.
+type Vehicle struct {
+	ID           uuid.UUID    `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	DriverID     uuid.UUID    `gorm:"type:uuid;not null;uniqueIndex" json:"driver_id"`
+	PlateNumber  string       `gorm:"type:varchar(20);not null;uniqueIndex" json:"plate_number"`
+	Make         string       `gorm:"type:varchar(50);not null" json:"make"`
+	Model        string       `gorm:"type:varchar(50);not null" json:"model"`
+	Color        string       `gorm:"type:varchar(30);not null" json:"color"`
+	Year         int          `gorm:"not null" json:"year"`
+	VehicleClass VehicleClass `gorm:"type:varchar(10);not null" json:"vehicle_class"`
+	// Seats is the number of passenger seats, excluding the driver's
+	Seats        int         `gorm:"not null;default:0" json:"seats"`
+	ServiceTypes StringArray `gorm:"type:text[]" json:"service_types"`
+	CreatedAt    time.Time   `json:"created_at"`
+	UpdatedAt    time.Time   `json:"updated_at"`
+}
+
+func (v *Vehicle) BeforeCreate(tx *gorm.DB) error {
+	if v.ID == uuid.Nil {
+		v.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Wallet is a customer's stored balance. It is the running total of the customer's
+// wallet transactions and is only changed together with one.
+type Wallet struct {
+	CustomerID uuid.UUID `gorm:"type:uuid;primary_key" json:"customer_id"`
+	Balance    float64   `gorm:"type:decimal(10,2);not null;default:0" json:"balance"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type WalletTransactionType string
+
+const (
+	WalletTransactionTopUp          WalletTransactionType = "top_up"
+	WalletTransactionRefund         WalletTransactionType = "refund"
+	WalletTransactionReferralCredit WalletTransactionType = "referral_credit"
+	WalletTransactionAdjustment     WalletTransactionType = "adjustment"
+	// WalletTransactionTripHold takes a wallet trip's fare when a driver accepts it
+	WalletTransactionTripHold WalletTransactionType = "trip_hold"
+	// WalletTransactionTripRelease returns what was held beyond the final fare, or all
+	// of it when the trip doesn't complete
+	WalletTransactionTripRelease WalletTransactionType = "trip_release"
+	// WalletTransactionTip takes a customer's tip on a wallet trip
+	WalletTransactionTip WalletTransactionType = "tip"
+)
+
+// WalletTransaction is an entry of a customer's append-only wallet ledger. Amount is
+// positive for credits and negative for debits.
+type WalletTransaction struct {
+	ID           uuid.UUID             `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	CustomerID   uuid.UUID             `gorm:"type:uuid;not null;index" json:"customer_id"`
+	Type         WalletTransactionType `gorm:"type:varchar(20);not null" json:"type"`
+	Amount       float64               `gorm:"type:decimal(10,2);not null" json:"amount"`
+	BalanceAfter float64               `gorm:"type:decimal(10,2);not null" json:"balance_after"`
+	TripID       *uuid.UUID            `gorm:"type:uuid;index" json:"trip_id,omitempty"`
+	// Reference is the gateway's ID for the card charge of a top-up
+	Reference   *string    `gorm:"type:varchar(255)" json:"reference,omitempty"`
+	Description *string    `gorm:"type:text" json:"description,omitempty"`
+	CreatedBy   *uuid.UUID `gorm:"type:uuid" json:"created_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func (t *WalletTransaction) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
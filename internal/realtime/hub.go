@@ -0,0 +1,132 @@
+package realtime
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Event types pushed to connected clients
+const (
+	EventTripStatus     = "trip.status"
+	EventTripWaypoint   = "trip.waypoint"
+	EventJobOffer       = "job.offer"
+	EventJobOfferClosed = "job.offer_closed"
+	EventDriverLocation = "driver.location"
+	EventBusLocation    = "bus.location"
+	EventChatMessage    = "chat.message"
+	EventChatRead       = "chat.read"
+)
+
+const subscriberBufferSize = 64
+
+// Event is a single message fanned out to every subscriber of a topic
+type Event struct {
+	Type      string      `json:"type"`
+	Topic     string      `json:"topic"`
+	Data      interface{} `json:"data"`
+	Timestamp int64       `json:"timestamp"`
+}
+
+// Subscription receives the events published to the topics it was created with
+type Subscription struct {
+	hub    *Hub
+	topics []string
+	events chan Event
+	once   sync.Once
+}
+
+// Events returns the channel events are delivered on. It is closed by Close.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Close removes the subscription from the hub and closes its channel
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.hub.remove(s)
+		close(s.events)
+	})
+}
+
+// Hub is an in-process publish/subscribe broker. Services publish events to
+// topics and the WebSocket gateway forwards them to subscribed clients.
+type Hub struct {
+	mu          sync.RWMutex
+	subscribers map[string]map[*Subscription]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		subscribers: make(map[string]map[*Subscription]struct{}),
+	}
+}
+
+// DefaultHub is the hub shared by services and the gateway
+var DefaultHub = NewHub()
+
+// Subscribe creates a subscription to the given topics
+func (h *Hub) Subscribe(topics ...string) *Subscription {
+	sub := &Subscription{
+		hub:    h,
+		topics: topics,
+		events: make(chan Event, subscriberBufferSize),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, topic := range topics {
+		if h.subscribers[topic] == nil {
+			h.subscribers[topic] = make(map[*Subscription]struct{})
+		}
+		h.subscribers[topic][sub] = struct{}{}
+	}
+
+	return sub
+}
+
+// Publish delivers an event to every subscriber of the topic. Slow subscribers
+// whose buffer is full miss the event rather than blocking the publisher.
+func (h *Hub) Publish(topic, eventType string, data interface{}) {
+	event := Event{
+		Type:      eventType,
+		Topic:     topic,
+		Data:      data,
+		Timestamp: time.Now().Unix(),
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for sub := range h.subscribers[topic] {
+		select {
+		case sub.events <- event:
+		default:
+		}
+	}
+}
+
+func (h *Hub) remove(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, topic := range sub.topics {
+		delete(h.subscribers[topic], sub)
+		if len(h.subscribers[topic]) == 0 {
+			delete(h.subscribers, topic)
+		}
+	}
+}
+
+// Publish publishes an event on the default hub
+func Publish(topic, eventType string, data interface{}) {
+	DefaultHub.Publish(topic, eventType, data)
+}
+
+// UserTopic is the private topic of a single user
+func UserTopic(userID fmt.Stringer) string {
+	return "user:" + userID.String()
+}
+
+// BusTopic carries live positions of a bus
+func BusTopic(busID fmt.Stringer) string {
+	return "bus:" + busID.String()
+}
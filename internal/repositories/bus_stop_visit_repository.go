@@ -0,0 +1,109 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/database"
+	"github.com/telemoz/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type BusStopVisitRepository interface {
+	Create(visit *models.BusStopVisit) error
+	FindOpen(busID, stopID uuid.UUID, since time.Time) (*models.BusStopVisit, error)
+	Update(visit *models.BusStopVisit) error
+	FindArrivedSince(busID uuid.UUID, since time.Time) ([]models.BusStopVisit, error)
+	AverageDwell(stopIDs []uuid.UUID, since time.Time) (map[uuid.UUID]time.Duration, error)
+}
+
+type BusStopAlertRepository interface {
+	Claim(alert *models.BusStopAlert) (bool, error)
+}
+
+type busStopVisitRepository struct {
+	db *gorm.DB
+}
+
+func NewBusStopVisitRepository() BusStopVisitRepository {
+	return &busStopVisitRepository{
+		db: database.DB,
+	}
+}
+
+func (r *busStopVisitRepository) Create(visit *models.BusStopVisit) error {
+	return r.db.Create(visit).Error
+}
+
+// FindOpen returns the bus's current visit to a stop, ignoring visits started before since
+func (r *busStopVisitRepository) FindOpen(busID, stopID uuid.UUID, since time.Time) (*models.BusStopVisit, error) {
+	var visit models.BusStopVisit
+	err := r.db.Where("bus_id = ? AND stop_id = ? AND closed_at IS NULL AND created_at >= ?", busID, stopID, since).
+		Order("created_at DESC").
+		First(&visit).Error
+	if err != nil {
+		return nil, err
+	}
+	return &visit, nil
+}
+
+func (r *busStopVisitRepository) Update(visit *models.BusStopVisit) error {
+	return r.db.Save(visit).Error
+}
+
+// FindArrivedSince returns the bus's visits that reached a stop at or after since
+func (r *busStopVisitRepository) FindArrivedSince(busID uuid.UUID, since time.Time) ([]models.BusStopVisit, error) {
+	var visits []models.BusStopVisit
+	err := r.db.Where("bus_id = ? AND arrived_at >= ?", busID, since).
+		Order("arrived_at ASC").
+		Find(&visits).Error
+	return visits, err
+}
+
+// AverageDwell returns the mean time buses spent inside each stop's geofence on
+// visits that arrived at or after since. Stops without history are left out.
+func (r *busStopVisitRepository) AverageDwell(stopIDs []uuid.UUID, since time.Time) (map[uuid.UUID]time.Duration, error) {
+	dwell := make(map[uuid.UUID]time.Duration)
+	if len(stopIDs) == 0 {
+		return dwell, nil
+	}
+
+	var rows []struct {
+		StopID  uuid.UUID
+		Seconds float64
+	}
+	err := r.db.Model(&models.BusStopVisit{}).
+		Select("stop_id, AVG(EXTRACT(EPOCH FROM departed_at - arrived_at)) AS seconds").
+		Where("stop_id IN ? AND arrived_at >= ? AND departed_at IS NOT NULL", stopIDs, since).
+		Group("stop_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		dwell[row.StopID] = time.Duration(row.Seconds * float64(time.Second))
+	}
+	return dwell, nil
+}
+
+type busStopAlertRepository struct {
+	db *gorm.DB
+}
+
+func NewBusStopAlertRepository() BusStopAlertRepository {
+	return &busStopAlertRepository{
+		db: database.DB,
+	}
+}
+
+// Claim records the alert unless one already exists for the same user, stop and
+// run. It reports whether this call created the record.
+func (r *busStopAlertRepository) Claim(alert *models.BusStopAlert) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(alert)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
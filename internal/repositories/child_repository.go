@@ -11,6 +11,7 @@ type ChildRepository interface {
 	Create(child *models.Child) error
 	FindByID(id uuid.UUID) (*models.Child, error)
 	FindByParentID(parentID uuid.UUID) ([]models.Child, error)
+	FindByStopID(stopID uuid.UUID) ([]models.Child, error)
 	Update(child *models.Child) error
 	Delete(id uuid.UUID) error
 }
@@ -47,6 +48,14 @@ func (r *childRepository) FindByParentID(parentID uuid.UUID) ([]models.Child, er
 	return children, err
 }
 
+func (r *childRepository) FindByStopID(stopID uuid.UUID) ([]models.Child, error) {
+	var children []models.Child
+	err := r.db.Where("pickup_stop_id = ? OR dropoff_stop_id = ?", stopID, stopID).
+		Preload("Parent").
+		Find(&children).Error
+	return children, err
+}
+
 func (r *childRepository) Update(child *models.Child) error {
 	return r.db.Save(child).Error
 }
@@ -1,6 +1,8 @@
 package repositories
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/telemoz/backend/internal/database"
 	"github.com/telemoz/backend/internal/models"
@@ -10,8 +12,10 @@ import (
 type DriverAvailabilityRepository interface {
 	Create(availability *models.DriverAvailability) error
 	Update(availability *models.DriverAvailability) error
+	UpdateLocation(availability *models.DriverAvailability) error
 	FindByDriverID(driverID uuid.UUID) (*models.DriverAvailability, error)
-	FindAvailableByServiceType(serviceType string) ([]uuid.UUID, error)
+	FindAvailableByServiceType(serviceType string) ([]models.DriverAvailability, error)
+	MarkStaleOffline(lastActiveBefore time.Time) (int64, error)
 }
 
 type driverAvailabilityRepository struct {
@@ -32,6 +36,14 @@ func (r *driverAvailabilityRepository) Update(availability *models.DriverAvailab
 	return r.db.Save(availability).Error
 }
 
+// UpdateLocation writes only a heartbeat's position and activity time, so it can't
+// undo an availability change made since the row was read
+func (r *driverAvailabilityRepository) UpdateLocation(availability *models.DriverAvailability) error {
+	return r.db.Model(availability).
+		Select("latitude", "longitude", "accuracy", "speed", "heading", "location_updated_at", "last_active_at", "updated_at").
+		Updates(availability).Error
+}
+
 func (r *driverAvailabilityRepository) FindByDriverID(driverID uuid.UUID) (*models.DriverAvailability, error) {
 	var availability models.DriverAvailability
 	err := r.db.Where("driver_id = ?", driverID).First(&availability).Error
@@ -41,10 +53,17 @@ func (r *driverAvailabilityRepository) FindByDriverID(driverID uuid.UUID) (*mode
 	return &availability, nil
 }
 
-func (r *driverAvailabilityRepository) FindAvailableByServiceType(serviceType string) ([]uuid.UUID, error) {
-	var driverIDs []uuid.UUID
-	err := r.db.Model(&models.DriverAvailability{}).
-		Where("is_available = ? AND ? = ANY(service_types)", true, serviceType).
-		Pluck("driver_id", &driverIDs).Error
-	return driverIDs, err
+func (r *driverAvailabilityRepository) FindAvailableByServiceType(serviceType string) ([]models.DriverAvailability, error) {
+	var availabilities []models.DriverAvailability
+	err := r.db.Where("is_available = ? AND ? = ANY(service_types)", true, serviceType).
+		Find(&availabilities).Error
+	return availabilities, err
+}
+
+// MarkStaleOffline takes available drivers offline when they have not been active since lastActiveBefore
+func (r *driverAvailabilityRepository) MarkStaleOffline(lastActiveBefore time.Time) (int64, error) {
+	result := r.db.Model(&models.DriverAvailability{}).
+		Where("is_available = ? AND last_active_at < ?", true, lastActiveBefore).
+		Update("is_available", false)
+	return result.RowsAffected, result.Error
 }
@@ -0,0 +1,114 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/database"
+	"github.com/telemoz/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type DriverDocumentRepository interface {
+	Create(document *models.DriverDocument) error
+	FindByID(id uuid.UUID) (*models.DriverDocument, error)
+	FindByDriverID(driverID uuid.UUID) ([]models.DriverDocument, error)
+	FindByDriverIDs(driverIDs []uuid.UUID) ([]models.DriverDocument, error)
+	FindByStatus(status models.DocumentStatus, limit, offset int) ([]models.DriverDocument, error)
+	Review(document *models.DriverDocument) error
+	FindExpiring(before time.Time) ([]models.DriverDocument, error)
+	ClaimExpiryWarning(document *models.DriverDocument, now time.Time) (bool, error)
+}
+
+type driverDocumentRepository struct {
+	db *gorm.DB
+}
+
+func NewDriverDocumentRepository() DriverDocumentRepository {
+	return &driverDocumentRepository{
+		db: database.DB,
+	}
+}
+
+func (r *driverDocumentRepository) Create(document *models.DriverDocument) error {
+	return r.db.Omit("Driver").Create(document).Error
+}
+
+func (r *driverDocumentRepository) FindByID(id uuid.UUID) (*models.DriverDocument, error) {
+	var document models.DriverDocument
+	err := r.db.Preload("Driver").Where("id = ?", id).First(&document).Error
+	if err != nil {
+		return nil, err
+	}
+	return &document, nil
+}
+
+// FindByDriverID returns a driver's documents, newest first
+func (r *driverDocumentRepository) FindByDriverID(driverID uuid.UUID) ([]models.DriverDocument, error) {
+	var documents []models.DriverDocument
+	err := r.db.Where("driver_id = ?", driverID).
+		Order("created_at DESC").
+		Find(&documents).Error
+	return documents, err
+}
+
+// FindByDriverIDs returns the documents of several drivers, newest first
+func (r *driverDocumentRepository) FindByDriverIDs(driverIDs []uuid.UUID) ([]models.DriverDocument, error) {
+	var documents []models.DriverDocument
+	if len(driverIDs) == 0 {
+		return documents, nil
+	}
+	err := r.db.Where("driver_id IN ?", driverIDs).
+		Order("created_at DESC").
+		Find(&documents).Error
+	return documents, err
+}
+
+// FindByStatus returns documents in a status, oldest first so reviews are first come
+// first served
+func (r *driverDocumentRepository) FindByStatus(status models.DocumentStatus, limit, offset int) ([]models.DriverDocument, error) {
+	var documents []models.DriverDocument
+	err := r.db.Preload("Driver").
+		Where("status = ?", status).
+		Order("created_at ASC").
+		Limit(limit).
+		Offset(offset).
+		Find(&documents).Error
+	return documents, err
+}
+
+// Review saves an admin's decision on a document while it is still pending,
+// returning ErrStatusChanged when someone else reviewed it first
+func (r *driverDocumentRepository) Review(document *models.DriverDocument) error {
+	result := r.db.Model(document).
+		Where("status = ?", models.DocumentStatusPending).
+		Select("status", "rejection_reason", "reviewed_by", "reviewed_at", "updated_at").
+		Updates(document)
+	return checkTransitioned(result)
+}
+
+// FindExpiring returns approved documents expiring before the given time whose
+// drivers haven't been warned yet
+func (r *driverDocumentRepository) FindExpiring(before time.Time) ([]models.DriverDocument, error) {
+	var documents []models.DriverDocument
+	err := r.db.Where("status = ? AND expires_at <= ? AND expiry_warned_at IS NULL", models.DocumentStatusApproved, before).
+		Order("expires_at ASC").
+		Find(&documents).Error
+	return documents, err
+}
+
+// ClaimExpiryWarning marks a document's driver as warned. It returns false when the
+// warning was already claimed, so each driver is warned once per document.
+func (r *driverDocumentRepository) ClaimExpiryWarning(document *models.DriverDocument, now time.Time) (bool, error) {
+	result := r.db.Model(&models.DriverDocument{}).
+		Where("id = ? AND expiry_warned_at IS NULL", document.ID).
+		Update("expiry_warned_at", now)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	document.ExpiryWarnedAt = &now
+	return true, nil
+}
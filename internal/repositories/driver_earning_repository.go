@@ -0,0 +1,110 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/database"
+	"github.com/telemoz/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DriverEarningRepository interface {
+	Append(entry *models.DriverEarning) error
+	FindByID(id uuid.UUID) (*models.DriverEarning, error)
+	FindByJobID(jobID uuid.UUID) ([]models.DriverEarning, error)
+	FindByDriverBetween(driverID uuid.UUID, from, to time.Time) ([]models.DriverEarning, error)
+	FindDriverIDsBetween(from, to time.Time) ([]uuid.UUID, error)
+	FindCashTripsBetween(driverID uuid.UUID, from, to time.Time) ([]models.Trip, error)
+	FindEarliestDate() (*time.Time, error)
+}
+
+type driverEarningRepository struct {
+	db *gorm.DB
+}
+
+func NewDriverEarningRepository() DriverEarningRepository {
+	return &driverEarningRepository{
+		db: database.DB,
+	}
+}
+
+// Append adds an entry to the ledger and credits it to the job's actual earnings
+// in the same transaction
+func (r *driverEarningRepository) Append(entry *models.DriverEarning) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(entry).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Job{}).
+			Where("id = ?", entry.JobID).
+			Update("actual_earnings", gorm.Expr("COALESCE(actual_earnings, 0) + ?", entry.Amount)).Error
+	})
+}
+
+func (r *driverEarningRepository) FindByID(id uuid.UUID) (*models.DriverEarning, error) {
+	var entry models.DriverEarning
+	err := r.db.Where("id = ?", id).First(&entry).Error
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *driverEarningRepository) FindByJobID(jobID uuid.UUID) ([]models.DriverEarning, error) {
+	var entries []models.DriverEarning
+	err := r.db.Where("job_id = ?", jobID).
+		Order("created_at ASC").
+		Find(&entries).Error
+	return entries, err
+}
+
+// FindByDriverBetween returns a driver's ledger entries dated from..to inclusive
+func (r *driverEarningRepository) FindByDriverBetween(driverID uuid.UUID, from, to time.Time) ([]models.DriverEarning, error) {
+	var entries []models.DriverEarning
+	err := r.db.Where("driver_id = ? AND date >= ? AND date <= ?", driverID, from.Format("2006-01-02"), to.Format("2006-01-02")).
+		Order("date ASC, created_at ASC").
+		Find(&entries).Error
+	return entries, err
+}
+
+// FindDriverIDsBetween returns the drivers with ledger entries dated from..to inclusive
+func (r *driverEarningRepository) FindDriverIDsBetween(from, to time.Time) ([]uuid.UUID, error) {
+	var driverIDs []uuid.UUID
+	err := r.db.Model(&models.DriverEarning{}).
+		Where("date >= ? AND date <= ?", from.Format("2006-01-02"), to.Format("2006-01-02")).
+		Distinct().
+		Pluck("driver_id", &driverIDs).Error
+	return driverIDs, err
+}
+
+// FindEarliestDate returns the date of the oldest ledger entry, or nil when the
+// ledger is empty
+func (r *driverEarningRepository) FindEarliestDate() (*time.Time, error) {
+	var entry models.DriverEarning
+	err := r.db.Order("date ASC").First(&entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entry.Date, nil
+}
+
+// FindCashTripsBetween returns the cash trips whose fare was credited to the driver
+// from..to inclusive; the driver collected those fares from the customer directly
+func (r *driverEarningRepository) FindCashTripsBetween(driverID uuid.UUID, from, to time.Time) ([]models.Trip, error) {
+	var trips []models.Trip
+	err := r.db.Model(&models.Trip{}).
+		Joins("JOIN jobs ON jobs.trip_id = trips.id").
+		Joins("JOIN driver_earnings ON driver_earnings.job_id = jobs.id").
+		Where("driver_earnings.driver_id = ? AND driver_earnings.type = ?", driverID, models.EarningTypeTrip).
+		Where("driver_earnings.date >= ? AND driver_earnings.date <= ?", from.Format("2006-01-02"), to.Format("2006-01-02")).
+		Where("trips.payment_method = ?", "cash").
+		Order("trips.created_at ASC").
+		Find(&trips).Error
+	return trips, err
+}
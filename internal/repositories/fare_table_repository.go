@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/database"
+	"github.com/telemoz/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type FareTableRepository interface {
+	Create(table *models.FareTable) error
+	FindByID(id uuid.UUID) (*models.FareTable, error)
+	FindAll(serviceType string) ([]models.FareTable, error)
+	FindEffective(serviceType string, at time.Time) ([]models.FareTable, error)
+}
+
+type fareTableRepository struct {
+	db *gorm.DB
+}
+
+func NewFareTableRepository() FareTableRepository {
+	return &fareTableRepository{
+		db: database.DB,
+	}
+}
+
+// Create stores the table as the next version for its service type and zone
+func (r *fareTableRepository) Create(table *models.FareTable) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var latest int
+		err := tx.Model(&models.FareTable{}).
+			Where("service_type = ? AND zone = ?", table.ServiceType, table.Zone).
+			Select("COALESCE(MAX(version), 0)").
+			Scan(&latest).Error
+		if err != nil {
+			return err
+		}
+		table.Version = latest + 1
+		return tx.Create(table).Error
+	})
+}
+
+func (r *fareTableRepository) FindByID(id uuid.UUID) (*models.FareTable, error) {
+	var table models.FareTable
+	err := r.db.Where("id = ?", id).First(&table).Error
+	if err != nil {
+		return nil, err
+	}
+	return &table, nil
+}
+
+// FindAll returns every version, optionally of one service type only
+func (r *fareTableRepository) FindAll(serviceType string) ([]models.FareTable, error) {
+	var tables []models.FareTable
+	query := r.db.Order("service_type ASC, zone ASC, version DESC")
+	if serviceType != "" {
+		query = query.Where("service_type = ?", serviceType)
+	}
+	err := query.Find(&tables).Error
+	return tables, err
+}
+
+// FindEffective returns the versions of a service type in effect at the given time,
+// in every zone
+func (r *fareTableRepository) FindEffective(serviceType string, at time.Time) ([]models.FareTable, error) {
+	var tables []models.FareTable
+	err := r.db.Where("service_type = ? AND effective_from <= ?", serviceType, at).
+		Where("effective_to IS NULL OR effective_to > ?", at).
+		Find(&tables).Error
+	return tables, err
+}
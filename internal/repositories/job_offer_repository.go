@@ -0,0 +1,104 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/database"
+	"github.com/telemoz/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type JobOfferRepository interface {
+	Create(offer *models.JobOffer) error
+	FindActive(jobID, driverID uuid.UUID) (*models.JobOffer, error)
+	FindActiveByDriverID(driverID uuid.UUID) ([]models.JobOffer, error)
+	FindOfferedDriverIDs(jobID uuid.UUID) ([]uuid.UUID, error)
+	FindActiveDriverIDs(jobID uuid.UUID) ([]uuid.UUID, error)
+	CountActiveByJobID(jobID uuid.UUID) (int64, error)
+	Update(offer *models.JobOffer) error
+	ExpireDue(now time.Time) error
+	WithdrawByJobID(jobID uuid.UUID) error
+}
+
+type jobOfferRepository struct {
+	db *gorm.DB
+}
+
+func NewJobOfferRepository() JobOfferRepository {
+	return &jobOfferRepository{
+		db: database.DB,
+	}
+}
+
+func (r *jobOfferRepository) Create(offer *models.JobOffer) error {
+	return r.db.Create(offer).Error
+}
+
+// FindActive finds the unexpired offer of a job to a driver
+func (r *jobOfferRepository) FindActive(jobID, driverID uuid.UUID) (*models.JobOffer, error) {
+	var offer models.JobOffer
+	err := r.db.Where("job_id = ? AND driver_id = ? AND status = ? AND expires_at > ?",
+		jobID, driverID, models.JobOfferStatusOffered, time.Now()).
+		First(&offer).Error
+	if err != nil {
+		return nil, err
+	}
+	return &offer, nil
+}
+
+func (r *jobOfferRepository) FindActiveByDriverID(driverID uuid.UUID) ([]models.JobOffer, error) {
+	var offers []models.JobOffer
+	err := r.db.Where("driver_id = ? AND status = ? AND expires_at > ?",
+		driverID, models.JobOfferStatusOffered, time.Now()).
+		Preload("Job").Preload("Job.Trip").Preload("Job.Trip.Customer").
+		Preload("Job.Trip.Waypoints", orderedWaypoints).Preload("Job.Trip.Package").
+		Order("created_at DESC").
+		Find(&offers).Error
+	return offers, err
+}
+
+// FindOfferedDriverIDs returns every driver the job has been offered to in any wave
+func (r *jobOfferRepository) FindOfferedDriverIDs(jobID uuid.UUID) ([]uuid.UUID, error) {
+	var driverIDs []uuid.UUID
+	err := r.db.Model(&models.JobOffer{}).
+		Where("job_id = ?", jobID).
+		Distinct().
+		Pluck("driver_id", &driverIDs).Error
+	return driverIDs, err
+}
+
+// FindActiveDriverIDs returns the drivers currently holding an open offer for the job
+func (r *jobOfferRepository) FindActiveDriverIDs(jobID uuid.UUID) ([]uuid.UUID, error) {
+	var driverIDs []uuid.UUID
+	err := r.db.Model(&models.JobOffer{}).
+		Where("job_id = ? AND status = ? AND expires_at > ?", jobID, models.JobOfferStatusOffered, time.Now()).
+		Pluck("driver_id", &driverIDs).Error
+	return driverIDs, err
+}
+
+func (r *jobOfferRepository) CountActiveByJobID(jobID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.JobOffer{}).
+		Where("job_id = ? AND status = ? AND expires_at > ?", jobID, models.JobOfferStatusOffered, time.Now()).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *jobOfferRepository) Update(offer *models.JobOffer) error {
+	return r.db.Save(offer).Error
+}
+
+// ExpireDue marks offers whose timeout has passed without a response as expired
+func (r *jobOfferRepository) ExpireDue(now time.Time) error {
+	return r.db.Model(&models.JobOffer{}).
+		Where("status = ? AND expires_at <= ?", models.JobOfferStatusOffered, now).
+		Update("status", models.JobOfferStatusExpired).Error
+}
+
+// WithdrawByJobID withdraws all outstanding offers for a job, e.g. once it has been taken
+func (r *jobOfferRepository) WithdrawByJobID(jobID uuid.UUID) error {
+	return r.db.Model(&models.JobOffer{}).
+		Where("job_id = ? AND status = ?", jobID, models.JobOfferStatusOffered).
+		Update("status", models.JobOfferStatusWithdrawn).Error
+}
@@ -1,6 +1,8 @@
 package repositories
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/telemoz/backend/internal/database"
 	"github.com/telemoz/backend/internal/models"
@@ -10,10 +12,15 @@ import (
 type JobRepository interface {
 	Create(job *models.Job) error
 	FindByID(id uuid.UUID) (*models.Job, error)
-	FindAvailable() ([]models.Job, error)
+	FindByTripID(tripID uuid.UUID) (*models.Job, error)
+	FindAwaitingDispatch(now time.Time) ([]models.Job, error)
+	FindBusyDriverIDs() ([]uuid.UUID, error)
 	FindActiveByDriverID(driverID uuid.UUID) (*models.Job, error)
 	FindHistoryByDriverID(driverID uuid.UUID, limit, offset int) ([]models.Job, error)
+	FindScheduled(after time.Time) ([]models.Job, error)
+	FindScheduledByDriverID(driverID uuid.UUID) ([]models.Job, error)
 	Update(job *models.Job) error
+	UpdateDispatch(job *models.Job) error
 	Delete(id uuid.UUID) error
 }
 
@@ -33,7 +40,7 @@ func (r *jobRepository) Create(job *models.Job) error {
 
 func (r *jobRepository) FindByID(id uuid.UUID) (*models.Job, error) {
 	var job models.Job
-	err := r.db.Preload("Trip").Preload("Trip.Customer").Preload("Driver").
+	err := r.db.Preload("Trip").Preload("Trip.Customer").Preload("Trip.Waypoints", orderedWaypoints).Preload("Trip.Package").Preload("Driver").
 		Where("id = ?", id).First(&job).Error
 	if err != nil {
 		return nil, err
@@ -41,19 +48,40 @@ func (r *jobRepository) FindByID(id uuid.UUID) (*models.Job, error) {
 	return &job, nil
 }
 
-func (r *jobRepository) FindAvailable() ([]models.Job, error) {
+func (r *jobRepository) FindByTripID(tripID uuid.UUID) (*models.Job, error) {
+	var job models.Job
+	err := r.db.Where("trip_id = ?", tripID).
+		Order("created_at DESC").First(&job).Error
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// FindAwaitingDispatch finds pending jobs that have never been dispatched or whose current wave has ended
+func (r *jobRepository) FindAwaitingDispatch(now time.Time) ([]models.Job, error) {
 	var jobs []models.Job
-	err := r.db.Where("status = ?", models.JobStatusPending).
-		Preload("Trip").Preload("Trip.Customer").
-		Order("created_at DESC").
+	err := r.db.Where("status = ? AND (wave_ends_at IS NULL OR wave_ends_at <= ?)", models.JobStatusPending, now).
+		Preload("Trip").
+		Order("created_at ASC").
 		Find(&jobs).Error
 	return jobs, err
 }
 
+// FindBusyDriverIDs returns drivers that currently hold an accepted or in-progress job
+func (r *jobRepository) FindBusyDriverIDs() ([]uuid.UUID, error) {
+	var driverIDs []uuid.UUID
+	err := r.db.Model(&models.Job{}).
+		Where("driver_id IS NOT NULL AND status IN ?", []string{"accepted", "arrived", "in_progress"}).
+		Distinct().
+		Pluck("driver_id", &driverIDs).Error
+	return driverIDs, err
+}
+
 func (r *jobRepository) FindActiveByDriverID(driverID uuid.UUID) (*models.Job, error) {
 	var job models.Job
-	err := r.db.Where("driver_id = ? AND status IN ?", driverID, []string{"accepted", "in_progress"}).
-		Preload("Trip").Preload("Trip.Customer").Preload("Driver").
+	err := r.db.Where("driver_id = ? AND status IN ?", driverID, []string{"accepted", "arrived", "in_progress"}).
+		Preload("Trip").Preload("Trip.Customer").Preload("Trip.Waypoints", orderedWaypoints).Preload("Trip.Package").Preload("Driver").
 		Order("created_at DESC").First(&job).Error
 	if err != nil {
 		return nil, err
@@ -64,17 +92,52 @@ func (r *jobRepository) FindActiveByDriverID(driverID uuid.UUID) (*models.Job, e
 func (r *jobRepository) FindHistoryByDriverID(driverID uuid.UUID, limit, offset int) ([]models.Job, error) {
 	var jobs []models.Job
 	err := r.db.Where("driver_id = ?", driverID).
-		Preload("Trip").Preload("Trip.Customer").
+		Preload("Trip").Preload("Trip.Customer").Preload("Trip.Waypoints", orderedWaypoints).Preload("Trip.Package").
 		Order("created_at DESC").
 		Limit(limit).Offset(offset).
 		Find(&jobs).Error
 	return jobs, err
 }
 
+// FindScheduled finds the jobs of scheduled trips picked up after the given time
+// that no driver has pre-accepted yet, soonest first
+func (r *jobRepository) FindScheduled(after time.Time) ([]models.Job, error) {
+	var jobs []models.Job
+	err := r.db.Joins("JOIN trips ON trips.id = jobs.trip_id").
+		Where("jobs.status = ? AND jobs.driver_id IS NULL", models.JobStatusPending).
+		Where("trips.status = ? AND trips.scheduled_at > ?", models.TripStatusPending, after).
+		Preload("Trip").Preload("Trip.Waypoints", orderedWaypoints).Preload("Trip.Package").
+		Order("trips.scheduled_at ASC").
+		Find(&jobs).Error
+	return jobs, err
+}
+
+// FindScheduledByDriverID finds the scheduled jobs a driver has pre-accepted, soonest first
+func (r *jobRepository) FindScheduledByDriverID(driverID uuid.UUID) ([]models.Job, error) {
+	var jobs []models.Job
+	err := r.db.Joins("JOIN trips ON trips.id = jobs.trip_id").
+		Where("jobs.status = ? AND jobs.driver_id = ?", models.JobStatusPending, driverID).
+		Where("trips.status = ? AND trips.scheduled_at IS NOT NULL", models.TripStatusPending).
+		Preload("Trip").Preload("Trip.Customer").Preload("Trip.Waypoints", orderedWaypoints).Preload("Trip.Package").
+		Order("trips.scheduled_at ASC").
+		Find(&jobs).Error
+	return jobs, err
+}
+
 func (r *jobRepository) Update(job *models.Job) error {
 	return r.db.Save(job).Error
 }
 
+// UpdateDispatch saves a pending job's dispatch progress (wave, wave end and, when
+// dispatch gives up, its status). Jobs that have left pending, e.g. because a driver
+// accepted them in the meantime, are left untouched.
+func (r *jobRepository) UpdateDispatch(job *models.Job) error {
+	return r.db.Model(job).
+		Where("status = ?", models.JobStatusPending).
+		Select("status", "dispatch_wave", "wave_ends_at", "updated_at").
+		Updates(job).Error
+}
+
 func (r *jobRepository) Delete(id uuid.UUID) error {
 	return r.db.Delete(&models.Job{}, id).Error
 }
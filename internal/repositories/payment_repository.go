@@ -0,0 +1,178 @@
+package repositories
+
+import (
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/database"
+	"github.com/telemoz/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PaymentMethodRepository interface {
+	Create(method *models.PaymentMethod) error
+	FindByID(id uuid.UUID) (*models.PaymentMethod, error)
+	FindByCustomerID(customerID uuid.UUID) ([]models.PaymentMethod, error)
+	FindDefault(customerID uuid.UUID) (*models.PaymentMethod, error)
+	SetDefault(method *models.PaymentMethod) error
+	Delete(id uuid.UUID) error
+}
+
+type PaymentRepository interface {
+	Create(payment *models.Payment) error
+	FindByID(id uuid.UUID) (*models.Payment, error)
+	FindByTripID(tripID uuid.UUID) (*models.Payment, error)
+	FindByGatewayReference(reference string) (*models.Payment, error)
+	Update(payment *models.Payment) error
+	CreateRefund(payment *models.Payment, refund *models.PaymentRefund) error
+	FindRefundByGatewayReference(reference string) (*models.PaymentRefund, error)
+	UpdateRefund(payment *models.Payment, refund *models.PaymentRefund) error
+	WebhookEventSeen(eventID string) (bool, error)
+	RecordWebhookEvent(event *models.PaymentWebhookEvent) error
+}
+
+type paymentMethodRepository struct {
+	db *gorm.DB
+}
+
+func NewPaymentMethodRepository() PaymentMethodRepository {
+	return &paymentMethodRepository{
+		db: database.DB,
+	}
+}
+
+func (r *paymentMethodRepository) Create(method *models.PaymentMethod) error {
+	return r.db.Create(method).Error
+}
+
+func (r *paymentMethodRepository) FindByID(id uuid.UUID) (*models.PaymentMethod, error) {
+	var method models.PaymentMethod
+	err := r.db.Where("id = ?", id).First(&method).Error
+	if err != nil {
+		return nil, err
+	}
+	return &method, nil
+}
+
+// FindByCustomerID returns a customer's saved cards, the default first
+func (r *paymentMethodRepository) FindByCustomerID(customerID uuid.UUID) ([]models.PaymentMethod, error) {
+	var methods []models.PaymentMethod
+	err := r.db.Where("customer_id = ?", customerID).
+		Order("is_default DESC, created_at ASC").
+		Find(&methods).Error
+	return methods, err
+}
+
+func (r *paymentMethodRepository) FindDefault(customerID uuid.UUID) (*models.PaymentMethod, error) {
+	var method models.PaymentMethod
+	err := r.db.Where("customer_id = ? AND is_default = ?", customerID, true).First(&method).Error
+	if err != nil {
+		return nil, err
+	}
+	return &method, nil
+}
+
+// SetDefault makes method its customer's only default card
+func (r *paymentMethodRepository) SetDefault(method *models.PaymentMethod) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.PaymentMethod{}).
+			Where("customer_id = ? AND id <> ?", method.CustomerID, method.ID).
+			Update("is_default", false).Error; err != nil {
+			return err
+		}
+		method.IsDefault = true
+		return tx.Model(method).Update("is_default", true).Error
+	})
+}
+
+func (r *paymentMethodRepository) Delete(id uuid.UUID) error {
+	return r.db.Delete(&models.PaymentMethod{}, id).Error
+}
+
+type paymentRepository struct {
+	db *gorm.DB
+}
+
+func NewPaymentRepository() PaymentRepository {
+	return &paymentRepository{
+		db: database.DB,
+	}
+}
+
+func (r *paymentRepository) Create(payment *models.Payment) error {
+	return r.db.Omit(clause.Associations).Create(payment).Error
+}
+
+func (r *paymentRepository) FindByID(id uuid.UUID) (*models.Payment, error) {
+	var payment models.Payment
+	err := r.db.Preload("Refunds").Where("id = ?", id).First(&payment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (r *paymentRepository) FindByTripID(tripID uuid.UUID) (*models.Payment, error) {
+	var payment models.Payment
+	err := r.db.Preload("Refunds").Where("trip_id = ?", tripID).First(&payment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (r *paymentRepository) FindByGatewayReference(reference string) (*models.Payment, error) {
+	var payment models.Payment
+	err := r.db.Preload("Refunds").Where("gateway_reference = ?", reference).First(&payment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (r *paymentRepository) Update(payment *models.Payment) error {
+	return r.db.Omit(clause.Associations).Save(payment).Error
+}
+
+// CreateRefund records a refund and the payment's new refunded amount together
+func (r *paymentRepository) CreateRefund(payment *models.Payment, refund *models.PaymentRefund) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(refund).Error; err != nil {
+			return err
+		}
+		return tx.Omit(clause.Associations).Save(payment).Error
+	})
+}
+
+func (r *paymentRepository) FindRefundByGatewayReference(reference string) (*models.PaymentRefund, error) {
+	var refund models.PaymentRefund
+	err := r.db.Where("gateway_reference = ?", reference).First(&refund).Error
+	if err != nil {
+		return nil, err
+	}
+	return &refund, nil
+}
+
+// UpdateRefund saves a refund and its payment together
+func (r *paymentRepository) UpdateRefund(payment *models.Payment, refund *models.PaymentRefund) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(refund).Error; err != nil {
+			return err
+		}
+		return tx.Omit(clause.Associations).Save(payment).Error
+	})
+}
+
+// WebhookEventSeen reports whether a webhook event has already been processed
+func (r *paymentRepository) WebhookEventSeen(eventID string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.PaymentWebhookEvent{}).Where("event_id = ?", eventID).Count(&count).Error
+	return count > 0, err
+}
+
+// RecordWebhookEvent marks a webhook event processed; recording it twice is not an error
+func (r *paymentRepository) RecordWebhookEvent(event *models.PaymentWebhookEvent) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "event_id"}},
+		DoNothing: true,
+	}).Create(event).Error
+}
@@ -0,0 +1,121 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/database"
+	"github.com/telemoz/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SettlementPeriodRepository interface {
+	Create(period *models.SettlementPeriod) error
+	FindByStartDate(start time.Time) (*models.SettlementPeriod, error)
+	// FindOldestUnsettled returns the earliest period whose settlement hasn't finished
+	FindOldestUnsettled() (*models.SettlementPeriod, error)
+	// FindLatest returns the period with the latest start date
+	FindLatest() (*models.SettlementPeriod, error)
+	Update(period *models.SettlementPeriod) error
+}
+
+type PayoutStatementRepository interface {
+	Create(statement *models.PayoutStatement) error
+	FindByID(id uuid.UUID) (*models.PayoutStatement, error)
+	FindByDriverID(driverID uuid.UUID, limit, offset int) ([]models.PayoutStatement, error)
+	FindByPeriodAndDriver(periodID, driverID uuid.UUID) (*models.PayoutStatement, error)
+	Update(statement *models.PayoutStatement) error
+}
+
+type settlementPeriodRepository struct {
+	db *gorm.DB
+}
+
+func NewSettlementPeriodRepository() SettlementPeriodRepository {
+	return &settlementPeriodRepository{
+		db: database.DB,
+	}
+}
+
+func (r *settlementPeriodRepository) Create(period *models.SettlementPeriod) error {
+	return r.db.Create(period).Error
+}
+
+func (r *settlementPeriodRepository) FindByStartDate(start time.Time) (*models.SettlementPeriod, error) {
+	var period models.SettlementPeriod
+	err := r.db.Where("start_date = ?", start.Format("2006-01-02")).First(&period).Error
+	if err != nil {
+		return nil, err
+	}
+	return &period, nil
+}
+
+func (r *settlementPeriodRepository) FindOldestUnsettled() (*models.SettlementPeriod, error) {
+	var period models.SettlementPeriod
+	err := r.db.Where("settled_at IS NULL").Order("start_date ASC").First(&period).Error
+	if err != nil {
+		return nil, err
+	}
+	return &period, nil
+}
+
+func (r *settlementPeriodRepository) FindLatest() (*models.SettlementPeriod, error) {
+	var period models.SettlementPeriod
+	err := r.db.Order("start_date DESC").First(&period).Error
+	if err != nil {
+		return nil, err
+	}
+	return &period, nil
+}
+
+func (r *settlementPeriodRepository) Update(period *models.SettlementPeriod) error {
+	return r.db.Save(period).Error
+}
+
+type payoutStatementRepository struct {
+	db *gorm.DB
+}
+
+func NewPayoutStatementRepository() PayoutStatementRepository {
+	return &payoutStatementRepository{
+		db: database.DB,
+	}
+}
+
+func (r *payoutStatementRepository) Create(statement *models.PayoutStatement) error {
+	return r.db.Omit(clause.Associations).Create(statement).Error
+}
+
+func (r *payoutStatementRepository) FindByID(id uuid.UUID) (*models.PayoutStatement, error) {
+	var statement models.PayoutStatement
+	err := r.db.Preload("Period").Where("id = ?", id).First(&statement).Error
+	if err != nil {
+		return nil, err
+	}
+	return &statement, nil
+}
+
+func (r *payoutStatementRepository) FindByDriverID(driverID uuid.UUID, limit, offset int) ([]models.PayoutStatement, error) {
+	var statements []models.PayoutStatement
+	err := r.db.Preload("Period").
+		Joins("JOIN settlement_periods ON settlement_periods.id = payout_statements.period_id").
+		Where("payout_statements.driver_id = ?", driverID).
+		Order("settlement_periods.start_date DESC").
+		Limit(limit).Offset(offset).
+		Find(&statements).Error
+	return statements, err
+}
+
+func (r *payoutStatementRepository) FindByPeriodAndDriver(periodID, driverID uuid.UUID) (*models.PayoutStatement, error) {
+	var statement models.PayoutStatement
+	err := r.db.Where("period_id = ? AND driver_id = ?", periodID, driverID).First(&statement).Error
+	if err != nil {
+		return nil, err
+	}
+	return &statement, nil
+}
+
+func (r *payoutStatementRepository) Update(statement *models.PayoutStatement) error {
+	return r.db.Omit(clause.Associations).Save(statement).Error
+}
@@ -0,0 +1,120 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/database"
+	"github.com/telemoz/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrPromoLimitReached is returned when a promo code has been redeemed as often as
+// its limits allow
+var ErrPromoLimitReached = errors.New("promo code redemption limit reached")
+
+type PromoCodeRepository interface {
+	Create(promo *models.PromoCode) error
+	FindByID(id uuid.UUID) (*models.PromoCode, error)
+	FindByCode(code string) (*models.PromoCode, error)
+	FindAll() ([]models.PromoCode, error)
+	Update(promo *models.PromoCode) error
+	// CountRedemptions returns how often a code is redeemed by a customer and in total
+	CountRedemptions(promoID, customerID uuid.UUID) (int64, int64, error)
+	// CreateTripWithRedemption creates a trip and its promo code redemption together,
+	// failing with ErrPromoLimitReached if the code's limits are used up
+	CreateTripWithRedemption(trip *models.Trip, redemption *models.PromoRedemption, promo *models.PromoCode) error
+	// ReleaseRedemption stops a trip's redemption counting towards its code's limits
+	ReleaseRedemption(tripID uuid.UUID) error
+}
+
+type promoCodeRepository struct {
+	db *gorm.DB
+}
+
+func NewPromoCodeRepository() PromoCodeRepository {
+	return &promoCodeRepository{
+		db: database.DB,
+	}
+}
+
+func (r *promoCodeRepository) Create(promo *models.PromoCode) error {
+	return r.db.Create(promo).Error
+}
+
+func (r *promoCodeRepository) FindByID(id uuid.UUID) (*models.PromoCode, error) {
+	var promo models.PromoCode
+	err := r.db.Where("id = ?", id).First(&promo).Error
+	if err != nil {
+		return nil, err
+	}
+	return &promo, nil
+}
+
+func (r *promoCodeRepository) FindByCode(code string) (*models.PromoCode, error) {
+	var promo models.PromoCode
+	err := r.db.Where("code = ?", code).First(&promo).Error
+	if err != nil {
+		return nil, err
+	}
+	return &promo, nil
+}
+
+func (r *promoCodeRepository) FindAll() ([]models.PromoCode, error) {
+	var promos []models.PromoCode
+	err := r.db.Order("created_at DESC").Find(&promos).Error
+	return promos, err
+}
+
+func (r *promoCodeRepository) Update(promo *models.PromoCode) error {
+	return r.db.Save(promo).Error
+}
+
+func (r *promoCodeRepository) CountRedemptions(promoID, customerID uuid.UUID) (int64, int64, error) {
+	return countRedemptions(r.db, promoID, customerID)
+}
+
+func (r *promoCodeRepository) CreateTripWithRedemption(trip *models.Trip, redemption *models.PromoRedemption, promo *models.PromoCode) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Concurrent redemptions of the code queue here, so each sees the others' counts
+		var locked models.PromoCode
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", promo.ID).First(&locked).Error; err != nil {
+			return err
+		}
+
+		byCustomer, total, err := countRedemptions(tx, promo.ID, trip.CustomerID)
+		if err != nil {
+			return err
+		}
+		if byCustomer >= int64(locked.PerUserLimit) ||
+			(locked.MaxRedemptions != nil && total >= int64(*locked.MaxRedemptions)) {
+			return ErrPromoLimitReached
+		}
+
+		if err := tx.Create(trip).Error; err != nil {
+			return err
+		}
+		redemption.TripID = trip.ID
+		return tx.Create(redemption).Error
+	})
+}
+
+func (r *promoCodeRepository) ReleaseRedemption(tripID uuid.UUID) error {
+	return r.db.Model(&models.PromoRedemption{}).
+		Where("trip_id = ? AND status = ?", tripID, models.PromoRedemptionRedeemed).
+		Update("status", models.PromoRedemptionReleased).Error
+}
+
+func countRedemptions(db *gorm.DB, promoID, customerID uuid.UUID) (int64, int64, error) {
+	var byCustomer, total int64
+	base := db.Model(&models.PromoRedemption{}).
+		Where("promo_code_id = ? AND status = ?", promoID, models.PromoRedemptionRedeemed)
+	if err := base.Session(&gorm.Session{}).Where("customer_id = ?", customerID).Count(&byCustomer).Error; err != nil {
+		return 0, 0, err
+	}
+	if err := base.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return 0, 0, err
+	}
+	return byCustomer, total, nil
+}
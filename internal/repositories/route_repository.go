@@ -0,0 +1,243 @@
+package repositories
+
+import (
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/database"
+	"github.com/telemoz/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type RouteRepository interface {
+	Create(route *models.Route) error
+	FindByID(id uuid.UUID) (*models.Route, error)
+	FindAll() ([]models.Route, error)
+	FindByBusID(busID uuid.UUID) ([]models.Route, error)
+	Update(route *models.Route) error
+	Delete(id uuid.UUID) error
+}
+
+type RouteStopRepository interface {
+	Create(stop *models.RouteStop) error
+	FindByID(id uuid.UUID) (*models.RouteStop, error)
+	FindByRouteID(routeID uuid.UUID) ([]models.RouteStop, error)
+	FindByBusID(busID uuid.UUID) ([]models.RouteStop, error)
+	Update(stop *models.RouteStop) error
+	Delete(id uuid.UUID) error
+}
+
+type ScheduleRepository interface {
+	Create(schedule *models.Schedule) error
+	FindByID(id uuid.UUID) (*models.Schedule, error)
+	FindByRouteID(routeID uuid.UUID) ([]models.Schedule, error)
+	FindByStopID(stopID uuid.UUID) ([]models.Schedule, error)
+	Update(schedule *models.Schedule) error
+	Delete(id uuid.UUID) error
+}
+
+type routeRepository struct {
+	db *gorm.DB
+}
+
+func NewRouteRepository() RouteRepository {
+	return &routeRepository{
+		db: database.DB,
+	}
+}
+
+func (r *routeRepository) Create(route *models.Route) error {
+	return r.db.Create(route).Error
+}
+
+func (r *routeRepository) FindByID(id uuid.UUID) (*models.Route, error) {
+	var route models.Route
+	err := r.db.Preload("Stops", func(db *gorm.DB) *gorm.DB {
+		return db.Order("sequence ASC")
+	}).Preload("Schedules.StopTimes").
+		Where("id = ?", id).First(&route).Error
+	if err != nil {
+		return nil, err
+	}
+	return &route, nil
+}
+
+func (r *routeRepository) FindAll() ([]models.Route, error) {
+	var routes []models.Route
+	err := r.db.Order("name ASC").Find(&routes).Error
+	return routes, err
+}
+
+func (r *routeRepository) FindByBusID(busID uuid.UUID) ([]models.Route, error) {
+	var routes []models.Route
+	err := r.db.Where("bus_id = ? AND is_active = ?", busID, true).
+		Order("name ASC").
+		Find(&routes).Error
+	return routes, err
+}
+
+func (r *routeRepository) Update(route *models.Route) error {
+	return r.db.Omit("Bus", "Stops", "Schedules").Save(route).Error
+}
+
+// Delete removes a route together with its stops and schedules and unassigns
+// children from its stops
+func (r *routeRepository) Delete(id uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		stopIDs := tx.Model(&models.RouteStop{}).Select("id").Where("route_id = ?", id)
+		scheduleIDs := tx.Model(&models.Schedule{}).Select("id").Where("route_id = ?", id)
+
+		if err := unassignStops(tx, stopIDs); err != nil {
+			return err
+		}
+		if err := tx.Where("schedule_id IN (?)", scheduleIDs).Delete(&models.ScheduleStopTime{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("route_id = ?", id).Delete(&models.Schedule{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("route_id = ?", id).Delete(&models.RouteStop{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Route{}, id).Error
+	})
+}
+
+type routeStopRepository struct {
+	db *gorm.DB
+}
+
+func NewRouteStopRepository() RouteStopRepository {
+	return &routeStopRepository{
+		db: database.DB,
+	}
+}
+
+func (r *routeStopRepository) Create(stop *models.RouteStop) error {
+	return r.db.Create(stop).Error
+}
+
+func (r *routeStopRepository) FindByID(id uuid.UUID) (*models.RouteStop, error) {
+	var stop models.RouteStop
+	err := r.db.Preload("Route").Where("id = ?", id).First(&stop).Error
+	if err != nil {
+		return nil, err
+	}
+	return &stop, nil
+}
+
+func (r *routeStopRepository) FindByRouteID(routeID uuid.UUID) ([]models.RouteStop, error) {
+	var stops []models.RouteStop
+	err := r.db.Where("route_id = ?", routeID).
+		Order("sequence ASC").
+		Find(&stops).Error
+	return stops, err
+}
+
+// FindByBusID returns the stops of every active route operated by the bus
+func (r *routeStopRepository) FindByBusID(busID uuid.UUID) ([]models.RouteStop, error) {
+	var stops []models.RouteStop
+	err := r.db.Joins("JOIN routes ON routes.id = route_stops.route_id").
+		Where("routes.bus_id = ? AND routes.is_active = ?", busID, true).
+		Order("route_stops.route_id, route_stops.sequence ASC").
+		Find(&stops).Error
+	return stops, err
+}
+
+func (r *routeStopRepository) Update(stop *models.RouteStop) error {
+	return r.db.Omit("Route").Save(stop).Error
+}
+
+// Delete removes a stop from its route and from every timetable and child that uses it
+func (r *routeStopRepository) Delete(id uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := unassignStops(tx, []uuid.UUID{id}); err != nil {
+			return err
+		}
+		if err := tx.Where("route_stop_id = ?", id).Delete(&models.ScheduleStopTime{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.RouteStop{}, id).Error
+	})
+}
+
+// unassignStops clears pickup and drop-off stops of children pointing at stopIDs,
+// which may be a slice of IDs or a subquery
+func unassignStops(tx *gorm.DB, stopIDs interface{}) error {
+	if err := tx.Model(&models.Child{}).Where("pickup_stop_id IN (?)", stopIDs).
+		Update("pickup_stop_id", nil).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.Child{}).Where("dropoff_stop_id IN (?)", stopIDs).
+		Update("dropoff_stop_id", nil).Error
+}
+
+type scheduleRepository struct {
+	db *gorm.DB
+}
+
+func NewScheduleRepository() ScheduleRepository {
+	return &scheduleRepository{
+		db: database.DB,
+	}
+}
+
+func (r *scheduleRepository) Create(schedule *models.Schedule) error {
+	return r.db.Create(schedule).Error
+}
+
+func (r *scheduleRepository) FindByID(id uuid.UUID) (*models.Schedule, error) {
+	var schedule models.Schedule
+	err := r.db.Preload("StopTimes").Where("id = ?", id).First(&schedule).Error
+	if err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+func (r *scheduleRepository) FindByRouteID(routeID uuid.UUID) ([]models.Schedule, error) {
+	var schedules []models.Schedule
+	err := r.db.Where("route_id = ?", routeID).
+		Preload("StopTimes").
+		Order("departure_time ASC").
+		Find(&schedules).Error
+	return schedules, err
+}
+
+// FindByStopID returns the active schedules with a timetabled arrival at the stop
+func (r *scheduleRepository) FindByStopID(stopID uuid.UUID) ([]models.Schedule, error) {
+	var schedules []models.Schedule
+	err := r.db.Where("is_active = ? AND id IN (?)", true,
+		r.db.Model(&models.ScheduleStopTime{}).Select("schedule_id").Where("route_stop_id = ?", stopID)).
+		Preload("StopTimes", "route_stop_id = ?", stopID).
+		Order("departure_time ASC").
+		Find(&schedules).Error
+	return schedules, err
+}
+
+// Update saves the schedule and replaces its stop times with schedule.StopTimes
+func (r *scheduleRepository) Update(schedule *models.Schedule) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("StopTimes").Save(schedule).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("schedule_id = ?", schedule.ID).Delete(&models.ScheduleStopTime{}).Error; err != nil {
+			return err
+		}
+		for i := range schedule.StopTimes {
+			schedule.StopTimes[i].ID = uuid.Nil
+			schedule.StopTimes[i].ScheduleID = schedule.ID
+		}
+		if len(schedule.StopTimes) == 0 {
+			return nil
+		}
+		return tx.Create(&schedule.StopTimes).Error
+	})
+}
+
+func (r *scheduleRepository) Delete(id uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("schedule_id = ?", id).Delete(&models.ScheduleStopTime{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Schedule{}, id).Error
+	})
+}
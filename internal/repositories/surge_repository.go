@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/telemoz/backend/internal/database"
+	"github.com/telemoz/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SurgeRepository interface {
+	Find(cell, serviceType string) (*models.SurgeZone, error)
+	FindSurging() ([]models.SurgeZone, error)
+	Save(zone *models.SurgeZone) error
+	DeleteUpdatedBefore(before time.Time) error
+}
+
+type surgeRepository struct {
+	db *gorm.DB
+}
+
+func NewSurgeRepository() SurgeRepository {
+	return &surgeRepository{
+		db: database.DB,
+	}
+}
+
+func (r *surgeRepository) Find(cell, serviceType string) (*models.SurgeZone, error) {
+	var zone models.SurgeZone
+	err := r.db.Where("cell = ? AND service_type = ?", cell, serviceType).First(&zone).Error
+	if err != nil {
+		return nil, err
+	}
+	return &zone, nil
+}
+
+// FindSurging returns the zones whose multiplier is above 1
+func (r *surgeRepository) FindSurging() ([]models.SurgeZone, error) {
+	var zones []models.SurgeZone
+	err := r.db.Where("multiplier > ?", 1).Find(&zones).Error
+	return zones, err
+}
+
+// Save inserts the zone or overwrites the stored reading for its cell and service type
+func (r *surgeRepository) Save(zone *models.SurgeZone) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "cell"}, {Name: "service_type"}},
+		DoUpdates: clause.AssignmentColumns([]string{"demand", "supply", "multiplier", "updated_at"}),
+	}).Create(zone).Error
+}
+
+// DeleteUpdatedBefore removes zones that have not been recomputed since the given time
+func (r *surgeRepository) DeleteUpdatedBefore(before time.Time) error {
+	return r.db.Where("updated_at < ?", before).Delete(&models.SurgeZone{}).Error
+}
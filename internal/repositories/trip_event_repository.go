@@ -0,0 +1,35 @@
+package repositories
+
+import (
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/database"
+	"github.com/telemoz/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type TripEventRepository interface {
+	Create(event *models.TripEvent) error
+	FindByTripID(tripID uuid.UUID) ([]models.TripEvent, error)
+}
+
+type tripEventRepository struct {
+	db *gorm.DB
+}
+
+func NewTripEventRepository() TripEventRepository {
+	return &tripEventRepository{
+		db: database.DB,
+	}
+}
+
+func (r *tripEventRepository) Create(event *models.TripEvent) error {
+	return r.db.Create(event).Error
+}
+
+func (r *tripEventRepository) FindByTripID(tripID uuid.UUID) ([]models.TripEvent, error) {
+	var events []models.TripEvent
+	err := r.db.Where("trip_id = ?", tripID).
+		Order("created_at ASC").
+		Find(&events).Error
+	return events, err
+}
@@ -0,0 +1,69 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/database"
+	"github.com/telemoz/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type TripMessageRepository interface {
+	Create(message *models.TripMessage) error
+	FindByTrip(tripID uuid.UUID, driverID *uuid.UUID, limit, offset int) ([]models.TripMessage, error)
+	CountUnread(tripID uuid.UUID, driverID *uuid.UUID, readerID uuid.UUID) (int64, error)
+	MarkRead(tripID uuid.UUID, driverID *uuid.UUID, readerID uuid.UUID, now time.Time) (int64, error)
+}
+
+type tripMessageRepository struct {
+	db *gorm.DB
+}
+
+func NewTripMessageRepository() TripMessageRepository {
+	return &tripMessageRepository{
+		db: database.DB,
+	}
+}
+
+func (r *tripMessageRepository) Create(message *models.TripMessage) error {
+	return r.db.Create(message).Error
+}
+
+// FindByTrip returns a trip's messages, newest first. A non-nil driverID limits them
+// to the conversation with that driver.
+func (r *tripMessageRepository) FindByTrip(tripID uuid.UUID, driverID *uuid.UUID, limit, offset int) ([]models.TripMessage, error) {
+	var messages []models.TripMessage
+	err := r.thread(tripID, driverID).
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&messages).Error
+	return messages, err
+}
+
+// CountUnread counts the messages sent to the reader that they haven't read yet
+func (r *tripMessageRepository) CountUnread(tripID uuid.UUID, driverID *uuid.UUID, readerID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.thread(tripID, driverID).
+		Where("sender_id <> ? AND read_at IS NULL", readerID).
+		Count(&count).Error
+	return count, err
+}
+
+// MarkRead marks every message sent to the reader up to now as read, returning how
+// many were newly read
+func (r *tripMessageRepository) MarkRead(tripID uuid.UUID, driverID *uuid.UUID, readerID uuid.UUID, now time.Time) (int64, error) {
+	result := r.thread(tripID, driverID).
+		Where("sender_id <> ? AND read_at IS NULL AND created_at <= ?", readerID, now).
+		Update("read_at", now)
+	return result.RowsAffected, result.Error
+}
+
+func (r *tripMessageRepository) thread(tripID uuid.UUID, driverID *uuid.UUID) *gorm.DB {
+	query := r.db.Model(&models.TripMessage{}).Where("trip_id = ?", tripID)
+	if driverID != nil {
+		query = query.Where("driver_id = ?", *driverID)
+	}
+	return query
+}
@@ -0,0 +1,59 @@
+package repositories
+
+import (
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/database"
+	"github.com/telemoz/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type TripTrackRepository interface {
+	Create(point *models.TripTrackPoint) error
+	FindByTripID(tripID uuid.UUID) ([]models.TripTrackPoint, error)
+}
+
+type TripReceiptRepository interface {
+	FindByTripID(tripID uuid.UUID) (*models.TripReceipt, error)
+}
+
+type tripTrackRepository struct {
+	db *gorm.DB
+}
+
+func NewTripTrackRepository() TripTrackRepository {
+	return &tripTrackRepository{
+		db: database.DB,
+	}
+}
+
+func (r *tripTrackRepository) Create(point *models.TripTrackPoint) error {
+	return r.db.Create(point).Error
+}
+
+// FindByTripID returns a trip's track in the order it was recorded
+func (r *tripTrackRepository) FindByTripID(tripID uuid.UUID) ([]models.TripTrackPoint, error) {
+	var points []models.TripTrackPoint
+	err := r.db.Where("trip_id = ?", tripID).
+		Order("recorded_at ASC").
+		Find(&points).Error
+	return points, err
+}
+
+type tripReceiptRepository struct {
+	db *gorm.DB
+}
+
+func NewTripReceiptRepository() TripReceiptRepository {
+	return &tripReceiptRepository{
+		db: database.DB,
+	}
+}
+
+func (r *tripReceiptRepository) FindByTripID(tripID uuid.UUID) (*models.TripReceipt, error) {
+	var receipt models.TripReceipt
+	err := r.db.Where("trip_id = ?", tripID).First(&receipt).Error
+	if err != nil {
+		return nil, err
+	}
+	return &receipt, nil
+}
@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/database"
+	"github.com/telemoz/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type TripRatingRepository interface {
+	Create(rating *models.TripRating) error
+	FindByTripAndRater(tripID, raterID uuid.UUID) (*models.TripRating, error)
+	RollingAverage(rateeID uuid.UUID, limit int) (float64, int, error)
+}
+
+type tripRatingRepository struct {
+	db *gorm.DB
+}
+
+func NewTripRatingRepository() TripRatingRepository {
+	return &tripRatingRepository{
+		db: database.DB,
+	}
+}
+
+func (r *tripRatingRepository) Create(rating *models.TripRating) error {
+	return r.db.Create(rating).Error
+}
+
+func (r *tripRatingRepository) FindByTripAndRater(tripID, raterID uuid.UUID) (*models.TripRating, error) {
+	var rating models.TripRating
+	err := r.db.Where("trip_id = ? AND rater_id = ?", tripID, raterID).First(&rating).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rating, nil
+}
+
+// RollingAverage averages the stars of the most recent ratings a user received,
+// returning the average and how many ratings it covers
+func (r *tripRatingRepository) RollingAverage(rateeID uuid.UUID, limit int) (float64, int, error) {
+	var result struct {
+		Average float64
+		Count   int
+	}
+	recent := r.db.Model(&models.TripRating{}).
+		Select("stars").
+		Where("ratee_id = ?", rateeID).
+		Order("created_at DESC").
+		Limit(limit)
+	err := r.db.Table("(?) AS recent", recent).
+		Select("COALESCE(AVG(stars), 0) AS average, COUNT(*) AS count").
+		Scan(&result).Error
+	return result.Average, result.Count, err
+}
@@ -1,24 +1,69 @@
 package repositories
 
 import (
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/telemoz/backend/internal/database"
 	"github.com/telemoz/backend/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrStatusChanged is returned when a row's status no longer matches the status an
+// update was based on because another request changed it first
+var ErrStatusChanged = errors.New("status changed concurrently")
+
+// ErrDriverCommitted is returned by PreAssign when the trip clashes with one the
+// driver already holds
+var ErrDriverCommitted = errors.New("driver already committed at that time")
+
+// TripTransition is a trip status change together with the rows that change with it.
+// Trip, Job and Offer hold the new values; each is only written while its stored
+// status still matches the one the change was based on.
+type TripTransition struct {
+	Trip          *models.Trip
+	FromStatus    models.TripStatus
+	Job           *models.Job
+	JobFromStatus models.JobStatus
+	// Offer is the accepted job offer, when the transition is a driver accepting one
+	Offer *models.JobOffer
+	// Earning is the driver's ledger entry and Receipt the metered fare, when the
+	// transition completes the trip. Cancellations and no-shows that charge the
+	// customer a fee also credit the driver.
+	Earning *models.DriverEarning
+	Receipt *models.TripReceipt
+	// Cancellation is recorded when the trip is cancelled, is a no-show or is given
+	// up by its driver
+	Cancellation *models.TripCancellation
+	Event        *models.TripEvent
+}
+
 type TripRepository interface {
 	Create(trip *models.Trip) error
 	FindByID(id uuid.UUID) (*models.Trip, error)
+	FindByQuoteID(quoteID uuid.UUID) (*models.Trip, error)
 	FindActiveByCustomerID(customerID uuid.UUID) (*models.Trip, error)
 	FindHistoryByCustomerID(customerID uuid.UUID, limit, offset int) ([]models.Trip, error)
 	FindByDriverID(driverID uuid.UUID) ([]models.Trip, error)
 	Update(trip *models.Trip) error
+	// UpdateRoute writes a trip's pickup and drop-off points, estimate, fare and ETA
+	// while it is still in status, and returns ErrStatusChanged otherwise
+	UpdateRoute(trip *models.Trip, status models.TripStatus) error
 	Delete(id uuid.UUID) error
 	FindPendingTrips() ([]models.Trip, error)
 	FindSearchingBefore(time time.Time) ([]models.Trip, error)
+	FindSearchStartedSince(since time.Time) ([]models.Trip, error)
+	FindScheduledByCustomerID(customerID uuid.UUID) ([]models.Trip, error)
+	FindScheduledDue(before time.Time) ([]models.Trip, error)
+	FindScheduledForReminder(before time.Time) ([]models.Trip, error)
+	ClaimReminder(trip *models.Trip, now time.Time) (bool, error)
+	// PreAssign assigns a scheduled trip to a driver unless conflicts reports a clash
+	// with the trips the driver is on or has pre-accepted
+	PreAssign(trip *models.Trip, job *models.Job, driverID uuid.UUID, conflicts func(commitments []models.Trip) bool) error
+	ReleasePreAssignment(trip *models.Trip, job *models.Job, driverID uuid.UUID) error
+	ApplyTransition(t *TripTransition) error
 }
 
 type tripRepository struct {
@@ -37,7 +82,16 @@ func (r *tripRepository) Create(trip *models.Trip) error {
 
 func (r *tripRepository) FindByID(id uuid.UUID) (*models.Trip, error) {
 	var trip models.Trip
-	err := r.db.Preload("Customer").Preload("Driver").Where("id = ?", id).First(&trip).Error
+	err := r.db.Preload("Customer").Preload("Driver").Preload("Waypoints", orderedWaypoints).Preload("Package").Where("id = ?", id).First(&trip).Error
+	if err != nil {
+		return nil, err
+	}
+	return &trip, nil
+}
+
+func (r *tripRepository) FindByQuoteID(quoteID uuid.UUID) (*models.Trip, error) {
+	var trip models.Trip
+	err := r.db.Where("quote_id = ?", quoteID).First(&trip).Error
 	if err != nil {
 		return nil, err
 	}
@@ -46,8 +100,10 @@ func (r *tripRepository) FindByID(id uuid.UUID) (*models.Trip, error) {
 
 func (r *tripRepository) FindActiveByCustomerID(customerID uuid.UUID) (*models.Trip, error) {
 	var trip models.Trip
-	err := r.db.Where("customer_id = ? AND status IN ?", customerID, []string{"pending", "accepted", "in_progress"}).
-		Preload("Customer").Preload("Driver").
+	// Scheduled trips waiting for their pickup time are not active yet
+	err := r.db.Where("customer_id = ? AND status IN ?", customerID, []string{"pending", "searching", "accepted", "arrived", "in_progress"}).
+		Where("status <> ? OR scheduled_at IS NULL", models.TripStatusPending).
+		Preload("Customer").Preload("Driver").Preload("Waypoints", orderedWaypoints).Preload("Package").
 		Order("created_at DESC").First(&trip).Error
 	if err != nil {
 		return nil, err
@@ -58,7 +114,7 @@ func (r *tripRepository) FindActiveByCustomerID(customerID uuid.UUID) (*models.T
 func (r *tripRepository) FindHistoryByCustomerID(customerID uuid.UUID, limit, offset int) ([]models.Trip, error) {
 	var trips []models.Trip
 	err := r.db.Where("customer_id = ?", customerID).
-		Preload("Customer").Preload("Driver").
+		Preload("Customer").Preload("Driver").Preload("Waypoints", orderedWaypoints).Preload("Package").
 		Order("created_at DESC").
 		Limit(limit).Offset(offset).
 		Find(&trips).Error
@@ -68,7 +124,7 @@ func (r *tripRepository) FindHistoryByCustomerID(customerID uuid.UUID, limit, of
 func (r *tripRepository) FindByDriverID(driverID uuid.UUID) ([]models.Trip, error) {
 	var trips []models.Trip
 	err := r.db.Where("driver_id = ?", driverID).
-		Preload("Customer").Preload("Driver").
+		Preload("Customer").Preload("Driver").Preload("Waypoints", orderedWaypoints).Preload("Package").
 		Order("created_at DESC").
 		Find(&trips).Error
 	return trips, err
@@ -78,6 +134,18 @@ func (r *tripRepository) Update(trip *models.Trip) error {
 	return r.db.Save(trip).Error
 }
 
+func (r *tripRepository) UpdateRoute(trip *models.Trip, status models.TripStatus) error {
+	result := r.db.Model(&models.Trip{}).
+		Where("id = ? AND status = ?", trip.ID, status).
+		Select(
+			"pickup_latitude", "pickup_longitude", "dropoff_latitude", "dropoff_longitude",
+			"estimated_distance", "estimated_duration", "distance_method", "estimated_arrival",
+			"fare_amount", "discount_amount", "surge_multiplier", "fare_table_id", "updated_at",
+		).
+		Updates(trip)
+	return checkTransitioned(result)
+}
+
 func (r *tripRepository) Delete(id uuid.UUID) error {
 	return r.db.Delete(&models.Trip{}, id).Error
 }
@@ -98,3 +166,189 @@ func (r *tripRepository) FindSearchingBefore(before time.Time) ([]models.Trip, e
 		Find(&trips).Error
 	return trips, err
 }
+
+// FindSearchStartedSince finds the trips that started searching for a driver at or after
+// the given time, whatever their status now
+func (r *tripRepository) FindSearchStartedSince(since time.Time) ([]models.Trip, error) {
+	var trips []models.Trip
+	err := r.db.Where("search_started_at >= ?", since).
+		Find(&trips).Error
+	return trips, err
+}
+
+// FindScheduledByCustomerID finds a customer's scheduled trips that haven't been
+// dispatched yet, soonest first
+func (r *tripRepository) FindScheduledByCustomerID(customerID uuid.UUID) ([]models.Trip, error) {
+	var trips []models.Trip
+	err := r.db.Where("customer_id = ? AND status = ? AND scheduled_at IS NOT NULL", customerID, models.TripStatusPending).
+		Preload("Driver").Preload("Waypoints", orderedWaypoints).Preload("Package").
+		Order("scheduled_at ASC").
+		Find(&trips).Error
+	return trips, err
+}
+
+// FindScheduledDue finds the scheduled trips still waiting to be dispatched whose
+// dispatch time is before the given time
+func (r *tripRepository) FindScheduledDue(before time.Time) ([]models.Trip, error) {
+	var trips []models.Trip
+	err := r.db.Where("status = ? AND scheduled_at <= ?", models.TripStatusPending, before).
+		Order("scheduled_at ASC").
+		Find(&trips).Error
+	return trips, err
+}
+
+// FindScheduledForReminder finds the scheduled trips picked up before the given time
+// whose customer hasn't been reminded yet
+func (r *tripRepository) FindScheduledForReminder(before time.Time) ([]models.Trip, error) {
+	var trips []models.Trip
+	err := r.db.Where("status = ? AND scheduled_at <= ? AND reminder_sent_at IS NULL", models.TripStatusPending, before).
+		Order("scheduled_at ASC").
+		Find(&trips).Error
+	return trips, err
+}
+
+// ClaimReminder marks a trip's reminder as sent. It returns false when another
+// instance sent it first.
+func (r *tripRepository) ClaimReminder(trip *models.Trip, now time.Time) (bool, error) {
+	result := r.db.Model(&models.Trip{}).
+		Where("id = ? AND reminder_sent_at IS NULL", trip.ID).
+		Update("reminder_sent_at", now)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	trip.ReminderSentAt = &now
+	return true, nil
+}
+
+// PreAssign assigns a scheduled trip and its job to a driver ahead of dispatch. It
+// returns ErrStatusChanged when the trip has left pending or another driver holds it,
+// and ErrDriverCommitted when conflicts rejects the driver's other trips.
+func (r *tripRepository) PreAssign(trip *models.Trip, job *models.Job, driverID uuid.UUID, conflicts func(commitments []models.Trip) bool) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Pre-accepts by the same driver queue here, so each sees the trips the others took
+		var availability models.DriverAvailability
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("driver_id = ?", driverID).First(&availability).Error; err != nil {
+			return err
+		}
+
+		var commitments []models.Trip
+		err := tx.Joins("JOIN jobs ON jobs.trip_id = trips.id").
+			Where("jobs.driver_id = ?", driverID).
+			Where("(jobs.status = ? AND trips.status = ? AND trips.scheduled_at IS NOT NULL) OR jobs.status IN ?",
+				models.JobStatusPending, models.TripStatusPending,
+				[]models.JobStatus{models.JobStatusAccepted, models.JobStatusArrived, models.JobStatusInProgress}).
+			Find(&commitments).Error
+		if err != nil {
+			return err
+		}
+		if conflicts(commitments) {
+			return ErrDriverCommitted
+		}
+
+		result := tx.Model(&models.Trip{}).
+			Where("id = ? AND status = ? AND driver_id IS NULL", trip.ID, models.TripStatusPending).
+			Update("driver_id", driverID)
+		if err := checkTransitioned(result); err != nil {
+			return err
+		}
+		result = tx.Model(&models.Job{}).
+			Where("id = ? AND status = ? AND driver_id IS NULL", job.ID, models.JobStatusPending).
+			Update("driver_id", driverID)
+		if err := checkTransitioned(result); err != nil {
+			return err
+		}
+		trip.DriverID = &driverID
+		job.DriverID = &driverID
+		return nil
+	})
+}
+
+// ReleasePreAssignment gives a scheduled trip a driver pre-accepted back. It returns
+// ErrStatusChanged when the trip has left pending or isn't held by the driver.
+func (r *tripRepository) ReleasePreAssignment(trip *models.Trip, job *models.Job, driverID uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Trip{}).
+			Where("id = ? AND status = ? AND driver_id = ?", trip.ID, models.TripStatusPending, driverID).
+			Update("driver_id", nil)
+		if err := checkTransitioned(result); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Job{}).
+			Where("id = ? AND status = ? AND driver_id = ?", job.ID, models.JobStatusPending, driverID).
+			Update("driver_id", nil).Error; err != nil {
+			return err
+		}
+		trip.DriverID = nil
+		job.DriverID = nil
+		return nil
+	})
+}
+
+// ApplyTransition writes a trip transition in one transaction. The trip, job and
+// offer are updated conditionally on their previous status, so of two concurrent
+// transitions from the same status exactly one succeeds; the other gets
+// ErrStatusChanged and nothing it wrote is kept.
+func (r *tripRepository) ApplyTransition(t *TripTransition) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(t.Trip).
+			Where("status = ?", t.FromStatus).
+			Select("*").Omit(clause.Associations, "created_at").
+			Updates(t.Trip)
+		if err := checkTransitioned(result); err != nil {
+			return err
+		}
+
+		if t.Job != nil {
+			result = tx.Model(t.Job).
+				Where("status = ?", t.JobFromStatus).
+				Select("*").Omit(clause.Associations, "created_at").
+				Updates(t.Job)
+			if err := checkTransitioned(result); err != nil {
+				return err
+			}
+		}
+
+		if t.Offer != nil {
+			result = tx.Model(t.Offer).
+				Where("status = ?", models.JobOfferStatusOffered).
+				Select("status", "responded_at", "updated_at").
+				Updates(t.Offer)
+			if err := checkTransitioned(result); err != nil {
+				return err
+			}
+		}
+
+		if t.Earning != nil {
+			if err := tx.Omit(clause.Associations).Create(t.Earning).Error; err != nil {
+				return err
+			}
+		}
+
+		if t.Receipt != nil {
+			if err := tx.Create(t.Receipt).Error; err != nil {
+				return err
+			}
+		}
+
+		if t.Cancellation != nil {
+			if err := tx.Create(t.Cancellation).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Create(t.Event).Error
+	})
+}
+
+func checkTransitioned(result *gorm.DB) error {
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrStatusChanged
+	}
+	return nil
+}
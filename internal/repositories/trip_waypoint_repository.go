@@ -0,0 +1,86 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/database"
+	"github.com/telemoz/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrPINAttemptsExhausted is returned when a waypoint has no delivery PIN attempts left
+var ErrPINAttemptsExhausted = errors.New("delivery PIN attempts exhausted")
+
+type TripWaypointRepository interface {
+	FindByID(id uuid.UUID) (*models.TripWaypoint, error)
+	FindByTripID(tripID uuid.UUID) ([]models.TripWaypoint, error)
+	UpdateProgress(waypoint *models.TripWaypoint, fromStatus models.WaypointStatus) error
+	ClaimPINAttempt(waypoint *models.TripWaypoint, maxAttempts int) error
+	ResetPINAttempts(waypoint *models.TripWaypoint) error
+}
+
+type tripWaypointRepository struct {
+	db *gorm.DB
+}
+
+func NewTripWaypointRepository() TripWaypointRepository {
+	return &tripWaypointRepository{
+		db: database.DB,
+	}
+}
+
+func (r *tripWaypointRepository) FindByID(id uuid.UUID) (*models.TripWaypoint, error) {
+	var waypoint models.TripWaypoint
+	err := r.db.Where("id = ?", id).First(&waypoint).Error
+	if err != nil {
+		return nil, err
+	}
+	return &waypoint, nil
+}
+
+func (r *tripWaypointRepository) FindByTripID(tripID uuid.UUID) ([]models.TripWaypoint, error) {
+	var waypoints []models.TripWaypoint
+	err := r.db.Where("trip_id = ?", tripID).
+		Order("sequence ASC").
+		Find(&waypoints).Error
+	return waypoints, err
+}
+
+// UpdateProgress saves a waypoint's status and proof of delivery while its stored
+// status is still fromStatus, returning ErrStatusChanged otherwise
+func (r *tripWaypointRepository) UpdateProgress(waypoint *models.TripWaypoint, fromStatus models.WaypointStatus) error {
+	result := r.db.Model(waypoint).
+		Where("status = ?", fromStatus).
+		Select("status", "arrived_at", "completed_at", "failure_reason", "proof_type", "proof_key", "recipient_name", "updated_at").
+		Updates(waypoint)
+	return checkTransitioned(result)
+}
+
+// ClaimPINAttempt counts a delivery PIN attempt against a waypoint before the PIN is
+// checked and updates its PINAttempts to the stored count. Once maxAttempts have been
+// made it returns ErrPINAttemptsExhausted, so concurrent attempts can't get past it.
+func (r *tripWaypointRepository) ClaimPINAttempt(waypoint *models.TripWaypoint, maxAttempts int) error {
+	result := r.db.Model(waypoint).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "pin_attempts"}}}).
+		Where("pin_attempts < ?", maxAttempts).
+		Update("pin_attempts", gorm.Expr("pin_attempts + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrPINAttemptsExhausted
+	}
+	return nil
+}
+
+// ResetPINAttempts clears a waypoint's count of PIN attempts
+func (r *tripWaypointRepository) ResetPINAttempts(waypoint *models.TripWaypoint) error {
+	return r.db.Model(waypoint).Update("pin_attempts", 0).Error
+}
+
+// orderedWaypoints preloads a trip's waypoints in the order they are visited
+func orderedWaypoints(db *gorm.DB) *gorm.DB {
+	return db.Order("trip_waypoints.sequence ASC")
+}
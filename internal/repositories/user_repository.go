@@ -1,6 +1,8 @@
 package repositories
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/telemoz/backend/internal/database"
 	"github.com/telemoz/backend/internal/models"
@@ -15,6 +17,9 @@ type UserRepository interface {
 	FindByEmailOrPhone(emailOrPhone string) (*models.User, error)
 	Update(user *models.User) error
 	Delete(id uuid.UUID) error
+	FindByIDs(ids []uuid.UUID) ([]models.User, error)
+	UpdateRating(userID uuid.UUID, average float64, count int, flaggedAt *time.Time) error
+	FindFlaggedDrivers(limit, offset int) ([]models.User, error)
 }
 
 type userRepository struct {
@@ -75,3 +80,34 @@ func (r *userRepository) Delete(id uuid.UUID) error {
 	return r.db.Delete(&models.User{}, id).Error
 }
 
+func (r *userRepository) FindByIDs(ids []uuid.UUID) ([]models.User, error) {
+	var users []models.User
+	if len(ids) == 0 {
+		return users, nil
+	}
+	err := r.db.Where("id IN ?", ids).Find(&users).Error
+	return users, err
+}
+
+// UpdateRating stores a user's rolling rating and whether they are flagged for
+// review, leaving the rest of their profile alone
+func (r *userRepository) UpdateRating(userID uuid.UUID, average float64, count int, flaggedAt *time.Time) error {
+	return r.db.Model(&models.User{}).
+		Where("id = ?", userID).
+		Updates(map[string]interface{}{
+			"rating_average":    average,
+			"rating_count":      count,
+			"rating_flagged_at": flaggedAt,
+		}).Error
+}
+
+// FindFlaggedDrivers returns drivers flagged for their rating, longest flagged first
+func (r *userRepository) FindFlaggedDrivers(limit, offset int) ([]models.User, error) {
+	var users []models.User
+	err := r.db.Where("user_type = ? AND rating_flagged_at IS NOT NULL", models.UserTypeDriver).
+		Order("rating_flagged_at ASC").
+		Limit(limit).
+		Offset(offset).
+		Find(&users).Error
+	return users, err
+}
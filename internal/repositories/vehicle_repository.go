@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/database"
+	"github.com/telemoz/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type VehicleRepository interface {
+	Save(vehicle *models.Vehicle) error
+	FindByDriverID(driverID uuid.UUID) (*models.Vehicle, error)
+	FindByDriverIDs(driverIDs []uuid.UUID) ([]models.Vehicle, error)
+	FindByPlateNumber(plateNumber string) (*models.Vehicle, error)
+}
+
+type vehicleRepository struct {
+	db *gorm.DB
+}
+
+func NewVehicleRepository() VehicleRepository {
+	return &vehicleRepository{
+		db: database.DB,
+	}
+}
+
+func (r *vehicleRepository) Save(vehicle *models.Vehicle) error {
+	return r.db.Save(vehicle).Error
+}
+
+func (r *vehicleRepository) FindByDriverID(driverID uuid.UUID) (*models.Vehicle, error) {
+	var vehicle models.Vehicle
+	err := r.db.Where("driver_id = ?", driverID).First(&vehicle).Error
+	if err != nil {
+		return nil, err
+	}
+	return &vehicle, nil
+}
+
+func (r *vehicleRepository) FindByDriverIDs(driverIDs []uuid.UUID) ([]models.Vehicle, error) {
+	var vehicles []models.Vehicle
+	if len(driverIDs) == 0 {
+		return vehicles, nil
+	}
+	err := r.db.Where("driver_id IN ?", driverIDs).Find(&vehicles).Error
+	return vehicles, err
+}
+
+func (r *vehicleRepository) FindByPlateNumber(plateNumber string) (*models.Vehicle, error) {
+	var vehicle models.Vehicle
+	err := r.db.Where("plate_number = ?", plateNumber).First(&vehicle).Error
+	if err != nil {
+		return nil, err
+	}
+	return &vehicle, nil
+}
@@ -0,0 +1,87 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/database"
+	"github.com/telemoz/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInsufficientBalance is returned when a debit would take a wallet below zero
+var ErrInsufficientBalance = errors.New("insufficient wallet balance")
+
+type WalletRepository interface {
+	// Balance returns a customer's balance; customers without a wallet have none
+	Balance(customerID uuid.UUID) (float64, error)
+	// Post appends a transaction to the customer's ledger and applies it to their
+	// balance in the same transaction. Debits beyond the balance fail with
+	// ErrInsufficientBalance.
+	Post(transaction *models.WalletTransaction) error
+	FindTransactions(customerID uuid.UUID, limit, offset int) ([]models.WalletTransaction, error)
+	FindTripTransactions(tripID uuid.UUID) ([]models.WalletTransaction, error)
+}
+
+type walletRepository struct {
+	db *gorm.DB
+}
+
+func NewWalletRepository() WalletRepository {
+	return &walletRepository{
+		db: database.DB,
+	}
+}
+
+func (r *walletRepository) Balance(customerID uuid.UUID) (float64, error) {
+	var wallet models.Wallet
+	err := r.db.Where("customer_id = ?", customerID).First(&wallet).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	return wallet.Balance, err
+}
+
+func (r *walletRepository) Post(transaction *models.WalletTransaction) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.Wallet{CustomerID: transaction.CustomerID}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Model(&models.Wallet{}).
+			Where("customer_id = ? AND balance + ? >= 0", transaction.CustomerID, transaction.Amount).
+			Update("balance", gorm.Expr("balance + ?", transaction.Amount))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInsufficientBalance
+		}
+
+		var wallet models.Wallet
+		if err := tx.Where("customer_id = ?", transaction.CustomerID).First(&wallet).Error; err != nil {
+			return err
+		}
+		transaction.BalanceAfter = wallet.Balance
+		return tx.Create(transaction).Error
+	})
+}
+
+func (r *walletRepository) FindTransactions(customerID uuid.UUID, limit, offset int) ([]models.WalletTransaction, error) {
+	var transactions []models.WalletTransaction
+	err := r.db.Where("customer_id = ?", customerID).
+		Order("created_at DESC").
+		Limit(limit).Offset(offset).
+		Find(&transactions).Error
+	return transactions, err
+}
+
+func (r *walletRepository) FindTripTransactions(tripID uuid.UUID) ([]models.WalletTransaction, error) {
+	var transactions []models.WalletTransaction
+	err := r.db.Where("trip_id = ?", tripID).
+		Order("created_at ASC").
+		Find(&transactions).Error
+	return transactions, err
+}
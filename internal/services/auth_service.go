@@ -162,11 +162,13 @@ func (s *authService) generateTokens(user *models.User) (*dto.LoginResponse, err
 
 	// Build user response
 	userResponse := dto.UserResponse{
-		ID:        user.ID.String(),
-		Email:     user.Email,
-		Name:      user.Name,
-		UserType:  string(user.UserType),
-		AvatarURL: user.AvatarURL,
+		ID:          user.ID.String(),
+		Email:       user.Email,
+		Name:        user.Name,
+		UserType:    string(user.UserType),
+		AvatarURL:   user.AvatarURL,
+		Rating:      user.RatingAverage,
+		RatingCount: user.RatingCount,
 	}
 	if user.Phone != nil {
 		userResponse.Phone = user.Phone
@@ -0,0 +1,328 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/config"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/repositories"
+)
+
+const (
+	// A run is considered under way from runLeadTime before its departure until
+	// runGracePeriod after its last timetabled stop
+	runLeadTime    = 30 * time.Minute
+	runGracePeriod = time.Hour
+	// Dwell history is averaged over this period
+	dwellHistoryPeriod = 30 * 24 * time.Hour
+	// Positions slower than this are treated as standing still when averaging speed
+	movingSpeedKmh = 3.0
+)
+
+// ETAStop is a stop ahead of the bus with the time it is expected to spend there
+type ETAStop struct {
+	StopID    uuid.UUID
+	Latitude  float64
+	Longitude float64
+	Dwell     time.Duration
+}
+
+// StopETA is the estimated travel distance and time from the bus to a stop
+type StopETA struct {
+	StopID     uuid.UUID
+	DistanceKm float64
+	Duration   time.Duration
+}
+
+// RemainingStop is a stop still to be served on the current run
+type RemainingStop struct {
+	Stop             models.RouteStop
+	ETA              StopETA
+	EstimatedArrival time.Time
+	ScheduledArrival string
+}
+
+// BusRunETA is the bus's progress along its current run
+type BusRunETA struct {
+	Schedule *models.Schedule
+	RunDate  string
+	SpeedKmh float64
+	Stops    []RemainingStop
+}
+
+// RunKey identifies one day's run of a schedule
+func (e *BusRunETA) RunKey() string {
+	return e.Schedule.ID.String() + ":" + e.RunDate
+}
+
+type BusETAService interface {
+	// EstimateRun returns nil without an error when the bus has no run under way
+	EstimateRun(location *models.BusLocation) (*BusRunETA, error)
+}
+
+type busETAService struct {
+	routeRepo       repositories.RouteRepository
+	stopRepo        repositories.RouteStopRepository
+	scheduleRepo    repositories.ScheduleRepository
+	visitRepo       repositories.BusStopVisitRepository
+	busLocationRepo repositories.BusLocationRepository
+	cfg             config.BusStopConfig
+	location        *time.Location
+}
+
+func NewBusETAService() BusETAService {
+	return &busETAService{
+		routeRepo:       repositories.NewRouteRepository(),
+		stopRepo:        repositories.NewRouteStopRepository(),
+		scheduleRepo:    repositories.NewScheduleRepository(),
+		visitRepo:       repositories.NewBusStopVisitRepository(),
+		busLocationRepo: repositories.NewBusLocationRepository(),
+		cfg:             config.AppConfig.BusStops,
+		location:        scheduleLocation(),
+	}
+}
+
+func (s *busETAService) EstimateRun(location *models.BusLocation) (*BusRunETA, error) {
+	now := location.Timestamp
+
+	routes, err := s.routeRepo.FindByBusID(location.BusID)
+	if err != nil {
+		return nil, err
+	}
+	var schedules []models.Schedule
+	for _, route := range routes {
+		routeSchedules, err := s.scheduleRepo.FindByRouteID(route.ID)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, routeSchedules...)
+	}
+
+	schedule, runStart := ActiveRun(schedules, now, s.location)
+	if schedule == nil {
+		return nil, nil
+	}
+
+	stops, err := s.stopRepo.FindByRouteID(schedule.RouteID)
+	if err != nil {
+		return nil, err
+	}
+	order, scheduled := runStopOrder(schedule, stops)
+
+	visits, err := s.visitRepo.FindArrivedSince(location.BusID, runStart)
+	if err != nil {
+		return nil, err
+	}
+	visited := make(map[uuid.UUID]bool, len(visits))
+	for _, visit := range visits {
+		visited[visit.StopID] = true
+	}
+	remaining := RemainingStops(order, visited)
+
+	recent, err := s.busLocationRepo.FindByBusIDAndTimeRange(location.BusID, now.Add(-s.cfg.ETASpeedWindow), now)
+	if err != nil {
+		return nil, err
+	}
+	speed := EstimateBusSpeed(recent, s.cfg.ETADefaultSpeedKmh)
+
+	stopIDs := make([]uuid.UUID, len(remaining))
+	for i, stop := range remaining {
+		stopIDs[i] = stop.ID
+	}
+	dwell, err := s.visitRepo.AverageDwell(stopIDs, now.Add(-dwellHistoryPeriod))
+	if err != nil {
+		return nil, err
+	}
+
+	etaStops := make([]ETAStop, len(remaining))
+	for i, stop := range remaining {
+		stopDwell, ok := dwell[stop.ID]
+		if !ok {
+			stopDwell = s.cfg.ETADefaultDwell
+		}
+		etaStops[i] = ETAStop{
+			StopID:    stop.ID,
+			Latitude:  stop.Latitude,
+			Longitude: stop.Longitude,
+			Dwell:     stopDwell,
+		}
+	}
+	etas := EstimateStopETAs(location.Latitude, location.Longitude, speed, s.cfg.ETARoadFactor, etaStops)
+
+	run := &BusRunETA{
+		Schedule: schedule,
+		RunDate:  runStart.In(s.location).Format("2006-01-02"),
+		SpeedKmh: math.Round(speed*10) / 10,
+		Stops:    make([]RemainingStop, len(remaining)),
+	}
+	for i, stop := range remaining {
+		run.Stops[i] = RemainingStop{
+			Stop:             stop,
+			ETA:              etas[i],
+			EstimatedArrival: now.Add(etas[i].Duration),
+			ScheduledArrival: scheduled[stop.ID],
+		}
+	}
+	return run, nil
+}
+
+// runStopOrder returns a run's stops in the order the bus serves them along with
+// their timetabled arrival times. Stops are ordered by timetable; without one,
+// morning runs follow the route sequence and afternoon runs reverse it.
+func runStopOrder(schedule *models.Schedule, stops []models.RouteStop) ([]models.RouteStop, map[uuid.UUID]string) {
+	scheduled := make(map[uuid.UUID]string, len(schedule.StopTimes))
+	for _, stopTime := range schedule.StopTimes {
+		scheduled[stopTime.RouteStopID] = stopTime.ArrivalTime
+	}
+
+	order := make([]models.RouteStop, 0, len(stops))
+	if len(scheduled) > 0 {
+		for _, stop := range stops {
+			if _, ok := scheduled[stop.ID]; ok {
+				order = append(order, stop)
+			}
+		}
+		sort.SliceStable(order, func(i, j int) bool {
+			return scheduled[order[i].ID] < scheduled[order[j].ID]
+		})
+		return order, scheduled
+	}
+
+	order = append(order, stops...)
+	if schedule.RunType == models.RunTypeAfternoon {
+		for i, j := 0, len(order)-1; i < j; i, j = i+1, j-1 {
+			order[i], order[j] = order[j], order[i]
+		}
+	}
+	return order, scheduled
+}
+
+// ActiveRun picks the schedule under way at now and returns it with the time the
+// run window opened. When runs overlap the one departing closest to now wins.
+func ActiveRun(schedules []models.Schedule, now time.Time, loc *time.Location) (*models.Schedule, time.Time) {
+	local := now.In(loc)
+
+	var active *models.Schedule
+	var activeStart time.Time
+	var bestGap time.Duration
+	for i := range schedules {
+		schedule := &schedules[i]
+		if !schedule.RunsOn(local) {
+			continue
+		}
+
+		departure, ok := clockOn(local, schedule.DepartureTime, loc)
+		if !ok {
+			continue
+		}
+		end := departure.Add(2 * time.Hour)
+		for _, stopTime := range schedule.StopTimes {
+			if arrival, ok := clockOn(local, stopTime.ArrivalTime, loc); ok && arrival.Add(runGracePeriod).After(end) {
+				end = arrival.Add(runGracePeriod)
+			}
+		}
+
+		start := departure.Add(-runLeadTime)
+		if now.Before(start) || now.After(end) {
+			continue
+		}
+
+		gap := now.Sub(departure)
+		if gap < 0 {
+			gap = -gap
+		}
+		if active == nil || gap < bestGap {
+			active, activeStart, bestGap = schedule, start, gap
+		}
+	}
+	return active, activeStart
+}
+
+// RemainingStops returns the stops after the furthest visited stop in run order
+func RemainingStops(order []models.RouteStop, visited map[uuid.UUID]bool) []models.RouteStop {
+	next := 0
+	for i, stop := range order {
+		if visited[stop.ID] {
+			next = i + 1
+		}
+	}
+	return order[next:]
+}
+
+// EstimateBusSpeed averages the reported speed of recent positions while the bus
+// was moving, in km/h. Positions without a reported speed are measured from the
+// distance to the previous position. Returns fallbackKmh without usable data.
+func EstimateBusSpeed(locations []models.BusLocation, fallbackKmh float64) float64 {
+	var total float64
+	var samples int
+	for i, location := range locations {
+		var speed float64
+		switch {
+		case location.Speed != nil:
+			speed = *location.Speed
+		case i > 0:
+			previous := locations[i-1]
+			elapsed := location.Timestamp.Sub(previous.Timestamp).Hours()
+			if elapsed <= 0 {
+				continue
+			}
+			speed = calculateHaversineDistance(previous.Latitude, previous.Longitude, location.Latitude, location.Longitude) / elapsed
+		default:
+			continue
+		}
+
+		if speed < movingSpeedKmh {
+			continue
+		}
+		total += speed
+		samples++
+	}
+
+	if samples == 0 {
+		return fallbackKmh
+	}
+	return total / float64(samples)
+}
+
+// EstimateStopETAs walks the stops in order from the bus's position. Straight-line
+// legs are stretched by roadFactor to approximate road distance and the bus is
+// assumed to dwell at each intermediate stop before moving on.
+func EstimateStopETAs(lat, lng, speedKmh, roadFactor float64, stops []ETAStop) []StopETA {
+	etas := make([]StopETA, len(stops))
+	if speedKmh <= 0 {
+		speedKmh = movingSpeedKmh
+	}
+	if roadFactor < 1 {
+		roadFactor = 1
+	}
+
+	var distance float64
+	var elapsed time.Duration
+	for i, stop := range stops {
+		leg := calculateHaversineDistance(lat, lng, stop.Latitude, stop.Longitude) * roadFactor
+		distance += leg
+		elapsed += time.Duration(leg / speedKmh * float64(time.Hour))
+
+		etas[i] = StopETA{
+			StopID:     stop.StopID,
+			DistanceKm: math.Round(distance*100) / 100,
+			Duration:   elapsed.Round(time.Second),
+		}
+
+		elapsed += stop.Dwell
+		lat, lng = stop.Latitude, stop.Longitude
+	}
+	return etas
+}
+
+// clockOn returns the "15:04" wall-clock time on day's date in loc
+func clockOn(day time.Time, clock string, loc *time.Location) (time.Time, bool) {
+	parsed, err := time.Parse("15:04", clock)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), parsed.Hour(), parsed.Minute(), 0, 0, loc), true
+}
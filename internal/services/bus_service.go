@@ -2,8 +2,12 @@ package services
 
 import (
 	"errors"
+	"log"
+	"math"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/dto"
 	"github.com/telemoz/backend/internal/models"
 	"github.com/telemoz/backend/internal/repositories"
 )
@@ -11,13 +15,19 @@ import (
 type BusService interface {
 	GetBusByChildID(childID uuid.UUID) (*models.Bus, error)
 	GetBusLocation(busID uuid.UUID) (*models.BusLocation, error)
+	GetBusTracking(busID uuid.UUID) (*dto.BusTrackResponse, error)
 	UpdateBusLocation(busID uuid.UUID, lat, lng, accuracy, speed, heading float64) error
+	RecordBusLocation(location *models.BusLocation) error
+	GetBusStops(busID uuid.UUID) ([]models.RouteStop, error)
 }
 
 type busService struct {
 	busRepo         repositories.BusRepository
 	busLocationRepo repositories.BusLocationRepository
 	childRepo       repositories.ChildRepository
+	stopRepo        repositories.RouteStopRepository
+	stopAlerts      StopAlertService
+	etaService      BusETAService
 }
 
 func NewBusService() BusService {
@@ -25,6 +35,9 @@ func NewBusService() BusService {
 		busRepo:         repositories.NewBusRepository(),
 		busLocationRepo: repositories.NewBusLocationRepository(),
 		childRepo:       repositories.NewChildRepository(),
+		stopRepo:        repositories.NewRouteStopRepository(),
+		stopAlerts:      NewStopAlertService(),
+		etaService:      NewBusETAService(),
 	}
 }
 
@@ -44,11 +57,65 @@ func (s *busService) GetBusLocation(busID uuid.UUID) (*models.BusLocation, error
 	return location, nil
 }
 
+// GetBusTracking returns the bus's latest position and, while a run is under way,
+// its ETA to the remaining stops
+func (s *busService) GetBusTracking(busID uuid.UUID) (*dto.BusTrackResponse, error) {
+	location, err := s.busLocationRepo.FindLatestByBusID(busID)
+	if err != nil {
+		return nil, errors.New("bus location not found")
+	}
+
+	response := &dto.BusTrackResponse{
+		ID:        location.ID.String(),
+		BusID:     location.BusID.String(),
+		Latitude:  location.Latitude,
+		Longitude: location.Longitude,
+		Accuracy:  location.Accuracy,
+		Speed:     location.Speed,
+		Heading:   location.Heading,
+		Timestamp: location.Timestamp.Format(time.RFC3339),
+	}
+
+	// Arrival times are projected from the time of the last fix
+	run, err := s.etaService.EstimateRun(location)
+	if err != nil {
+		log.Printf("Failed to estimate ETA for bus %s: %v", busID, err)
+	}
+	if run != nil {
+		response.ETA = busRunToDTO(run)
+	}
+
+	return response, nil
+}
+
+func busRunToDTO(run *BusRunETA) *dto.BusRunResponse {
+	response := &dto.BusRunResponse{
+		ScheduleID: run.Schedule.ID.String(),
+		RouteID:    run.Schedule.RouteID.String(),
+		RunType:    string(run.Schedule.RunType),
+		SpeedKmh:   run.SpeedKmh,
+		Stops:      make([]dto.StopETAResponse, len(run.Stops)),
+	}
+	for i, stop := range run.Stops {
+		response.Stops[i] = dto.StopETAResponse{
+			StopID:           stop.Stop.ID.String(),
+			StopName:         stop.Stop.Name,
+			Sequence:         stop.Stop.Sequence,
+			DistanceKm:       stop.ETA.DistanceKm,
+			EtaMinutes:       int(math.Ceil(stop.ETA.Duration.Minutes())),
+			EstimatedArrival: stop.EstimatedArrival.Format(time.RFC3339),
+			ScheduledArrival: stop.ScheduledArrival,
+		}
+	}
+	return response
+}
+
 func (s *busService) UpdateBusLocation(busID uuid.UUID, lat, lng, accuracy, speed, heading float64) error {
 	location := &models.BusLocation{
 		BusID:     busID,
 		Latitude:  lat,
 		Longitude: lng,
+		Timestamp: time.Now(),
 	}
 
 	if accuracy > 0 {
@@ -61,6 +128,32 @@ func (s *busService) UpdateBusLocation(busID uuid.UUID, lat, lng, accuracy, spee
 		location.Heading = &heading
 	}
 
-	return s.busLocationRepo.Create(location)
+	return s.RecordBusLocation(location)
+}
+
+// RecordBusLocation persists a position reported by a tracker and streams it to subscribers
+func (s *busService) RecordBusLocation(location *models.BusLocation) error {
+	if location.Timestamp.IsZero() {
+		location.Timestamp = time.Now()
+	}
+
+	if err := s.busLocationRepo.Create(location); err != nil {
+		return err
+	}
+
+	publishBusLocation(location)
+
+	// The position is already stored; a failed stop check shouldn't reject it
+	if err := s.stopAlerts.ProcessBusLocation(location); err != nil {
+		log.Printf("Failed to process stop alerts for bus %s: %v", location.BusID, err)
+	}
+	return nil
 }
 
+func (s *busService) GetBusStops(busID uuid.UUID) ([]models.RouteStop, error) {
+	stops, err := s.stopRepo.FindByBusID(busID)
+	if err != nil {
+		return nil, errors.New("failed to fetch bus stops")
+	}
+	return stops, nil
+}
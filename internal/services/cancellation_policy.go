@@ -0,0 +1,56 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/telemoz/backend/internal/config"
+	"github.com/telemoz/backend/internal/models"
+)
+
+// ErrNoShowTooEarly means the driver has not waited at the pickup long enough to
+// report the customer as a no-show
+var ErrNoShowTooEarly = errors.New("the customer can't be reported as a no-show yet")
+
+// CancellationFee is what a customer pays for cancelling a trip that is in status
+// from: nothing while searching or within the free window after a driver accepted,
+// the cancellation fee after that or once the driver has arrived. The fee never
+// exceeds the fare, when there is one.
+func CancellationFee(cfg config.CancellationConfig, from models.TripStatus, acceptedAt *time.Time, fare *float64, now time.Time) float64 {
+	var fee float64
+	switch from {
+	case models.TripStatusAccepted:
+		if acceptedAt != nil && now.Sub(*acceptedAt) > cfg.FreeWindow {
+			fee = cfg.Fee
+		}
+	case models.TripStatusArrived:
+		fee = cfg.Fee
+	}
+	return capFee(fee, fare)
+}
+
+// NoShowFee is what a customer pays for not showing up to a trip with fare
+func NoShowFee(cfg config.CancellationConfig, fare *float64) float64 {
+	return capFee(cfg.NoShowFee, fare)
+}
+
+// NoShowDue reports whether a driver who arrived at arrivedAt has waited long enough
+// by now to report a no-show. Trips without a recorded arrival can always be reported.
+func NoShowDue(cfg config.CancellationConfig, arrivedAt *time.Time, now time.Time) bool {
+	return arrivedAt == nil || now.Sub(*arrivedAt) >= cfg.NoShowWait
+}
+
+// FeeCollectable reports whether a cancellation or no-show fee can be taken from the
+// customer of a trip paid by paymentMethod. Card and wallet trips pay it from their
+// hold; the fee on a cash trip is only recorded.
+func FeeCollectable(paymentMethod string) bool {
+	return paymentMethod == models.PaymentTypeCard || paymentMethod == models.PaymentTypeWallet
+}
+
+func capFee(fee float64, fare *float64) float64 {
+	if fare != nil {
+		fee = math.Min(fee, *fare)
+	}
+	return roundFare(math.Max(fee, 0))
+}
@@ -0,0 +1,293 @@
+package services
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/dto"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/repositories"
+)
+
+var (
+	// ErrChatNotFound means the trip doesn't exist or the user isn't its customer or driver
+	ErrChatNotFound = errors.New("trip not found")
+	// ErrChatClosed means the trip isn't under way, so its chat is read-only
+	ErrChatClosed = errors.New("chat is only open while the trip is accepted or in progress")
+	// ErrEmptyMessage means a message has neither text nor a quick reply, or both
+	ErrEmptyMessage = errors.New("send either text or a quick reply")
+	// ErrInvalidQuickReply means the quick reply isn't one offered to the sender
+	ErrInvalidQuickReply = errors.New("invalid quick reply")
+)
+
+// quickReplies are the canned messages each side can send, keyed by the sender's type
+var quickReplies = map[models.UserType][]dto.QuickReply{
+	models.UserTypeCustomer: {
+		{Code: "coming_out", Text: "I'm coming out now"},
+		{Code: "wait_please", Text: "Please wait, I'll be there in a few minutes"},
+		{Code: "at_pickup", Text: "I'm at the pickup point"},
+		{Code: "cant_see_you", Text: "I can't see you, where are you?"},
+		{Code: "thanks", Text: "Thank you!"},
+	},
+	models.UserTypeDriver: {
+		{Code: "on_my_way", Text: "I'm on my way"},
+		{Code: "arrived", Text: "I've arrived at the pickup point"},
+		{Code: "running_late", Text: "Stuck in traffic, I'll be a few minutes late"},
+		{Code: "cant_find_you", Text: "I can't find you, where are you?"},
+		{Code: "thanks", Text: "Thank you!"},
+	},
+}
+
+// phoneNumberPattern matches runs of digits that may be phone numbers, allowing the
+// spaces, dots, dashes and brackets people write them with
+var phoneNumberPattern = regexp.MustCompile(`\+?\(?\d[\d\s().-]{5,}\d`)
+
+// minPhoneDigits is the fewest digits treated as a phone number
+const minPhoneDigits = 7
+
+type ChatService interface {
+	ListMessages(tripID, userID uuid.UUID, limit, offset int) (*dto.TripChatResponse, error)
+	SendMessage(tripID, userID uuid.UUID, req dto.SendMessageRequest) (*dto.TripMessageResponse, error)
+	MarkRead(tripID, userID uuid.UUID) (*dto.ChatReadResponse, error)
+	GetQuickReplies(senderType models.UserType) []dto.QuickReply
+	ListMessagesForSupport(tripID uuid.UUID, limit, offset int) (*dto.TripChatResponse, error)
+}
+
+type chatService struct {
+	messageRepo repositories.TripMessageRepository
+	tripRepo    repositories.TripRepository
+}
+
+func NewChatService() ChatService {
+	return &chatService{
+		messageRepo: repositories.NewTripMessageRepository(),
+		tripRepo:    repositories.NewTripRepository(),
+	}
+}
+
+// ChatOpen reports whether the customer and driver of a trip in status can message
+// each other: from the driver accepting until the trip ends
+func ChatOpen(status models.TripStatus) bool {
+	switch status {
+	case models.TripStatusAccepted, models.TripStatusArrived, models.TripStatusInProgress:
+		return true
+	}
+	return false
+}
+
+// MaskPhoneNumbers hides anything in a chat message that looks like a phone number,
+// so the parties can't pass their numbers to each other
+func MaskPhoneNumbers(text string) string {
+	return phoneNumberPattern.ReplaceAllStringFunc(text, func(match string) string {
+		digits := 0
+		for _, r := range match {
+			if r >= '0' && r <= '9' {
+				digits++
+			}
+		}
+		if digits < minPhoneDigits {
+			return match
+		}
+		return "[hidden]"
+	})
+}
+
+// QuickReplyText returns the text of a sender's quick reply
+func QuickReplyText(senderType models.UserType, code string) (string, bool) {
+	for _, reply := range quickReplies[senderType] {
+		if reply.Code == code {
+			return reply.Text, true
+		}
+	}
+	return "", false
+}
+
+// ListMessages returns a page of the user's chat on a trip, newest first. The chat
+// stays readable after the trip ends.
+func (s *chatService) ListMessages(tripID, userID uuid.UUID, limit, offset int) (*dto.TripChatResponse, error) {
+	trip, _, err := s.findChat(tripID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	limit, offset = chatPage(limit, offset)
+	driverID := chatDriverFilter(trip, userID)
+	messages, err := s.messageRepo.FindByTrip(tripID, driverID, limit, offset)
+	if err != nil {
+		return nil, errors.New("failed to fetch messages")
+	}
+	unread, err := s.messageRepo.CountUnread(tripID, driverID, userID)
+	if err != nil {
+		return nil, errors.New("failed to fetch messages")
+	}
+
+	return chatToDTO(trip, messages, unread), nil
+}
+
+// SendMessage sends a text or quick reply message to the other party of a trip under
+// way and delivers it to both parties in real time
+func (s *chatService) SendMessage(tripID, userID uuid.UUID, req dto.SendMessageRequest) (*dto.TripMessageResponse, error) {
+	trip, senderType, err := s.findChat(tripID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !ChatOpen(trip.Status) || trip.DriverID == nil {
+		return nil, ErrChatClosed
+	}
+
+	text := strings.TrimSpace(req.Text)
+	if (text == "") == (req.QuickReply == "") {
+		return nil, ErrEmptyMessage
+	}
+
+	message := &models.TripMessage{
+		TripID:     trip.ID,
+		DriverID:   *trip.DriverID,
+		SenderID:   userID,
+		SenderType: senderType,
+		Kind:       models.MessageKindText,
+		Body:       MaskPhoneNumbers(text),
+	}
+	if req.QuickReply != "" {
+		body, ok := QuickReplyText(senderType, req.QuickReply)
+		if !ok {
+			return nil, ErrInvalidQuickReply
+		}
+		code := req.QuickReply
+		message.Kind = models.MessageKindQuickReply
+		message.QuickReply = &code
+		message.Body = body
+	}
+
+	if err := s.messageRepo.Create(message); err != nil {
+		return nil, errors.New("failed to send message")
+	}
+
+	response := messageToDTO(message)
+	publishChatMessage(trip, response)
+	return response, nil
+}
+
+// MarkRead marks the messages the user has received on a trip as read and sends the
+// read receipt to the other party
+func (s *chatService) MarkRead(tripID, userID uuid.UUID) (*dto.ChatReadResponse, error) {
+	trip, readerType, err := s.findChat(tripID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	read, err := s.messageRepo.MarkRead(tripID, chatDriverFilter(trip, userID), userID, now)
+	if err != nil {
+		return nil, errors.New("failed to mark messages as read")
+	}
+
+	if read > 0 {
+		switch {
+		case readerType == models.UserTypeDriver:
+			publishChatRead(trip.CustomerID, tripID, userID, now)
+		case trip.DriverID != nil:
+			publishChatRead(*trip.DriverID, tripID, userID, now)
+		}
+	}
+
+	return &dto.ChatReadResponse{
+		TripID: tripID.String(),
+		Read:   read,
+		ReadAt: now.Format(time.RFC3339),
+	}, nil
+}
+
+// GetQuickReplies returns the canned messages a sender of the given type can send
+func (s *chatService) GetQuickReplies(senderType models.UserType) []dto.QuickReply {
+	replies := quickReplies[senderType]
+	if replies == nil {
+		return []dto.QuickReply{}
+	}
+	return replies
+}
+
+// ListMessagesForSupport returns a page of every message on a trip, with any driver,
+// newest first (admin)
+func (s *chatService) ListMessagesForSupport(tripID uuid.UUID, limit, offset int) (*dto.TripChatResponse, error) {
+	trip, err := s.tripRepo.FindByID(tripID)
+	if err != nil {
+		return nil, ErrChatNotFound
+	}
+
+	limit, offset = chatPage(limit, offset)
+	messages, err := s.messageRepo.FindByTrip(tripID, nil, limit, offset)
+	if err != nil {
+		return nil, errors.New("failed to fetch messages")
+	}
+
+	return chatToDTO(trip, messages, 0), nil
+}
+
+// findChat loads a trip for one of its parties, returning which side the user is on
+func (s *chatService) findChat(tripID, userID uuid.UUID) (*models.Trip, models.UserType, error) {
+	trip, err := s.tripRepo.FindByID(tripID)
+	if err != nil {
+		return nil, "", ErrChatNotFound
+	}
+	switch {
+	case trip.CustomerID == userID:
+		return trip, models.UserTypeCustomer, nil
+	case trip.DriverID != nil && *trip.DriverID == userID:
+		return trip, models.UserTypeDriver, nil
+	}
+	return nil, "", ErrChatNotFound
+}
+
+// chatDriverFilter limits a driver to their own conversation on the trip. Customers
+// see every conversation they had on it.
+func chatDriverFilter(trip *models.Trip, userID uuid.UUID) *uuid.UUID {
+	if trip.DriverID != nil && *trip.DriverID == userID {
+		return trip.DriverID
+	}
+	return nil
+}
+
+func chatPage(limit, offset int) (int, int) {
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
+
+func chatToDTO(trip *models.Trip, messages []models.TripMessage, unread int64) *dto.TripChatResponse {
+	responses := make([]dto.TripMessageResponse, 0, len(messages))
+	for i := range messages {
+		responses = append(responses, *messageToDTO(&messages[i]))
+	}
+	return &dto.TripChatResponse{
+		TripID:   trip.ID.String(),
+		ReadOnly: !ChatOpen(trip.Status),
+		Unread:   unread,
+		Messages: responses,
+	}
+}
+
+func messageToDTO(message *models.TripMessage) *dto.TripMessageResponse {
+	response := &dto.TripMessageResponse{
+		ID:         message.ID.String(),
+		TripID:     message.TripID.String(),
+		SenderID:   message.SenderID.String(),
+		SenderType: string(message.SenderType),
+		Kind:       string(message.Kind),
+		QuickReply: message.QuickReply,
+		Body:       message.Body,
+		CreatedAt:  message.CreatedAt.Format(time.RFC3339),
+	}
+	if message.ReadAt != nil {
+		readAt := message.ReadAt.Format(time.RFC3339)
+		response.ReadAt = &readAt
+	}
+	return response
+}
@@ -10,28 +10,36 @@ import (
 )
 
 type ChildService interface {
-	CreateChild(parentID uuid.UUID, name, schoolName string, busID *uuid.UUID) (*models.Child, error)
+	CreateChild(parentID uuid.UUID, name, schoolName string, busID, pickupStopID, dropoffStopID *uuid.UUID) (*models.Child, error)
 	GetChildByID(childID uuid.UUID) (*models.Child, error)
 	ListChildren(parentID uuid.UUID) ([]models.Child, error)
-	UpdateChild(childID, parentID uuid.UUID, name, schoolName *string, busID *uuid.UUID) (*models.Child, error)
+	UpdateChild(childID, parentID uuid.UUID, name, schoolName *string, busID, pickupStopID, dropoffStopID *uuid.UUID) (*models.Child, error)
 	DeleteChild(childID, parentID uuid.UUID) error
 }
 
 type childService struct {
 	childRepo repositories.ChildRepository
+	stopRepo  repositories.RouteStopRepository
 }
 
 func NewChildService() ChildService {
 	return &childService{
 		childRepo: repositories.NewChildRepository(),
+		stopRepo:  repositories.NewRouteStopRepository(),
 	}
 }
 
-func (s *childService) CreateChild(parentID uuid.UUID, name, schoolName string, busID *uuid.UUID) (*models.Child, error) {
+func (s *childService) CreateChild(parentID uuid.UUID, name, schoolName string, busID, pickupStopID, dropoffStopID *uuid.UUID) (*models.Child, error) {
 	child := &models.Child{
-		ParentID: parentID,
-		Name:     utils.SanitizeString(name),
-		BusID:    busID,
+		ParentID:      parentID,
+		Name:          utils.SanitizeString(name),
+		BusID:         busID,
+		PickupStopID:  pickupStopID,
+		DropoffStopID: dropoffStopID,
+	}
+
+	if err := s.resolveStops(child); err != nil {
+		return nil, err
 	}
 
 	if schoolName != "" {
@@ -62,7 +70,7 @@ func (s *childService) ListChildren(parentID uuid.UUID) ([]models.Child, error)
 	return children, nil
 }
 
-func (s *childService) UpdateChild(childID, parentID uuid.UUID, name, schoolName *string, busID *uuid.UUID) (*models.Child, error) {
+func (s *childService) UpdateChild(childID, parentID uuid.UUID, name, schoolName *string, busID, pickupStopID, dropoffStopID *uuid.UUID) (*models.Child, error) {
 	child, err := s.childRepo.FindByID(childID)
 	if err != nil {
 		return nil, errors.New("child not found")
@@ -80,7 +88,23 @@ func (s *childService) UpdateChild(childID, parentID uuid.UUID, name, schoolName
 		child.SchoolName = &school
 	}
 	if busID != nil {
+		if child.BusID == nil || *child.BusID != *busID {
+			// Stops belong to the old bus's routes; moving buses clears them unless new ones are given
+			child.PickupStopID = nil
+			child.DropoffStopID = nil
+		}
 		child.BusID = busID
+		child.Bus = nil
+	}
+	if pickupStopID != nil {
+		child.PickupStopID = pickupStopID
+	}
+	if dropoffStopID != nil {
+		child.DropoffStopID = dropoffStopID
+	}
+
+	if err := s.resolveStops(child); err != nil {
+		return nil, err
 	}
 
 	if err := s.childRepo.Update(child); err != nil {
@@ -90,6 +114,33 @@ func (s *childService) UpdateChild(childID, parentID uuid.UUID, name, schoolName
 	return child, nil
 }
 
+// resolveStops checks that the child's stops are on a route run by the child's bus.
+// A child without a bus is assigned the bus operating its stop's route.
+func (s *childService) resolveStops(child *models.Child) error {
+	for _, stopID := range []*uuid.UUID{child.PickupStopID, child.DropoffStopID} {
+		if stopID == nil {
+			continue
+		}
+
+		stop, err := s.stopRepo.FindByID(*stopID)
+		if err != nil {
+			return errors.New("bus stop not found")
+		}
+		if stop.Route == nil || stop.Route.BusID == nil {
+			continue
+		}
+
+		if child.BusID == nil {
+			busID := *stop.Route.BusID
+			child.BusID = &busID
+			child.Bus = nil
+		} else if *child.BusID != *stop.Route.BusID {
+			return errors.New("bus stop is not on the child's bus route")
+		}
+	}
+	return nil
+}
+
 func (s *childService) DeleteChild(childID, parentID uuid.UUID) error {
 	child, err := s.childRepo.FindByID(childID)
 	if err != nil {
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/telemoz/backend/internal/config"
+	"github.com/telemoz/backend/internal/dto"
+	"github.com/telemoz/backend/internal/models"
+)
+
+var (
+	// ErrPackageRequired is a delivery booked without package details
+	ErrPackageRequired = errors.New("package details are required for deliveries")
+	// ErrPackageNotAllowed is package details given for a service other than delivery
+	ErrPackageNotAllowed = errors.New("package details are only accepted for deliveries")
+	// ErrPackageTooLarge is a package too heavy or too big for any vehicle
+	ErrPackageTooLarge = errors.New("package is too large for any of our vehicles")
+	// ErrVehicleTooSmall is a driver taking a delivery their vehicle can't carry
+	ErrVehicleTooSmall = errors.New("the package doesn't fit your vehicle")
+)
+
+// vehicleCapacity is the largest package a vehicle class carries. Dimensions are
+// sorted from longest to shortest so packages fit whichever way round they go.
+type vehicleCapacity struct {
+	Class        models.VehicleClass
+	MaxWeightKg  float64
+	DimensionsCm [3]float64
+}
+
+// vehicleCapacities lists the vehicle classes from smallest to largest
+var vehicleCapacities = []vehicleCapacity{
+	{Class: models.VehicleClassBike, MaxWeightKg: 15, DimensionsCm: [3]float64{50, 40, 40}},
+	{Class: models.VehicleClassCar, MaxWeightKg: 100, DimensionsCm: [3]float64{100, 80, 50}},
+	{Class: models.VehicleClassVan, MaxWeightKg: 800, DimensionsCm: [3]float64{300, 170, 140}},
+	{Class: models.VehicleClassTruck, MaxWeightKg: 2000, DimensionsCm: [3]float64{600, 240, 240}},
+}
+
+// VehicleClassForPackage returns the smallest vehicle class a package fits in
+func VehicleClassForPackage(weightKg, lengthCm, widthCm, heightCm float64) (models.VehicleClass, error) {
+	dimensions := []float64{lengthCm, widthCm, heightCm}
+	sort.Sort(sort.Reverse(sort.Float64Slice(dimensions)))
+
+	for _, capacity := range vehicleCapacities {
+		if weightKg > capacity.MaxWeightKg {
+			continue
+		}
+		if dimensions[0] <= capacity.DimensionsCm[0] &&
+			dimensions[1] <= capacity.DimensionsCm[1] &&
+			dimensions[2] <= capacity.DimensionsCm[2] {
+			return capacity.Class, nil
+		}
+	}
+	return "", ErrPackageTooLarge
+}
+
+// VehicleClassFits reports whether a vehicle of class vehicle can carry a package
+// needing class required. A driver who hasn't set their class counts as a car.
+func VehicleClassFits(vehicle, required models.VehicleClass) bool {
+	if vehicle == "" {
+		vehicle = models.VehicleClassCar
+	}
+	return vehicleClassRank(vehicle) >= vehicleClassRank(required)
+}
+
+func vehicleClassRank(class models.VehicleClass) int {
+	for i, capacity := range vehicleCapacities {
+		if capacity.Class == class {
+			return i
+		}
+	}
+	return -1
+}
+
+// WeightSurcharge is the surcharge of the heaviest tier a package of weightKg exceeds,
+// zero below the lightest tier. Tiers are sorted by weight.
+func WeightSurcharge(tiers []config.WeightTier, weightKg float64) float64 {
+	surcharge := 0.0
+	for _, tier := range tiers {
+		if weightKg > tier.OverKg {
+			surcharge = tier.Surcharge
+		}
+	}
+	return roundFare(surcharge)
+}
+
+// newTripPackage checks the package details of a new trip against its service type
+// and sizes the package. Trips other than deliveries get no package.
+func newTripPackage(req *dto.PackageRequest, serviceType string, tiers []config.WeightTier) (*models.TripPackage, error) {
+	if serviceType != string(models.ServiceTypeDelivery) {
+		if req != nil {
+			return nil, ErrPackageNotAllowed
+		}
+		return nil, nil
+	}
+	if req == nil {
+		return nil, ErrPackageRequired
+	}
+
+	vehicleClass, err := VehicleClassForPackage(req.WeightKg, req.LengthCm, req.WidthCm, req.HeightCm)
+	if err != nil {
+		return nil, err
+	}
+	return &models.TripPackage{
+		WeightKg:        req.WeightKg,
+		LengthCm:        req.LengthCm,
+		WidthCm:         req.WidthCm,
+		HeightCm:        req.HeightCm,
+		Category:        models.PackageCategory(req.Category),
+		DeclaredValue:   req.DeclaredValue,
+		Fragile:         req.Fragile,
+		VehicleClass:    vehicleClass,
+		WeightSurcharge: WeightSurcharge(tiers, req.WeightKg),
+	}, nil
+}
+
+// addWeightSurcharge adds a package's weight surcharge to a fare estimate
+func addWeightSurcharge(estimate FareEstimate, surcharge float64) FareEstimate {
+	estimate.Breakdown.WeightSurcharge = surcharge
+	estimate.Breakdown.Total = roundFare(estimate.Breakdown.Total + surcharge)
+	estimate.Fare = estimate.Breakdown.Total
+	return estimate
+}
+
+// canCarry reports whether a driver's vehicle can carry a trip's package
+func canCarry(availability *models.DriverAvailability, trip *models.Trip) bool {
+	return trip.Package == nil || VehicleClassFits(availability.VehicleClass, trip.Package.VehicleClass)
+}
+
+func packageToDTO(p *models.TripPackage) *dto.PackageResponse {
+	if p == nil {
+		return nil
+	}
+	return &dto.PackageResponse{
+		WeightKg:        p.WeightKg,
+		LengthCm:        p.LengthCm,
+		WidthCm:         p.WidthCm,
+		HeightCm:        p.HeightCm,
+		Category:        string(p.Category),
+		DeclaredValue:   p.DeclaredValue,
+		Fragile:         p.Fragile,
+		VehicleClass:    string(p.VehicleClass),
+		WeightSurcharge: p.WeightSurcharge,
+	}
+}
@@ -0,0 +1,313 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/config"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/repositories"
+)
+
+// DriverCandidate is an available driver ranked for a pickup point. Score is what
+// candidates are ordered by, lowest first: the distance, plus a penalty for a low
+// rating when ranked by rating.
+type DriverCandidate struct {
+	DriverID   uuid.UUID
+	DistanceKm float64
+	Score      float64
+}
+
+type DispatchService interface {
+	StartDispatch(job *models.Job, trip *models.Trip) error
+	AdvanceDispatch() error
+	DeclineOffer(jobID, driverID uuid.UUID) error
+	WithdrawOffers(jobID uuid.UUID) error
+}
+
+type dispatchService struct {
+	jobRepo             repositories.JobRepository
+	tripRepo            repositories.TripRepository
+	offerRepo           repositories.JobOfferRepository
+	availabilityService DriverAvailabilityService
+	lifecycle           *tripLifecycle
+	compliance          *driverComplianceService
+	ratings             *ratingService
+	cfg                 config.DispatchConfig
+}
+
+func NewDispatchService() DispatchService {
+	return &dispatchService{
+		jobRepo:             repositories.NewJobRepository(),
+		tripRepo:            repositories.NewTripRepository(),
+		offerRepo:           repositories.NewJobOfferRepository(),
+		availabilityService: NewDriverAvailabilityService(repositories.NewDriverAvailabilityRepository()),
+		lifecycle:           newTripLifecycle(),
+		compliance:          newDriverComplianceService(),
+		ratings:             newRatingService(),
+		cfg:                 config.AppConfig.Dispatch,
+	}
+}
+
+// StartDispatch offers a freshly created job to the first wave of drivers
+func (s *dispatchService) StartDispatch(job *models.Job, trip *models.Trip) error {
+	return s.offerNextWave(job, trip, time.Now())
+}
+
+// AdvanceDispatch expires unanswered offers, moves jobs whose wave has ended on to
+// the next wave and expires trips once every wave has been exhausted
+func (s *dispatchService) AdvanceDispatch() error {
+	now := time.Now()
+
+	if err := s.offerRepo.ExpireDue(now); err != nil {
+		return err
+	}
+
+	jobs, err := s.jobRepo.FindAwaitingDispatch(now)
+	if err != nil {
+		return err
+	}
+
+	for i := range jobs {
+		job := &jobs[i]
+		if job.Trip.Status == models.TripStatusPending {
+			// Scheduled trips are dispatched by the scheduler once they are due
+			continue
+		}
+		if job.Trip.Status != models.TripStatusSearching {
+			// The trip was cancelled or expired elsewhere; stop offering its job
+			if err := s.closeJob(job); err != nil {
+				log.Printf("Failed to close job %s of trip %s: %v", job.ID, job.TripID, err)
+			}
+			continue
+		}
+		if err := s.offerNextWave(job, &job.Trip, now); err != nil {
+			// Continue with the remaining jobs
+			log.Printf("Failed to dispatch job %s of trip %s: %v", job.ID, job.TripID, err)
+		}
+	}
+
+	return nil
+}
+
+// DeclineOffer records a driver passing on a job. When nobody in the current wave
+// is left to answer, the wave is ended early so the next one starts on the following tick
+func (s *dispatchService) DeclineOffer(jobID, driverID uuid.UUID) error {
+	offer, err := s.offerRepo.FindActive(jobID, driverID)
+	if err != nil {
+		return errors.New("no active offer for this job")
+	}
+
+	now := time.Now()
+	offer.Status = models.JobOfferStatusDeclined
+	offer.RespondedAt = &now
+	if err := s.offerRepo.Update(offer); err != nil {
+		return errors.New("failed to decline offer")
+	}
+
+	remaining, err := s.offerRepo.CountActiveByJobID(jobID)
+	if err != nil || remaining > 0 {
+		return nil
+	}
+
+	job, err := s.jobRepo.FindByID(jobID)
+	if err != nil || job.Status != models.JobStatusPending {
+		return nil
+	}
+	job.WaveEndsAt = &now
+	return s.jobRepo.UpdateDispatch(job)
+}
+
+func (s *dispatchService) offerNextWave(job *models.Job, trip *models.Trip, now time.Time) error {
+	if job.DispatchWave >= s.cfg.MaxWaves {
+		return s.expire(job, trip)
+	}
+
+	available, err := s.availabilityService.GetAvailableDrivers(string(trip.ServiceType))
+	if err != nil {
+		return err
+	}
+
+	excluded := make(map[uuid.UUID]bool)
+	offered, err := s.offerRepo.FindOfferedDriverIDs(job.ID)
+	if err != nil {
+		return err
+	}
+	for _, id := range offered {
+		excluded[id] = true
+	}
+	busy, err := s.jobRepo.FindBusyDriverIDs()
+	if err != nil {
+		return err
+	}
+	for _, id := range busy {
+		excluded[id] = true
+	}
+	// Deliveries only go to drivers whose vehicle the package fits in
+	for i := range available {
+		if !canCarry(&available[i], trip) {
+			excluded[available[i].DriverID] = true
+		}
+	}
+	// Drivers whose vehicle or documents aren't approved get no jobs
+	driverIDs := make([]uuid.UUID, 0, len(available))
+	for _, driver := range available {
+		if !excluded[driver.DriverID] {
+			driverIDs = append(driverIDs, driver.DriverID)
+		}
+	}
+	compliant, err := s.compliance.compliantDrivers(driverIDs, now)
+	if err != nil {
+		return err
+	}
+	for _, id := range driverIDs {
+		if !compliant[id] {
+			excluded[id] = true
+		}
+	}
+
+	candidates := RankDriversByDistance(
+		available, trip.PickupLatitude, trip.PickupLongitude,
+		s.cfg.MaxRadiusKm, now.Add(-s.cfg.LocationMaxAge), excluded,
+	)
+	if s.cfg.RatingWeightKm > 0 && len(candidates) > 0 {
+		candidateIDs := make([]uuid.UUID, len(candidates))
+		for i, candidate := range candidates {
+			candidateIDs[i] = candidate.DriverID
+		}
+		ratings, err := s.ratings.driverRatings(candidateIDs)
+		if err != nil {
+			return err
+		}
+		candidates = RankDriversByRating(candidates, ratings, s.cfg.RatingWeightKm)
+	}
+	if len(candidates) > s.cfg.WaveSize {
+		candidates = candidates[:s.cfg.WaveSize]
+	}
+
+	// An empty wave still counts towards the limit so that a trip with no drivers
+	// nearby expires after MaxWaves * OfferTimeout instead of searching forever
+	wave := job.DispatchWave + 1
+	expiresAt := now.Add(s.cfg.OfferTimeout)
+	for _, candidate := range candidates {
+		offer := &models.JobOffer{
+			JobID:      job.ID,
+			DriverID:   candidate.DriverID,
+			Wave:       wave,
+			DistanceKm: math.Round(candidate.DistanceKm*100) / 100,
+			Status:     models.JobOfferStatusOffered,
+			ExpiresAt:  expiresAt,
+		}
+		if err := s.offerRepo.Create(offer); err != nil {
+			return errors.New("failed to create job offer")
+		}
+		publishJobOffer(offer, trip)
+	}
+
+	job.DispatchWave = wave
+	job.WaveEndsAt = &expiresAt
+	return s.jobRepo.UpdateDispatch(job)
+}
+
+func (s *dispatchService) expire(job *models.Job, trip *models.Trip) error {
+	if err := s.lifecycle.apply(trip, job, tripTransition{
+		To:    models.TripStatusExpired,
+		Actor: models.TripActorSystem,
+	}); err != nil {
+		return err
+	}
+
+	return s.WithdrawOffers(job.ID)
+}
+
+func (s *dispatchService) closeJob(job *models.Job) error {
+	job.Status = models.JobStatusExpired
+	job.WaveEndsAt = nil
+	if err := s.jobRepo.UpdateDispatch(job); err != nil {
+		return errors.New("failed to expire job")
+	}
+
+	return s.WithdrawOffers(job.ID)
+}
+
+// WithdrawOffers closes every open offer for a job and tells the drivers holding them
+func (s *dispatchService) WithdrawOffers(jobID uuid.UUID) error {
+	driverIDs, err := s.offerRepo.FindActiveDriverIDs(jobID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.offerRepo.WithdrawByJobID(jobID); err != nil {
+		return err
+	}
+
+	for _, driverID := range driverIDs {
+		publishJobOfferClosed(jobID, driverID)
+	}
+	return nil
+}
+
+// RankDriversByDistance orders available drivers by straight-line distance to the
+// pickup point. Drivers without a position, with a position reported before
+// locationSince, beyond maxRadiusKm or present in excluded are left out.
+func RankDriversByDistance(
+	available []models.DriverAvailability,
+	pickupLat, pickupLng float64,
+	maxRadiusKm float64,
+	locationSince time.Time,
+	excluded map[uuid.UUID]bool,
+) []DriverCandidate {
+	candidates := make([]DriverCandidate, 0, len(available))
+	for _, driver := range available {
+		if excluded[driver.DriverID] {
+			continue
+		}
+		if driver.Latitude == nil || driver.Longitude == nil || driver.LocationUpdatedAt == nil {
+			continue
+		}
+		if driver.LocationUpdatedAt.Before(locationSince) {
+			continue
+		}
+
+		distance := calculateHaversineDistance(*driver.Latitude, *driver.Longitude, pickupLat, pickupLng)
+		if maxRadiusKm > 0 && distance > maxRadiusKm {
+			continue
+		}
+
+		candidates = append(candidates, DriverCandidate{
+			DriverID:   driver.DriverID,
+			DistanceKm: distance,
+			Score:      distance,
+		})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].DistanceKm < candidates[j].DistanceKm
+	})
+
+	return candidates
+}
+
+// RankDriversByRating reorders candidates so that each star a driver's rating falls
+// below five counts as weightKm of extra distance. Drivers missing from ratings
+// haven't been rated yet and aren't penalised.
+func RankDriversByRating(candidates []DriverCandidate, ratings map[uuid.UUID]float64, weightKm float64) []DriverCandidate {
+	ranked := make([]DriverCandidate, len(candidates))
+	for i, candidate := range candidates {
+		candidate.Score = candidate.DistanceKm
+		if rating, ok := ratings[candidate.DriverID]; ok {
+			candidate.Score += (5 - rating) * weightKm
+		}
+		ranked[i] = candidate
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Score < ranked[j].Score
+	})
+
+	return ranked
+}
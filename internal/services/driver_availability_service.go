@@ -5,49 +5,95 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/dto"
 	"github.com/telemoz/backend/internal/models"
 	"github.com/telemoz/backend/internal/repositories"
+	"github.com/telemoz/backend/internal/utils"
 )
 
 type DriverAvailabilityService interface {
 	UpdateAvailability(driverID uuid.UUID, isAvailable bool, serviceTypes []string) error
 	GetAvailability(driverID uuid.UUID) (*models.DriverAvailability, error)
-	GetAvailableDrivers(serviceType string) ([]uuid.UUID, error)
+	GetAvailableDrivers(serviceType string) ([]models.DriverAvailability, error)
+	RecordHeartbeat(driverID uuid.UUID, req dto.HeartbeatRequest) (*models.DriverAvailability, error)
+	MarkStaleDriversOffline(timeout time.Duration) (int64, error)
 }
 
 type driverAvailabilityService struct {
 	availabilityRepo repositories.DriverAvailabilityRepository
+	jobRepo          repositories.JobRepository
+	meter            *tripMeter
+	compliance       *driverComplianceService
 }
 
 func NewDriverAvailabilityService(repo repositories.DriverAvailabilityRepository) DriverAvailabilityService {
 	return &driverAvailabilityService{
 		availabilityRepo: repo,
+		jobRepo:          repositories.NewJobRepository(),
+		meter:            newTripMeter(),
+		compliance:       newDriverComplianceService(),
 	}
 }
 
+// UpdateAvailability toggles a driver online or offline. A nil serviceTypes keeps
+// the driver's current selection. Going online needs an approved vehicle and
+// documents; the driver's vehicle class and service types follow their vehicle.
 func (s *driverAvailabilityService) UpdateAvailability(
 	driverID uuid.UUID,
 	isAvailable bool,
 	serviceTypes []string,
 ) error {
-	availability, err := s.availabilityRepo.FindByDriverID(driverID)
-
 	now := time.Now()
 
+	if isAvailable {
+		if err := s.compliance.checkDriver(driverID, now); err != nil {
+			return err
+		}
+	}
+	vehicle, err := s.compliance.vehicleRepo.FindByDriverID(driverID)
+	if err != nil {
+		vehicle = nil
+	}
+	if vehicle != nil {
+		for _, serviceType := range serviceTypes {
+			if !vehicleOffers(vehicle, serviceType) {
+				return ErrServiceNotOnVehicle
+			}
+		}
+	}
+
+	availability, err := s.availabilityRepo.FindByDriverID(driverID)
+
 	if err != nil {
+		if isAvailable && len(serviceTypes) == 0 {
+			return errors.New("select at least one service type to go online")
+		}
+
 		// Create new record
 		availability = &models.DriverAvailability{
 			DriverID:     driverID,
 			IsAvailable:  isAvailable,
 			ServiceTypes: serviceTypes,
+			VehicleClass: models.VehicleClassCar,
 			LastActiveAt: now,
 		}
+		if vehicle != nil {
+			availability.VehicleClass = vehicle.VehicleClass
+		}
 		return s.availabilityRepo.Create(availability)
 	}
 
 	// Update existing
+	if serviceTypes != nil {
+		availability.ServiceTypes = serviceTypes
+	}
+	if isAvailable && len(availability.ServiceTypes) == 0 {
+		return errors.New("select at least one service type to go online")
+	}
+	if vehicle != nil {
+		availability.VehicleClass = vehicle.VehicleClass
+	}
 	availability.IsAvailable = isAvailable
-	availability.ServiceTypes = serviceTypes
 	availability.LastActiveAt = now
 
 	return s.availabilityRepo.Update(availability)
@@ -61,6 +107,57 @@ func (s *driverAvailabilityService) GetAvailability(driverID uuid.UUID) (*models
 	return availability, nil
 }
 
-func (s *driverAvailabilityService) GetAvailableDrivers(serviceType string) ([]uuid.UUID, error) {
+func (s *driverAvailabilityService) GetAvailableDrivers(serviceType string) ([]models.DriverAvailability, error) {
 	return s.availabilityRepo.FindAvailableByServiceType(serviceType)
 }
+
+// RecordHeartbeat keeps a driver's session alive and stores their current position
+func (s *driverAvailabilityService) RecordHeartbeat(driverID uuid.UUID, req dto.HeartbeatRequest) (*models.DriverAvailability, error) {
+	if !utils.ValidateCoordinates(*req.Latitude, *req.Longitude) {
+		return nil, errors.New("invalid coordinates")
+	}
+
+	now := time.Now()
+
+	availability, err := s.availabilityRepo.FindByDriverID(driverID)
+	isNew := err != nil
+	if isNew {
+		// First contact from this driver; they stay offline until they opt in
+		availability = &models.DriverAvailability{
+			DriverID:    driverID,
+			IsAvailable: false,
+		}
+	}
+
+	availability.LastActiveAt = now
+	availability.Latitude = req.Latitude
+	availability.Longitude = req.Longitude
+	availability.Accuracy = req.Accuracy
+	availability.Speed = req.Speed
+	availability.Heading = req.Heading
+	availability.LocationUpdatedAt = &now
+
+	if isNew {
+		err = s.availabilityRepo.Create(availability)
+	} else {
+		err = s.availabilityRepo.UpdateLocation(availability)
+	}
+	if err != nil {
+		return nil, errors.New("failed to record heartbeat")
+	}
+
+	// Stream the driver's position to the customer of the trip they are serving and
+	// meter it once the trip is under way. A point lost here only makes the metered
+	// distance a little shorter, so it doesn't fail the heartbeat.
+	if job, err := s.jobRepo.FindActiveByDriverID(driverID); err == nil {
+		publishDriverLocation(&job.Trip, availability)
+		_ = s.meter.record(&job.Trip, availability)
+	}
+
+	return availability, nil
+}
+
+// MarkStaleDriversOffline takes drivers offline whose last heartbeat is older than timeout
+func (s *driverAvailabilityService) MarkStaleDriversOffline(timeout time.Duration) (int64, error) {
+	return s.availabilityRepo.MarkStaleOffline(time.Now().Add(-timeout))
+}
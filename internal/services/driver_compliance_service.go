@@ -0,0 +1,509 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/config"
+	"github.com/telemoz/backend/internal/dto"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/repositories"
+	"github.com/telemoz/backend/pkg/storage"
+)
+
+var (
+	// ErrDriverNotCompliant means the driver's vehicle or documents aren't approved
+	ErrDriverNotCompliant = errors.New("your vehicle and documents must be approved before you can take jobs")
+	// ErrPlateNumberTaken means another driver registered the vehicle
+	ErrPlateNumberTaken = errors.New("this plate number is registered to another driver")
+	// ErrServiceNotOnVehicle means the driver chose a service their vehicle isn't registered for
+	ErrServiceNotOnVehicle = errors.New("your vehicle isn't registered for this service type")
+	// ErrInvalidDocumentFile means the scan isn't a PNG, JPEG or PDF within the size limit
+	ErrInvalidDocumentFile = errors.New("document must be a PNG, JPEG or PDF within the upload size limit")
+	// ErrInvalidDocumentExpiry means expires_at isn't a date in the future
+	ErrInvalidDocumentExpiry = errors.New("invalid expires_at, expected a future date as YYYY-MM-DD")
+	// ErrDocumentNotFound means the document doesn't exist or isn't the driver's
+	ErrDocumentNotFound = errors.New("document not found")
+	// ErrDocumentReviewed means another admin reviewed the document first
+	ErrDocumentReviewed = errors.New("document has already been reviewed")
+	// ErrDocumentExpired means an expired document was submitted for approval
+	ErrDocumentExpired = errors.New("document has expired")
+)
+
+const (
+	notificationDocumentApproved = "driver_document_approved"
+	notificationDocumentRejected = "driver_document_rejected"
+	notificationDocumentExpiring = "driver_document_expiring"
+)
+
+// documentFileExtensions are the file types accepted as document scans
+var documentFileExtensions = map[string]string{
+	"image/png":       ".png",
+	"image/jpeg":      ".jpg",
+	"application/pdf": ".pdf",
+}
+
+// DocumentUpload is the scan of an uploaded driver document
+type DocumentUpload struct {
+	Content io.Reader
+}
+
+// DocumentFile is a stored document scan
+type DocumentFile struct {
+	Content     []byte
+	ContentType string
+}
+
+type DriverComplianceService interface {
+	GetCompliance(driverID uuid.UUID) (*dto.DriverComplianceResponse, error)
+	SaveVehicle(driverID uuid.UUID, req dto.VehicleRequest) (*dto.VehicleResponse, error)
+	UploadDocument(driverID uuid.UUID, req dto.UploadDocumentRequest, upload DocumentUpload) (*dto.DriverDocumentResponse, error)
+	GetDocumentFile(documentID, userID uuid.UUID, admin bool) (*DocumentFile, error)
+	ListDocuments(status string, limit, offset int) ([]dto.DriverDocumentResponse, error)
+	ApproveDocument(documentID, adminID uuid.UUID) (*dto.DriverDocumentResponse, error)
+	RejectDocument(documentID, adminID uuid.UUID, req dto.RejectDocumentRequest) (*dto.DriverDocumentResponse, error)
+	WarnExpiringDocuments(now time.Time) error
+}
+
+type driverComplianceService struct {
+	vehicleRepo         repositories.VehicleRepository
+	documentRepo        repositories.DriverDocumentRepository
+	availabilityRepo    repositories.DriverAvailabilityRepository
+	notificationService NotificationService
+	store               storage.Store
+	maxUploadBytes      int
+	cfg                 config.ComplianceConfig
+}
+
+func NewDriverComplianceService() DriverComplianceService {
+	return newDriverComplianceService()
+}
+
+func newDriverComplianceService() *driverComplianceService {
+	return &driverComplianceService{
+		vehicleRepo:         repositories.NewVehicleRepository(),
+		documentRepo:        repositories.NewDriverDocumentRepository(),
+		availabilityRepo:    repositories.NewDriverAvailabilityRepository(),
+		notificationService: NewNotificationService(),
+		store:               storage.NewStore(),
+		maxUploadBytes:      config.AppConfig.Storage.MaxUploadBytes,
+		cfg:                 config.AppConfig.Compliance,
+	}
+}
+
+// DocumentValid reports whether a document is approved and hasn't expired
+func DocumentValid(document *models.DriverDocument, now time.Time) bool {
+	return document.Status == models.DocumentStatusApproved && now.Before(document.ExpiresAt)
+}
+
+// DriverComplianceIssues lists what keeps a driver from taking jobs: a missing
+// vehicle, and each required document type without a valid document. A type is
+// reported by the state of its latest upload. No issues means the driver is compliant.
+func DriverComplianceIssues(vehicle *models.Vehicle, documents []models.DriverDocument, required []string, now time.Time) []string {
+	issues := []string{}
+	if vehicle == nil {
+		issues = append(issues, "no vehicle registered")
+	}
+
+	for _, documentType := range required {
+		var latest *models.DriverDocument
+		valid := false
+		for i := range documents {
+			document := &documents[i]
+			if string(document.Type) != documentType {
+				continue
+			}
+			if DocumentValid(document, now) {
+				valid = true
+				break
+			}
+			if latest == nil || document.CreatedAt.After(latest.CreatedAt) {
+				latest = document
+			}
+		}
+		if valid {
+			continue
+		}
+
+		switch {
+		case latest == nil:
+			issues = append(issues, documentType+" missing")
+		case latest.Status == models.DocumentStatusPending:
+			issues = append(issues, documentType+" awaiting review")
+		case latest.Status == models.DocumentStatusRejected:
+			issues = append(issues, documentType+" rejected")
+		default:
+			issues = append(issues, documentType+" expired")
+		}
+	}
+	return issues
+}
+
+// GetCompliance returns the driver's vehicle and documents and whether they may take jobs
+func (s *driverComplianceService) GetCompliance(driverID uuid.UUID) (*dto.DriverComplianceResponse, error) {
+	vehicle, err := s.vehicleRepo.FindByDriverID(driverID)
+	if err != nil {
+		vehicle = nil
+	}
+	documents, err := s.documentRepo.FindByDriverID(driverID)
+	if err != nil {
+		return nil, errors.New("failed to fetch documents")
+	}
+
+	now := time.Now()
+	issues := DriverComplianceIssues(vehicle, documents, s.cfg.RequiredDocuments, now)
+	response := &dto.DriverComplianceResponse{
+		DriverID:  driverID.String(),
+		Compliant: len(issues) == 0,
+		Issues:    issues,
+		Vehicle:   vehicleToDTO(vehicle),
+		Documents: make([]dto.DriverDocumentResponse, len(documents)),
+	}
+	for i := range documents {
+		response.Documents[i] = documentToDTO(&documents[i], now, false)
+	}
+	return response, nil
+}
+
+// SaveVehicle registers the driver's vehicle or updates it. The driver's vehicle class
+// and service types follow the vehicle.
+func (s *driverComplianceService) SaveVehicle(driverID uuid.UUID, req dto.VehicleRequest) (*dto.VehicleResponse, error) {
+	plateNumber := strings.ToUpper(strings.Join(strings.Fields(req.PlateNumber), ""))
+	if other, err := s.vehicleRepo.FindByPlateNumber(plateNumber); err == nil && other.DriverID != driverID {
+		return nil, ErrPlateNumberTaken
+	}
+
+	vehicle, err := s.vehicleRepo.FindByDriverID(driverID)
+	if err != nil {
+		vehicle = &models.Vehicle{DriverID: driverID}
+	}
+	vehicle.PlateNumber = plateNumber
+	vehicle.Make = req.Make
+	vehicle.Model = req.Model
+	vehicle.Color = req.Color
+	vehicle.Year = req.Year
+	vehicle.VehicleClass = models.VehicleClass(req.VehicleClass)
+	vehicle.Seats = req.Seats
+	vehicle.ServiceTypes = models.StringArray(req.ServiceTypes)
+	if err := s.vehicleRepo.Save(vehicle); err != nil {
+		return nil, errors.New("failed to save vehicle")
+	}
+
+	// Drop services the vehicle no longer offers from the driver's selection
+	if availability, err := s.availabilityRepo.FindByDriverID(driverID); err == nil {
+		availability.VehicleClass = vehicle.VehicleClass
+		serviceTypes := models.StringArray{}
+		for _, serviceType := range availability.ServiceTypes {
+			if vehicleOffers(vehicle, serviceType) {
+				serviceTypes = append(serviceTypes, serviceType)
+			}
+		}
+		availability.ServiceTypes = serviceTypes
+		if len(serviceTypes) == 0 {
+			availability.IsAvailable = false
+		}
+		if err := s.availabilityRepo.Update(availability); err != nil {
+			log.Printf("Failed to update availability of driver %s for their vehicle: %v", driverID, err)
+		}
+	}
+
+	return vehicleToDTO(vehicle), nil
+}
+
+// UploadDocument stores a scan of one of the driver's documents for review
+func (s *driverComplianceService) UploadDocument(driverID uuid.UUID, req dto.UploadDocumentRequest, upload DocumentUpload) (*dto.DriverDocumentResponse, error) {
+	// Documents are valid through their last day
+	lastDay, err := time.Parse("2006-01-02", req.ExpiresAt)
+	if err != nil {
+		return nil, ErrInvalidDocumentExpiry
+	}
+	now := time.Now()
+	expiresAt := lastDay.AddDate(0, 0, 1)
+	if !expiresAt.After(now) {
+		return nil, ErrInvalidDocumentExpiry
+	}
+
+	content, err := io.ReadAll(io.LimitReader(upload.Content, int64(s.maxUploadBytes)+1))
+	if err != nil {
+		return nil, errors.New("failed to read document")
+	}
+	extension, ok := documentFileExtensions[http.DetectContentType(content)]
+	if !ok || len(content) > s.maxUploadBytes {
+		return nil, ErrInvalidDocumentFile
+	}
+
+	document := &models.DriverDocument{
+		ID:        uuid.New(),
+		DriverID:  driverID,
+		Type:      models.DocumentType(req.Type),
+		Status:    models.DocumentStatusPending,
+		ExpiresAt: expiresAt,
+	}
+	document.FileKey = fmt.Sprintf("documents/%s/%s-%s%s", driverID, document.ID, document.Type, extension)
+	if err := s.store.Put(document.FileKey, bytes.NewReader(content)); err != nil {
+		return nil, errors.New("failed to store document")
+	}
+	if err := s.documentRepo.Create(document); err != nil {
+		return nil, errors.New("failed to save document")
+	}
+
+	response := documentToDTO(document, now, false)
+	return &response, nil
+}
+
+// GetDocumentFile returns the scan of a document to its driver or an admin
+func (s *driverComplianceService) GetDocumentFile(documentID, userID uuid.UUID, admin bool) (*DocumentFile, error) {
+	document, err := s.documentRepo.FindByID(documentID)
+	if err != nil || (!admin && document.DriverID != userID) {
+		return nil, ErrDocumentNotFound
+	}
+
+	file, err := s.store.Open(document.FileKey)
+	if err != nil {
+		return nil, ErrDocumentNotFound
+	}
+	defer file.Close()
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return nil, errors.New("failed to read document")
+	}
+	return &DocumentFile{
+		Content:     content,
+		ContentType: mime.TypeByExtension(path.Ext(document.FileKey)),
+	}, nil
+}
+
+// ListDocuments returns the documents in a status for review, pending by default
+func (s *driverComplianceService) ListDocuments(status string, limit, offset int) ([]dto.DriverDocumentResponse, error) {
+	if status == "" {
+		status = string(models.DocumentStatusPending)
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	documents, err := s.documentRepo.FindByStatus(models.DocumentStatus(status), limit, offset)
+	if err != nil {
+		return nil, errors.New("failed to fetch documents")
+	}
+
+	now := time.Now()
+	responses := make([]dto.DriverDocumentResponse, len(documents))
+	for i := range documents {
+		responses[i] = documentToDTO(&documents[i], now, true)
+	}
+	return responses, nil
+}
+
+// ApproveDocument accepts a pending document. Expired documents can't be approved.
+func (s *driverComplianceService) ApproveDocument(documentID, adminID uuid.UUID) (*dto.DriverDocumentResponse, error) {
+	document, err := s.documentRepo.FindByID(documentID)
+	if err != nil {
+		return nil, ErrDocumentNotFound
+	}
+	now := time.Now()
+	if !now.Before(document.ExpiresAt) {
+		return nil, ErrDocumentExpired
+	}
+
+	document.Status = models.DocumentStatusApproved
+	response, err := s.review(document, adminID, now)
+	if err != nil {
+		return nil, err
+	}
+	s.notify(document, notificationDocumentApproved, "Document approved",
+		fmt.Sprintf("Your %s has been approved", document.Type))
+	return response, nil
+}
+
+// RejectDocument turns down a pending document, telling the driver why
+func (s *driverComplianceService) RejectDocument(documentID, adminID uuid.UUID, req dto.RejectDocumentRequest) (*dto.DriverDocumentResponse, error) {
+	document, err := s.documentRepo.FindByID(documentID)
+	if err != nil {
+		return nil, ErrDocumentNotFound
+	}
+
+	document.Status = models.DocumentStatusRejected
+	document.RejectionReason = &req.Reason
+	response, err := s.review(document, adminID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	s.notify(document, notificationDocumentRejected, "Document rejected",
+		fmt.Sprintf("Your %s was rejected: %s. Please upload a new one.", document.Type, req.Reason))
+	return response, nil
+}
+
+// WarnExpiringDocuments warns drivers once about each approved document expiring
+// within the warning period, unless they already have an approved renewal
+func (s *driverComplianceService) WarnExpiringDocuments(now time.Time) error {
+	documents, err := s.documentRepo.FindExpiring(now.Add(s.cfg.ExpiryWarning))
+	if err != nil {
+		return err
+	}
+
+	for i := range documents {
+		document := &documents[i]
+		claimed, err := s.documentRepo.ClaimExpiryWarning(document, now)
+		if err != nil {
+			return err
+		}
+		if !claimed || s.renewed(document) {
+			continue
+		}
+
+		lastDay := document.ExpiresAt.AddDate(0, 0, -1).Format("2006-01-02")
+		message := fmt.Sprintf("Your %s expires on %s. Upload a renewed one to keep receiving jobs.", document.Type, lastDay)
+		if !now.Before(document.ExpiresAt) {
+			message = fmt.Sprintf("Your %s expired on %s. Upload a renewed one to receive jobs again.", document.Type, lastDay)
+		}
+		s.notify(document, notificationDocumentExpiring, "Document expiring", message)
+	}
+	return nil
+}
+
+// checkDriver returns ErrDriverNotCompliant unless the driver may take jobs
+func (s *driverComplianceService) checkDriver(driverID uuid.UUID, now time.Time) error {
+	compliant, err := s.compliantDrivers([]uuid.UUID{driverID}, now)
+	if err != nil {
+		return errors.New("failed to check driver documents")
+	}
+	if !compliant[driverID] {
+		return ErrDriverNotCompliant
+	}
+	return nil
+}
+
+// compliantDrivers returns which of the drivers may take jobs
+func (s *driverComplianceService) compliantDrivers(driverIDs []uuid.UUID, now time.Time) (map[uuid.UUID]bool, error) {
+	vehicles, err := s.vehicleRepo.FindByDriverIDs(driverIDs)
+	if err != nil {
+		return nil, err
+	}
+	documents, err := s.documentRepo.FindByDriverIDs(driverIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	vehicleByDriver := make(map[uuid.UUID]*models.Vehicle, len(vehicles))
+	for i := range vehicles {
+		vehicleByDriver[vehicles[i].DriverID] = &vehicles[i]
+	}
+	documentsByDriver := make(map[uuid.UUID][]models.DriverDocument)
+	for _, document := range documents {
+		documentsByDriver[document.DriverID] = append(documentsByDriver[document.DriverID], document)
+	}
+
+	compliant := make(map[uuid.UUID]bool, len(driverIDs))
+	for _, driverID := range driverIDs {
+		issues := DriverComplianceIssues(vehicleByDriver[driverID], documentsByDriver[driverID], s.cfg.RequiredDocuments, now)
+		compliant[driverID] = len(issues) == 0
+	}
+	return compliant, nil
+}
+
+func (s *driverComplianceService) review(document *models.DriverDocument, adminID uuid.UUID, now time.Time) (*dto.DriverDocumentResponse, error) {
+	document.ReviewedBy = &adminID
+	document.ReviewedAt = &now
+	if err := s.documentRepo.Review(document); err != nil {
+		if errors.Is(err, repositories.ErrStatusChanged) {
+			return nil, ErrDocumentReviewed
+		}
+		return nil, errors.New("failed to review document")
+	}
+	response := documentToDTO(document, now, true)
+	return &response, nil
+}
+
+// renewed reports whether the driver has another approved document of the same type
+// that is valid for longer
+func (s *driverComplianceService) renewed(document *models.DriverDocument) bool {
+	documents, err := s.documentRepo.FindByDriverID(document.DriverID)
+	if err != nil {
+		return false
+	}
+	for _, other := range documents {
+		if other.ID != document.ID && other.Type == document.Type &&
+			other.Status == models.DocumentStatusApproved && other.ExpiresAt.After(document.ExpiresAt) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *driverComplianceService) notify(document *models.DriverDocument, notificationType, title, message string) {
+	data := map[string]interface{}{
+		"document_id": document.ID.String(),
+		"type":        string(document.Type),
+	}
+	if err := s.notificationService.CreateNotification(document.DriverID, notificationType, title, message, data); err != nil {
+		log.Printf("Failed to notify driver %s about document %s: %v", document.DriverID, document.ID, err)
+	}
+}
+
+func vehicleOffers(vehicle *models.Vehicle, serviceType string) bool {
+	for _, offered := range vehicle.ServiceTypes {
+		if offered == serviceType {
+			return true
+		}
+	}
+	return false
+}
+
+func vehicleToDTO(vehicle *models.Vehicle) *dto.VehicleResponse {
+	if vehicle == nil {
+		return nil
+	}
+	response := &dto.VehicleResponse{
+		ID:           vehicle.ID.String(),
+		PlateNumber:  vehicle.PlateNumber,
+		Make:         vehicle.Make,
+		Model:        vehicle.Model,
+		Color:        vehicle.Color,
+		Year:         vehicle.Year,
+		VehicleClass: string(vehicle.VehicleClass),
+		Seats:        vehicle.Seats,
+		ServiceTypes: []string(vehicle.ServiceTypes),
+	}
+	if response.ServiceTypes == nil {
+		response.ServiceTypes = []string{}
+	}
+	return response
+}
+
+// documentToDTO converts a document; forAdmin links the file through the admin API
+// and names the driver
+func documentToDTO(document *models.DriverDocument, now time.Time, forAdmin bool) dto.DriverDocumentResponse {
+	response := dto.DriverDocumentResponse{
+		ID:              document.ID.String(),
+		DriverID:        document.DriverID.String(),
+		Type:            string(document.Type),
+		Status:          string(document.Status),
+		ExpiresAt:       document.ExpiresAt.AddDate(0, 0, -1).Format("2006-01-02"),
+		Expired:         !now.Before(document.ExpiresAt),
+		RejectionReason: document.RejectionReason,
+		FileURL:         fmt.Sprintf("/api/drivers/documents/%s/file", document.ID),
+		CreatedAt:       document.CreatedAt.Format(time.RFC3339),
+	}
+	if forAdmin {
+		response.FileURL = fmt.Sprintf("/api/admin/driver-documents/%s/file", document.ID)
+		response.DriverName = document.Driver.Name
+	}
+	if document.ReviewedAt != nil {
+		reviewedAt := document.ReviewedAt.Format(time.RFC3339)
+		response.ReviewedAt = &reviewedAt
+	}
+	return response
+}
@@ -2,26 +2,46 @@ package services
 
 import (
 	"errors"
+	"fmt"
+	"log"
+	"math"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/config"
 	"github.com/telemoz/backend/internal/database"
 	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/repositories"
 	"gorm.io/gorm"
 )
 
 type EarningsService interface {
 	GetSummary(driverID uuid.UUID) (map[string]float64, error)
 	GetHistory(driverID uuid.UUID, limit, offset int) ([]models.DriverEarning, error)
+	AddTip(tripID, customerID uuid.UUID, amount float64) (*models.DriverEarning, error)
+	AdjustEarning(earningID, adminID uuid.UUID, amount float64, reason string) (*models.DriverEarning, error)
 }
 
 type earningsService struct {
-	db *gorm.DB
+	db          *gorm.DB
+	earningRepo repositories.DriverEarningRepository
+	tripRepo    repositories.TripRepository
+	jobRepo     repositories.JobRepository
+	payments    *paymentService
+	wallet      *walletService
+	cfg         config.CommissionConfig
 }
 
 func NewEarningsService() EarningsService {
+	gateway := defaultPaymentGateway()
 	return &earningsService{
-		db: database.DB,
+		db:          database.DB,
+		earningRepo: repositories.NewDriverEarningRepository(),
+		tripRepo:    repositories.NewTripRepository(),
+		jobRepo:     repositories.NewJobRepository(),
+		payments:    newPaymentService(gateway),
+		wallet:      newWalletService(gateway),
+		cfg:         config.AppConfig.Commission,
 	}
 }
 
@@ -102,3 +122,146 @@ func (s *earningsService) GetHistory(driverID uuid.UUID, limit, offset int) ([]m
 	return earnings, nil
 }
 
+// AddTip charges a customer's tip on a completed trip and credits it to its driver
+func (s *earningsService) AddTip(tripID, customerID uuid.UUID, amount float64) (*models.DriverEarning, error) {
+	amount = roundFare(amount)
+	if amount <= 0 || amount > s.cfg.TipMax {
+		return nil, fmt.Errorf("tip must be between 0.01 and %.2f", s.cfg.TipMax)
+	}
+
+	trip, err := s.tripRepo.FindByID(tripID)
+	if err != nil {
+		return nil, errors.New("trip not found")
+	}
+	if trip.CustomerID != customerID {
+		return nil, errors.New("unauthorized to tip on this trip")
+	}
+	if trip.Status != models.TripStatusCompleted || trip.DriverID == nil {
+		return nil, errors.New("only completed trips can be tipped")
+	}
+
+	job, err := s.jobRepo.FindByTripID(tripID)
+	if err != nil {
+		return nil, errors.New("job not found")
+	}
+	tipped, err := s.tipped(job.ID)
+	if err != nil {
+		return nil, errors.New("failed to fetch trip earnings")
+	}
+	if tipped {
+		return nil, errors.New("trip has already been tipped")
+	}
+
+	// The entry's ID keys the charge, so a retried charge isn't taken twice
+	tip := newEarningEntry(*trip.DriverID, job.ID, models.EarningTypeTip, amount, s.cfg.TipFeePercent, time.Now())
+	tip.ID = uuid.New()
+	tip.CreatedBy = &customerID
+	refund, err := s.chargeTip(trip, tip.ID, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.earningRepo.Append(tip); err != nil {
+		// A job takes a single tip entry, so of two concurrent tips only one is
+		// recorded; the other is given back
+		refund()
+		if tipped, _ := s.tipped(job.ID); tipped {
+			return nil, errors.New("trip has already been tipped")
+		}
+		return nil, errors.New("failed to record tip")
+	}
+	return tip, nil
+}
+
+// chargeTip takes a tip from the customer the way the trip was paid: wallet trips
+// from the balance, card trips on the trip's card and cash trips on the default
+// card. It returns a func that gives the tip back.
+func (s *earningsService) chargeTip(trip *models.Trip, tipID uuid.UUID, amount float64) (func(), error) {
+	if trip.PaymentMethod == models.PaymentTypeWallet {
+		transaction, err := s.wallet.chargeTip(trip, amount)
+		if err != nil {
+			return nil, err
+		}
+		return func() { s.wallet.refundTip(trip, transaction) }, nil
+	}
+
+	var methodID string
+	if trip.PaymentMethod == models.PaymentTypeCard && trip.PaymentMethodID != nil {
+		methodID = trip.PaymentMethodID.String()
+	}
+	key := "tip-" + tipID.String()
+	reference, err := s.payments.charge(trip.CustomerID, methodID, amount, key)
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		if _, err := s.payments.gateway.Refund(reference, amount, key); err != nil {
+			log.Printf("Failed to refund tip charge %s of trip %s: %v", reference, trip.ID, err)
+		}
+	}, nil
+}
+
+// tipped reports whether a job already has a tip entry
+func (s *earningsService) tipped(jobID uuid.UUID) (bool, error) {
+	entries, err := s.earningRepo.FindByJobID(jobID)
+	if err != nil {
+		return false, err
+	}
+	for _, entry := range entries {
+		if entry.Type == models.EarningTypeTip {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// AdjustEarning corrects a ledger entry by appending an adjustment of amount (which
+// may be negative) credited to the same driver and job. The original entry is kept.
+func (s *earningsService) AdjustEarning(earningID, adminID uuid.UUID, amount float64, reason string) (*models.DriverEarning, error) {
+	original, err := s.earningRepo.FindByID(earningID)
+	if err != nil {
+		return nil, errors.New("earning not found")
+	}
+
+	// Adjustments are stated net of commission
+	adjustment := newEarningEntry(original.DriverID, original.JobID, models.EarningTypeAdjustment, amount, 0, time.Now())
+	adjustment.AdjustsID = &original.ID
+	adjustment.Reason = &reason
+	adjustment.CreatedBy = &adminID
+	if err := s.earningRepo.Append(adjustment); err != nil {
+		return nil, errors.New("failed to record adjustment")
+	}
+	return adjustment, nil
+}
+
+// CommissionPercent returns the share of a fare the platform keeps for a service type
+func CommissionPercent(cfg config.CommissionConfig, serviceType string) float64 {
+	if percent, ok := cfg.ServiceFeePercent[serviceType]; ok {
+		return percent
+	}
+	return cfg.PlatformFeePercent
+}
+
+// SplitEarning splits gross into the platform's commission at feePercent and the
+// driver's share, both rounded to cents. The two always add up to gross.
+func SplitEarning(gross, feePercent float64) (commission, net float64) {
+	gross = math.Round(gross*100) / 100
+	commission = math.Round(gross*feePercent) / 100
+	net = math.Round((gross-commission)*100) / 100
+	return commission, net
+}
+
+// newEarningEntry builds a ledger entry crediting a driver with gross less feePercent
+func newEarningEntry(driverID, jobID uuid.UUID, earningType models.EarningType, gross, feePercent float64, now time.Time) *models.DriverEarning {
+	commission, net := SplitEarning(gross, feePercent)
+	return &models.DriverEarning{
+		DriverID:       driverID,
+		JobID:          jobID,
+		Type:           earningType,
+		GrossAmount:    math.Round(gross*100) / 100,
+		CommissionRate: feePercent,
+		Commission:     commission,
+		Amount:         net,
+		Date:           time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()),
+	}
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/dto"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/realtime"
+)
+
+// publishTripStatus notifies the customer and, once assigned, the driver of a trip status change
+func publishTripStatus(trip *models.Trip) {
+	event := dto.TripStatusEvent{
+		TripID: trip.ID.String(),
+		Status: string(trip.Status),
+	}
+	if trip.DriverID != nil {
+		driverID := trip.DriverID.String()
+		event.DriverID = &driverID
+	}
+
+	realtime.Publish(realtime.UserTopic(trip.CustomerID), realtime.EventTripStatus, event)
+	if trip.DriverID != nil {
+		realtime.Publish(realtime.UserTopic(*trip.DriverID), realtime.EventTripStatus, event)
+	}
+}
+
+func publishWaypointStatus(trip *models.Trip, waypoint *models.TripWaypoint) {
+	realtime.Publish(realtime.UserTopic(trip.CustomerID), realtime.EventTripWaypoint, dto.TripWaypointEvent{
+		TripID:     trip.ID.String(),
+		WaypointID: waypoint.ID.String(),
+		Sequence:   waypoint.Sequence,
+		Status:     string(waypoint.Status),
+	})
+}
+
+// publishChatMessage delivers a chat message to both parties, so the sender's other
+// devices see it too
+func publishChatMessage(trip *models.Trip, message *dto.TripMessageResponse) {
+	realtime.Publish(realtime.UserTopic(trip.CustomerID), realtime.EventChatMessage, message)
+	if trip.DriverID != nil {
+		realtime.Publish(realtime.UserTopic(*trip.DriverID), realtime.EventChatMessage, message)
+	}
+}
+
+// publishChatRead tells the other party of a chat their messages were read
+func publishChatRead(recipientID, tripID, readerID uuid.UUID, readAt time.Time) {
+	realtime.Publish(realtime.UserTopic(recipientID), realtime.EventChatRead, dto.ChatReadEvent{
+		TripID:   tripID.String(),
+		ReaderID: readerID.String(),
+		ReadAt:   readAt.Format(time.RFC3339),
+	})
+}
+
+func publishJobOffer(offer *models.JobOffer, trip *models.Trip) {
+	realtime.Publish(realtime.UserTopic(offer.DriverID), realtime.EventJobOffer, dto.JobOfferEvent{
+		JobID:       offer.JobID.String(),
+		TripID:      trip.ID.String(),
+		ServiceType: string(trip.ServiceType),
+		PickupLocation: dto.Location{
+			Latitude:  trip.PickupLatitude,
+			Longitude: trip.PickupLongitude,
+		},
+		DropoffLocation: dto.Location{
+			Latitude:  trip.DropoffLatitude,
+			Longitude: trip.DropoffLongitude,
+		},
+		DistanceToPickup: offer.DistanceKm,
+		FareAmount:       trip.FareAmount,
+		ExpiresAt:        offer.ExpiresAt.Format(time.RFC3339),
+	})
+}
+
+func publishJobOfferClosed(jobID, driverID uuid.UUID) {
+	realtime.Publish(realtime.UserTopic(driverID), realtime.EventJobOfferClosed, dto.JobOfferClosedEvent{
+		JobID: jobID.String(),
+	})
+}
+
+func publishDriverLocation(trip *models.Trip, availability *models.DriverAvailability) {
+	realtime.Publish(realtime.UserTopic(trip.CustomerID), realtime.EventDriverLocation, dto.DriverLocationEvent{
+		TripID:   trip.ID.String(),
+		DriverID: availability.DriverID.String(),
+		Location: dto.Location{
+			Latitude:  *availability.Latitude,
+			Longitude: *availability.Longitude,
+			Accuracy:  availability.Accuracy,
+		},
+		Speed:   availability.Speed,
+		Heading: availability.Heading,
+	})
+}
+
+func publishBusLocation(location *models.BusLocation) {
+	timestamp := location.Timestamp.Unix()
+	realtime.Publish(realtime.BusTopic(location.BusID), realtime.EventBusLocation, dto.BusLocationEvent{
+		BusID: location.BusID.String(),
+		Location: dto.Location{
+			Latitude:  location.Latitude,
+			Longitude: location.Longitude,
+			Accuracy:  location.Accuracy,
+			Timestamp: &timestamp,
+		},
+		Speed:   location.Speed,
+		Heading: location.Heading,
+	})
+}
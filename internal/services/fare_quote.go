@@ -0,0 +1,159 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrQuoteInvalid  = errors.New("invalid fare quote")
+	ErrQuoteExpired  = errors.New("fare quote has expired, please estimate the fare again")
+	ErrQuoteMismatch = errors.New("fare quote does not match this trip")
+	ErrQuoteUsed     = errors.New("fare quote has already been used")
+)
+
+// quoteAudience keeps quotes and access tokens signed with the same key apart
+const quoteAudience = "fare-quote"
+
+// FareQuote is a fare offered to a customer for a route, bookable until it expires
+type FareQuote struct {
+	ID               uuid.UUID
+	CustomerID       uuid.UUID
+	ServiceType      string
+	PickupLatitude   float64
+	PickupLongitude  float64
+	DropoffLatitude  float64
+	DropoffLongitude float64
+	Estimate         FareEstimate
+	ExpiresAt        time.Time
+}
+
+type fareQuoteClaims struct {
+	ServiceType      string     `json:"service_type"`
+	PickupLatitude   float64    `json:"pickup_lat"`
+	PickupLongitude  float64    `json:"pickup_lng"`
+	DropoffLatitude  float64    `json:"dropoff_lat"`
+	DropoffLongitude float64    `json:"dropoff_lng"`
+	Distance         float64    `json:"distance_km"`
+	Duration         float64    `json:"duration_minutes"`
+	DistanceMethod   string     `json:"distance_method"`
+	Fare             float64    `json:"fare"`
+	SurgeMultiplier  float64    `json:"surge_multiplier"`
+	FareTableID      *uuid.UUID `json:"fare_table_id,omitempty"`
+	WeightSurcharge  float64    `json:"weight_surcharge,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// QuoteSigner issues fare quotes as signed tokens, so a quote's price can't be changed
+// by the client and needs no storage until it is booked
+type QuoteSigner struct {
+	key []byte
+	ttl time.Duration
+}
+
+func NewQuoteSigner(key string, ttl time.Duration) *QuoteSigner {
+	return &QuoteSigner{key: []byte(key), ttl: ttl}
+}
+
+// Issue signs a quote for the estimate, valid for the signer's TTL from now
+func (s *QuoteSigner) Issue(quote *FareQuote, now time.Time) (string, error) {
+	quote.ID = uuid.New()
+	quote.ExpiresAt = now.Add(s.ttl).Truncate(time.Second)
+
+	claims := fareQuoteClaims{
+		ServiceType:      quote.ServiceType,
+		PickupLatitude:   quote.PickupLatitude,
+		PickupLongitude:  quote.PickupLongitude,
+		DropoffLatitude:  quote.DropoffLatitude,
+		DropoffLongitude: quote.DropoffLongitude,
+		Distance:         quote.Estimate.Distance,
+		Duration:         quote.Estimate.Duration,
+		DistanceMethod:   quote.Estimate.DistanceMethod,
+		Fare:             quote.Estimate.Fare,
+		SurgeMultiplier:  quote.Estimate.SurgeMultiplier,
+		FareTableID:      quote.Estimate.FareTableID,
+		WeightSurcharge:  quote.Estimate.Breakdown.WeightSurcharge,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        quote.ID.String(),
+			Subject:   quote.CustomerID.String(),
+			Audience:  jwt.ClaimStrings{quoteAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(quote.ExpiresAt),
+			Issuer:    "telemoz",
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(s.key)
+}
+
+// Verify checks a quote's signature and expiry as of now and returns the quote
+func (s *QuoteSigner) Verify(tokenString string, now time.Time) (*FareQuote, error) {
+	claims := &fareQuoteClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return s.key, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithAudience(quoteAudience),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(func() time.Time { return now }),
+	)
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return nil, ErrQuoteExpired
+	}
+	if err != nil {
+		return nil, ErrQuoteInvalid
+	}
+
+	quoteID, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return nil, ErrQuoteInvalid
+	}
+	customerID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return nil, ErrQuoteInvalid
+	}
+
+	return &FareQuote{
+		ID:               quoteID,
+		CustomerID:       customerID,
+		ServiceType:      claims.ServiceType,
+		PickupLatitude:   claims.PickupLatitude,
+		PickupLongitude:  claims.PickupLongitude,
+		DropoffLatitude:  claims.DropoffLatitude,
+		DropoffLongitude: claims.DropoffLongitude,
+		Estimate: FareEstimate{
+			Distance:        claims.Distance,
+			Duration:        claims.Duration,
+			Fare:            claims.Fare,
+			SurgeMultiplier: claims.SurgeMultiplier,
+			DistanceMethod:  claims.DistanceMethod,
+			FareTableID:     claims.FareTableID,
+			Breakdown:       FareBreakdown{WeightSurcharge: claims.WeightSurcharge},
+		},
+		ExpiresAt: claims.ExpiresAt.Time,
+	}, nil
+}
+
+// CheckQuoteMatches verifies that a quote was issued to the customer for the same
+// service type and, within toleranceMeters, the same pickup and drop-off points
+func CheckQuoteMatches(
+	quote *FareQuote,
+	customerID uuid.UUID,
+	serviceType string,
+	pickupLat, pickupLng, dropoffLat, dropoffLng float64,
+	toleranceMeters float64,
+) error {
+	if quote.CustomerID != customerID || quote.ServiceType != serviceType {
+		return ErrQuoteMismatch
+	}
+	toleranceKm := toleranceMeters / 1000
+	if calculateHaversineDistance(quote.PickupLatitude, quote.PickupLongitude, pickupLat, pickupLng) > toleranceKm ||
+		calculateHaversineDistance(quote.DropoffLatitude, quote.DropoffLongitude, dropoffLat, dropoffLng) > toleranceKm {
+		return ErrQuoteMismatch
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/dto"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/repositories"
+)
+
+type FareTableService interface {
+	CreateFareTable(adminID uuid.UUID, req *dto.FareTableRequest) (*models.FareTable, error)
+	ListFareTables(serviceType string) ([]models.FareTable, error)
+	GetFareTable(id uuid.UUID) (*models.FareTable, error)
+}
+
+type fareTableService struct {
+	fareTableRepo repositories.FareTableRepository
+}
+
+func NewFareTableService() FareTableService {
+	return &fareTableService{
+		fareTableRepo: repositories.NewFareTableRepository(),
+	}
+}
+
+// CreateFareTable adds a new pricing version. From its effective time it replaces the
+// previous versions for the same service type and zone.
+func (s *fareTableService) CreateFareTable(adminID uuid.UUID, req *dto.FareTableRequest) (*models.FareTable, error) {
+	table := &models.FareTable{
+		ServiceType:           req.ServiceType,
+		Zone:                  strings.ToLower(req.Zone),
+		Name:                  strings.TrimSpace(req.Name),
+		EffectiveFrom:         time.Now(),
+		Timezone:              "UTC",
+		BaseFare:              *req.BaseFare,
+		PerKmRate:             *req.PerKmRate,
+		PerMinuteRate:         req.PerMinuteRate,
+		MinimumFare:           req.MinimumFare,
+		BookingFee:            req.BookingFee,
+		WaitingPerMinuteRate:  req.WaitingPerMinuteRate,
+		FreeWaitingMinutes:    req.FreeWaitingMinutes,
+		NightSurchargePercent: req.NightSurchargePercent,
+		NightStart:            req.NightStart,
+		NightEnd:              req.NightEnd,
+		AirportSurcharge:      req.AirportSurcharge,
+		AirportZones:          models.StringArray{},
+		CreatedBy:             &adminID,
+	}
+
+	if req.EffectiveFrom != nil {
+		effectiveFrom, err := time.Parse(time.RFC3339, *req.EffectiveFrom)
+		if err != nil {
+			return nil, errors.New("invalid effective_from")
+		}
+		table.EffectiveFrom = effectiveFrom
+	}
+	if req.EffectiveTo != nil {
+		effectiveTo, err := time.Parse(time.RFC3339, *req.EffectiveTo)
+		if err != nil {
+			return nil, errors.New("invalid effective_to")
+		}
+		if !effectiveTo.After(table.EffectiveFrom) {
+			return nil, errors.New("effective_to must be after effective_from")
+		}
+		table.EffectiveTo = &effectiveTo
+	}
+	if req.Timezone != "" {
+		if _, err := time.LoadLocation(req.Timezone); err != nil {
+			return nil, errors.New("invalid timezone")
+		}
+		table.Timezone = req.Timezone
+	}
+	if (req.NightStart == nil) != (req.NightEnd == nil) {
+		return nil, errors.New("night_start and night_end must be set together")
+	}
+	for _, zone := range req.AirportZones {
+		table.AirportZones = append(table.AirportZones, strings.ToLower(zone))
+	}
+
+	if err := s.fareTableRepo.Create(table); err != nil {
+		return nil, errors.New("failed to create fare table")
+	}
+	return table, nil
+}
+
+func (s *fareTableService) ListFareTables(serviceType string) ([]models.FareTable, error) {
+	tables, err := s.fareTableRepo.FindAll(serviceType)
+	if err != nil {
+		return nil, errors.New("failed to fetch fare tables")
+	}
+	return tables, nil
+}
+
+func (s *fareTableService) GetFareTable(id uuid.UUID) (*models.FareTable, error) {
+	table, err := s.fareTableRepo.FindByID(id)
+	if err != nil {
+		return nil, errors.New("fare table not found")
+	}
+	return table, nil
+}
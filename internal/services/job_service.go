@@ -11,35 +11,54 @@ import (
 )
 
 type JobService interface {
-	GetAvailableJobs() ([]dto.JobResponse, error)
+	GetAvailableJobs(driverID uuid.UUID) ([]dto.JobResponse, error)
 	AcceptJob(jobID, driverID uuid.UUID) (*dto.JobResponse, error)
 	RejectJob(jobID, driverID uuid.UUID) error
 	GetActiveJob(driverID uuid.UUID) (*dto.JobResponse, error)
 	GetJobHistory(driverID uuid.UUID, limit, offset int) ([]dto.JobResponse, error)
-	UpdateJobStatus(jobID, driverID uuid.UUID, status string) (*dto.JobResponse, error)
+	UpdateJobStatus(jobID, driverID uuid.UUID, req dto.UpdateJobStatusRequest) (*dto.JobResponse, error)
+	CancelJob(jobID, driverID uuid.UUID, req dto.CancelJobRequest) error
 }
 
 type jobService struct {
-	jobRepo  repositories.JobRepository
-	tripRepo repositories.TripRepository
+	jobRepo         repositories.JobRepository
+	tripRepo        repositories.TripRepository
+	offerRepo       repositories.JobOfferRepository
+	dispatchService DispatchService
+	lifecycle       *tripLifecycle
 }
 
 func NewJobService() JobService {
+	return newJobService()
+}
+
+func newJobService() *jobService {
 	return &jobService{
-		jobRepo:  repositories.NewJobRepository(),
-		tripRepo: repositories.NewTripRepository(),
+		jobRepo:         repositories.NewJobRepository(),
+		tripRepo:        repositories.NewTripRepository(),
+		offerRepo:       repositories.NewJobOfferRepository(),
+		dispatchService: NewDispatchService(),
+		lifecycle:       newTripLifecycle(),
 	}
 }
 
-func (s *jobService) GetAvailableJobs() ([]dto.JobResponse, error) {
-	jobs, err := s.jobRepo.FindAvailable()
+// GetAvailableJobs returns the jobs currently offered to the driver by the dispatcher
+func (s *jobService) GetAvailableJobs(driverID uuid.UUID) ([]dto.JobResponse, error) {
+	offers, err := s.offerRepo.FindActiveByDriverID(driverID)
 	if err != nil {
 		return nil, errors.New("failed to fetch available jobs")
 	}
 
-	responses := make([]dto.JobResponse, len(jobs))
-	for i, job := range jobs {
-		responses[i] = *s.jobToDTO(&job)
+	responses := make([]dto.JobResponse, 0, len(offers))
+	for _, offer := range offers {
+		if offer.Job.Status != models.JobStatusPending {
+			continue
+		}
+		response := s.jobToDTO(&offer.Job)
+		expiresAt := offer.ExpiresAt.Format(time.RFC3339)
+		response.OfferExpiresAt = &expiresAt
+		response.DistanceToPickup = &offer.DistanceKm
+		responses = append(responses, *response)
 	}
 
 	return responses, nil
@@ -51,35 +70,38 @@ func (s *jobService) AcceptJob(jobID, driverID uuid.UUID) (*dto.JobResponse, err
 		return nil, errors.New("job not found")
 	}
 
+	if job.Status == models.JobStatusAccepted {
+		return nil, ErrJobTaken
+	}
 	if job.Status != models.JobStatusPending {
 		return nil, errors.New("job is not available")
 	}
 
-	now := time.Now()
-	job.DriverID = &driverID
-	job.Status = models.JobStatusAccepted
-	job.AcceptedAt = &now
+	offer, err := s.offerRepo.FindActive(jobID, driverID)
+	if err != nil {
+		// Offers are withdrawn once someone accepts, so a missing offer may mean we lost
+		if current, err := s.jobRepo.FindByID(jobID); err == nil && current.Status == models.JobStatusAccepted {
+			return nil, ErrJobTaken
+		}
+		return nil, errors.New("job has not been offered to you or the offer has expired")
+	}
 
-	// Update trip
 	trip, err := s.tripRepo.FindByID(job.TripID)
 	if err != nil {
 		return nil, errors.New("trip not found")
 	}
 
-	trip.DriverID = &driverID
-	trip.Status = models.TripStatusAccepted
-
-	if err := s.tripRepo.Update(trip); err != nil {
-		return nil, errors.New("failed to update trip")
-	}
-
-	if err := s.jobRepo.Update(job); err != nil {
-		return nil, errors.New("failed to accept job")
+	// The offer, job and trip are accepted together; if another driver got there
+	// first none of them change
+	if err := s.lifecycle.accept(trip, job, offer, driverID); err != nil {
+		return nil, err
 	}
+	s.dispatchService.WithdrawOffers(jobID)
 
 	return s.jobToDTO(job), nil
 }
 
+// RejectJob declines the driver's offer; the job stays open for other drivers
 func (s *jobService) RejectJob(jobID, driverID uuid.UUID) error {
 	job, err := s.jobRepo.FindByID(jobID)
 	if err != nil {
@@ -90,8 +112,7 @@ func (s *jobService) RejectJob(jobID, driverID uuid.UUID) error {
 		return errors.New("job cannot be rejected")
 	}
 
-	job.Status = models.JobStatusRejected
-	return s.jobRepo.Update(job)
+	return s.dispatchService.DeclineOffer(jobID, driverID)
 }
 
 func (s *jobService) GetActiveJob(driverID uuid.UUID) (*dto.JobResponse, error) {
@@ -123,7 +144,9 @@ func (s *jobService) GetJobHistory(driverID uuid.UUID, limit, offset int) ([]dto
 	return responses, nil
 }
 
-func (s *jobService) UpdateJobStatus(jobID, driverID uuid.UUID, status string) (*dto.JobResponse, error) {
+// UpdateJobStatus moves the driver's job, and its trip, on to the next stage of the
+// trip lifecycle. Job and trip statuses share their names from accepted onwards.
+func (s *jobService) UpdateJobStatus(jobID, driverID uuid.UUID, req dto.UpdateJobStatusRequest) (*dto.JobResponse, error) {
 	job, err := s.jobRepo.FindByID(jobID)
 	if err != nil {
 		return nil, errors.New("job not found")
@@ -133,26 +156,57 @@ func (s *jobService) UpdateJobStatus(jobID, driverID uuid.UUID, status string) (
 		return nil, errors.New("unauthorized to update this job")
 	}
 
-	job.Status = models.JobStatus(status)
-	if status == "completed" {
-		now := time.Now()
-		job.CompletedAt = &now
-
-		// Update trip status
-		trip, err := s.tripRepo.FindByID(job.TripID)
-		if err == nil {
-			trip.Status = models.TripStatusCompleted
-			s.tripRepo.Update(trip)
-		}
+	trip, err := s.tripRepo.FindByID(job.TripID)
+	if err != nil {
+		return nil, errors.New("trip not found")
 	}
 
-	if err := s.jobRepo.Update(job); err != nil {
-		return nil, errors.New("failed to update job status")
+	if err := s.lifecycle.apply(trip, job, tripTransition{
+		To:       models.TripStatus(req.Status),
+		Actor:    models.TripActorDriver,
+		ActorID:  &driverID,
+		Location: req.Location,
+		Note:     req.Note,
+	}); err != nil {
+		return nil, err
 	}
+	job.Trip = *trip
 
 	return s.jobToDTO(job), nil
 }
 
+// CancelJob gives up a job the driver accepted. Its trip goes back to searching and
+// is offered to other drivers; the driver who gave it up is not offered it again.
+func (s *jobService) CancelJob(jobID, driverID uuid.UUID, req dto.CancelJobRequest) error {
+	job, err := s.jobRepo.FindByID(jobID)
+	if err != nil {
+		return errors.New("job not found")
+	}
+
+	if job.DriverID == nil || *job.DriverID != driverID {
+		return errors.New("unauthorized to cancel this job")
+	}
+
+	trip, err := s.tripRepo.FindByID(job.TripID)
+	if err != nil {
+		return errors.New("trip not found")
+	}
+
+	if err := s.lifecycle.apply(trip, job, tripTransition{
+		To:      models.TripStatusSearching,
+		Actor:   models.TripActorDriver,
+		ActorID: &driverID,
+		Reason:  models.DriverCancelReason(req.Reason),
+		Note:    req.Note,
+	}); err != nil {
+		return err
+	}
+
+	// If this fails the dispatch job offers it on its next tick
+	s.dispatchService.StartDispatch(job, trip)
+	return nil
+}
+
 func (s *jobService) jobToDTO(job *models.Job) *dto.JobResponse {
 	response := &dto.JobResponse{
 		ID:              job.ID.String(),
@@ -172,6 +226,8 @@ func (s *jobService) jobToDTO(job *models.Job) *dto.JobResponse {
 	if job.Trip.ID != uuid.Nil {
 		response.ServiceType = string(job.Trip.ServiceType)
 		response.CustomerID = job.Trip.CustomerID.String()
+		response.CustomerRating = job.Trip.Customer.RatingAverage
+		response.CustomerRatingCount = job.Trip.Customer.RatingCount
 		response.PickupLocation = dto.Location{
 			Latitude:  job.Trip.PickupLatitude,
 			Longitude: job.Trip.PickupLongitude,
@@ -180,6 +236,12 @@ func (s *jobService) jobToDTO(job *models.Job) *dto.JobResponse {
 			Latitude:  job.Trip.DropoffLatitude,
 			Longitude: job.Trip.DropoffLongitude,
 		}
+		response.Waypoints = waypointsToDTO(job.Trip.Waypoints, false)
+		response.Package = packageToDTO(job.Trip.Package)
+		if job.Trip.ScheduledAt != nil {
+			scheduledAt := job.Trip.ScheduledAt.Format(time.RFC3339)
+			response.ScheduledAt = &scheduledAt
+		}
 	}
 
 	return response
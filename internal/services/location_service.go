@@ -53,7 +53,12 @@ func (s *locationService) UpdateBusLocation(busID uuid.UUID, lat, lng, accuracy,
 		location.Heading = &heading
 	}
 
-	return s.busLocationRepo.Create(location)
+	if err := s.busLocationRepo.Create(location); err != nil {
+		return err
+	}
+
+	publishBusLocation(location)
+	return nil
 }
 
 func (s *locationService) CalculateETA(tripID uuid.UUID) (*time.Time, error) {
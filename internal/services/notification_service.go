@@ -95,6 +95,7 @@ func (s *notificationService) UpdateSettings(userID uuid.UUID, settings *models.
 	existing.BusNearbyAlert = settings.BusNearbyAlert
 	existing.BusArrived = settings.BusArrived
 	existing.BusDeparted = settings.BusDeparted
+	existing.BusNearbyMinutes = settings.BusNearbyMinutes
 	existing.RouteChange = settings.RouteChange
 	existing.SMSEnabled = settings.SMSEnabled
 	existing.CallEnabled = settings.CallEnabled
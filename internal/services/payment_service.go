@@ -0,0 +1,628 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/config"
+	"github.com/telemoz/backend/internal/dto"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/repositories"
+	"github.com/telemoz/backend/pkg/payment"
+)
+
+var (
+	// ErrCardPaymentsDisabled means no payment gateway is configured
+	ErrCardPaymentsDisabled = errors.New("card payments are not available")
+	// ErrPaymentMethodRequired means a card trip was requested by a customer without a saved card
+	ErrPaymentMethodRequired = errors.New("add a card before paying by card")
+	// ErrPaymentMethodNotFound means the card does not exist or belongs to someone else
+	ErrPaymentMethodNotFound = errors.New("payment method not found")
+	// ErrPaymentMethodInUse means the card is charged for a trip still under way
+	ErrPaymentMethodInUse = errors.New("payment method is used by an active trip")
+	// ErrPaymentNotFound means the trip has no card payment
+	ErrPaymentNotFound = errors.New("payment not found")
+	// ErrRefundExceedsCapture means the refund is larger than what is left of the captured amount
+	ErrRefundExceedsCapture = errors.New("refund exceeds the captured amount")
+	// ErrInvalidWebhook means a webhook was not signed by the gateway or could not be read
+	ErrInvalidWebhook = errors.New("invalid webhook")
+)
+
+type PaymentService interface {
+	ListPaymentMethods(customerID uuid.UUID) ([]dto.PaymentMethodResponse, error)
+	AddPaymentMethod(customerID uuid.UUID, req dto.AddPaymentMethodRequest) (*dto.PaymentMethodResponse, error)
+	SetDefaultPaymentMethod(customerID, methodID uuid.UUID) error
+	DeletePaymentMethod(customerID, methodID uuid.UUID) error
+	GetTripPayment(tripID, userID uuid.UUID) (*dto.PaymentResponse, error)
+	RefundTripPayment(tripID, adminID uuid.UUID, req dto.RefundRequest) (*dto.PaymentResponse, error)
+	HandleWebhook(payload []byte, signature string) error
+}
+
+type paymentService struct {
+	methodRepo  repositories.PaymentMethodRepository
+	paymentRepo repositories.PaymentRepository
+	tripRepo    repositories.TripRepository
+	gateway     payment.PaymentGateway
+	cfg         config.PaymentConfig
+}
+
+func NewPaymentService() PaymentService {
+	return newPaymentService(defaultPaymentGateway())
+}
+
+// NewPaymentServiceWithGateway creates the service with an explicit gateway, e.g. a fake in tests
+func NewPaymentServiceWithGateway(gateway payment.PaymentGateway) PaymentService {
+	return newPaymentService(gateway)
+}
+
+func newPaymentService(gateway payment.PaymentGateway) *paymentService {
+	return &paymentService{
+		methodRepo:  repositories.NewPaymentMethodRepository(),
+		paymentRepo: repositories.NewPaymentRepository(),
+		tripRepo:    repositories.NewTripRepository(),
+		gateway:     gateway,
+		cfg:         config.AppConfig.Payment,
+	}
+}
+
+var (
+	sharedPaymentGatewayOnce sync.Once
+	sharedPaymentGateway     payment.PaymentGateway
+)
+
+// defaultPaymentGateway is the configured gateway, shared so charges held by one
+// service can be captured by another
+func defaultPaymentGateway() payment.PaymentGateway {
+	sharedPaymentGatewayOnce.Do(func() {
+		sharedPaymentGateway = payment.NewGateway()
+	})
+	return sharedPaymentGateway
+}
+
+// HoldAmount is the amount held on a card for a trip: the upfront fare plus a margin
+// for a higher metered fare
+func HoldAmount(fare, marginPercent float64) float64 {
+	if marginPercent < 0 {
+		marginPercent = 0
+	}
+	return roundFare(fare * (100 + marginPercent) / 100)
+}
+
+// CaptureAmount is how much of a hold is taken for the final fare. A fare above the
+// hold is capped at the hold; the gateway cannot take more than was authorized.
+func CaptureAmount(fare, authorized float64) float64 {
+	return roundFare(math.Max(0, math.Min(fare, authorized)))
+}
+
+// RefundedStatus is the status of a captured payment once refunded has been returned
+func RefundedStatus(captured, refunded float64) models.PaymentStatus {
+	switch {
+	case refunded <= 0:
+		return models.PaymentStatusCaptured
+	case roundFare(captured-refunded) <= 0:
+		return models.PaymentStatusRefunded
+	default:
+		return models.PaymentStatusPartiallyRefunded
+	}
+}
+
+func (s *paymentService) ListPaymentMethods(customerID uuid.UUID) ([]dto.PaymentMethodResponse, error) {
+	methods, err := s.methodRepo.FindByCustomerID(customerID)
+	if err != nil {
+		return nil, errors.New("failed to fetch payment methods")
+	}
+
+	responses := make([]dto.PaymentMethodResponse, len(methods))
+	for i := range methods {
+		responses[i] = paymentMethodToDTO(&methods[i])
+	}
+	return responses, nil
+}
+
+// AddPaymentMethod saves the card behind a gateway token. A customer's first card
+// becomes their default.
+func (s *paymentService) AddPaymentMethod(customerID uuid.UUID, req dto.AddPaymentMethodRequest) (*dto.PaymentMethodResponse, error) {
+	if s.gateway == nil {
+		return nil, ErrCardPaymentsDisabled
+	}
+
+	card, err := s.gateway.Card(req.Token)
+	if err != nil {
+		if errors.Is(err, payment.ErrUnknownCard) {
+			return nil, errors.New("invalid card token")
+		}
+		return nil, errors.New("failed to verify card")
+	}
+
+	existing, err := s.methodRepo.FindByCustomerID(customerID)
+	if err != nil {
+		return nil, errors.New("failed to fetch payment methods")
+	}
+
+	method := &models.PaymentMethod{
+		CustomerID:   customerID,
+		GatewayToken: card.Token,
+		Brand:        card.Brand,
+		Last4:        card.Last4,
+		ExpMonth:     card.ExpMonth,
+		ExpYear:      card.ExpYear,
+	}
+	if err := s.methodRepo.Create(method); err != nil {
+		return nil, errors.New("failed to save payment method")
+	}
+	if req.Default || len(existing) == 0 {
+		if err := s.methodRepo.SetDefault(method); err != nil {
+			return nil, errors.New("failed to set default payment method")
+		}
+	}
+
+	response := paymentMethodToDTO(method)
+	return &response, nil
+}
+
+func (s *paymentService) SetDefaultPaymentMethod(customerID, methodID uuid.UUID) error {
+	method, err := s.findMethod(customerID, methodID)
+	if err != nil {
+		return err
+	}
+	if err := s.methodRepo.SetDefault(method); err != nil {
+		return errors.New("failed to set default payment method")
+	}
+	return nil
+}
+
+// DeletePaymentMethod removes a saved card. Removing the default card makes the
+// oldest remaining card the default.
+func (s *paymentService) DeletePaymentMethod(customerID, methodID uuid.UUID) error {
+	method, err := s.findMethod(customerID, methodID)
+	if err != nil {
+		return err
+	}
+
+	if trip, err := s.tripRepo.FindActiveByCustomerID(customerID); err == nil &&
+		trip.PaymentMethodID != nil && *trip.PaymentMethodID == method.ID {
+		return ErrPaymentMethodInUse
+	}
+
+	if err := s.methodRepo.Delete(method.ID); err != nil {
+		return errors.New("failed to delete payment method")
+	}
+
+	if method.IsDefault {
+		remaining, err := s.methodRepo.FindByCustomerID(customerID)
+		if err == nil && len(remaining) > 0 {
+			if err := s.methodRepo.SetDefault(&remaining[0]); err != nil {
+				log.Printf("Failed to set default payment method of customer %s: %v", customerID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// GetTripPayment returns the card payment of a trip to its customer or driver
+func (s *paymentService) GetTripPayment(tripID, userID uuid.UUID) (*dto.PaymentResponse, error) {
+	trip, err := s.tripRepo.FindByID(tripID)
+	if err != nil {
+		return nil, errors.New("trip not found")
+	}
+	if trip.CustomerID != userID && (trip.DriverID == nil || *trip.DriverID != userID) {
+		return nil, errors.New("unauthorized to view this trip")
+	}
+
+	tripPayment, err := s.paymentRepo.FindByTripID(tripID)
+	if err != nil {
+		return nil, ErrPaymentNotFound
+	}
+	return paymentToDTO(tripPayment), nil
+}
+
+// RefundTripPayment returns part or all of a trip's captured payment to the customer
+func (s *paymentService) RefundTripPayment(tripID, adminID uuid.UUID, req dto.RefundRequest) (*dto.PaymentResponse, error) {
+	if s.gateway == nil {
+		return nil, ErrCardPaymentsDisabled
+	}
+
+	tripPayment, err := s.paymentRepo.FindByTripID(tripID)
+	if err != nil {
+		return nil, ErrPaymentNotFound
+	}
+	if tripPayment.Status != models.PaymentStatusCaptured && tripPayment.Status != models.PaymentStatusPartiallyRefunded {
+		return nil, errors.New("only captured payments can be refunded")
+	}
+
+	amount := roundFare(req.Amount)
+	if amount > roundFare(tripPayment.CapturedAmount-tripPayment.RefundedAmount) {
+		return nil, ErrRefundExceedsCapture
+	}
+
+	// The refund ID is the idempotency key, so a retried request is not refunded twice
+	refund := &models.PaymentRefund{
+		ID:        uuid.New(),
+		PaymentID: tripPayment.ID,
+		Amount:    amount,
+		Reason:    req.Reason,
+		CreatedBy: adminID,
+	}
+	reference, err := s.gateway.Refund(*tripPayment.GatewayReference, amount, refund.ID.String())
+	if err != nil {
+		return nil, fmt.Errorf("refund failed: %w", err)
+	}
+
+	refund.Status = models.RefundStatusSucceeded
+	refund.GatewayReference = &reference
+	tripPayment.RefundedAmount = roundFare(tripPayment.RefundedAmount + amount)
+	tripPayment.Status = RefundedStatus(tripPayment.CapturedAmount, tripPayment.RefundedAmount)
+	if err := s.paymentRepo.CreateRefund(tripPayment, refund); err != nil {
+		log.Printf("Refund %s of payment %s was made but not recorded: %v", reference, tripPayment.ID, err)
+		return nil, errors.New("refund was made but could not be recorded")
+	}
+
+	tripPayment.Refunds = append(tripPayment.Refunds, *refund)
+	return paymentToDTO(tripPayment), nil
+}
+
+// HandleWebhook applies an asynchronous update from the gateway. Each event is
+// processed once; redeliveries and events about unknown charges are acknowledged
+// and ignored.
+func (s *paymentService) HandleWebhook(payload []byte, signature string) error {
+	if s.gateway == nil {
+		return ErrCardPaymentsDisabled
+	}
+
+	event, err := s.gateway.ParseWebhook(payload, signature)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidWebhook, err)
+	}
+
+	seen, err := s.paymentRepo.WebhookEventSeen(event.ID)
+	if err != nil {
+		return errors.New("failed to process webhook")
+	}
+	if seen {
+		return nil
+	}
+
+	if err := s.applyWebhook(event); err != nil {
+		return err
+	}
+
+	if err := s.paymentRepo.RecordWebhookEvent(&models.PaymentWebhookEvent{
+		EventID:    event.ID,
+		Type:       event.Type,
+		ReceivedAt: time.Now(),
+	}); err != nil {
+		log.Printf("Failed to record payment webhook %s: %v", event.ID, err)
+	}
+	return nil
+}
+
+// applyWebhook updates the payment or refund an event is about. Updates only move
+// records forward, so applying an event twice changes nothing.
+func (s *paymentService) applyWebhook(event *payment.WebhookEvent) error {
+	tripPayment, err := s.paymentRepo.FindByGatewayReference(event.ChargeReference)
+	if err != nil {
+		return nil
+	}
+
+	now := time.Now()
+	switch event.Type {
+	case payment.EventChargeCaptured:
+		if tripPayment.Status != models.PaymentStatusAuthorized {
+			return nil
+		}
+		tripPayment.Status = models.PaymentStatusCaptured
+		tripPayment.CapturedAmount = roundFare(event.Amount)
+		tripPayment.CapturedAt = &now
+	case payment.EventChargeFailed, payment.EventChargeExpired:
+		if tripPayment.Status != models.PaymentStatusAuthorized {
+			return nil
+		}
+		reason := event.FailureReason
+		if reason == "" {
+			reason = event.Type
+		}
+		tripPayment.Status = models.PaymentStatusFailed
+		tripPayment.FailureReason = &reason
+	case payment.EventRefundFailed:
+		refund, err := s.paymentRepo.FindRefundByGatewayReference(event.RefundReference)
+		if err != nil || refund.Status != models.RefundStatusSucceeded {
+			return nil
+		}
+		reason := event.FailureReason
+		refund.Status = models.RefundStatusFailed
+		refund.FailureReason = &reason
+		tripPayment.RefundedAmount = roundFare(math.Max(0, tripPayment.RefundedAmount-refund.Amount))
+		tripPayment.Status = RefundedStatus(tripPayment.CapturedAmount, tripPayment.RefundedAmount)
+		if err := s.paymentRepo.UpdateRefund(tripPayment, refund); err != nil {
+			return errors.New("failed to process webhook")
+		}
+		return nil
+	default:
+		return nil
+	}
+
+	if err := s.paymentRepo.Update(tripPayment); err != nil {
+		return errors.New("failed to process webhook")
+	}
+	return nil
+}
+
+// methodForTrip returns the card a new trip is charged to: the one the customer
+// chose or their default. Cash trips have none.
+func (s *paymentService) methodForTrip(customerID uuid.UUID, paymentType, methodID string) (*uuid.UUID, error) {
+	if paymentType != models.PaymentTypeCard {
+		return nil, nil
+	}
+	if s.gateway == nil {
+		return nil, ErrCardPaymentsDisabled
+	}
+
+	if methodID != "" {
+		id, err := uuid.Parse(methodID)
+		if err != nil {
+			return nil, ErrPaymentMethodNotFound
+		}
+		method, err := s.findMethod(customerID, id)
+		if err != nil {
+			return nil, err
+		}
+		return &method.ID, nil
+	}
+
+	method, err := s.methodRepo.FindDefault(customerID)
+	if err != nil {
+		return nil, ErrPaymentMethodRequired
+	}
+	return &method.ID, nil
+}
+
+// authorize holds a card trip's fare as a driver accepts it. The outcome is recorded
+// either way; an error means the card could not be held.
+func (s *paymentService) authorize(trip *models.Trip) error {
+	if s.gateway == nil {
+		return ErrCardPaymentsDisabled
+	}
+	if trip.PaymentMethodID == nil {
+		return ErrPaymentMethodRequired
+	}
+	// A trip dispatched again after its driver gave it up is still held
+	if existing, err := s.paymentRepo.FindByTripID(trip.ID); err == nil && existing.Status == models.PaymentStatusAuthorized {
+		return nil
+	}
+	method, err := s.methodRepo.FindByID(*trip.PaymentMethodID)
+	if err != nil {
+		return ErrPaymentMethodNotFound
+	}
+
+	var fare float64
+	if trip.FareAmount != nil {
+		fare = *trip.FareAmount
+	}
+	tripPayment := &models.Payment{
+		TripID:           trip.ID,
+		CustomerID:       trip.CustomerID,
+		PaymentMethodID:  method.ID,
+		AuthorizedAmount: HoldAmount(fare, s.cfg.HoldMarginPercent),
+		Currency:         s.cfg.Currency,
+	}
+	reference, err := s.gateway.Authorize(payment.Charge{
+		IdempotencyKey: "trip-" + trip.ID.String(),
+		CardToken:      method.GatewayToken,
+		Amount:         tripPayment.AuthorizedAmount,
+		Currency:       tripPayment.Currency,
+	})
+	if err != nil {
+		reason := err.Error()
+		tripPayment.Status = models.PaymentStatusFailed
+		tripPayment.FailureReason = &reason
+	} else {
+		now := time.Now()
+		tripPayment.Status = models.PaymentStatusAuthorized
+		tripPayment.GatewayReference = &reference
+		tripPayment.AuthorizedAt = &now
+	}
+
+	if createErr := s.paymentRepo.Create(tripPayment); createErr != nil {
+		log.Printf("Failed to record payment of trip %s: %v", trip.ID, createErr)
+	}
+	return err
+}
+
+// reauthorize replaces a trip's card hold with a larger one once its fare has grown
+// beyond the hold, e.g. after a reroute. The old hold is voided once the new one is
+// in place; trips without a hold are left alone.
+func (s *paymentService) reauthorize(trip *models.Trip) error {
+	tripPayment, err := s.heldPayment(trip)
+	if err != nil {
+		return nil
+	}
+	var fare float64
+	if trip.FareAmount != nil {
+		fare = *trip.FareAmount
+	}
+	if fare <= tripPayment.AuthorizedAmount {
+		return nil
+	}
+	method, err := s.methodRepo.FindByID(tripPayment.PaymentMethodID)
+	if err != nil {
+		return ErrPaymentMethodNotFound
+	}
+
+	amount := HoldAmount(fare, s.cfg.HoldMarginPercent)
+	reference, err := s.gateway.Authorize(payment.Charge{
+		IdempotencyKey: fmt.Sprintf("trip-%s-%.2f", trip.ID, amount),
+		CardToken:      method.GatewayToken,
+		Amount:         amount,
+		Currency:       tripPayment.Currency,
+	})
+	if err != nil {
+		return err
+	}
+
+	previous := *tripPayment.GatewayReference
+	now := time.Now()
+	tripPayment.GatewayReference = &reference
+	tripPayment.AuthorizedAmount = amount
+	tripPayment.AuthorizedAt = &now
+	if err := s.paymentRepo.Update(tripPayment); err != nil {
+		if voidErr := s.gateway.Void(reference); voidErr != nil {
+			log.Printf("Failed to void charge %s: %v", reference, voidErr)
+		}
+		return errors.New("failed to record payment")
+	}
+	if err := s.gateway.Void(previous); err != nil {
+		log.Printf("Failed to void replaced charge %s of trip %s: %v", previous, trip.ID, err)
+	}
+	return nil
+}
+
+// capture takes amount from a trip's hold and releases the rest: the final fare
+// of a completed trip, or the cancellation fee of one that ended otherwise
+func (s *paymentService) capture(trip *models.Trip, amount float64) {
+	tripPayment, err := s.heldPayment(trip)
+	if err != nil {
+		return
+	}
+
+	amount = CaptureAmount(amount, tripPayment.AuthorizedAmount)
+	if amount <= 0 {
+		s.release(tripPayment)
+		return
+	}
+
+	if err := s.gateway.Capture(*tripPayment.GatewayReference, amount); err != nil {
+		reason := err.Error()
+		tripPayment.Status = models.PaymentStatusFailed
+		tripPayment.FailureReason = &reason
+	} else {
+		now := time.Now()
+		tripPayment.Status = models.PaymentStatusCaptured
+		tripPayment.CapturedAmount = amount
+		tripPayment.CapturedAt = &now
+	}
+
+	if err := s.paymentRepo.Update(tripPayment); err != nil {
+		log.Printf("Failed to record capture of trip %s: %v", trip.ID, err)
+	}
+}
+
+func (s *paymentService) release(tripPayment *models.Payment) {
+	if err := s.gateway.Void(*tripPayment.GatewayReference); err != nil {
+		log.Printf("Failed to void payment %s: %v", tripPayment.ID, err)
+		return
+	}
+
+	now := time.Now()
+	tripPayment.Status = models.PaymentStatusVoided
+	tripPayment.VoidedAt = &now
+	if err := s.paymentRepo.Update(tripPayment); err != nil {
+		log.Printf("Failed to record void of payment %s: %v", tripPayment.ID, err)
+	}
+}
+
+// charge takes amount off a customer's chosen or default card straight away, e.g.
+// for a tip or a wallet top-up, and returns the gateway reference
+func (s *paymentService) charge(customerID uuid.UUID, methodID string, amount float64, idempotencyKey string) (string, error) {
+	id, err := s.methodForTrip(customerID, models.PaymentTypeCard, methodID)
+	if err != nil {
+		return "", err
+	}
+	method, err := s.findMethod(customerID, *id)
+	if err != nil {
+		return "", err
+	}
+
+	reference, err := s.gateway.Authorize(payment.Charge{
+		IdempotencyKey: idempotencyKey,
+		CardToken:      method.GatewayToken,
+		Amount:         amount,
+		Currency:       s.cfg.Currency,
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := s.gateway.Capture(reference, amount); err != nil {
+		if voidErr := s.gateway.Void(reference); voidErr != nil {
+			log.Printf("Failed to void charge %s: %v", reference, voidErr)
+		}
+		return "", err
+	}
+	return reference, nil
+}
+
+// heldPayment returns a trip's payment if its card is currently held
+func (s *paymentService) heldPayment(trip *models.Trip) (*models.Payment, error) {
+	if s.gateway == nil {
+		return nil, ErrCardPaymentsDisabled
+	}
+	tripPayment, err := s.paymentRepo.FindByTripID(trip.ID)
+	if err != nil {
+		return nil, ErrPaymentNotFound
+	}
+	if tripPayment.Status != models.PaymentStatusAuthorized || tripPayment.GatewayReference == nil {
+		return nil, errors.New("payment is not held")
+	}
+	return tripPayment, nil
+}
+
+func (s *paymentService) findMethod(customerID, methodID uuid.UUID) (*models.PaymentMethod, error) {
+	method, err := s.methodRepo.FindByID(methodID)
+	if err != nil || method.CustomerID != customerID {
+		return nil, ErrPaymentMethodNotFound
+	}
+	return method, nil
+}
+
+func paymentMethodToDTO(method *models.PaymentMethod) dto.PaymentMethodResponse {
+	return dto.PaymentMethodResponse{
+		ID:        method.ID.String(),
+		Brand:     method.Brand,
+		Last4:     method.Last4,
+		ExpMonth:  method.ExpMonth,
+		ExpYear:   method.ExpYear,
+		IsDefault: method.IsDefault,
+		CreatedAt: method.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+func paymentToDTO(tripPayment *models.Payment) *dto.PaymentResponse {
+	response := &dto.PaymentResponse{
+		ID:               tripPayment.ID.String(),
+		TripID:           tripPayment.TripID.String(),
+		PaymentMethodID:  tripPayment.PaymentMethodID.String(),
+		Status:           string(tripPayment.Status),
+		AuthorizedAmount: tripPayment.AuthorizedAmount,
+		CapturedAmount:   tripPayment.CapturedAmount,
+		RefundedAmount:   tripPayment.RefundedAmount,
+		Currency:         tripPayment.Currency,
+		FailureReason:    tripPayment.FailureReason,
+		AuthorizedAt:     formatOptionalTime(tripPayment.AuthorizedAt),
+		CapturedAt:       formatOptionalTime(tripPayment.CapturedAt),
+		VoidedAt:         formatOptionalTime(tripPayment.VoidedAt),
+		Refunds:          make([]dto.RefundResponse, len(tripPayment.Refunds)),
+	}
+	for i, refund := range tripPayment.Refunds {
+		response.Refunds[i] = dto.RefundResponse{
+			ID:            refund.ID.String(),
+			Amount:        refund.Amount,
+			Reason:        refund.Reason,
+			Status:        string(refund.Status),
+			FailureReason: refund.FailureReason,
+			CreatedAt:     refund.CreatedAt.Format(time.RFC3339),
+		}
+	}
+	return response
+}
+
+func formatOptionalTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	formatted := t.Format(time.RFC3339)
+	return &formatted
+}
@@ -0,0 +1,440 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/config"
+	"github.com/telemoz/backend/internal/dto"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/repositories"
+	"github.com/telemoz/backend/pkg/payout"
+	"github.com/telemoz/backend/pkg/pdf"
+	"gorm.io/gorm"
+)
+
+type PayoutService interface {
+	ListStatements(driverID uuid.UUID, limit, offset int) ([]dto.PayoutStatementResponse, error)
+	GetStatement(statementID, driverID uuid.UUID) (*dto.PayoutStatementDetailResponse, error)
+	CurrentStatement(driverID uuid.UUID) (*dto.PayoutStatementDetailResponse, error)
+	SettleDuePeriods(now time.Time) error
+	RetryPayout(statementID uuid.UUID) (*dto.PayoutStatementResponse, error)
+}
+
+type payoutService struct {
+	periodRepo    repositories.SettlementPeriodRepository
+	statementRepo repositories.PayoutStatementRepository
+	earningRepo   repositories.DriverEarningRepository
+	provider      payout.Provider
+	cfg           config.PayoutConfig
+}
+
+func NewPayoutService() PayoutService {
+	return NewPayoutServiceWithProvider(payout.NewProvider())
+}
+
+// NewPayoutServiceWithProvider creates the service with an explicit payout provider, e.g. a fake in tests
+func NewPayoutServiceWithProvider(provider payout.Provider) PayoutService {
+	return &payoutService{
+		periodRepo:    repositories.NewSettlementPeriodRepository(),
+		statementRepo: repositories.NewPayoutStatementRepository(),
+		earningRepo:   repositories.NewDriverEarningRepository(),
+		provider:      provider,
+		cfg:           config.AppConfig.Payout,
+	}
+}
+
+func (s *payoutService) ListStatements(driverID uuid.UUID, limit, offset int) ([]dto.PayoutStatementResponse, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	statements, err := s.statementRepo.FindByDriverID(driverID, limit, offset)
+	if err != nil {
+		return nil, errors.New("failed to fetch statements")
+	}
+
+	responses := make([]dto.PayoutStatementResponse, len(statements))
+	for i := range statements {
+		responses[i] = s.statementToDTO(&statements[i])
+	}
+	return responses, nil
+}
+
+func (s *payoutService) GetStatement(statementID, driverID uuid.UUID) (*dto.PayoutStatementDetailResponse, error) {
+	statement, err := s.statementRepo.FindByID(statementID)
+	if err != nil || statement.DriverID != driverID {
+		return nil, errors.New("statement not found")
+	}
+
+	entries, cashTrips, err := s.lines(driverID, statement.Period.StartDate, statement.Period.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	return statementDetail(s.statementToDTO(statement), entries, cashTrips), nil
+}
+
+// CurrentStatement previews the statement for the period still in progress
+func (s *payoutService) CurrentStatement(driverID uuid.UUID) (*dto.PayoutStatementDetailResponse, error) {
+	start, end := SettlementPeriodFor(time.Now(), s.weekStart())
+	entries, cashTrips, err := s.lines(driverID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	statement := s.newStatement(driverID, entries, cashTrips)
+
+	summary := dto.PayoutStatementResponse{
+		PeriodStart:        start.Format("2006-01-02"),
+		PeriodEnd:          end.Format("2006-01-02"),
+		ExpectedPayoutDate: s.payoutDate(end).Format("2006-01-02"),
+		Earnings:           statement.Earnings,
+		CashCollected:      statement.CashCollected,
+		NetPayout:          statement.NetPayout,
+		Currency:           statement.Currency,
+		Status:             string(models.PayoutStatusPending),
+	}
+	return statementDetail(summary, entries, cashTrips), nil
+}
+
+// SettleDuePeriods closes every past settlement period that isn't settled yet and
+// whose settlement delay has passed, oldest first, so weeks missed while the job
+// wasn't running are caught up. Each period gets a statement for every driver with
+// earnings in it, and those with a positive balance are paid. Drivers already holding
+// a statement for a period are skipped, so an interrupted run can simply be repeated;
+// a period that fails to settle holds back the ones after it.
+func (s *payoutService) SettleDuePeriods(now time.Time) error {
+	from, err := s.firstUnsettledPeriod()
+	if err != nil || from == nil {
+		return err
+	}
+	for _, start := range DuePeriods(*from, now, s.weekStart(), s.cfg.SettlementDelay) {
+		if err := s.settlePeriod(start, start.AddDate(0, 0, 6)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// firstUnsettledPeriod returns the first day of the oldest period that may need
+// settling: the oldest one left unsettled, else the one after the latest period, else
+// the week of the first ledger entry. It returns nil while the ledger is empty.
+func (s *payoutService) firstUnsettledPeriod() (*time.Time, error) {
+	period, err := s.periodRepo.FindOldestUnsettled()
+	if err == nil {
+		return &period.StartDate, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	period, err = s.periodRepo.FindLatest()
+	if err == nil {
+		next := period.StartDate.AddDate(0, 0, 7)
+		return &next, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	first, err := s.earningRepo.FindEarliestDate()
+	if err != nil || first == nil {
+		return nil, err
+	}
+	return first, nil
+}
+
+// settlePeriod writes and pays the statements of the period start..end inclusive
+func (s *payoutService) settlePeriod(start, end time.Time) error {
+	period, err := s.periodRepo.FindByStartDate(start)
+	if err != nil {
+		period = &models.SettlementPeriod{StartDate: start, EndDate: end}
+		if err := s.periodRepo.Create(period); err != nil {
+			return err
+		}
+	}
+	if period.SettledAt != nil {
+		return nil
+	}
+
+	driverIDs, err := s.earningRepo.FindDriverIDsBetween(start, end)
+	if err != nil {
+		return err
+	}
+	for _, driverID := range driverIDs {
+		if _, err := s.statementRepo.FindByPeriodAndDriver(period.ID, driverID); err == nil {
+			continue
+		}
+
+		entries, cashTrips, err := s.lines(driverID, start, end)
+		if err != nil {
+			return err
+		}
+		statement := s.newStatement(driverID, entries, cashTrips)
+		statement.PeriodID = period.ID
+		if err := s.statementRepo.Create(statement); err != nil {
+			return err
+		}
+		s.pay(statement)
+	}
+
+	settledAt := time.Now()
+	period.SettledAt = &settledAt
+	return s.periodRepo.Update(period)
+}
+
+// RetryPayout pays a statement whose payout failed
+func (s *payoutService) RetryPayout(statementID uuid.UUID) (*dto.PayoutStatementResponse, error) {
+	statement, err := s.statementRepo.FindByID(statementID)
+	if err != nil {
+		return nil, errors.New("statement not found")
+	}
+	if statement.Status != models.PayoutStatusFailed {
+		return nil, errors.New("only failed payouts can be retried")
+	}
+
+	s.pay(statement)
+	response := s.statementToDTO(statement)
+	return &response, nil
+}
+
+// pay sends a statement's balance to the driver and records the outcome. Statements
+// with nothing to pay stay pending: the driver collected at least as much cash as
+// they earned and any balance they owe is settled outside the app.
+func (s *payoutService) pay(statement *models.PayoutStatement) {
+	if statement.NetPayout <= 0 {
+		return
+	}
+
+	reference, err := s.provider.Pay(payout.Transfer{
+		StatementID: statement.ID.String(),
+		DriverID:    statement.DriverID.String(),
+		Amount:      statement.NetPayout,
+		Currency:    statement.Currency,
+	})
+	if err != nil {
+		reason := err.Error()
+		statement.Status = models.PayoutStatusFailed
+		statement.FailureReason = &reason
+	} else {
+		now := time.Now()
+		statement.Status = models.PayoutStatusPaid
+		statement.ProviderReference = &reference
+		statement.FailureReason = nil
+		statement.PaidAt = &now
+	}
+
+	if err := s.statementRepo.Update(statement); err != nil {
+		log.Printf("Failed to record payout of statement %s: %v", statement.ID, err)
+	}
+}
+
+// lines returns a driver's ledger entries and cash trips for start..end inclusive
+func (s *payoutService) lines(driverID uuid.UUID, start, end time.Time) ([]models.DriverEarning, []models.Trip, error) {
+	entries, err := s.earningRepo.FindByDriverBetween(driverID, start, end)
+	if err != nil {
+		return nil, nil, errors.New("failed to fetch earnings")
+	}
+	cashTrips, err := s.earningRepo.FindCashTripsBetween(driverID, start, end)
+	if err != nil {
+		return nil, nil, errors.New("failed to fetch cash trips")
+	}
+	return entries, cashTrips, nil
+}
+
+func (s *payoutService) newStatement(driverID uuid.UUID, entries []models.DriverEarning, cashTrips []models.Trip) *models.PayoutStatement {
+	earnings, cash := SumStatement(entries, cashTrips)
+	return &models.PayoutStatement{
+		DriverID:      driverID,
+		Earnings:      earnings,
+		CashCollected: cash,
+		NetPayout:     math.Round((earnings-cash)*100) / 100,
+		Currency:      s.cfg.Currency,
+		Status:        models.PayoutStatusPending,
+	}
+}
+
+func statementDetail(summary dto.PayoutStatementResponse, entries []models.DriverEarning, cashTrips []models.Trip) *dto.PayoutStatementDetailResponse {
+	detail := &dto.PayoutStatementDetailResponse{
+		PayoutStatementResponse: summary,
+		Entries:                 make([]dto.StatementEntry, len(entries)),
+		CashTrips:               make([]dto.StatementCashTrip, len(cashTrips)),
+	}
+	for i, entry := range entries {
+		detail.Entries[i] = dto.StatementEntry{
+			Date:        entry.Date.Format("2006-01-02"),
+			Type:        string(entry.Type),
+			JobID:       entry.JobID.String(),
+			GrossAmount: entry.GrossAmount,
+			Commission:  entry.Commission,
+			Amount:      entry.Amount,
+			Reason:      entry.Reason,
+		}
+	}
+	for i, trip := range cashTrips {
+		detail.CashTrips[i] = dto.StatementCashTrip{
+			TripID: trip.ID.String(),
+			Date:   trip.CreatedAt.Format("2006-01-02"),
+		}
+		if trip.FareAmount != nil {
+			detail.CashTrips[i].Fare = *trip.FareAmount
+		}
+	}
+	return detail
+}
+
+func (s *payoutService) statementToDTO(statement *models.PayoutStatement) dto.PayoutStatementResponse {
+	response := dto.PayoutStatementResponse{
+		ID:                 statement.ID.String(),
+		PeriodStart:        statement.Period.StartDate.Format("2006-01-02"),
+		PeriodEnd:          statement.Period.EndDate.Format("2006-01-02"),
+		ExpectedPayoutDate: s.payoutDate(statement.Period.EndDate).Format("2006-01-02"),
+		Earnings:           statement.Earnings,
+		CashCollected:      statement.CashCollected,
+		NetPayout:          statement.NetPayout,
+		Currency:           statement.Currency,
+		Status:             string(statement.Status),
+		ProviderReference:  statement.ProviderReference,
+		FailureReason:      statement.FailureReason,
+	}
+	if statement.PaidAt != nil {
+		paidAt := statement.PaidAt.Format(time.RFC3339)
+		response.PaidAt = &paidAt
+	}
+	return response
+}
+
+// payoutDate is when a period ending on end is settled
+func (s *payoutService) payoutDate(end time.Time) time.Time {
+	return end.AddDate(0, 0, 1).Add(s.cfg.SettlementDelay)
+}
+
+func (s *payoutService) weekStart() time.Weekday {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(day.String(), s.cfg.WeekStart) {
+			return day
+		}
+	}
+	return time.Monday
+}
+
+// DuePeriods returns the first days of the settlement periods from the one containing
+// from up to the last one whose settlement delay has passed by now, oldest first
+func DuePeriods(from, now time.Time, weekStart time.Weekday, delay time.Duration) []time.Time {
+	// Period dates are stored without a time zone; they are days in now's
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, now.Location())
+	start, _ := SettlementPeriodFor(day, weekStart)
+
+	var starts []time.Time
+	for !now.Before(start.AddDate(0, 0, 7).Add(delay)) {
+		starts = append(starts, start)
+		start = start.AddDate(0, 0, 7)
+	}
+	return starts
+}
+
+// SettlementPeriodFor returns the first and last day of the week containing t, for
+// weeks starting on weekStart
+func SettlementPeriodFor(t time.Time, weekStart time.Weekday) (time.Time, time.Time) {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	offset := (int(day.Weekday()) - int(weekStart) + 7) % 7
+	start := day.AddDate(0, 0, -offset)
+	return start, start.AddDate(0, 0, 6)
+}
+
+// SumStatement totals ledger entries and the fares of cash trips, rounded to cents
+func SumStatement(entries []models.DriverEarning, cashTrips []models.Trip) (float64, float64) {
+	var earnings, cash float64
+	for _, entry := range entries {
+		earnings += entry.Amount
+	}
+	for _, trip := range cashTrips {
+		if trip.FareAmount != nil {
+			cash += *trip.FareAmount
+		}
+	}
+	return math.Round(earnings*100) / 100, math.Round(cash*100) / 100
+}
+
+// WriteStatementCSV writes a statement as CSV: a summary block followed by its lines
+func WriteStatementCSV(w io.Writer, statement *dto.PayoutStatementDetailResponse) error {
+	out := csv.NewWriter(w)
+	money := func(amount float64) string { return fmt.Sprintf("%.2f", amount) }
+
+	rows := [][]string{
+		{"Period", statement.PeriodStart + " to " + statement.PeriodEnd},
+		{"Status", statement.Status},
+		{"Expected payout date", statement.ExpectedPayoutDate},
+		{"Earnings", money(statement.Earnings)},
+		{"Cash collected", money(statement.CashCollected)},
+		{"Net payout", money(statement.NetPayout)},
+		{"Currency", statement.Currency},
+		{},
+		{"date", "type", "job_id", "gross_amount", "commission", "amount", "reason"},
+	}
+	for _, entry := range statement.Entries {
+		reason := ""
+		if entry.Reason != nil {
+			reason = *entry.Reason
+		}
+		rows = append(rows, []string{
+			entry.Date, entry.Type, entry.JobID,
+			money(entry.GrossAmount), money(entry.Commission), money(entry.Amount), reason,
+		})
+	}
+	if len(statement.CashTrips) > 0 {
+		rows = append(rows, []string{}, []string{"date", "cash_trip_id", "fare"})
+		for _, trip := range statement.CashTrips {
+			rows = append(rows, []string{trip.Date, trip.TripID, money(trip.Fare)})
+		}
+	}
+
+	if err := out.WriteAll(rows); err != nil {
+		return err
+	}
+	return out.Error()
+}
+
+// RenderStatementPDF renders a statement as a printable PDF
+func RenderStatementPDF(statement *dto.PayoutStatementDetailResponse) []byte {
+	doc := pdf.New()
+	money := func(amount float64) string { return fmt.Sprintf("%.2f %s", amount, statement.Currency) }
+
+	doc.Heading("Driver Payout Statement")
+	doc.Text(fmt.Sprintf("Period: %s to %s", statement.PeriodStart, statement.PeriodEnd))
+	doc.Text("Status: " + statement.Status)
+	doc.Text("Expected payout date: " + statement.ExpectedPayoutDate)
+	doc.Blank()
+	doc.Text("Earnings: " + money(statement.Earnings))
+	doc.Text("Cash collected: " + money(statement.CashCollected))
+	doc.Bold("Net payout: " + money(statement.NetPayout))
+
+	doc.Blank()
+	doc.Bold("Earnings")
+	for _, entry := range statement.Entries {
+		line := fmt.Sprintf("%s  %-10s  gross %.2f  commission %.2f  net %.2f", entry.Date, entry.Type, entry.GrossAmount, entry.Commission, entry.Amount)
+		if entry.Reason != nil {
+			line += "  (" + *entry.Reason + ")"
+		}
+		doc.Text(line)
+	}
+
+	if len(statement.CashTrips) > 0 {
+		doc.Blank()
+		doc.Bold("Cash collected")
+		for _, trip := range statement.CashTrips {
+			doc.Text(fmt.Sprintf("%s  trip %s  %.2f", trip.Date, trip.TripID, trip.Fare))
+		}
+	}
+
+	return doc.Bytes()
+}
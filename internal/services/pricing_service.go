@@ -1,55 +1,259 @@
 package services
 
 import (
+	"errors"
 	"math"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/telemoz/backend/internal/config"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/repositories"
+	"github.com/telemoz/backend/pkg/geohash"
 )
 
+// FareEstimate is the price of a trip as quoted to the customer
+type FareEstimate struct {
+	Distance        float64 // kilometers
+	Duration        float64 // minutes
+	Fare            float64
+	SurgeMultiplier float64
+	// DistanceMethod tells whether Distance and Duration are a road route or estimated
+	DistanceMethod string
+	Breakdown      FareBreakdown
+	// FareTableID is the pricing version the fare was computed with, nil when the
+	// built-in defaults were used
+	FareTableID *uuid.UUID
+}
+
+// FareBreakdown itemizes a fare; Total is the sum of the other items
+type FareBreakdown struct {
+	BaseFare         float64
+	DistanceFare     float64
+	TimeFare         float64
+	SurgeAmount      float64
+	NightSurcharge   float64
+	MinimumFareTopUp float64
+	BookingFee       float64
+	AirportSurcharge float64
+	// WeightSurcharge is added to deliveries of heavy packages, outside the fare table
+	WeightSurcharge float64
+	Total           float64
+}
+
+// RoutePoint is a stop on a route to be priced
+type RoutePoint struct {
+	Latitude  float64
+	Longitude float64
+}
+
 type PricingService interface {
-	CalculateFare(pickupLat, pickupLng, dropoffLat, dropoffLng float64, serviceType string) (distance float64, fare float64)
-	EstimateFare(pickupLat, pickupLng, dropoffLat, dropoffLng float64, serviceType string) (distance float64, fare float64, duration float64)
+	CalculateFare(serviceType string, pickupLat, pickupLng, dropoffLat, dropoffLng, distanceKm, durationMinutes float64, at time.Time) (FareEstimate, error)
+	EstimateFare(pickupLat, pickupLng, dropoffLat, dropoffLng float64, serviceType string) (FareEstimate, error)
+	EstimateRouteFare(points []RoutePoint, serviceType string) (FareEstimate, error)
 }
 
-type pricingService struct{}
+type pricingService struct {
+	fareTableRepo repositories.FareTableRepository
+	surgeService  SurgeService
+	routes        *RouteEstimator
+}
 
 func NewPricingService() PricingService {
-	return &pricingService{}
+	return &pricingService{
+		fareTableRepo: repositories.NewFareTableRepository(),
+		surgeService:  NewSurgeService(),
+		routes:        defaultRouteEstimator(),
+	}
+}
+
+// CalculateFare prices a trip of the given distance and duration under the fare table
+// in effect at the pickup point at the given time, with the surge multiplier in
+// effect there now
+func (s *pricingService) CalculateFare(
+	serviceType string,
+	pickupLat, pickupLng, dropoffLat, dropoffLng float64,
+	distanceKm, durationMinutes float64,
+	at time.Time,
+) (FareEstimate, error) {
+	tables, err := s.fareTableRepo.FindEffective(serviceType, at)
+	if err != nil {
+		return FareEstimate{}, err
+	}
+	table := SelectFareTable(tables, pickupLat, pickupLng)
+	estimate := FareEstimate{}
+	if table != nil {
+		estimate.FareTableID = &table.ID
+	} else {
+		table = defaultFareTable(serviceType)
+	}
+
+	surge := s.surgeService.Multiplier(pickupLat, pickupLng, serviceType)
+	airport := InFareZones(table.AirportZones, pickupLat, pickupLng) || InFareZones(table.AirportZones, dropoffLat, dropoffLng)
+
+	estimate.Distance = math.Round(distanceKm*100) / 100
+	estimate.Duration = math.Round(durationMinutes*100) / 100
+	estimate.SurgeMultiplier = surge
+	estimate.Breakdown = ComputeFare(table, distanceKm, durationMinutes, surge, at, airport)
+	estimate.Fare = estimate.Breakdown.Total
+	return estimate, nil
+}
+
+// EstimateFare prices a trip as of now on its road route, or on an estimate from the
+// straight-line distance when no route is available
+func (s *pricingService) EstimateFare(pickupLat, pickupLng, dropoffLat, dropoffLng float64, serviceType string) (FareEstimate, error) {
+	return s.EstimateRouteFare([]RoutePoint{
+		{Latitude: pickupLat, Longitude: pickupLng},
+		{Latitude: dropoffLat, Longitude: dropoffLng},
+	}, serviceType)
+}
+
+// EstimateRouteFare prices a trip through points in order, from the pickup to the final
+// drop-off, as of now. Each leg is routed separately, all within one routing timeout,
+// and the fare is computed once over the whole distance and duration.
+func (s *pricingService) EstimateRouteFare(points []RoutePoint, serviceType string) (FareEstimate, error) {
+	if len(points) < 2 {
+		return FareEstimate{}, errors.New("a route needs at least two points")
+	}
+	route := CombineRouteLegs(s.routes.EstimateLegs(points))
+
+	pickup, dropoff := points[0], points[len(points)-1]
+	estimate, err := s.CalculateFare(serviceType, pickup.Latitude, pickup.Longitude, dropoff.Latitude, dropoff.Longitude,
+		route.DistanceKm, route.DurationMinutes, time.Now())
+	if err != nil {
+		return FareEstimate{}, err
+	}
+	estimate.DistanceMethod = route.Method
+	return estimate, nil
 }
 
-// CalculateFare calculates fare based on distance using Haversine formula
-func (s *pricingService) CalculateFare(pickupLat, pickupLng, dropoffLat, dropoffLng float64, serviceType string) (float64, float64) {
-	// Calculate distance in kilometers
-	distance := calculateHaversineDistance(pickupLat, pickupLng, dropoffLat, dropoffLng)
+// CombineRouteLegs adds up the legs of a multi-stop route. The route only counts as a
+// road route when every leg is one.
+func CombineRouteLegs(legs []RouteEstimate) RouteEstimate {
+	route := RouteEstimate{Method: DistanceMethodRoad}
+	for _, leg := range legs {
+		route.DistanceKm += leg.DistanceKm
+		route.DurationMinutes += leg.DurationMinutes
+		if leg.Method != DistanceMethodRoad {
+			route.Method = DistanceMethodStraightLine
+		}
+	}
+	return route
+}
 
-	// Get pricing config for service type
+// defaultFareTable is the built-in pricing of a service type, used where no fare table
+// is in effect
+func defaultFareTable(serviceType string) *models.FareTable {
 	pricing := config.GetPricingForService(serviceType)
+	return &models.FareTable{
+		ServiceType: serviceType,
+		Timezone:    "UTC",
+		BaseFare:    pricing.BaseFare,
+		PerKmRate:   pricing.PerKmRate,
+		MinimumFare: pricing.MinimumFare,
+	}
+}
 
-	// Calculate fare: base fare + (distance * per km rate) * surge multiplier
-	fare := (pricing.BaseFare + (distance * pricing.PerKmRate)) * pricing.SurgeMultiplier
+// SelectFareTable picks the table for a pickup point from the tables in effect: the
+// one with the longest zone containing the point, and of those the latest version
+func SelectFareTable(tables []models.FareTable, pickupLat, pickupLng float64) *models.FareTable {
+	var matching []models.FareTable
+	for _, table := range tables {
+		if table.Zone == "" || InFareZones([]string{table.Zone}, pickupLat, pickupLng) {
+			matching = append(matching, table)
+		}
+	}
+	if len(matching) == 0 {
+		return nil
+	}
 
-	// Apply minimum fare
-	if fare < pricing.MinimumFare {
-		fare = pricing.MinimumFare
+	sort.SliceStable(matching, func(i, j int) bool {
+		if len(matching[i].Zone) != len(matching[j].Zone) {
+			return len(matching[i].Zone) > len(matching[j].Zone)
+		}
+		if !matching[i].EffectiveFrom.Equal(matching[j].EffectiveFrom) {
+			return matching[i].EffectiveFrom.After(matching[j].EffectiveFrom)
+		}
+		return matching[i].Version > matching[j].Version
+	})
+	return &matching[0]
+}
+
+// InFareZones reports whether a point lies in any of the zones, given as geohash prefixes
+func InFareZones(zones []string, lat, lng float64) bool {
+	for _, zone := range zones {
+		zone = strings.ToLower(strings.TrimSpace(zone))
+		if zone != "" && geohash.Encode(lat, lng, len(zone)) == zone {
+			return true
+		}
 	}
+	return false
+}
 
-	// Round to 2 decimal places
-	distance = math.Round(distance*100) / 100
-	fare = math.Round(fare*100) / 100
+// ComputeFare prices a trip under a fare table. The metered fare (base, distance and
+// time) is multiplied by surge and raised by the night surcharge when the trip starts
+// at night, then topped up to the minimum fare; booking fee and airport surcharge are
+// added on top.
+func ComputeFare(table *models.FareTable, distanceKm, durationMinutes, surge float64, at time.Time, airport bool) FareBreakdown {
+	breakdown := FareBreakdown{
+		BaseFare:     roundFare(table.BaseFare),
+		DistanceFare: roundFare(distanceKm * table.PerKmRate),
+		TimeFare:     roundFare(durationMinutes * table.PerMinuteRate),
+		BookingFee:   roundFare(table.BookingFee),
+	}
+	metered := breakdown.BaseFare + breakdown.DistanceFare + breakdown.TimeFare
+	if surge > 1 {
+		breakdown.SurgeAmount = roundFare(metered * (surge - 1))
+	}
+	if isNight(table, at) {
+		breakdown.NightSurcharge = roundFare((metered + breakdown.SurgeAmount) * table.NightSurchargePercent / 100)
+	}
+	subtotal := metered + breakdown.SurgeAmount + breakdown.NightSurcharge
+	if subtotal < table.MinimumFare {
+		breakdown.MinimumFareTopUp = roundFare(table.MinimumFare - subtotal)
+	}
+	if airport {
+		breakdown.AirportSurcharge = roundFare(table.AirportSurcharge)
+	}
 
-	return distance, fare
+	breakdown.Total = roundFare(subtotal + breakdown.MinimumFareTopUp + breakdown.BookingFee + breakdown.AirportSurcharge)
+	return breakdown
 }
 
-// EstimateFare estimates fare and adds estimated duration
-func (s *pricingService) EstimateFare(pickupLat, pickupLng, dropoffLat, dropoffLng float64, serviceType string) (float64, float64, float64) {
-	distance, fare := s.CalculateFare(pickupLat, pickupLng, dropoffLat, dropoffLng, serviceType)
+// isNight reports whether at falls in the table's night hours, in the table's timezone
+func isNight(table *models.FareTable, at time.Time) bool {
+	if table.NightSurchargePercent <= 0 || table.NightStart == nil || table.NightEnd == nil {
+		return false
+	}
+	start, err := time.Parse("15:04", *table.NightStart)
+	if err != nil {
+		return false
+	}
+	end, err := time.Parse("15:04", *table.NightEnd)
+	if err != nil {
+		return false
+	}
+
+	loc, err := time.LoadLocation(table.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	local := at.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	startMinute := start.Hour()*60 + start.Minute()
+	endMinute := end.Hour()*60 + end.Minute()
 
-	// Estimate duration: assume average speed of 40 km/h in city
-	// Duration in minutes
-	duration := (distance / 40.0) * 60.0
-	duration = math.Round(duration*100) / 100
+	if startMinute <= endMinute {
+		return minute >= startMinute && minute < endMinute
+	}
+	// The night wraps past midnight, e.g. 22:00 to 06:00
+	return minute >= startMinute || minute < endMinute
+}
 
-	return distance, fare, duration
+func roundFare(amount float64) float64 {
+	return math.Round(amount*100) / 100
 }
 
 // calculateHaversineDistance calculates the distance between two points on Earth
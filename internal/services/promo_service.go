@@ -0,0 +1,237 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/dto"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/repositories"
+)
+
+var (
+	// ErrPromoNotFound means no promo code has the entered code
+	ErrPromoNotFound = errors.New("promo code not found")
+	// ErrPromoInactive means the code is switched off, not yet valid or no longer valid
+	ErrPromoInactive = errors.New("promo code is not valid at this time")
+	// ErrPromoNotApplicable means the code doesn't apply to the trip's service type
+	ErrPromoNotApplicable = errors.New("promo code does not apply to this service")
+	// ErrPromoLimitReached means the customer or everyone has used the code as often as allowed
+	ErrPromoLimitReached = errors.New("promo code has already been used the maximum number of times")
+)
+
+type PromoService interface {
+	CreatePromoCode(adminID uuid.UUID, req *dto.PromoCodeRequest) (*models.PromoCode, error)
+	ListPromoCodes() ([]models.PromoCode, error)
+	DeactivatePromoCode(id uuid.UUID) (*models.PromoCode, error)
+}
+
+type promoService struct {
+	promoRepo repositories.PromoCodeRepository
+}
+
+func NewPromoService() PromoService {
+	return newPromoService()
+}
+
+func newPromoService() *promoService {
+	return &promoService{
+		promoRepo: repositories.NewPromoCodeRepository(),
+	}
+}
+
+// NormalizePromoCode is the form codes are stored and looked up in
+func NormalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// CheckPromo reports whether a customer who has redeemed the code customerUses
+// times, out of totalUses redemptions overall, may apply it to a trip of
+// serviceType at now
+func CheckPromo(promo *models.PromoCode, serviceType string, customerUses, totalUses int64, now time.Time) error {
+	if !promo.Active || now.Before(promo.StartsAt) || (promo.EndsAt != nil && !now.Before(*promo.EndsAt)) {
+		return ErrPromoInactive
+	}
+	if len(promo.ServiceTypes) > 0 {
+		applies := false
+		for _, allowed := range promo.ServiceTypes {
+			if allowed == serviceType {
+				applies = true
+				break
+			}
+		}
+		if !applies {
+			return ErrPromoNotApplicable
+		}
+	}
+	if customerUses >= int64(promo.PerUserLimit) ||
+		(promo.MaxRedemptions != nil && totalUses >= int64(*promo.MaxRedemptions)) {
+		return ErrPromoLimitReached
+	}
+	return nil
+}
+
+// PromoDiscount is what a promo code takes off a fare: a percentage of it capped at
+// the code's maximum discount, or a fixed amount. It never exceeds the fare.
+func PromoDiscount(promo *models.PromoCode, fare float64) float64 {
+	if fare <= 0 {
+		return 0
+	}
+
+	var discount float64
+	switch promo.DiscountType {
+	case models.PromoDiscountPercentage:
+		discount = fare * promo.DiscountValue / 100
+	case models.PromoDiscountFixed:
+		discount = promo.DiscountValue
+	}
+	if promo.MaxDiscount != nil {
+		discount = math.Min(discount, *promo.MaxDiscount)
+	}
+	return roundFare(math.Min(discount, fare))
+}
+
+// CreatePromoCode adds a promo code, active from its start time
+func (s *promoService) CreatePromoCode(adminID uuid.UUID, req *dto.PromoCodeRequest) (*models.PromoCode, error) {
+	promo := &models.PromoCode{
+		Code:           NormalizePromoCode(req.Code),
+		Description:    strings.TrimSpace(req.Description),
+		DiscountType:   models.PromoDiscountType(req.DiscountType),
+		DiscountValue:  req.DiscountValue,
+		MaxDiscount:    req.MaxDiscount,
+		ServiceTypes:   models.StringArray(req.ServiceTypes),
+		StartsAt:       time.Now(),
+		MaxRedemptions: req.MaxRedemptions,
+		PerUserLimit:   1,
+		Active:         true,
+		CreatedBy:      &adminID,
+	}
+	if promo.ServiceTypes == nil {
+		promo.ServiceTypes = models.StringArray{}
+	}
+	if promo.DiscountType == models.PromoDiscountPercentage && promo.DiscountValue > 100 {
+		return nil, errors.New("percentage discount cannot exceed 100")
+	}
+	if req.PerUserLimit != nil {
+		promo.PerUserLimit = *req.PerUserLimit
+	}
+
+	if req.StartsAt != nil {
+		startsAt, err := time.Parse(time.RFC3339, *req.StartsAt)
+		if err != nil {
+			return nil, errors.New("invalid starts_at")
+		}
+		promo.StartsAt = startsAt
+	}
+	if req.EndsAt != nil {
+		endsAt, err := time.Parse(time.RFC3339, *req.EndsAt)
+		if err != nil {
+			return nil, errors.New("invalid ends_at")
+		}
+		if !endsAt.After(promo.StartsAt) {
+			return nil, errors.New("ends_at must be after starts_at")
+		}
+		promo.EndsAt = &endsAt
+	}
+
+	if _, err := s.promoRepo.FindByCode(promo.Code); err == nil {
+		return nil, errors.New("promo code already exists")
+	}
+	if err := s.promoRepo.Create(promo); err != nil {
+		return nil, errors.New("failed to create promo code")
+	}
+	return promo, nil
+}
+
+func (s *promoService) ListPromoCodes() ([]models.PromoCode, error) {
+	promos, err := s.promoRepo.FindAll()
+	if err != nil {
+		return nil, errors.New("failed to fetch promo codes")
+	}
+	return promos, nil
+}
+
+// DeactivatePromoCode stops a code from being applied to new trips; trips that
+// already use it keep their discount
+func (s *promoService) DeactivatePromoCode(id uuid.UUID) (*models.PromoCode, error) {
+	promo, err := s.promoRepo.FindByID(id)
+	if err != nil {
+		return nil, ErrPromoNotFound
+	}
+	promo.Active = false
+	if err := s.promoRepo.Update(promo); err != nil {
+		return nil, errors.New("failed to deactivate promo code")
+	}
+	return promo, nil
+}
+
+// apply validates a code a customer entered for a trip and returns it with the
+// discount it takes off fare
+func (s *promoService) apply(code string, customerID uuid.UUID, serviceType string, fare float64) (*models.PromoCode, float64, error) {
+	promo, err := s.promoRepo.FindByCode(NormalizePromoCode(code))
+	if err != nil {
+		return nil, 0, ErrPromoNotFound
+	}
+	customerUses, totalUses, err := s.promoRepo.CountRedemptions(promo.ID, customerID)
+	if err != nil {
+		return nil, 0, errors.New("failed to check promo code")
+	}
+	if err := CheckPromo(promo, serviceType, customerUses, totalUses, time.Now()); err != nil {
+		return nil, 0, err
+	}
+	return promo, PromoDiscount(promo, fare), nil
+}
+
+// createTrip creates a trip that uses a promo code together with its redemption
+func (s *promoService) createTrip(trip *models.Trip, promo *models.PromoCode) error {
+	redemption := &models.PromoRedemption{
+		PromoCodeID: promo.ID,
+		CustomerID:  trip.CustomerID,
+		Discount:    trip.DiscountAmount,
+		Status:      models.PromoRedemptionRedeemed,
+	}
+	err := s.promoRepo.CreateTripWithRedemption(trip, redemption, promo)
+	if errors.Is(err, repositories.ErrPromoLimitReached) {
+		return ErrPromoLimitReached
+	}
+	return err
+}
+
+// discount is what a trip's promo code takes off fare, e.g. after the trip was
+// rerouted or metered
+func (s *promoService) discount(trip *models.Trip, fare float64) float64 {
+	if trip.PromoCodeID == nil {
+		return 0
+	}
+	promo, err := s.promoRepo.FindByID(*trip.PromoCodeID)
+	if err != nil {
+		return math.Min(trip.DiscountAmount, math.Max(fare, 0))
+	}
+	return PromoDiscount(promo, fare)
+}
+
+// release frees the redemption of a trip that ended without completing, so the
+// customer can use the code again
+func (s *promoService) release(trip *models.Trip) {
+	if trip.PromoCodeID == nil {
+		return
+	}
+	if err := s.promoRepo.ReleaseRedemption(trip.ID); err != nil {
+		log.Printf("Failed to release promo code redemption of trip %s: %v", trip.ID, err)
+	}
+}
+
+// discountLine returns the discount shown on a trip, if it has one
+func discountLine(trip *models.Trip) *dto.DiscountLine {
+	if trip.PromoCode == nil {
+		return nil
+	}
+	return &dto.DiscountLine{
+		PromoCode: *trip.PromoCode,
+		Amount:    trip.DiscountAmount,
+	}
+}
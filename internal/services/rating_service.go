@@ -0,0 +1,263 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/config"
+	"github.com/telemoz/backend/internal/dto"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/repositories"
+)
+
+var (
+	// ErrRatingTripNotFound means the trip doesn't exist or the rater wasn't part of it
+	ErrRatingTripNotFound = errors.New("trip not found")
+	// ErrTripNotRateable means the trip wasn't completed
+	ErrTripNotRateable = errors.New("only completed trips can be rated")
+	// ErrRatingWindowClosed means the trip was completed too long ago to be rated
+	ErrRatingWindowClosed = errors.New("the rating window for this trip has closed")
+	// ErrAlreadyRated means the rater has already rated the trip
+	ErrAlreadyRated = errors.New("you have already rated this trip")
+	// ErrInvalidRatingTag means a tag isn't one offered to the rater
+	ErrInvalidRatingTag = errors.New("invalid rating tag")
+)
+
+// ratingTags are the tags each side can pick from, keyed by the type of the rater
+var ratingTags = map[models.UserType][]string{
+	models.UserTypeCustomer: {
+		"polite", "safe_driving", "clean_vehicle", "good_navigation", "on_time",
+		"rude", "unsafe_driving", "dirty_vehicle", "wrong_route", "late",
+	},
+	models.UserTypeDriver: {
+		"polite", "on_time", "clear_directions", "respectful",
+		"rude", "late", "wrong_pickup", "messy", "unsafe_behaviour",
+	},
+}
+
+type RatingService interface {
+	RateTrip(tripID, customerID uuid.UUID, req dto.RateTripRequest) (*dto.RatingResponse, error)
+	RateJob(jobID, driverID uuid.UUID, req dto.RateTripRequest) (*dto.RatingResponse, error)
+	GetTags(raterType models.UserType) []string
+	ListFlaggedDrivers(limit, offset int) ([]dto.FlaggedDriverResponse, error)
+}
+
+type ratingService struct {
+	ratingRepo repositories.TripRatingRepository
+	tripRepo   repositories.TripRepository
+	jobRepo    repositories.JobRepository
+	userRepo   repositories.UserRepository
+	cfg        config.RatingConfig
+}
+
+func NewRatingService() RatingService {
+	return newRatingService()
+}
+
+func newRatingService() *ratingService {
+	return &ratingService{
+		ratingRepo: repositories.NewTripRatingRepository(),
+		tripRepo:   repositories.NewTripRepository(),
+		jobRepo:    repositories.NewJobRepository(),
+		userRepo:   repositories.NewUserRepository(),
+		cfg:        config.AppConfig.Rating,
+	}
+}
+
+// RatingWindowOpen reports whether a trip completed at completedAt can still be rated
+func RatingWindowOpen(completedAt time.Time, window time.Duration, now time.Time) bool {
+	return now.Before(completedAt.Add(window))
+}
+
+// ValidateRatingTags checks that every tag is one offered to the rater and none repeats
+func ValidateRatingTags(raterType models.UserType, tags []string) error {
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		if seen[tag] || !containsTag(ratingTags[raterType], tag) {
+			return ErrInvalidRatingTag
+		}
+		seen[tag] = true
+	}
+	return nil
+}
+
+// DriverFlagged reports whether a driver's rolling average puts them up for review.
+// Drivers with fewer than FlagMinRatings ratings are never flagged.
+func DriverFlagged(average float64, count int, cfg config.RatingConfig) bool {
+	return count >= cfg.FlagMinRatings && average < cfg.FlagThreshold
+}
+
+// RateTrip records a customer's rating of the driver of their completed trip
+func (s *ratingService) RateTrip(tripID, customerID uuid.UUID, req dto.RateTripRequest) (*dto.RatingResponse, error) {
+	trip, err := s.tripRepo.FindByID(tripID)
+	if err != nil || trip.CustomerID != customerID {
+		return nil, ErrRatingTripNotFound
+	}
+	if trip.Status != models.TripStatusCompleted || trip.DriverID == nil {
+		return nil, ErrTripNotRateable
+	}
+
+	return s.rate(trip, customerID, models.UserTypeCustomer, *trip.DriverID, req, time.Now())
+}
+
+// RateJob records a driver's rating of the customer of a job they completed
+func (s *ratingService) RateJob(jobID, driverID uuid.UUID, req dto.RateTripRequest) (*dto.RatingResponse, error) {
+	job, err := s.jobRepo.FindByID(jobID)
+	if err != nil || job.DriverID == nil || *job.DriverID != driverID {
+		return nil, ErrRatingTripNotFound
+	}
+	if job.Trip.Status != models.TripStatusCompleted {
+		return nil, ErrTripNotRateable
+	}
+
+	return s.rate(&job.Trip, driverID, models.UserTypeDriver, job.Trip.CustomerID, req, time.Now())
+}
+
+// GetTags returns the tags a rater of the given type can pick from
+func (s *ratingService) GetTags(raterType models.UserType) []string {
+	tags := ratingTags[raterType]
+	if tags == nil {
+		return []string{}
+	}
+	return tags
+}
+
+// ListFlaggedDrivers lists drivers whose rating is below the review threshold
+func (s *ratingService) ListFlaggedDrivers(limit, offset int) ([]dto.FlaggedDriverResponse, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	drivers, err := s.userRepo.FindFlaggedDrivers(limit, offset)
+	if err != nil {
+		return nil, errors.New("failed to fetch flagged drivers")
+	}
+
+	responses := make([]dto.FlaggedDriverResponse, 0, len(drivers))
+	for _, driver := range drivers {
+		responses = append(responses, dto.FlaggedDriverResponse{
+			DriverID:    driver.ID.String(),
+			Name:        driver.Name,
+			Email:       driver.Email,
+			Rating:      driver.RatingAverage,
+			RatingCount: driver.RatingCount,
+			FlaggedAt:   driver.RatingFlaggedAt.Format(time.RFC3339),
+		})
+	}
+	return responses, nil
+}
+
+func (s *ratingService) rate(
+	trip *models.Trip,
+	raterID uuid.UUID,
+	raterType models.UserType,
+	rateeID uuid.UUID,
+	req dto.RateTripRequest,
+	now time.Time,
+) (*dto.RatingResponse, error) {
+	// Trips completed before completion times were recorded fall back to their last update
+	completedAt := trip.UpdatedAt
+	if trip.CompletedAt != nil {
+		completedAt = *trip.CompletedAt
+	}
+	if !RatingWindowOpen(completedAt, s.cfg.Window, now) {
+		return nil, ErrRatingWindowClosed
+	}
+	if err := ValidateRatingTags(raterType, req.Tags); err != nil {
+		return nil, err
+	}
+	if _, err := s.ratingRepo.FindByTripAndRater(trip.ID, raterID); err == nil {
+		return nil, ErrAlreadyRated
+	}
+
+	rating := &models.TripRating{
+		TripID:    trip.ID,
+		RaterID:   raterID,
+		RateeID:   rateeID,
+		RaterType: raterType,
+		Stars:     req.Stars,
+		Tags:      req.Tags,
+	}
+	if comment := strings.TrimSpace(req.Comment); comment != "" {
+		rating.Comment = &comment
+	}
+	if err := s.ratingRepo.Create(rating); err != nil {
+		return nil, errors.New("failed to save rating")
+	}
+
+	// The rating is kept even if the average can't be refreshed; the next rating
+	// recomputes it from scratch
+	if err := s.refreshRating(rateeID, now); err != nil {
+		log.Printf("Failed to refresh rating of user %s: %v", rateeID, err)
+	}
+
+	return ratingToDTO(rating), nil
+}
+
+// refreshRating recomputes a user's rolling average and, for drivers, flags them for
+// review while the average is below the threshold
+func (s *ratingService) refreshRating(userID uuid.UUID, now time.Time) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	average, count, err := s.ratingRepo.RollingAverage(userID, s.cfg.RollingCount)
+	if err != nil {
+		return err
+	}
+	average = math.Round(average*100) / 100
+
+	flaggedAt := user.RatingFlaggedAt
+	if user.UserType == models.UserTypeDriver && DriverFlagged(average, count, s.cfg) {
+		if flaggedAt == nil {
+			flaggedAt = &now
+		}
+	} else {
+		flaggedAt = nil
+	}
+
+	return s.userRepo.UpdateRating(userID, average, count, flaggedAt)
+}
+
+// driverRatings returns the rolling averages of the given drivers; drivers who
+// haven't been rated yet are left out
+func (s *ratingService) driverRatings(driverIDs []uuid.UUID) (map[uuid.UUID]float64, error) {
+	drivers, err := s.userRepo.FindByIDs(driverIDs)
+	if err != nil {
+		return nil, err
+	}
+	ratings := make(map[uuid.UUID]float64, len(drivers))
+	for _, driver := range drivers {
+		if driver.RatingAverage != nil {
+			ratings[driver.ID] = *driver.RatingAverage
+		}
+	}
+	return ratings, nil
+}
+
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func ratingToDTO(rating *models.TripRating) *dto.RatingResponse {
+	return &dto.RatingResponse{
+		ID:        rating.ID.String(),
+		TripID:    rating.TripID.String(),
+		Stars:     rating.Stars,
+		Tags:      rating.Tags,
+		Comment:   rating.Comment,
+		CreatedAt: rating.CreatedAt.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/telemoz/backend/internal/config"
+	"github.com/telemoz/backend/pkg/maps"
+)
+
+const (
+	// DistanceMethodRoad is a distance and duration from the routing provider
+	DistanceMethodRoad = "road"
+	// DistanceMethodStraightLine is estimated from the straight-line distance
+	DistanceMethodStraightLine = "straight_line"
+)
+
+// RouteEstimate is the distance and duration a trip is priced on
+type RouteEstimate struct {
+	DistanceKm      float64
+	DurationMinutes float64
+	Method          string
+}
+
+type routeKey struct {
+	originLat, originLng, destLat, destLng int64
+}
+
+type cachedRoute struct {
+	estimate  RouteEstimate
+	expiresAt time.Time
+}
+
+// RouteEstimator gets road distances from a routing provider and caches them. When the
+// provider is disabled, fails or takes longer than the timeout it estimates them from
+// the straight-line distance instead.
+type RouteEstimator struct {
+	router maps.Router
+	cfg    config.RoutingConfig
+
+	mu    sync.Mutex
+	cache map[routeKey]cachedRoute
+}
+
+func NewRouteEstimator(router maps.Router, cfg config.RoutingConfig) *RouteEstimator {
+	return &RouteEstimator{
+		router: router,
+		cfg:    cfg,
+		cache:  make(map[routeKey]cachedRoute),
+	}
+}
+
+var (
+	sharedRouteEstimatorOnce sync.Once
+	sharedRouteEstimator     *RouteEstimator
+)
+
+// defaultRouteEstimator is the estimator of the configured provider, shared so every
+// service uses the same cache
+func defaultRouteEstimator() *RouteEstimator {
+	sharedRouteEstimatorOnce.Do(func() {
+		sharedRouteEstimator = NewRouteEstimator(maps.NewRouter(), config.AppConfig.Routing)
+	})
+	return sharedRouteEstimator
+}
+
+// Estimate returns the distance and duration from origin to destination
+func (e *RouteEstimator) Estimate(originLat, originLng, destLat, destLng float64) RouteEstimate {
+	ctx, cancel := e.deadline()
+	defer cancel()
+	return e.estimate(ctx, originLat, originLng, destLat, destLng)
+}
+
+// EstimateLegs returns the distance and duration of each leg of a route through
+// points. The legs share one timeout; once it has run out, the legs left are
+// estimated from the straight-line distance without asking the provider.
+func (e *RouteEstimator) EstimateLegs(points []RoutePoint) []RouteEstimate {
+	ctx, cancel := e.deadline()
+	defer cancel()
+
+	legs := make([]RouteEstimate, 0, len(points))
+	for i := 1; i < len(points); i++ {
+		legs = append(legs, e.estimate(ctx, points[i-1].Latitude, points[i-1].Longitude, points[i].Latitude, points[i].Longitude))
+	}
+	return legs
+}
+
+// deadline returns the context provider requests are made under
+func (e *RouteEstimator) deadline() (context.Context, context.CancelFunc) {
+	if e.cfg.Timeout > 0 {
+		return context.WithTimeout(context.Background(), e.cfg.Timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+func (e *RouteEstimator) estimate(ctx context.Context, originLat, originLng, destLat, destLng float64) RouteEstimate {
+	if e.router == nil {
+		return e.straightLine(originLat, originLng, destLat, destLng)
+	}
+
+	key := routeKey{
+		originLat: coordinateKey(originLat),
+		originLng: coordinateKey(originLng),
+		destLat:   coordinateKey(destLat),
+		destLng:   coordinateKey(destLng),
+	}
+	now := time.Now()
+	if cached, ok := e.cached(key, now); ok {
+		return cached
+	}
+
+	if ctx.Err() != nil {
+		return e.straightLine(originLat, originLng, destLat, destLng)
+	}
+	route, err := e.router.Route(ctx, originLat, originLng, destLat, destLng)
+	if err != nil {
+		return e.straightLine(originLat, originLng, destLat, destLng)
+	}
+
+	estimate := RouteEstimate{
+		DistanceKm:      route.Distance,
+		DurationMinutes: float64(route.DurationSeconds) / 60,
+		Method:          DistanceMethodRoad,
+	}
+	e.store(key, estimate, now)
+	return estimate
+}
+
+func (e *RouteEstimator) straightLine(originLat, originLng, destLat, destLng float64) RouteEstimate {
+	return EstimateStraightLineRoute(originLat, originLng, destLat, destLng, e.cfg.RoadFactor, e.cfg.FallbackSpeedKmh)
+}
+
+func (e *RouteEstimator) cached(key routeKey, now time.Time) (RouteEstimate, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	cached, ok := e.cache[key]
+	if !ok || now.After(cached.expiresAt) {
+		return RouteEstimate{}, false
+	}
+	return cached.estimate, true
+}
+
+func (e *RouteEstimator) store(key routeKey, estimate RouteEstimate, now time.Time) {
+	if e.cfg.CacheTTL <= 0 || e.cfg.CacheSize <= 0 {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(e.cache) >= e.cfg.CacheSize {
+		for cachedKey, cached := range e.cache {
+			if now.After(cached.expiresAt) {
+				delete(e.cache, cachedKey)
+			}
+		}
+	}
+	// Still full: make room by dropping an arbitrary entry
+	for cachedKey := range e.cache {
+		if len(e.cache) < e.cfg.CacheSize {
+			break
+		}
+		delete(e.cache, cachedKey)
+	}
+
+	e.cache[key] = cachedRoute{estimate: estimate, expiresAt: now.Add(e.cfg.CacheTTL)}
+}
+
+// coordinateKey rounds a coordinate to 4 decimal places, about 10m
+func coordinateKey(coordinate float64) int64 {
+	return int64(math.Round(coordinate * 1e4))
+}
+
+// EstimateStraightLineRoute estimates the road distance between two points as the
+// straight-line distance times roadFactor, and its duration at speedKmh
+func EstimateStraightLineRoute(originLat, originLng, destLat, destLng, roadFactor, speedKmh float64) RouteEstimate {
+	if roadFactor < 1 {
+		roadFactor = 1
+	}
+	if speedKmh <= 0 {
+		speedKmh = 40
+	}
+	distance := calculateHaversineDistance(originLat, originLng, destLat, destLng) * roadFactor
+	return RouteEstimate{
+		DistanceKm:      distance,
+		DurationMinutes: distance / speedKmh * 60,
+		Method:          DistanceMethodStraightLine,
+	}
+}
@@ -0,0 +1,415 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/config"
+	"github.com/telemoz/backend/internal/dto"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/repositories"
+	"github.com/telemoz/backend/internal/utils"
+)
+
+type RouteService interface {
+	CreateRoute(req *dto.CreateRouteRequest) (*models.Route, error)
+	ListRoutes() ([]models.Route, error)
+	GetRoute(routeID uuid.UUID) (*models.Route, error)
+	UpdateRoute(routeID uuid.UUID, req *dto.UpdateRouteRequest) (*models.Route, error)
+	DeleteRoute(routeID uuid.UUID) error
+
+	AddStop(routeID uuid.UUID, req *dto.RouteStopRequest) (*models.RouteStop, error)
+	UpdateStop(routeID, stopID uuid.UUID, req *dto.RouteStopRequest) (*models.RouteStop, error)
+	DeleteStop(routeID, stopID uuid.UUID) error
+
+	AddSchedule(routeID uuid.UUID, req *dto.ScheduleRequest) (*models.Schedule, error)
+	UpdateSchedule(routeID, scheduleID uuid.UUID, req *dto.ScheduleRequest) (*models.Schedule, error)
+	DeleteSchedule(routeID, scheduleID uuid.UUID) error
+
+	GetChildStops(childID, parentID uuid.UUID) (*dto.ChildStopsResponse, error)
+}
+
+type routeService struct {
+	routeRepo    repositories.RouteRepository
+	stopRepo     repositories.RouteStopRepository
+	scheduleRepo repositories.ScheduleRepository
+	busRepo      repositories.BusRepository
+	childRepo    repositories.ChildRepository
+	location     *time.Location
+}
+
+func NewRouteService() RouteService {
+	return &routeService{
+		routeRepo:    repositories.NewRouteRepository(),
+		stopRepo:     repositories.NewRouteStopRepository(),
+		scheduleRepo: repositories.NewScheduleRepository(),
+		busRepo:      repositories.NewBusRepository(),
+		childRepo:    repositories.NewChildRepository(),
+		location:     scheduleLocation(),
+	}
+}
+
+func (s *routeService) CreateRoute(req *dto.CreateRouteRequest) (*models.Route, error) {
+	route := &models.Route{
+		Name:     utils.SanitizeString(req.Name),
+		IsActive: true,
+	}
+	if req.Description != nil {
+		description := utils.SanitizeString(*req.Description)
+		route.Description = &description
+	}
+	if req.BusID != nil {
+		busID, err := s.findBusID(*req.BusID)
+		if err != nil {
+			return nil, err
+		}
+		route.BusID = &busID
+	}
+
+	if err := s.routeRepo.Create(route); err != nil {
+		return nil, errors.New("failed to create route")
+	}
+	return route, nil
+}
+
+func (s *routeService) ListRoutes() ([]models.Route, error) {
+	routes, err := s.routeRepo.FindAll()
+	if err != nil {
+		return nil, errors.New("failed to fetch routes")
+	}
+	return routes, nil
+}
+
+func (s *routeService) GetRoute(routeID uuid.UUID) (*models.Route, error) {
+	route, err := s.routeRepo.FindByID(routeID)
+	if err != nil {
+		return nil, errors.New("route not found")
+	}
+	return route, nil
+}
+
+func (s *routeService) UpdateRoute(routeID uuid.UUID, req *dto.UpdateRouteRequest) (*models.Route, error) {
+	route, err := s.routeRepo.FindByID(routeID)
+	if err != nil {
+		return nil, errors.New("route not found")
+	}
+
+	if req.Name != nil {
+		route.Name = utils.SanitizeString(*req.Name)
+	}
+	if req.Description != nil {
+		description := utils.SanitizeString(*req.Description)
+		route.Description = &description
+	}
+	if req.BusID != nil {
+		if *req.BusID == "" {
+			route.BusID = nil
+		} else {
+			busID, err := s.findBusID(*req.BusID)
+			if err != nil {
+				return nil, err
+			}
+			route.BusID = &busID
+		}
+	}
+	if req.IsActive != nil {
+		route.IsActive = *req.IsActive
+	}
+
+	if err := s.routeRepo.Update(route); err != nil {
+		return nil, errors.New("failed to update route")
+	}
+	return route, nil
+}
+
+func (s *routeService) DeleteRoute(routeID uuid.UUID) error {
+	if _, err := s.routeRepo.FindByID(routeID); err != nil {
+		return errors.New("route not found")
+	}
+	if err := s.routeRepo.Delete(routeID); err != nil {
+		return errors.New("failed to delete route")
+	}
+	return nil
+}
+
+func (s *routeService) AddStop(routeID uuid.UUID, req *dto.RouteStopRequest) (*models.RouteStop, error) {
+	if _, err := s.routeRepo.FindByID(routeID); err != nil {
+		return nil, errors.New("route not found")
+	}
+
+	stop := &models.RouteStop{RouteID: routeID}
+	applyStopRequest(stop, req)
+
+	if err := s.stopRepo.Create(stop); err != nil {
+		return nil, errors.New("failed to create stop")
+	}
+	return stop, nil
+}
+
+func (s *routeService) UpdateStop(routeID, stopID uuid.UUID, req *dto.RouteStopRequest) (*models.RouteStop, error) {
+	stop, err := s.findStop(routeID, stopID)
+	if err != nil {
+		return nil, err
+	}
+
+	applyStopRequest(stop, req)
+
+	if err := s.stopRepo.Update(stop); err != nil {
+		return nil, errors.New("failed to update stop")
+	}
+	return stop, nil
+}
+
+func (s *routeService) DeleteStop(routeID, stopID uuid.UUID) error {
+	if _, err := s.findStop(routeID, stopID); err != nil {
+		return err
+	}
+	if err := s.stopRepo.Delete(stopID); err != nil {
+		return errors.New("failed to delete stop")
+	}
+	return nil
+}
+
+func (s *routeService) AddSchedule(routeID uuid.UUID, req *dto.ScheduleRequest) (*models.Schedule, error) {
+	if _, err := s.routeRepo.FindByID(routeID); err != nil {
+		return nil, errors.New("route not found")
+	}
+
+	schedule := &models.Schedule{RouteID: routeID, IsActive: true}
+	if err := s.applyScheduleRequest(schedule, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.scheduleRepo.Create(schedule); err != nil {
+		return nil, errors.New("failed to create schedule")
+	}
+	return schedule, nil
+}
+
+func (s *routeService) UpdateSchedule(routeID, scheduleID uuid.UUID, req *dto.ScheduleRequest) (*models.Schedule, error) {
+	schedule, err := s.findSchedule(routeID, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.applyScheduleRequest(schedule, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.scheduleRepo.Update(schedule); err != nil {
+		return nil, errors.New("failed to update schedule")
+	}
+	return schedule, nil
+}
+
+func (s *routeService) DeleteSchedule(routeID, scheduleID uuid.UUID) error {
+	if _, err := s.findSchedule(routeID, scheduleID); err != nil {
+		return err
+	}
+	if err := s.scheduleRepo.Delete(scheduleID); err != nil {
+		return errors.New("failed to delete schedule")
+	}
+	return nil
+}
+
+// GetChildStops returns the child's pickup and drop-off stops with every timetabled
+// run that serves them and the next scheduled arrival
+func (s *routeService) GetChildStops(childID, parentID uuid.UUID) (*dto.ChildStopsResponse, error) {
+	child, err := s.childRepo.FindByID(childID)
+	if err != nil {
+		return nil, errors.New("child not found")
+	}
+	if child.ParentID != parentID {
+		return nil, errors.New("unauthorized to view this child")
+	}
+
+	response := &dto.ChildStopsResponse{ChildID: child.ID.String()}
+	if child.BusID != nil {
+		busID := child.BusID.String()
+		response.BusID = &busID
+	}
+
+	now := time.Now()
+	if child.PickupStopID != nil {
+		if response.Pickup, err = s.stopTimes(*child.PickupStopID, models.RunTypeMorning, now); err != nil {
+			return nil, err
+		}
+	}
+	if child.DropoffStopID != nil {
+		if response.Dropoff, err = s.stopTimes(*child.DropoffStopID, models.RunTypeAfternoon, now); err != nil {
+			return nil, err
+		}
+	}
+
+	return response, nil
+}
+
+// stopTimes lists the runs of the given type that serve a stop. Children are picked
+// up on morning runs and dropped off on afternoon runs.
+func (s *routeService) stopTimes(stopID uuid.UUID, runType models.RunType, now time.Time) (*dto.ChildStopTimes, error) {
+	stop, err := s.stopRepo.FindByID(stopID)
+	if err != nil {
+		return nil, errors.New("stop not found")
+	}
+	schedules, err := s.scheduleRepo.FindByStopID(stopID)
+	if err != nil {
+		return nil, errors.New("failed to fetch schedules")
+	}
+
+	times := &dto.ChildStopTimes{
+		StopID:   stop.ID.String(),
+		StopName: stop.Name,
+		Location: dto.Location{
+			Latitude:  stop.Latitude,
+			Longitude: stop.Longitude,
+		},
+		RouteID: stop.RouteID.String(),
+		Runs:    []dto.ScheduledStop{},
+	}
+	if stop.Route != nil {
+		times.RouteName = stop.Route.Name
+	}
+
+	var next *time.Time
+	for i := range schedules {
+		schedule := &schedules[i]
+		if schedule.RunType != runType {
+			continue
+		}
+		for _, stopTime := range schedule.StopTimes {
+			times.Runs = append(times.Runs, dto.ScheduledStop{
+				ScheduleID:  schedule.ID.String(),
+				RunType:     string(schedule.RunType),
+				Weekdays:    schedule.Weekdays,
+				ArrivalTime: stopTime.ArrivalTime,
+			})
+
+			arrival, ok := NextScheduledArrival(schedule, stopTime.ArrivalTime, now, s.location)
+			if ok && (next == nil || arrival.Before(*next)) {
+				next = &arrival
+			}
+		}
+	}
+
+	if next != nil {
+		formatted := next.Format(time.RFC3339)
+		times.NextArrival = &formatted
+	}
+	return times, nil
+}
+
+func (s *routeService) findBusID(id string) (uuid.UUID, error) {
+	busID, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, errors.New("invalid bus ID")
+	}
+	if _, err := s.busRepo.FindByID(busID); err != nil {
+		return uuid.Nil, errors.New("bus not found")
+	}
+	return busID, nil
+}
+
+func (s *routeService) findStop(routeID, stopID uuid.UUID) (*models.RouteStop, error) {
+	stop, err := s.stopRepo.FindByID(stopID)
+	if err != nil || stop.RouteID != routeID {
+		return nil, errors.New("stop not found")
+	}
+	stop.Route = nil
+	return stop, nil
+}
+
+func (s *routeService) findSchedule(routeID, scheduleID uuid.UUID) (*models.Schedule, error) {
+	schedule, err := s.scheduleRepo.FindByID(scheduleID)
+	if err != nil || schedule.RouteID != routeID {
+		return nil, errors.New("schedule not found")
+	}
+	return schedule, nil
+}
+
+func applyStopRequest(stop *models.RouteStop, req *dto.RouteStopRequest) {
+	stop.Name = utils.SanitizeString(req.Name)
+	stop.Latitude = *req.Latitude
+	stop.Longitude = *req.Longitude
+	stop.Sequence = req.Sequence
+	if req.GeofenceRadiusMeters != nil {
+		stop.GeofenceRadiusMeters = *req.GeofenceRadiusMeters
+	} else if stop.GeofenceRadiusMeters == 0 {
+		stop.GeofenceRadiusMeters = 50
+	}
+}
+
+func (s *routeService) applyScheduleRequest(schedule *models.Schedule, req *dto.ScheduleRequest) error {
+	schedule.RunType = models.RunType(req.RunType)
+	schedule.Weekdays = models.StringArray(req.Weekdays)
+	schedule.DepartureTime = req.DepartureTime
+	if req.IsActive != nil {
+		schedule.IsActive = *req.IsActive
+	}
+
+	schedule.StartsOn = nil
+	schedule.EndsOn = nil
+	if req.StartsOn != nil {
+		startsOn, _ := time.Parse("2006-01-02", *req.StartsOn)
+		schedule.StartsOn = &startsOn
+	}
+	if req.EndsOn != nil {
+		endsOn, _ := time.Parse("2006-01-02", *req.EndsOn)
+		schedule.EndsOn = &endsOn
+	}
+	if schedule.StartsOn != nil && schedule.EndsOn != nil && schedule.EndsOn.Before(*schedule.StartsOn) {
+		return errors.New("schedule ends before it starts")
+	}
+
+	stops, err := s.stopRepo.FindByRouteID(schedule.RouteID)
+	if err != nil {
+		return errors.New("failed to fetch stops")
+	}
+	onRoute := make(map[uuid.UUID]bool, len(stops))
+	for _, stop := range stops {
+		onRoute[stop.ID] = true
+	}
+
+	stopTimes := make([]models.ScheduleStopTime, 0, len(req.StopTimes))
+	for _, item := range req.StopTimes {
+		stopID, _ := uuid.Parse(item.StopID)
+		if !onRoute[stopID] {
+			return errors.New("stop is not on this route")
+		}
+		stopTimes = append(stopTimes, models.ScheduleStopTime{
+			ScheduleID:  schedule.ID,
+			RouteStopID: stopID,
+			ArrivalTime: item.ArrivalTime,
+		})
+	}
+	schedule.StopTimes = stopTimes
+
+	return nil
+}
+
+// NextScheduledArrival returns the first arrival at or after now for a stop the
+// schedule reaches at arrivalTime ("15:04" wall-clock time in loc), looking up to
+// a week ahead
+func NextScheduledArrival(schedule *models.Schedule, arrivalTime string, now time.Time, loc *time.Location) (time.Time, bool) {
+	local := now.In(loc)
+	for offset := 0; offset <= 7; offset++ {
+		day := local.AddDate(0, 0, offset)
+		arrival, ok := clockOn(day, arrivalTime, loc)
+		if !ok {
+			return time.Time{}, false
+		}
+		if arrival.Before(now) || !schedule.RunsOn(day) {
+			continue
+		}
+		return arrival, true
+	}
+	return time.Time{}, false
+}
+
+func scheduleLocation() *time.Location {
+	loc, err := time.LoadLocation(config.AppConfig.BusStops.ScheduleTimezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
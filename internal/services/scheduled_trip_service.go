@@ -0,0 +1,329 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/config"
+	"github.com/telemoz/backend/internal/dto"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/repositories"
+)
+
+var (
+	// ErrInvalidScheduledAt means the requested pickup time could not be parsed
+	ErrInvalidScheduledAt = errors.New("invalid scheduled_at, expected an RFC3339 time")
+	// ErrScheduleTooSoon means the pickup is closer than the minimum lead time
+	ErrScheduleTooSoon = errors.New("scheduled pickup is too soon, book an immediate ride instead")
+	// ErrScheduleTooFar means the pickup is further ahead than trips can be booked
+	ErrScheduleTooFar = errors.New("scheduled pickup is too far ahead")
+	// ErrScheduleConflict means a driver's other trips leave no time for the scheduled one
+	ErrScheduleConflict = errors.New("scheduled trip overlaps another trip you have accepted")
+)
+
+const (
+	notificationScheduledTripReminder   = "scheduled_trip_reminder"
+	notificationScheduledTripAccepted   = "scheduled_trip_accepted"
+	notificationScheduledTripReleased   = "scheduled_trip_released"
+	notificationScheduledTripReassigned = "scheduled_trip_reassigned"
+)
+
+type ScheduledTripService interface {
+	ListCustomerTrips(customerID uuid.UUID) ([]dto.TripResponse, error)
+	ListJobs(driverID uuid.UUID) ([]dto.JobResponse, error)
+	PreAcceptJob(jobID, driverID uuid.UUID) (*dto.JobResponse, error)
+	ReleaseJob(jobID, driverID uuid.UUID) error
+	ProcessDue(now time.Time) error
+}
+
+type scheduledTripService struct {
+	tripRepo            repositories.TripRepository
+	jobRepo             repositories.JobRepository
+	availabilityRepo    repositories.DriverAvailabilityRepository
+	notificationService NotificationService
+	dispatchService     DispatchService
+	lifecycle           *tripLifecycle
+	compliance          *driverComplianceService
+	trips               *tripService
+	jobs                *jobService
+	cfg                 config.SchedulingConfig
+}
+
+func NewScheduledTripService() ScheduledTripService {
+	return &scheduledTripService{
+		tripRepo:            repositories.NewTripRepository(),
+		jobRepo:             repositories.NewJobRepository(),
+		availabilityRepo:    repositories.NewDriverAvailabilityRepository(),
+		notificationService: NewNotificationService(),
+		dispatchService:     NewDispatchService(),
+		lifecycle:           newTripLifecycle(),
+		compliance:          newDriverComplianceService(),
+		trips:               newTripService(),
+		jobs:                newJobService(),
+		cfg:                 config.AppConfig.Scheduling,
+	}
+}
+
+// ValidateScheduledAt checks that a pickup requested at scheduledAt is within the
+// lead times trips can be booked ahead with
+func ValidateScheduledAt(cfg config.SchedulingConfig, scheduledAt, now time.Time) error {
+	lead := scheduledAt.Sub(now)
+	if lead < cfg.MinLead {
+		return ErrScheduleTooSoon
+	}
+	if lead > cfg.MaxLead {
+		return ErrScheduleTooFar
+	}
+	return nil
+}
+
+// ScheduledTripsConflict reports whether one driver cannot serve both trips. Each
+// trip occupies the driver from its scheduled pickup, or from now when it isn't
+// scheduled, for its estimated duration; buffer must be left between the two.
+func ScheduledTripsConflict(a, b *models.Trip, buffer time.Duration, now time.Time) bool {
+	aStart, aEnd := tripWindow(a, now)
+	bStart, bEnd := tripWindow(b, now)
+	return aStart.Before(bEnd.Add(buffer)) && bStart.Before(aEnd.Add(buffer))
+}
+
+func tripWindow(trip *models.Trip, now time.Time) (time.Time, time.Time) {
+	start := now
+	if trip.ScheduledAt != nil {
+		start = *trip.ScheduledAt
+	}
+	end := start
+	if trip.EstimatedDuration != nil {
+		end = start.Add(time.Duration(*trip.EstimatedDuration) * time.Minute)
+	}
+	return start, end
+}
+
+// ListCustomerTrips returns a customer's scheduled trips that haven't been dispatched yet
+func (s *scheduledTripService) ListCustomerTrips(customerID uuid.UUID) ([]dto.TripResponse, error) {
+	trips, err := s.tripRepo.FindScheduledByCustomerID(customerID)
+	if err != nil {
+		return nil, errors.New("failed to fetch scheduled trips")
+	}
+
+	responses := make([]dto.TripResponse, len(trips))
+	for i := range trips {
+		responses[i] = *s.trips.tripToDTO(&trips[i])
+	}
+	return responses, nil
+}
+
+// ListJobs returns the scheduled jobs a driver has pre-accepted followed by the open
+// ones for the services they offer
+func (s *scheduledTripService) ListJobs(driverID uuid.UUID) ([]dto.JobResponse, error) {
+	accepted, err := s.jobRepo.FindScheduledByDriverID(driverID)
+	if err != nil {
+		return nil, errors.New("failed to fetch scheduled jobs")
+	}
+	open, err := s.jobRepo.FindScheduled(time.Now())
+	if err != nil {
+		return nil, errors.New("failed to fetch scheduled jobs")
+	}
+	availability, err := s.availabilityRepo.FindByDriverID(driverID)
+	if err != nil {
+		availability = &models.DriverAvailability{}
+	}
+
+	responses := make([]dto.JobResponse, 0, len(accepted)+len(open))
+	for i := range accepted {
+		responses = append(responses, *s.jobs.jobToDTO(&accepted[i]))
+	}
+	for i := range open {
+		if offersService(availability, string(open[i].Trip.ServiceType)) && canCarry(availability, &open[i].Trip) {
+			responses = append(responses, *s.jobs.jobToDTO(&open[i]))
+		}
+	}
+	return responses, nil
+}
+
+// PreAcceptJob assigns a scheduled job to a driver ahead of its pickup, provided it
+// fits around the trips they already have. The driver gets the trip when it is
+// dispatched if they are online and free by then.
+func (s *scheduledTripService) PreAcceptJob(jobID, driverID uuid.UUID) (*dto.JobResponse, error) {
+	job, err := s.jobRepo.FindByID(jobID)
+	if err != nil {
+		return nil, errors.New("job not found")
+	}
+	trip := &job.Trip
+	if trip.Status != models.TripStatusPending || trip.ScheduledAt == nil {
+		return nil, errors.New("job is not an upcoming scheduled job")
+	}
+	if job.DriverID != nil {
+		if *job.DriverID == driverID {
+			return s.jobs.jobToDTO(job), nil
+		}
+		return nil, ErrJobTaken
+	}
+
+	availability, err := s.availabilityRepo.FindByDriverID(driverID)
+	if err != nil || !offersService(availability, string(trip.ServiceType)) {
+		return nil, errors.New("you don't offer this service")
+	}
+	if !canCarry(availability, trip) {
+		return nil, ErrVehicleTooSmall
+	}
+	if err := s.compliance.checkDriver(driverID, time.Now()); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	conflicts := func(commitments []models.Trip) bool {
+		for i := range commitments {
+			if ScheduledTripsConflict(trip, &commitments[i], s.cfg.ConflictBuffer, now) {
+				return true
+			}
+		}
+		return false
+	}
+	if err := s.tripRepo.PreAssign(trip, job, driverID, conflicts); err != nil {
+		if errors.Is(err, repositories.ErrStatusChanged) {
+			return nil, ErrJobTaken
+		}
+		if errors.Is(err, repositories.ErrDriverCommitted) {
+			return nil, ErrScheduleConflict
+		}
+		return nil, errors.New("failed to accept scheduled job")
+	}
+
+	s.notify(trip.CustomerID, notificationScheduledTripAccepted, "Driver confirmed",
+		fmt.Sprintf("A driver has accepted your trip scheduled for %s", trip.ScheduledAt.Format(time.RFC3339)), trip)
+	return s.jobs.jobToDTO(job), nil
+}
+
+// ReleaseJob gives a pre-accepted scheduled job back so other drivers can take it
+func (s *scheduledTripService) ReleaseJob(jobID, driverID uuid.UUID) error {
+	job, err := s.jobRepo.FindByID(jobID)
+	if err != nil {
+		return errors.New("job not found")
+	}
+	trip := &job.Trip
+	if job.DriverID == nil || *job.DriverID != driverID {
+		return errors.New("job has not been accepted by you")
+	}
+
+	if err := s.tripRepo.ReleasePreAssignment(trip, job, driverID); err != nil {
+		if errors.Is(err, repositories.ErrStatusChanged) {
+			return ErrTripStatusChanged
+		}
+		return errors.New("failed to release scheduled job")
+	}
+
+	s.notify(trip.CustomerID, notificationScheduledTripReleased, "Driver unavailable",
+		"The driver who accepted your scheduled trip can no longer make it; we'll find you another one", trip)
+	return nil
+}
+
+// ProcessDue reminds customers and pre-accepted drivers of upcoming scheduled trips
+// and dispatches the trips whose pickup is close
+func (s *scheduledTripService) ProcessDue(now time.Time) error {
+	reminders, err := s.tripRepo.FindScheduledForReminder(now.Add(s.cfg.ReminderBefore))
+	if err != nil {
+		return err
+	}
+	for i := range reminders {
+		s.remind(&reminders[i], now)
+	}
+
+	due, err := s.tripRepo.FindScheduledDue(now.Add(s.cfg.DispatchBefore))
+	if err != nil {
+		return err
+	}
+	for i := range due {
+		if err := s.dispatch(&due[i]); err != nil {
+			// Continue with the remaining trips; this one is retried on the next run
+			log.Printf("Failed to dispatch scheduled trip %s: %v", due[i].ID, err)
+		}
+	}
+	return nil
+}
+
+func (s *scheduledTripService) remind(trip *models.Trip, now time.Time) {
+	claimed, err := s.tripRepo.ClaimReminder(trip, now)
+	if err != nil || !claimed {
+		return
+	}
+
+	pickupAt := trip.ScheduledAt.Format(time.RFC3339)
+	s.notify(trip.CustomerID, notificationScheduledTripReminder, "Upcoming trip",
+		"Your scheduled trip picks you up at "+pickupAt, trip)
+	if trip.DriverID != nil {
+		s.notify(*trip.DriverID, notificationScheduledTripReminder, "Upcoming trip",
+			"You have a scheduled pickup at "+pickupAt, trip)
+	}
+}
+
+// dispatch starts a due scheduled trip. A driver who pre-accepted it gets it right
+// away if they are online and free; otherwise it is offered to nearby drivers like
+// any other trip.
+func (s *scheduledTripService) dispatch(trip *models.Trip) error {
+	job, err := s.jobRepo.FindByTripID(trip.ID)
+	if err != nil {
+		return errors.New("scheduled trip has no job")
+	}
+
+	preAssigned := trip.DriverID
+	trip.DriverID = nil
+	job.DriverID = nil
+	if err := s.lifecycle.apply(trip, job, tripTransition{
+		To:    models.TripStatusSearching,
+		Actor: models.TripActorSystem,
+	}); err != nil {
+		return err
+	}
+
+	if preAssigned != nil {
+		if s.driverReady(*preAssigned) {
+			err := s.lifecycle.accept(trip, job, nil, *preAssigned)
+			if err == nil {
+				return nil
+			}
+			log.Printf("Pre-accepted driver %s could not take scheduled trip %s: %v", *preAssigned, trip.ID, err)
+		}
+		s.notify(*preAssigned, notificationScheduledTripReassigned, "Scheduled trip reassigned",
+			"You weren't available when your scheduled trip was due, so it was offered to other drivers", trip)
+	}
+
+	// If this fails the dispatch job offers it on its next tick
+	s.dispatchService.StartDispatch(job, trip)
+	return nil
+}
+
+// driverReady reports whether a driver is online, still allowed to take jobs and not
+// on another trip
+func (s *scheduledTripService) driverReady(driverID uuid.UUID) bool {
+	availability, err := s.availabilityRepo.FindByDriverID(driverID)
+	if err != nil || !availability.IsAvailable {
+		return false
+	}
+	if s.compliance.checkDriver(driverID, time.Now()) != nil {
+		return false
+	}
+	_, err = s.jobRepo.FindActiveByDriverID(driverID)
+	return err != nil
+}
+
+func (s *scheduledTripService) notify(userID uuid.UUID, notificationType, title, message string, trip *models.Trip) {
+	data := map[string]interface{}{"trip_id": trip.ID.String()}
+	if trip.ScheduledAt != nil {
+		data["scheduled_at"] = trip.ScheduledAt.Format(time.RFC3339)
+	}
+	if err := s.notificationService.CreateNotification(userID, notificationType, title, message, data); err != nil {
+		log.Printf("Failed to notify %s about scheduled trip %s: %v", userID, trip.ID, err)
+	}
+}
+
+func offersService(availability *models.DriverAvailability, serviceType string) bool {
+	for _, offered := range availability.ServiceTypes {
+		if offered == serviceType {
+			return true
+		}
+	}
+	return false
+}
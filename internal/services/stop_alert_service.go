@@ -0,0 +1,325 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/config"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/repositories"
+	"github.com/telemoz/backend/pkg/sms"
+	"github.com/telemoz/backend/pkg/voice"
+)
+
+// StopAlert is a notification type sent to parents as a bus moves through a stop
+type StopAlert string
+
+const (
+	StopAlertNearby   StopAlert = "bus_nearby"
+	StopAlertArrived  StopAlert = "bus_arrived"
+	StopAlertDeparted StopAlert = "bus_departed"
+)
+
+type StopAlertService interface {
+	ProcessBusLocation(location *models.BusLocation) error
+}
+
+type stopAlertService struct {
+	stopRepo            repositories.RouteStopRepository
+	visitRepo           repositories.BusStopVisitRepository
+	alertRepo           repositories.BusStopAlertRepository
+	childRepo           repositories.ChildRepository
+	notificationService NotificationService
+	etaService          BusETAService
+	smsProvider         sms.Provider
+	voiceProvider       voice.Provider
+	cfg                 config.BusStopConfig
+}
+
+func NewStopAlertService() StopAlertService {
+	return &stopAlertService{
+		stopRepo:            repositories.NewRouteStopRepository(),
+		visitRepo:           repositories.NewBusStopVisitRepository(),
+		alertRepo:           repositories.NewBusStopAlertRepository(),
+		childRepo:           repositories.NewChildRepository(),
+		notificationService: NewNotificationService(),
+		etaService:          NewBusETAService(),
+		smsProvider:         sms.NewProvider(),
+		voiceProvider:       voice.NewProvider(),
+		cfg:                 config.AppConfig.BusStops,
+	}
+}
+
+// maxNearbyMinutes bounds NotificationSettings.BusNearbyMinutes; stops further
+// away than this are not checked for ETA alerts
+const maxNearbyMinutes = 60
+
+// ProcessBusLocation advances the bus's visit to each of its stops and alerts the
+// parents of children assigned to a stop when the bus approaches, arrives or leaves.
+// Parents with an ETA threshold are also alerted once the bus is due within it.
+func (s *stopAlertService) ProcessBusLocation(location *models.BusLocation) error {
+	stops, err := s.stopRepo.FindByBusID(location.BusID)
+	if err != nil {
+		return err
+	}
+	if len(stops) == 0 {
+		return nil
+	}
+
+	run, err := s.etaService.EstimateRun(location)
+	if err != nil {
+		log.Printf("Failed to estimate ETA for bus %s: %v", location.BusID, err)
+		run = nil
+	}
+
+	now := location.Timestamp
+	for i := range stops {
+		stop := &stops[i]
+		distance := calculateHaversineDistance(location.Latitude, location.Longitude, stop.Latitude, stop.Longitude) * 1000
+
+		visit, err := s.visitRepo.FindOpen(location.BusID, stop.ID, now.Add(-s.cfg.VisitTimeout))
+		if err != nil {
+			visit = &models.BusStopVisit{BusID: location.BusID, StopID: stop.ID}
+		}
+
+		alerts, changed := AdvanceStopVisit(visit, distance, stop.GeofenceRadiusMeters, s.cfg.NearbyDistanceMeters, now)
+		if !changed {
+			continue
+		}
+
+		// Persist the visit before alerting so a failed write can't cause repeat alerts
+		if visit.ID == uuid.Nil {
+			err = s.visitRepo.Create(visit)
+		} else {
+			err = s.visitRepo.Update(visit)
+		}
+		if err != nil {
+			log.Printf("Failed to save visit of bus %s to stop %s: %v", location.BusID, stop.ID, err)
+			continue
+		}
+
+		// Nearby alerts sent by ETA and by distance share a claim per run so parents
+		// with an ETA threshold get whichever comes first
+		runKey := "visit:" + visit.ID.String()
+		if run != nil {
+			runKey = run.RunKey()
+		}
+		for _, alert := range alerts {
+			s.notifyParents(location.BusID, stop, alert, distance, runKey)
+		}
+	}
+
+	if run != nil {
+		s.notifyByETA(location.BusID, run)
+	}
+	return nil
+}
+
+func (s *stopAlertService) notifyParents(busID uuid.UUID, stop *models.RouteStop, alert StopAlert, distance float64, runKey string) {
+	children, err := s.childRepo.FindByStopID(stop.ID)
+	if err != nil || len(children) == 0 {
+		return
+	}
+
+	for parentID, group := range groupByParent(children) {
+		settings, err := s.notificationService.GetSettings(parentID)
+		if err != nil || !stopAlertEnabled(settings, alert) {
+			continue
+		}
+		if alert == StopAlertNearby && settings.BusNearbyMinutes > 0 && !s.claim(parentID, stop.ID, runKey) {
+			continue
+		}
+
+		title, message := stopAlertMessage(stop, alert, distance, group.names)
+		s.deliver(group.parent, settings, alert, title, message, map[string]interface{}{
+			"bus_id":      busID.String(),
+			"stop_id":     stop.ID.String(),
+			"stop_name":   stop.Name,
+			"distance_m":  math.Round(distance),
+			"child_names": group.names,
+		})
+	}
+}
+
+// notifyByETA sends the nearby alert to parents whose chosen threshold the bus's
+// ETA to their child's stop has dropped below
+func (s *stopAlertService) notifyByETA(busID uuid.UUID, run *BusRunETA) {
+	for _, remaining := range run.Stops {
+		minutes := int(math.Ceil(remaining.ETA.Duration.Minutes()))
+		if minutes > maxNearbyMinutes {
+			// Stops are in run order, so the rest are further still
+			break
+		}
+
+		children, err := s.childRepo.FindByStopID(remaining.Stop.ID)
+		if err != nil {
+			continue
+		}
+		// Children board at their pickup stop in the morning and leave at their drop-off stop in the afternoon
+		riding := children[:0]
+		for _, child := range children {
+			stopID := child.PickupStopID
+			if run.Schedule.RunType == models.RunTypeAfternoon {
+				stopID = child.DropoffStopID
+			}
+			if stopID != nil && *stopID == remaining.Stop.ID {
+				riding = append(riding, child)
+			}
+		}
+
+		for parentID, group := range groupByParent(riding) {
+			settings, err := s.notificationService.GetSettings(parentID)
+			if err != nil || !settings.BusNearbyAlert || settings.BusNearbyMinutes == 0 || minutes > settings.BusNearbyMinutes {
+				continue
+			}
+			if !s.claim(parentID, remaining.Stop.ID, run.RunKey()) {
+				continue
+			}
+
+			message := fmt.Sprintf("The bus for %s will reach %s in about %d min.", strings.Join(group.names, ", "), remaining.Stop.Name, minutes)
+			s.deliver(group.parent, settings, StopAlertNearby, "Bus is nearby", message, map[string]interface{}{
+				"bus_id":            busID.String(),
+				"stop_id":           remaining.Stop.ID.String(),
+				"stop_name":         remaining.Stop.Name,
+				"eta_minutes":       minutes,
+				"estimated_arrival": remaining.EstimatedArrival.Format(time.RFC3339),
+				"child_names":       group.names,
+			})
+		}
+	}
+}
+
+// claim records that a parent has been sent the nearby alert for a stop on a run
+func (s *stopAlertService) claim(parentID, stopID uuid.UUID, runKey string) bool {
+	claimed, err := s.alertRepo.Claim(&models.BusStopAlert{
+		UserID: parentID,
+		StopID: stopID,
+		RunKey: runKey,
+	})
+	if err != nil {
+		log.Printf("Failed to record nearby alert for %s: %v", parentID, err)
+		return false
+	}
+	return claimed
+}
+
+// deliver creates the in-app notification and, per the parent's settings, sends it by SMS and voice call
+func (s *stopAlertService) deliver(parent *models.User, settings *models.NotificationSettings, alert StopAlert, title, message string, data map[string]interface{}) {
+	parentID := parent.ID
+	if err := s.notificationService.CreateNotification(parentID, string(alert), title, message, data); err != nil {
+		log.Printf("Failed to create %s notification for %s: %v", alert, parentID, err)
+	}
+
+	if parent.Phone == nil || *parent.Phone == "" {
+		return
+	}
+	phone := *parent.Phone
+	// SMS and voice providers call out over HTTP; keep them off the ingestion path
+	if settings.SMSEnabled {
+		go func() {
+			if err := s.smsProvider.SendSMS(phone, message); err != nil {
+				log.Printf("Failed to send %s SMS to %s: %v", alert, parentID, err)
+			}
+		}()
+	}
+	if settings.CallEnabled {
+		go func() {
+			if err := s.voiceProvider.MakeCall(phone, message); err != nil {
+				log.Printf("Failed to place %s call to %s: %v", alert, parentID, err)
+			}
+		}()
+	}
+}
+
+type parentChildren struct {
+	parent *models.User
+	names  []string
+}
+
+// groupByParent groups children by parent so each parent gets one alert naming all of them
+func groupByParent(children []models.Child) map[uuid.UUID]*parentChildren {
+	groups := make(map[uuid.UUID]*parentChildren)
+	for i := range children {
+		child := &children[i]
+		group, ok := groups[child.ParentID]
+		if !ok {
+			group = &parentChildren{parent: &child.Parent}
+			groups[child.ParentID] = group
+		}
+		group.names = append(group.names, child.Name)
+	}
+	return groups
+}
+
+// AdvanceStopVisit applies a bus position, distanceMeters away from a stop, to the
+// bus's current visit of that stop. It returns the alerts that became due and whether
+// the visit changed. A visit opens when the bus comes within nearbyMeters, records
+// arrival and departure across the stop's geofence and closes once the bus is beyond
+// nearbyMeters again; each alert fires at most once per visit.
+func AdvanceStopVisit(visit *models.BusStopVisit, distanceMeters, radiusMeters, nearbyMeters float64, now time.Time) ([]StopAlert, bool) {
+	if visit.ClosedAt != nil {
+		return nil, false
+	}
+
+	inside := distanceMeters <= radiusMeters
+	nearby := inside || distanceMeters <= nearbyMeters
+
+	var alerts []StopAlert
+	changed := false
+
+	if nearby && visit.NearbyAlertedAt == nil {
+		visit.NearbyAlertedAt = &now
+		changed = true
+		// A bus first seen inside the geofence only needs the arrival alert
+		if !inside {
+			alerts = append(alerts, StopAlertNearby)
+		}
+	}
+
+	if inside && visit.ArrivedAt == nil {
+		visit.ArrivedAt = &now
+		alerts = append(alerts, StopAlertArrived)
+		changed = true
+	}
+
+	if !inside && visit.ArrivedAt != nil && visit.DepartedAt == nil {
+		visit.DepartedAt = &now
+		alerts = append(alerts, StopAlertDeparted)
+		changed = true
+	}
+
+	if !nearby && visit.NearbyAlertedAt != nil {
+		visit.ClosedAt = &now
+		changed = true
+	}
+
+	return alerts, changed
+}
+
+func stopAlertEnabled(settings *models.NotificationSettings, alert StopAlert) bool {
+	switch alert {
+	case StopAlertNearby:
+		return settings.BusNearbyAlert
+	case StopAlertArrived:
+		return settings.BusArrived
+	case StopAlertDeparted:
+		return settings.BusDeparted
+	}
+	return false
+}
+
+func stopAlertMessage(stop *models.RouteStop, alert StopAlert, distance float64, childNames []string) (string, string) {
+	children := strings.Join(childNames, ", ")
+	switch alert {
+	case StopAlertNearby:
+		return "Bus is nearby", fmt.Sprintf("The bus for %s is about %.0f m from %s.", children, distance, stop.Name)
+	case StopAlertArrived:
+		return "Bus has arrived", fmt.Sprintf("The bus for %s has arrived at %s.", children, stop.Name)
+	default:
+		return "Bus has departed", fmt.Sprintf("The bus for %s has left %s.", children, stop.Name)
+	}
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/telemoz/backend/internal/config"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/repositories"
+	"github.com/telemoz/backend/pkg/geohash"
+)
+
+// ErrSurgeChanged is returned when a trip is requested with a lower surge multiplier
+// than the one now in effect at the pickup point
+var ErrSurgeChanged = errors.New("surge pricing has gone up since the fare was estimated")
+
+var surgeServiceTypes = []models.ServiceType{
+	models.ServiceTypeDelivery,
+	models.ServiceTypeTaxi,
+	models.ServiceTypeSchoolBus,
+}
+
+type SurgeService interface {
+	Multiplier(lat, lng float64, serviceType string) float64
+	Recompute(now time.Time) error
+}
+
+type surgeService struct {
+	surgeRepo        repositories.SurgeRepository
+	tripRepo         repositories.TripRepository
+	availabilityRepo repositories.DriverAvailabilityRepository
+	cfg              config.SurgeConfig
+	locationMaxAge   time.Duration
+}
+
+func NewSurgeService() SurgeService {
+	return &surgeService{
+		surgeRepo:        repositories.NewSurgeRepository(),
+		tripRepo:         repositories.NewTripRepository(),
+		availabilityRepo: repositories.NewDriverAvailabilityRepository(),
+		cfg:              config.AppConfig.Surge,
+		locationMaxAge:   config.AppConfig.Dispatch.LocationMaxAge,
+	}
+}
+
+// Multiplier returns the surge multiplier in effect at a point, 1 when the cell isn't
+// surging or its reading is older than the demand window
+func (s *surgeService) Multiplier(lat, lng float64, serviceType string) float64 {
+	if !s.cfg.Enabled {
+		return 1
+	}
+	zone, err := s.surgeRepo.Find(geohash.Encode(lat, lng, s.cfg.CellPrecision), serviceType)
+	if err != nil || zone.UpdatedAt.Before(time.Now().Add(-s.cfg.Window)) || zone.Multiplier < 1 {
+		return 1
+	}
+	return zone.Multiplier
+}
+
+// Recompute counts demand and supply per cell and updates the multipliers of cells
+// with demand and of cells still surging from earlier readings, so they ease back to 1
+func (s *surgeService) Recompute(now time.Time) error {
+	trips, err := s.tripRepo.FindSearchStartedSince(now.Add(-s.cfg.Window))
+	if err != nil {
+		return err
+	}
+	demand := CountSurgeDemand(trips, s.cfg.CellPrecision)
+
+	supply := make(map[SurgeCell]int)
+	for _, serviceType := range surgeServiceTypes {
+		drivers, err := s.availabilityRepo.FindAvailableByServiceType(string(serviceType))
+		if err != nil {
+			return err
+		}
+		for cell, count := range CountSurgeSupply(drivers, string(serviceType), s.cfg.CellPrecision, now.Add(-s.locationMaxAge)) {
+			supply[cell] = count
+		}
+	}
+
+	surging, err := s.surgeRepo.FindSurging()
+	if err != nil {
+		return err
+	}
+	previous := make(map[SurgeCell]float64, len(surging))
+	for _, zone := range surging {
+		previous[SurgeCell{Cell: zone.Cell, ServiceType: zone.ServiceType}] = zone.Multiplier
+	}
+
+	cells := make(map[SurgeCell]bool, len(demand)+len(previous))
+	for cell := range demand {
+		cells[cell] = true
+	}
+	for cell := range previous {
+		cells[cell] = true
+	}
+
+	for cell := range cells {
+		last, wasSurging := previous[cell]
+		if !wasSurging {
+			last = 1
+		}
+		multiplier := SurgeMultiplier(demand[cell], supply[cell], last, s.cfg)
+		if multiplier <= 1 && !wasSurging {
+			continue
+		}
+		zone := &models.SurgeZone{
+			Cell:        cell.Cell,
+			ServiceType: cell.ServiceType,
+			Demand:      demand[cell],
+			Supply:      supply[cell],
+			Multiplier:  multiplier,
+			UpdatedAt:   now,
+		}
+		if err := s.surgeRepo.Save(zone); err != nil {
+			return err
+		}
+	}
+
+	return s.surgeRepo.DeleteUpdatedBefore(now.Add(-s.cfg.Window))
+}
+
+// SurgeCell is a geohash cell for one service type
+type SurgeCell struct {
+	Cell        string
+	ServiceType string
+}
+
+// CountSurgeDemand counts trips by the cell of their pickup point
+func CountSurgeDemand(trips []models.Trip, precision int) map[SurgeCell]int {
+	demand := make(map[SurgeCell]int)
+	for _, trip := range trips {
+		cell := SurgeCell{
+			Cell:        geohash.Encode(trip.PickupLatitude, trip.PickupLongitude, precision),
+			ServiceType: string(trip.ServiceType),
+		}
+		demand[cell]++
+	}
+	return demand
+}
+
+// CountSurgeSupply counts available drivers by the cell they are in. Drivers without a
+// position or with a position reported before locationSince are left out.
+func CountSurgeSupply(drivers []models.DriverAvailability, serviceType string, precision int, locationSince time.Time) map[SurgeCell]int {
+	supply := make(map[SurgeCell]int)
+	for _, driver := range drivers {
+		if driver.Latitude == nil || driver.Longitude == nil || driver.LocationUpdatedAt == nil {
+			continue
+		}
+		if driver.LocationUpdatedAt.Before(locationSince) {
+			continue
+		}
+		cell := SurgeCell{
+			Cell:        geohash.Encode(*driver.Latitude, *driver.Longitude, precision),
+			ServiceType: serviceType,
+		}
+		supply[cell]++
+	}
+	return supply
+}
+
+// SurgeMultiplier computes a cell's next multiplier from its demand, supply and
+// previous multiplier. The target rises linearly with searching trips per driver above
+// the threshold; the result moves from previous towards it by the smoothing factor,
+// stays between 1 and the maximum and is rounded down to the cent.
+func SurgeMultiplier(demand, supply int, previous float64, cfg config.SurgeConfig) float64 {
+	target := 1.0
+	if demand >= cfg.MinDemand {
+		ratio := float64(demand) / math.Max(float64(supply), 1)
+		if ratio > cfg.Threshold {
+			target = 1 + (ratio-cfg.Threshold)*cfg.Sensitivity
+		}
+	}
+
+	smoothing := cfg.Smoothing
+	if smoothing <= 0 || smoothing > 1 {
+		smoothing = 1
+	}
+	previous = math.Max(previous, 1)
+	multiplier := previous + (target-previous)*smoothing
+
+	multiplier = math.Min(multiplier, math.Max(cfg.MaxMultiplier, 1))
+	multiplier = math.Max(multiplier, 1)
+
+	// Rounding down lets a cell settle at exactly 1 instead of hovering just above it
+	return math.Floor(multiplier*100+1e-9) / 100
+}
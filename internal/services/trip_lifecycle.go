@@ -0,0 +1,509 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/config"
+	"github.com/telemoz/backend/internal/dto"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/repositories"
+)
+
+var (
+	// ErrIllegalTransition means the trip cannot move from its current status to the requested one
+	ErrIllegalTransition = errors.New("illegal trip status transition")
+	// ErrTransitionForbidden means the transition exists but the actor may not make it
+	ErrTransitionForbidden = errors.New("trip status transition not allowed for this user")
+	// ErrTripStatusChanged means another request changed the trip first
+	ErrTripStatusChanged = errors.New("trip status was changed by another request")
+	// ErrJobTaken means another driver accepted the job first
+	ErrJobTaken = errors.New("job has already been taken by another driver")
+)
+
+// TransitionError describes a rejected trip status change. It unwraps to
+// ErrIllegalTransition or ErrTransitionForbidden.
+type TransitionError struct {
+	From  models.TripStatus
+	To    models.TripStatus
+	Actor models.TripActor
+	Err   error
+}
+
+func (e *TransitionError) Error() string {
+	if errors.Is(e.Err, ErrTransitionForbidden) {
+		return fmt.Sprintf("a %s cannot move a trip from %s to %s", e.Actor, e.From, e.To)
+	}
+	return fmt.Sprintf("trip cannot move from %s to %s", e.From, e.To)
+}
+
+func (e *TransitionError) Unwrap() error {
+	return e.Err
+}
+
+// tripTransitions lists, for each status, the statuses a trip may move to and who may move it there
+var tripTransitions = map[models.TripStatus]map[models.TripStatus][]models.TripActor{
+	models.TripStatusPending: {
+		models.TripStatusSearching: {models.TripActorSystem},
+		models.TripStatusCancelled: {models.TripActorCustomer, models.TripActorSystem},
+	},
+	models.TripStatusSearching: {
+		models.TripStatusAccepted:  {models.TripActorDriver},
+		models.TripStatusCancelled: {models.TripActorCustomer, models.TripActorSystem},
+		models.TripStatusExpired:   {models.TripActorSystem},
+	},
+	// Drivers who give up an assigned trip send it back to searching
+	models.TripStatusAccepted: {
+		models.TripStatusArrived:   {models.TripActorDriver},
+		models.TripStatusSearching: {models.TripActorDriver},
+		models.TripStatusCancelled: {models.TripActorCustomer, models.TripActorSystem},
+	},
+	models.TripStatusArrived: {
+		models.TripStatusInProgress: {models.TripActorDriver},
+		models.TripStatusNoShow:     {models.TripActorDriver},
+		models.TripStatusSearching:  {models.TripActorDriver},
+		models.TripStatusCancelled:  {models.TripActorCustomer, models.TripActorSystem},
+	},
+	models.TripStatusInProgress: {
+		models.TripStatusCompleted: {models.TripActorDriver},
+	},
+}
+
+// jobStatusForTrip is the status a trip's job takes as the trip moves through its lifecycle
+var jobStatusForTrip = map[models.TripStatus]models.JobStatus{
+	models.TripStatusSearching:  models.JobStatusPending,
+	models.TripStatusAccepted:   models.JobStatusAccepted,
+	models.TripStatusArrived:    models.JobStatusArrived,
+	models.TripStatusInProgress: models.JobStatusInProgress,
+	models.TripStatusCompleted:  models.JobStatusCompleted,
+	models.TripStatusCancelled:  models.JobStatusCancelled,
+	models.TripStatusExpired:    models.JobStatusExpired,
+	models.TripStatusNoShow:     models.JobStatusNoShow,
+}
+
+// ValidateTripTransition checks that actor may move a trip from one status to
+// another. It returns a *TransitionError when the move is rejected.
+func ValidateTripTransition(from, to models.TripStatus, actor models.TripActor) error {
+	actors, ok := tripTransitions[from][to]
+	if !ok {
+		return &TransitionError{From: from, To: to, Actor: actor, Err: ErrIllegalTransition}
+	}
+	for _, allowed := range actors {
+		if allowed == actor {
+			return nil
+		}
+	}
+	return &TransitionError{From: from, To: to, Actor: actor, Err: ErrTransitionForbidden}
+}
+
+// JobStatusForTrip returns the job status matching a trip status
+func JobStatusForTrip(status models.TripStatus) (models.JobStatus, bool) {
+	jobStatus, ok := jobStatusForTrip[status]
+	return jobStatus, ok
+}
+
+// tripTransition is a requested status change and who is making it
+type tripTransition struct {
+	To       models.TripStatus
+	Actor    models.TripActor
+	ActorID  *uuid.UUID
+	Location *dto.Location
+	Note     string
+	// Reason is the reason code of a driver giving the trip up
+	Reason models.DriverCancelReason
+	// Offer is the driver's offer being accepted, if any
+	Offer *models.JobOffer
+}
+
+// tripLifecycle is the only place trip and job statuses change. Every change is
+// validated against tripTransitions, applied to the trip and its job together and
+// recorded in trip_events.
+type tripLifecycle struct {
+	tripRepo         repositories.TripRepository
+	eventRepo        repositories.TripEventRepository
+	availabilityRepo repositories.DriverAvailabilityRepository
+	meter            *tripMeter
+	payments         *paymentService
+	wallet           *walletService
+	promos           *promoService
+	commission       config.CommissionConfig
+	cancellation     config.CancellationConfig
+}
+
+func newTripLifecycle() *tripLifecycle {
+	return &tripLifecycle{
+		tripRepo:         repositories.NewTripRepository(),
+		eventRepo:        repositories.NewTripEventRepository(),
+		availabilityRepo: repositories.NewDriverAvailabilityRepository(),
+		meter:            newTripMeter(),
+		payments:         newPaymentService(defaultPaymentGateway()),
+		wallet:           newWalletService(defaultPaymentGateway()),
+		promos:           newPromoService(),
+		commission:       config.AppConfig.Commission,
+		cancellation:     config.AppConfig.Cancel,
+	}
+}
+
+// started records the creation of a trip as the first event of its timeline
+func (l *tripLifecycle) started(trip *models.Trip) error {
+	event := &models.TripEvent{
+		TripID:    trip.ID,
+		ToStatus:  trip.Status,
+		ActorType: models.TripActorCustomer,
+		ActorID:   &trip.CustomerID,
+		Latitude:  &trip.PickupLatitude,
+		Longitude: &trip.PickupLongitude,
+	}
+	return l.eventRepo.Create(event)
+}
+
+// apply moves trip (and job, when given) to t.To. On success the trip and job
+// reflect the new state and subscribers have been told.
+func (l *tripLifecycle) apply(trip *models.Trip, job *models.Job, t tripTransition) error {
+	from := trip.Status
+	if err := ValidateTripTransition(from, t.To, t.Actor); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if t.To == models.TripStatusNoShow && !NoShowDue(l.cancellation, trip.ArrivedAt, now) {
+		return ErrNoShowTooEarly
+	}
+	if t.To == models.TripStatusCompleted && NextWaypoint(trip.Waypoints) != nil {
+		return ErrWaypointsOutstanding
+	}
+
+	// Restored if the transition cannot be written
+	previousTrip := *trip
+	var previousJob models.Job
+	if job != nil {
+		previousJob = *job
+	}
+
+	requeue := t.To == models.TripStatusSearching && from != models.TripStatusPending
+	trip.Status = t.To
+	if from == models.TripStatusSearching {
+		trip.SearchEndedAt = &now
+	}
+
+	// The meter runs from the start of the trip; on completion the track is priced and
+	// the final fare replaces the upfront one
+	var receipt *models.TripReceipt
+	switch t.To {
+	case models.TripStatusArrived:
+		trip.ArrivedAt = &now
+	case models.TripStatusInProgress:
+		trip.StartedAt = &now
+	case models.TripStatusSearching:
+		// Scheduled trips start searching when they are due; a requeued trip is
+		// dispatched again from scratch
+		trip.SearchStartedAt = &now
+		trip.SearchEndedAt = nil
+		if requeue {
+			trip.DriverID = nil
+			trip.ArrivedAt = nil
+			trip.EstimatedArrival = nil
+		}
+	case models.TripStatusCompleted:
+		trip.CompletedAt = &now
+		receipt = l.meter.receipt(trip, now)
+		trip.FareAmount = &receipt.FinalFare
+		trip.DiscountAmount = receipt.Discount
+	}
+	if t.To == models.TripStatusCancelled {
+		trip.CancelledBy = t.ActorID
+		if t.Note != "" {
+			reason := t.Note
+			trip.CancellationReason = &reason
+		}
+	}
+
+	// Customers pay for late cancellations and no-shows
+	switch {
+	case t.To == models.TripStatusCancelled && t.Actor == models.TripActorCustomer:
+		var acceptedAt *time.Time
+		if job != nil {
+			acceptedAt = job.AcceptedAt
+		}
+		trip.CancellationFee = CancellationFee(l.cancellation, from, acceptedAt, trip.FareAmount, now)
+	case t.To == models.TripStatusNoShow:
+		trip.CancellationFee = NoShowFee(l.cancellation, trip.FareAmount)
+	}
+
+	event := &models.TripEvent{
+		TripID:     trip.ID,
+		FromStatus: from,
+		ToStatus:   t.To,
+		ActorType:  t.Actor,
+		ActorID:    t.ActorID,
+	}
+	if note := transitionNote(t); note != "" {
+		event.Note = &note
+	}
+	if lat, lng, ok := l.locate(t); ok {
+		event.Latitude = &lat
+		event.Longitude = &lng
+	}
+
+	var jobFrom models.JobStatus
+	var earning *models.DriverEarning
+	if job != nil {
+		jobFrom = job.Status
+		event.JobID = &job.ID
+		if jobStatus, ok := JobStatusForTrip(t.To); ok {
+			job.Status = jobStatus
+		}
+		job.WaveEndsAt = nil
+		switch t.To {
+		case models.TripStatusAccepted:
+			job.AcceptedAt = &now
+		case models.TripStatusSearching:
+			if requeue {
+				job.DriverID = nil
+				job.AcceptedAt = nil
+				job.DispatchWave = 0
+			}
+		case models.TripStatusCancelled, models.TripStatusNoShow:
+			// The driver is compensated out of the customer's fee, when it is collected
+			if job.DriverID != nil && trip.CancellationFee > 0 && FeeCollectable(trip.PaymentMethod) {
+				earning = newEarningEntry(*job.DriverID, job.ID, models.EarningTypeCancellation, trip.CancellationFee,
+					CommissionPercent(l.commission, string(trip.ServiceType)), now)
+				job.ActualEarnings = &earning.Amount
+			}
+		case models.TripStatusCompleted:
+			job.CompletedAt = &now
+			// The driver is credited their share of the fare as the trip completes; promo
+			// discounts are on the platform
+			if job.DriverID != nil {
+				fare := trip.DiscountAmount
+				if trip.FareAmount != nil {
+					fare += *trip.FareAmount
+				}
+				earning = newEarningEntry(*job.DriverID, job.ID, models.EarningTypeTrip, fare,
+					CommissionPercent(l.commission, string(trip.ServiceType)), now)
+				job.ActualEarnings = &earning.Amount
+			}
+		}
+	}
+
+	var cancellation *models.TripCancellation
+	if t.To == models.TripStatusCancelled || t.To == models.TripStatusNoShow || requeue {
+		cancellation = &models.TripCancellation{
+			TripID:     trip.ID,
+			DriverID:   previousTrip.DriverID,
+			FromStatus: from,
+			ToStatus:   t.To,
+			ActorType:  t.Actor,
+			ActorID:    t.ActorID,
+			Fee:        trip.CancellationFee,
+		}
+		if job != nil {
+			cancellation.JobID = &job.ID
+		}
+		if t.Reason != "" {
+			reasonCode := t.Reason
+			cancellation.ReasonCode = &reasonCode
+		}
+		if t.Note != "" {
+			reason := t.Note
+			cancellation.Reason = &reason
+		}
+		if earning != nil {
+			cancellation.DriverCompensation = earning.Amount
+		}
+	}
+
+	if t.Offer != nil {
+		t.Offer.Status = models.JobOfferStatusAccepted
+		t.Offer.RespondedAt = &now
+	}
+
+	if err := l.tripRepo.ApplyTransition(&repositories.TripTransition{
+		Trip:          trip,
+		FromStatus:    from,
+		Job:           job,
+		JobFromStatus: jobFrom,
+		Offer:         t.Offer,
+		Earning:       earning,
+		Receipt:       receipt,
+		Cancellation:  cancellation,
+		Event:         event,
+	}); err != nil {
+		*trip = previousTrip
+		if job != nil {
+			*job = previousJob
+		}
+		if errors.Is(err, repositories.ErrStatusChanged) {
+			return ErrTripStatusChanged
+		}
+		return errors.New("failed to update trip status")
+	}
+
+	publishTripStatus(trip)
+	l.settlePayment(trip, job)
+	return nil
+}
+
+// settlePayment moves a card or wallet trip's payment along with the trip: the fare
+// is held when a driver accepts, charged on completion and released when the trip
+// ends any other way, less any cancellation fee. The hold stays in place while a
+// trip its driver gave up is dispatched again. A trip whose fare cannot be held is
+// cancelled. Trips that end without completing also give back their promo code
+// redemption.
+func (l *tripLifecycle) settlePayment(trip *models.Trip, job *models.Job) {
+	switch trip.Status {
+	case models.TripStatusCancelled, models.TripStatusExpired, models.TripStatusNoShow:
+		l.promos.release(trip)
+	}
+
+	switch trip.PaymentMethod {
+	case models.PaymentTypeCard:
+		l.settleCard(trip, job)
+	case models.PaymentTypeWallet:
+		l.settleWallet(trip, job)
+	}
+}
+
+func (l *tripLifecycle) settleCard(trip *models.Trip, job *models.Job) {
+	switch trip.Status {
+	case models.TripStatusAccepted:
+		if err := l.payments.authorize(trip); err != nil {
+			if cancelErr := l.apply(trip, job, tripTransition{
+				To:    models.TripStatusCancelled,
+				Actor: models.TripActorSystem,
+				Note:  "payment authorization failed: " + err.Error(),
+			}); cancelErr != nil {
+				log.Printf("Failed to cancel trip %s after payment authorization failed: %v", trip.ID, cancelErr)
+			}
+		}
+	case models.TripStatusCompleted:
+		var fare float64
+		if trip.FareAmount != nil {
+			fare = *trip.FareAmount
+		}
+		l.payments.capture(trip, fare)
+	case models.TripStatusCancelled, models.TripStatusExpired, models.TripStatusNoShow:
+		l.payments.capture(trip, trip.CancellationFee)
+	}
+}
+
+func (l *tripLifecycle) settleWallet(trip *models.Trip, job *models.Job) {
+	switch trip.Status {
+	case models.TripStatusAccepted:
+		if err := l.wallet.hold(trip); err != nil {
+			if cancelErr := l.apply(trip, job, tripTransition{
+				To:    models.TripStatusCancelled,
+				Actor: models.TripActorSystem,
+				Note:  "wallet hold failed: " + err.Error(),
+			}); cancelErr != nil {
+				log.Printf("Failed to cancel trip %s after wallet hold failed: %v", trip.ID, cancelErr)
+			}
+		}
+	case models.TripStatusCompleted:
+		var fare float64
+		if trip.FareAmount != nil {
+			fare = *trip.FareAmount
+		}
+		l.wallet.settle(trip, fare)
+	case models.TripStatusCancelled, models.TripStatusExpired, models.TripStatusNoShow:
+		l.wallet.settle(trip, trip.CancellationFee)
+	}
+}
+
+// transitionNote is the timeline note of a transition: the driver's reason code for
+// giving a trip up, followed by any note
+func transitionNote(t tripTransition) string {
+	switch {
+	case t.Reason == "":
+		return t.Note
+	case t.Note == "":
+		return string(t.Reason)
+	default:
+		return string(t.Reason) + ": " + t.Note
+	}
+}
+
+// accept assigns a searching trip and its job to a driver. Concurrent acceptances
+// are settled by the database: exactly one driver wins and the others get ErrJobTaken.
+func (l *tripLifecycle) accept(trip *models.Trip, job *models.Job, offer *models.JobOffer, driverID uuid.UUID) error {
+	if trip.DriverID != nil && *trip.DriverID != driverID {
+		return ErrJobTaken
+	}
+
+	trip.DriverID = &driverID
+	if job != nil {
+		job.DriverID = &driverID
+	}
+	err := l.apply(trip, job, tripTransition{
+		To:      models.TripStatusAccepted,
+		Actor:   models.TripActorDriver,
+		ActorID: &driverID,
+		Offer:   offer,
+	})
+	if err == nil {
+		return nil
+	}
+
+	trip.DriverID = nil
+	if job != nil {
+		job.DriverID = nil
+	}
+	var transitionErr *TransitionError
+	if errors.Is(err, ErrTripStatusChanged) ||
+		(errors.As(err, &transitionErr) && transitionErr.From == models.TripStatusAccepted) {
+		return ErrJobTaken
+	}
+	return err
+}
+
+// locate returns where a transition happened: the location given with the request
+// or, for drivers, their last reported position
+func (l *tripLifecycle) locate(t tripTransition) (float64, float64, bool) {
+	if t.Location != nil {
+		return t.Location.Latitude, t.Location.Longitude, true
+	}
+	if t.Actor != models.TripActorDriver || t.ActorID == nil {
+		return 0, 0, false
+	}
+
+	availability, err := l.availabilityRepo.FindByDriverID(*t.ActorID)
+	if err != nil || availability.Latitude == nil || availability.Longitude == nil {
+		return 0, 0, false
+	}
+	return *availability.Latitude, *availability.Longitude, true
+}
+
+// timeline returns a trip's recorded events in order
+func (l *tripLifecycle) timeline(tripID uuid.UUID) ([]dto.TripEventResponse, error) {
+	events, err := l.eventRepo.FindByTripID(tripID)
+	if err != nil {
+		return nil, errors.New("failed to fetch trip timeline")
+	}
+
+	responses := make([]dto.TripEventResponse, len(events))
+	for i, event := range events {
+		response := dto.TripEventResponse{
+			ID:         event.ID.String(),
+			FromStatus: string(event.FromStatus),
+			ToStatus:   string(event.ToStatus),
+			ActorType:  string(event.ActorType),
+			Note:       event.Note,
+			CreatedAt:  event.CreatedAt.Format(time.RFC3339),
+		}
+		if event.ActorID != nil {
+			actorID := event.ActorID.String()
+			response.ActorID = &actorID
+		}
+		if event.Latitude != nil && event.Longitude != nil {
+			response.Location = &dto.Location{
+				Latitude:  *event.Latitude,
+				Longitude: *event.Longitude,
+			}
+		}
+		responses[i] = response
+	}
+	return responses, nil
+}
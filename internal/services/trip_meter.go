@@ -0,0 +1,205 @@
+package services
+
+import (
+	"math"
+	"time"
+
+	"github.com/telemoz/backend/internal/config"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/repositories"
+)
+
+const (
+	// MeteredDistanceGPS is a distance measured along the recorded track
+	MeteredDistanceGPS = "gps"
+	// MeteredDistanceEstimated is the trip's estimated distance, used when the track is too sparse
+	MeteredDistanceEstimated = "estimated"
+)
+
+// TrackMeasurement is the distance driven and time spent waiting along a track
+type TrackMeasurement struct {
+	DistanceKm     float64
+	WaitingMinutes float64
+	// Points is the number of track points that passed the noise filters
+	Points int
+}
+
+// tripMeter records the track of trips in progress and turns it into the final fare
+// when they complete
+type tripMeter struct {
+	trackRepo     repositories.TripTrackRepository
+	fareTableRepo repositories.FareTableRepository
+	promos        *promoService
+	cfg           config.MeterConfig
+}
+
+func newTripMeter() *tripMeter {
+	return &tripMeter{
+		trackRepo:     repositories.NewTripTrackRepository(),
+		fareTableRepo: repositories.NewFareTableRepository(),
+		promos:        newPromoService(),
+		cfg:           config.AppConfig.Meter,
+	}
+}
+
+// record adds the driver's reported position to the track of the trip they are
+// driving; positions reported outside the in progress phase are not metered
+func (m *tripMeter) record(trip *models.Trip, availability *models.DriverAvailability) error {
+	if trip.Status != models.TripStatusInProgress || availability.Latitude == nil || availability.Longitude == nil {
+		return nil
+	}
+
+	recordedAt := time.Now()
+	if availability.LocationUpdatedAt != nil {
+		recordedAt = *availability.LocationUpdatedAt
+	}
+	return m.trackRepo.Create(&models.TripTrackPoint{
+		TripID:     trip.ID,
+		Latitude:   *availability.Latitude,
+		Longitude:  *availability.Longitude,
+		Accuracy:   availability.Accuracy,
+		Speed:      availability.Speed,
+		RecordedAt: recordedAt,
+	})
+}
+
+// receipt meters a trip completing at completedAt. It prices the driven distance,
+// moving time and waiting time under the fare table the trip was quoted with, and
+// takes the trip's promo code discount off the result.
+func (m *tripMeter) receipt(trip *models.Trip, completedAt time.Time) *models.TripReceipt {
+	points, err := m.trackRepo.FindByTripID(trip.ID)
+	if err != nil {
+		points = nil
+	}
+	measurement := MeasureTrack(points, m.cfg)
+
+	startedAt := completedAt
+	if trip.StartedAt != nil {
+		startedAt = *trip.StartedAt
+	} else if len(points) > 0 {
+		startedAt = points[0].RecordedAt
+	}
+	duration := math.Max(completedAt.Sub(startedAt).Minutes(), 0)
+	waiting := math.Min(measurement.WaitingMinutes, duration)
+
+	receipt := &models.TripReceipt{
+		TripID:          trip.ID,
+		FareTableID:     trip.FareTableID,
+		DistanceMethod:  MeteredDistanceGPS,
+		DistanceKm:      roundFare(measurement.DistanceKm),
+		DurationMinutes: roundFare(duration),
+		WaitingMinutes:  roundFare(waiting),
+		SurgeMultiplier: math.Max(trip.SurgeMultiplier, 1),
+	}
+	if trip.FareAmount != nil {
+		receipt.UpfrontFare = roundFare(*trip.FareAmount + trip.DiscountAmount)
+	}
+	if measurement.Points < 2 && trip.EstimatedDistance != nil {
+		receipt.DistanceMethod = MeteredDistanceEstimated
+		receipt.DistanceKm = *trip.EstimatedDistance
+	}
+
+	table := m.fareTable(trip)
+	airport := InFareZones(table.AirportZones, trip.PickupLatitude, trip.PickupLongitude) ||
+		InFareZones(table.AirportZones, trip.DropoffLatitude, trip.DropoffLongitude)
+	breakdown := ComputeFare(table, receipt.DistanceKm, duration-waiting, receipt.SurgeMultiplier, startedAt, airport)
+
+	receipt.BaseFare = breakdown.BaseFare
+	receipt.DistanceFare = breakdown.DistanceFare
+	receipt.TimeFare = breakdown.TimeFare
+	receipt.WaitingCharge = WaitingCharge(table, waiting)
+	receipt.SurgeAmount = breakdown.SurgeAmount
+	receipt.NightSurcharge = breakdown.NightSurcharge
+	receipt.MinimumFareTopUp = breakdown.MinimumFareTopUp
+	receipt.BookingFee = breakdown.BookingFee
+	receipt.AirportSurcharge = breakdown.AirportSurcharge
+	if trip.Package != nil {
+		receipt.WeightSurcharge = trip.Package.WeightSurcharge
+	}
+	receipt.MeteredFare = roundFare(breakdown.Total + receipt.WaitingCharge + receipt.WeightSurcharge)
+	fare := receipt.MeteredFare
+	if trip.FareAmount != nil {
+		fare = BoundFare(receipt.UpfrontFare, receipt.MeteredFare, m.cfg.FareTolerancePercent)
+	}
+	receipt.PromoCode = trip.PromoCode
+	receipt.Discount = m.promos.discount(trip, fare)
+	receipt.FinalFare = roundFare(fare - receipt.Discount)
+	return receipt
+}
+
+// fareTable returns the fare table a trip was quoted with, or the built-in prices
+func (m *tripMeter) fareTable(trip *models.Trip) *models.FareTable {
+	if trip.FareTableID != nil {
+		if table, err := m.fareTableRepo.FindByID(*trip.FareTableID); err == nil {
+			return table
+		}
+	}
+	return defaultFareTable(string(trip.ServiceType))
+}
+
+// MeasureTrack measures the distance driven along a track, in recording order, and the
+// time spent waiting. Inaccurate points and impossible jumps are dropped, and
+// movements below the jitter threshold count as standing still.
+func MeasureTrack(points []models.TripTrackPoint, cfg config.MeterConfig) TrackMeasurement {
+	var measurement TrackMeasurement
+	var anchor *models.TripTrackPoint
+	var anchorTime time.Time
+
+	for i := range points {
+		point := &points[i]
+		if cfg.MaxAccuracyMeters > 0 && point.Accuracy != nil && *point.Accuracy > cfg.MaxAccuracyMeters {
+			continue
+		}
+		if anchor == nil {
+			anchor = point
+			anchorTime = point.RecordedAt
+			measurement.Points++
+			continue
+		}
+
+		hours := point.RecordedAt.Sub(anchorTime).Hours()
+		if hours <= 0 {
+			continue
+		}
+		distance := calculateHaversineDistance(anchor.Latitude, anchor.Longitude, point.Latitude, point.Longitude)
+		if cfg.MaxSpeedKmh > 0 && distance/hours > cfg.MaxSpeedKmh {
+			continue
+		}
+
+		if distance*1000 < cfg.MinMoveMeters {
+			// Standing still: the time passes but the position stays put so jitter
+			// doesn't add up to distance
+			measurement.WaitingMinutes += hours * 60
+			measurement.Points++
+			anchorTime = point.RecordedAt
+			continue
+		}
+		if distance/hours < cfg.WaitingSpeedKmh {
+			measurement.WaitingMinutes += hours * 60
+		}
+		measurement.DistanceKm += distance
+		measurement.Points++
+		anchor = point
+		anchorTime = point.RecordedAt
+	}
+	return measurement
+}
+
+// WaitingCharge prices waiting time beyond the table's free allowance
+func WaitingCharge(table *models.FareTable, waitingMinutes float64) float64 {
+	chargeable := waitingMinutes - table.FreeWaitingMinutes
+	if chargeable <= 0 {
+		return 0
+	}
+	return roundFare(chargeable * table.WaitingPerMinuteRate)
+}
+
+// BoundFare keeps a metered fare within tolerancePercent of the upfront fare
+func BoundFare(upfront, metered, tolerancePercent float64) float64 {
+	if tolerancePercent < 0 {
+		return metered
+	}
+	low := upfront * (1 - tolerancePercent/100)
+	high := upfront * (1 + tolerancePercent/100)
+	return roundFare(math.Min(math.Max(metered, low), high))
+}
@@ -2,9 +2,11 @@ package services
 
 import (
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/config"
 	"github.com/telemoz/backend/internal/dto"
 	"github.com/telemoz/backend/internal/models"
 	"github.com/telemoz/backend/internal/repositories"
@@ -13,34 +15,73 @@ import (
 
 type TripService interface {
 	CreateTrip(customerID uuid.UUID, req dto.CreateTripRequest) (*dto.TripResponse, error)
-	EstimateFare(req dto.EstimateFareRequest) (*dto.EstimateFareResponse, error)
+	EstimateFare(customerID uuid.UUID, req dto.EstimateFareRequest) (*dto.EstimateFareResponse, error)
 	GetActiveTrip(customerID uuid.UUID) (*dto.TripResponse, error)
 	GetTripHistory(customerID uuid.UUID, limit, offset int) ([]dto.TripResponse, error)
 	GetTripByID(tripID uuid.UUID) (*dto.TripResponse, error)
-	UpdateTrip(tripID uuid.UUID, req dto.UpdateTripRequest) (*dto.TripResponse, error)
-	CancelTrip(tripID uuid.UUID, customerID uuid.UUID) error
+	UpdateTrip(tripID, customerID uuid.UUID, req dto.UpdateTripRequest) (*dto.TripResponse, error)
+	CancelTrip(tripID uuid.UUID, customerID uuid.UUID, req dto.CancelTripRequest) (*dto.TripResponse, error)
+	GetCancellationFee(tripID, customerID uuid.UUID) (*dto.CancellationFeeResponse, error)
 	AcceptTrip(tripID uuid.UUID, driverID uuid.UUID) error
 	GetTripStatus(tripID uuid.UUID) (string, error)
 	ExpireSearchingTrips() error
+	GetTripTimeline(tripID, userID uuid.UUID) ([]dto.TripEventResponse, error)
+	GetTripReceipt(tripID, userID uuid.UUID) (*models.TripReceipt, error)
 }
 
 type tripService struct {
-	tripRepo       repositories.TripRepository
-	jobRepo        repositories.JobRepository
-	userRepo       repositories.UserRepository
-	pricingService PricingService
+	tripRepo        repositories.TripRepository
+	jobRepo         repositories.JobRepository
+	userRepo        repositories.UserRepository
+	pricingService  PricingService
+	dispatchService DispatchService
+	lifecycle       *tripLifecycle
+	cancellation    config.CancellationConfig
+	scheduling      config.SchedulingConfig
+	delivery        config.DeliveryConfig
+	receiptRepo     repositories.TripReceiptRepository
+	payments        *paymentService
+	promos          *promoService
+	wallet          *walletService
+	quotes          *QuoteSigner
+	quoteTolerance  float64
 }
 
 func NewTripService() TripService {
+	return newTripService()
+}
+
+func newTripService() *tripService {
 	return &tripService{
-		tripRepo:       repositories.NewTripRepository(),
-		jobRepo:        repositories.NewJobRepository(),
-		userRepo:       repositories.NewUserRepository(),
-		pricingService: NewPricingService(),
+		tripRepo:        repositories.NewTripRepository(),
+		jobRepo:         repositories.NewJobRepository(),
+		userRepo:        repositories.NewUserRepository(),
+		pricingService:  NewPricingService(),
+		dispatchService: NewDispatchService(),
+		lifecycle:       newTripLifecycle(),
+		cancellation:    config.AppConfig.Cancel,
+		scheduling:      config.AppConfig.Scheduling,
+		delivery:        config.AppConfig.Delivery,
+		receiptRepo:     repositories.NewTripReceiptRepository(),
+		payments:        newPaymentService(defaultPaymentGateway()),
+		promos:          newPromoService(),
+		wallet:          newWalletService(defaultPaymentGateway()),
+		quotes:          NewQuoteSigner(config.AppConfig.Quote.SigningKey, config.AppConfig.Quote.TTL),
+		quoteTolerance:  config.AppConfig.Quote.RouteToleranceMeters,
 	}
 }
 
 func (s *tripService) CreateTrip(customerID uuid.UUID, req dto.CreateTripRequest) (*dto.TripResponse, error) {
+	// The drop-off of a multi-stop trip is its last stop
+	if len(req.Waypoints) > 0 {
+		for _, waypoint := range req.Waypoints {
+			if !utils.ValidateCoordinates(waypoint.Location.Latitude, waypoint.Location.Longitude) {
+				return nil, errors.New("invalid waypoint coordinates")
+			}
+		}
+		req.DropoffLocation = req.Waypoints[len(req.Waypoints)-1].Location
+	}
+
 	// Validate coordinates
 	if !utils.ValidateCoordinates(req.PickupLocation.Latitude, req.PickupLocation.Longitude) {
 		return nil, errors.New("invalid pickup coordinates")
@@ -49,18 +90,56 @@ func (s *tripService) CreateTrip(customerID uuid.UUID, req dto.CreateTripRequest
 		return nil, errors.New("invalid dropoff coordinates")
 	}
 
-	// Calculate fare using pricing service
-	distance, fare, duration := s.pricingService.EstimateFare(
-		req.PickupLocation.Latitude,
-		req.PickupLocation.Longitude,
-		req.DropoffLocation.Latitude,
-		req.DropoffLocation.Longitude,
-		req.ServiceType,
-	)
+	// Deliveries are sized and surcharged by their package
+	tripPackage, err := newTripPackage(req.Package, req.ServiceType, s.delivery.WeightSurcharges)
+	if err != nil {
+		return nil, err
+	}
 
 	// Set search started time
 	now := time.Now()
 
+	var scheduledAt *time.Time
+	if req.ScheduledAt != "" {
+		pickupAt, err := time.Parse(time.RFC3339, req.ScheduledAt)
+		if err != nil {
+			return nil, ErrInvalidScheduledAt
+		}
+		if err := ValidateScheduledAt(s.scheduling, pickupAt, now); err != nil {
+			return nil, err
+		}
+		scheduledAt = &pickupAt
+	}
+
+	estimate, quoteID, err := s.priceTrip(customerID, req, tripPackage)
+	if err != nil {
+		return nil, err
+	}
+
+	// A promo code comes off the fare the customer pays
+	fare := estimate.Fare
+	var promo *models.PromoCode
+	var discount float64
+	if req.PromoCode != "" {
+		promo, discount, err = s.promos.apply(req.PromoCode, customerID, req.ServiceType, estimate.Fare)
+		if err != nil {
+			return nil, err
+		}
+		fare = roundFare(estimate.Fare - discount)
+	}
+
+	// Card trips are charged to a saved card, held once a driver accepts
+	paymentMethodID, err := s.payments.methodForTrip(customerID, req.PaymentMethod, req.PaymentMethodID)
+	if err != nil {
+		return nil, err
+	}
+	// Wallet trips need the balance to cover the fare up front
+	if req.PaymentMethod == models.PaymentTypeWallet {
+		if err := s.wallet.checkTrip(customerID, fare); err != nil {
+			return nil, err
+		}
+	}
+
 	// Create trip with searching status
 	trip := &models.Trip{
 		CustomerID:        customerID,
@@ -70,11 +149,32 @@ func (s *tripService) CreateTrip(customerID uuid.UUID, req dto.CreateTripRequest
 		PickupLongitude:   req.PickupLocation.Longitude,
 		DropoffLatitude:   req.DropoffLocation.Latitude,
 		DropoffLongitude:  req.DropoffLocation.Longitude,
-		EstimatedDistance: &distance,
-		EstimatedDuration: utils.Float64ToIntPointer(duration),
+		EstimatedDistance: &estimate.Distance,
+		EstimatedDuration: utils.Float64ToIntPointer(estimate.Duration),
 		FareAmount:        &fare,
-		PaymentMethod:     "cash",
+		SurgeMultiplier:   estimate.SurgeMultiplier,
+		FareTableID:       estimate.FareTableID,
+		DistanceMethod:    &estimate.DistanceMethod,
+		QuoteID:           quoteID,
+		DiscountAmount:    discount,
+		PaymentMethod:     models.PaymentTypeCash,
+		PaymentMethodID:   paymentMethodID,
 		SearchStartedAt:   &now,
+		Package:           tripPackage,
+	}
+
+	if len(req.Waypoints) > 0 {
+		trip.Waypoints, err = newTripWaypoints(req.Waypoints, trip.ServiceType)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Scheduled trips wait in pending until the scheduler dispatches them
+	if scheduledAt != nil {
+		trip.Status = models.TripStatusPending
+		trip.ScheduledAt = scheduledAt
+		trip.SearchStartedAt = nil
 	}
 
 	// Set addresses if provided
@@ -89,9 +189,20 @@ func (s *tripService) CreateTrip(customerID uuid.UUID, req dto.CreateTripRequest
 		trip.PaymentMethod = req.PaymentMethod
 	}
 
-	if err := s.tripRepo.Create(trip); err != nil {
+	if promo != nil {
+		trip.PromoCodeID = &promo.ID
+		trip.PromoCode = &promo.Code
+		if err := s.promos.createTrip(trip, promo); err != nil {
+			if errors.Is(err, ErrPromoLimitReached) {
+				return nil, err
+			}
+			return nil, errors.New("failed to create trip")
+		}
+	} else if err := s.tripRepo.Create(trip); err != nil {
 		return nil, errors.New("failed to create trip")
 	}
+	s.lifecycle.started(trip)
+	publishTripStatus(trip)
 
 	// Create a job for drivers to accept
 	job := &models.Job{
@@ -101,13 +212,87 @@ func (s *tripService) CreateTrip(customerID uuid.UUID, req dto.CreateTripRequest
 	if err := s.jobRepo.Create(job); err != nil {
 		// Log error but don't fail trip creation
 		// In production, you might want to handle this differently
+	} else if trip.ScheduledAt == nil {
+		// Offer the job to the nearest drivers right away; if this fails the
+		// dispatch job picks it up on its next tick
+		s.dispatchService.StartDispatch(job, trip)
 	}
 
 	return s.tripToDTO(trip), nil
 }
 
-// EstimateFare estimates the fare for a trip without creating it
-func (s *tripService) EstimateFare(req dto.EstimateFareRequest) (*dto.EstimateFareResponse, error) {
+// priceTrip returns the fare of a new trip: the fare of its quote when it has one,
+// otherwise a fresh estimate. Deliveries pay their package's weight surcharge on top.
+func (s *tripService) priceTrip(customerID uuid.UUID, req dto.CreateTripRequest, tripPackage *models.TripPackage) (FareEstimate, *uuid.UUID, error) {
+	weightSurcharge := 0.0
+	if tripPackage != nil {
+		weightSurcharge = tripPackage.WeightSurcharge
+	}
+
+	if req.QuoteID != "" {
+		// Quotes are only issued for a single drop-off
+		if len(req.Waypoints) > 0 {
+			return FareEstimate{}, nil, ErrQuoteMismatch
+		}
+		quote, err := s.quotes.Verify(req.QuoteID, time.Now())
+		if err != nil {
+			return FareEstimate{}, nil, err
+		}
+		err = CheckQuoteMatches(quote, customerID, req.ServiceType,
+			req.PickupLocation.Latitude, req.PickupLocation.Longitude,
+			req.DropoffLocation.Latitude, req.DropoffLocation.Longitude,
+			s.quoteTolerance,
+		)
+		if err != nil {
+			return FareEstimate{}, nil, err
+		}
+		// The quoted fare only holds for a package in the same weight tier
+		if quote.Estimate.Breakdown.WeightSurcharge != weightSurcharge {
+			return FareEstimate{}, nil, ErrQuoteMismatch
+		}
+		if _, err := s.tripRepo.FindByQuoteID(quote.ID); err == nil {
+			return FareEstimate{}, nil, ErrQuoteUsed
+		}
+		return quote.Estimate, &quote.ID, nil
+	}
+
+	// Calculate fare using pricing service, across every leg of a multi-stop trip
+	points := []RoutePoint{{Latitude: req.PickupLocation.Latitude, Longitude: req.PickupLocation.Longitude}}
+	if len(req.Waypoints) == 0 {
+		points = append(points, RoutePoint{Latitude: req.DropoffLocation.Latitude, Longitude: req.DropoffLocation.Longitude})
+	}
+	for _, waypoint := range req.Waypoints {
+		points = append(points, RoutePoint{Latitude: waypoint.Location.Latitude, Longitude: waypoint.Location.Longitude})
+	}
+	estimate, err := s.pricingService.EstimateRouteFare(points, req.ServiceType)
+	if err != nil {
+		return FareEstimate{}, nil, errors.New("failed to calculate fare")
+	}
+	estimate = addWeightSurcharge(estimate, weightSurcharge)
+
+	// The customer agreed to the multiplier they were quoted; if surge has risen since,
+	// they have to confirm the new fare first
+	if req.SurgeMultiplier != nil && estimate.SurgeMultiplier > *req.SurgeMultiplier {
+		return FareEstimate{}, nil, ErrSurgeChanged
+	}
+	return estimate, nil, nil
+}
+
+// EstimateFare estimates the fare for a trip without creating it and quotes it to the customer
+func (s *tripService) EstimateFare(customerID uuid.UUID, req dto.EstimateFareRequest) (*dto.EstimateFareResponse, error) {
+	points := []RoutePoint{{Latitude: req.PickupLocation.Latitude, Longitude: req.PickupLocation.Longitude}}
+	for _, waypoint := range req.Waypoints {
+		if !utils.ValidateCoordinates(waypoint.Latitude, waypoint.Longitude) {
+			return nil, errors.New("invalid waypoint coordinates")
+		}
+		points = append(points, RoutePoint{Latitude: waypoint.Latitude, Longitude: waypoint.Longitude})
+	}
+	if len(req.Waypoints) > 0 {
+		req.DropoffLocation = req.Waypoints[len(req.Waypoints)-1]
+	} else {
+		points = append(points, RoutePoint{Latitude: req.DropoffLocation.Latitude, Longitude: req.DropoffLocation.Longitude})
+	}
+
 	// Validate coordinates
 	if !utils.ValidateCoordinates(req.PickupLocation.Latitude, req.PickupLocation.Longitude) {
 		return nil, errors.New("invalid pickup coordinates")
@@ -116,21 +301,74 @@ func (s *tripService) EstimateFare(req dto.EstimateFareRequest) (*dto.EstimateFa
 		return nil, errors.New("invalid dropoff coordinates")
 	}
 
+	// The package is optional here; without it deliveries are estimated unsurcharged
+	var tripPackage *models.TripPackage
+	if req.Package != nil {
+		var err error
+		tripPackage, err = newTripPackage(req.Package, req.ServiceType, s.delivery.WeightSurcharges)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Calculate fare
-	distance, fare, duration := s.pricingService.EstimateFare(
-		req.PickupLocation.Latitude,
-		req.PickupLocation.Longitude,
-		req.DropoffLocation.Latitude,
-		req.DropoffLocation.Longitude,
-		req.ServiceType,
-	)
-
-	return &dto.EstimateFareResponse{
-		Distance:          distance,
-		EstimatedDuration: duration,
-		EstimatedFare:     fare,
+	estimate, err := s.pricingService.EstimateRouteFare(points, req.ServiceType)
+	if err != nil {
+		return nil, errors.New("failed to calculate fare")
+	}
+	if tripPackage != nil {
+		estimate = addWeightSurcharge(estimate, tripPackage.WeightSurcharge)
+	}
+
+	response := &dto.EstimateFareResponse{
+		Distance:          estimate.Distance,
+		EstimatedDuration: estimate.Duration,
+		DistanceMethod:    estimate.DistanceMethod,
+		EstimatedFare:     estimate.Fare,
+		SurgeMultiplier:   estimate.SurgeMultiplier,
+		Breakdown:         fareBreakdownToDTO(estimate.Breakdown),
+		TotalFare:         estimate.Fare,
 		ServiceType:       req.ServiceType,
-	}, nil
+	}
+	if req.PromoCode != "" {
+		promo, discount, err := s.promos.apply(req.PromoCode, customerID, req.ServiceType, estimate.Fare)
+		if err != nil {
+			return nil, err
+		}
+		response.Discount = &dto.DiscountLine{
+			PromoCode:   promo.Code,
+			Description: promo.Description,
+			Amount:      discount,
+		}
+		response.TotalFare = roundFare(estimate.Fare - discount)
+	}
+	if estimate.FareTableID != nil {
+		pricingVersionID := estimate.FareTableID.String()
+		response.PricingVersionID = &pricingVersionID
+	}
+
+	// Quotes only cover a single drop-off
+	if len(req.Waypoints) > 0 {
+		return response, nil
+	}
+
+	quote := &FareQuote{
+		CustomerID:       customerID,
+		ServiceType:      req.ServiceType,
+		PickupLatitude:   req.PickupLocation.Latitude,
+		PickupLongitude:  req.PickupLocation.Longitude,
+		DropoffLatitude:  req.DropoffLocation.Latitude,
+		DropoffLongitude: req.DropoffLocation.Longitude,
+		Estimate:         estimate,
+	}
+	quoteID, err := s.quotes.Issue(quote, time.Now())
+	if err != nil {
+		return nil, errors.New("failed to issue fare quote")
+	}
+	response.QuoteID = quoteID
+	response.QuoteExpiresAt = quote.ExpiresAt.Format(time.RFC3339)
+
+	return response, nil
 }
 
 func (s *tripService) GetActiveTrip(customerID uuid.UUID) (*dto.TripResponse, error) {
@@ -170,51 +408,176 @@ func (s *tripService) GetTripByID(tripID uuid.UUID) (*dto.TripResponse, error) {
 	return s.tripToDTO(trip), nil
 }
 
-func (s *tripService) UpdateTrip(tripID uuid.UUID, req dto.UpdateTripRequest) (*dto.TripResponse, error) {
+func (s *tripService) UpdateTrip(tripID, customerID uuid.UUID, req dto.UpdateTripRequest) (*dto.TripResponse, error) {
 	trip, err := s.tripRepo.FindByID(tripID)
 	if err != nil {
 		return nil, errors.New("trip not found")
 	}
 
-	if req.Status != nil {
-		trip.Status = models.TripStatus(*req.Status)
+	if trip.CustomerID != customerID {
+		return nil, errors.New("unauthorized to update this trip")
 	}
+
+	// Status changes go through the lifecycle; the only one open to customers is cancelling
+	if req.Status != nil && models.TripStatus(*req.Status) != trip.Status {
+		if err := s.customerTransition(trip, models.TripStatus(*req.Status), customerID, ""); err != nil {
+			return nil, err
+		}
+	}
+
 	if req.EstimatedArrival != nil {
 		arrival := time.Unix(*req.EstimatedArrival, 0)
 		trip.EstimatedArrival = &arrival
 	}
-	if req.PickupLocation != nil {
-		trip.PickupLatitude = req.PickupLocation.Latitude
-		trip.PickupLongitude = req.PickupLocation.Longitude
-	}
-	if req.DropoffLocation != nil {
-		trip.DropoffLatitude = req.DropoffLocation.Latitude
-		trip.DropoffLongitude = req.DropoffLocation.Longitude
+	if req.PickupLocation != nil || req.DropoffLocation != nil {
+		if err := s.reroute(trip, req.PickupLocation, req.DropoffLocation); err != nil {
+			return nil, err
+		}
 	}
 
-	if err := s.tripRepo.Update(trip); err != nil {
-		return nil, errors.New("failed to update trip")
+	if req.EstimatedArrival != nil || req.PickupLocation != nil || req.DropoffLocation != nil {
+		if err := s.tripRepo.UpdateRoute(trip, trip.Status); err != nil {
+			if errors.Is(err, repositories.ErrStatusChanged) {
+				return nil, ErrTripStatusChanged
+			}
+			return nil, errors.New("failed to update trip")
+		}
 	}
 
 	return s.tripToDTO(trip), nil
 }
 
-func (s *tripService) CancelTrip(tripID uuid.UUID, customerID uuid.UUID) error {
+// reroute moves a trip's pickup or drop-off point and prices the new route, so the
+// upfront fare the final fare is held against follows the change
+func (s *tripService) reroute(trip *models.Trip, pickup, dropoff *dto.Location) error {
+	switch trip.Status {
+	case models.TripStatusPending, models.TripStatusSearching, models.TripStatusAccepted,
+		models.TripStatusArrived, models.TripStatusInProgress:
+	default:
+		return errors.New("trip locations can no longer be changed")
+	}
+	if pickup != nil && trip.Status == models.TripStatusInProgress {
+		return errors.New("pickup cannot be changed once the trip has started")
+	}
+	if dropoff != nil && len(trip.Waypoints) > 0 {
+		return errors.New("the drop-off of a multi-stop trip is its last stop and cannot be changed")
+	}
+
+	if pickup != nil {
+		if !utils.ValidateCoordinates(pickup.Latitude, pickup.Longitude) {
+			return errors.New("invalid pickup coordinates")
+		}
+		trip.PickupLatitude = pickup.Latitude
+		trip.PickupLongitude = pickup.Longitude
+	}
+	if dropoff != nil {
+		if !utils.ValidateCoordinates(dropoff.Latitude, dropoff.Longitude) {
+			return errors.New("invalid dropoff coordinates")
+		}
+		trip.DropoffLatitude = dropoff.Latitude
+		trip.DropoffLongitude = dropoff.Longitude
+	}
+
+	estimate, err := s.pricingService.EstimateRouteFare(tripRoute(trip), string(trip.ServiceType))
+	if err != nil {
+		return errors.New("failed to calculate fare")
+	}
+	if trip.Package != nil {
+		estimate = addWeightSurcharge(estimate, trip.Package.WeightSurcharge)
+	}
+	trip.EstimatedDistance = &estimate.Distance
+	trip.EstimatedDuration = utils.Float64ToIntPointer(estimate.Duration)
+	trip.DistanceMethod = &estimate.DistanceMethod
+	trip.DiscountAmount = s.promos.discount(trip, estimate.Fare)
+	fare := roundFare(estimate.Fare - trip.DiscountAmount)
+	trip.FareAmount = &fare
+	trip.SurgeMultiplier = estimate.SurgeMultiplier
+	trip.FareTableID = estimate.FareTableID
+	return s.raiseHold(trip)
+}
+
+// raiseHold makes sure what is held for a trip a driver has accepted still covers
+// its fare, so a dearer route is refused when the customer can't pay for it
+func (s *tripService) raiseHold(trip *models.Trip) error {
+	switch trip.Status {
+	case models.TripStatusAccepted, models.TripStatusArrived, models.TripStatusInProgress:
+	default:
+		return nil
+	}
+	switch trip.PaymentMethod {
+	case models.PaymentTypeCard:
+		return s.payments.reauthorize(trip)
+	case models.PaymentTypeWallet:
+		return s.wallet.raiseHold(trip)
+	}
+	return nil
+}
+
+// CancelTrip cancels a trip for its customer, charging the cancellation fee the
+// policy sets for its current status
+func (s *tripService) CancelTrip(tripID uuid.UUID, customerID uuid.UUID, req dto.CancelTripRequest) (*dto.TripResponse, error) {
 	trip, err := s.tripRepo.FindByID(tripID)
 	if err != nil {
-		return errors.New("trip not found")
+		return nil, errors.New("trip not found")
 	}
 
 	if trip.CustomerID != customerID {
-		return errors.New("unauthorized to cancel this trip")
+		return nil, errors.New("unauthorized to cancel this trip")
 	}
 
-	if trip.Status == models.TripStatusCompleted || trip.Status == models.TripStatusCancelled {
-		return errors.New("trip cannot be cancelled")
+	if err := s.customerTransition(trip, models.TripStatusCancelled, customerID, strings.TrimSpace(req.Reason)); err != nil {
+		return nil, err
 	}
+	return s.tripToDTO(trip), nil
+}
 
-	trip.Status = models.TripStatusCancelled
-	return s.tripRepo.Update(trip)
+// GetCancellationFee tells a customer what cancelling their trip would cost now and
+// until when it is free
+func (s *tripService) GetCancellationFee(tripID, customerID uuid.UUID) (*dto.CancellationFeeResponse, error) {
+	trip, err := s.tripRepo.FindByID(tripID)
+	if err != nil || trip.CustomerID != customerID {
+		return nil, errors.New("trip not found")
+	}
+	if err := ValidateTripTransition(trip.Status, models.TripStatusCancelled, models.TripActorCustomer); err != nil {
+		return nil, err
+	}
+
+	var acceptedAt *time.Time
+	if job, err := s.jobRepo.FindByTripID(trip.ID); err == nil {
+		acceptedAt = job.AcceptedAt
+	}
+	now := time.Now()
+	response := &dto.CancellationFeeResponse{
+		Fee: CancellationFee(s.cancellation, trip.Status, acceptedAt, trip.FareAmount, now),
+	}
+	if trip.Status == models.TripStatusAccepted && acceptedAt != nil && response.Fee == 0 {
+		freeUntil := acceptedAt.Add(s.cancellation.FreeWindow).Format(time.RFC3339)
+		response.FreeUntil = &freeUntil
+	}
+	return response, nil
+}
+
+// customerTransition moves a trip to status on behalf of its customer, closing its
+// job and withdrawing any offers still out to drivers
+func (s *tripService) customerTransition(trip *models.Trip, status models.TripStatus, customerID uuid.UUID, note string) error {
+	job, err := s.jobRepo.FindByTripID(trip.ID)
+	if err != nil {
+		job = nil
+	}
+
+	if err := s.lifecycle.apply(trip, job, tripTransition{
+		To:      status,
+		Actor:   models.TripActorCustomer,
+		ActorID: &customerID,
+		Note:    note,
+	}); err != nil {
+		return err
+	}
+
+	if job != nil {
+		s.dispatchService.WithdrawOffers(job.ID)
+	}
+	return nil
 }
 
 func (s *tripService) tripToDTO(trip *models.Trip) *dto.TripResponse {
@@ -231,12 +594,19 @@ func (s *tripService) tripToDTO(trip *models.Trip) *dto.TripResponse {
 			Latitude:  trip.DropoffLatitude,
 			Longitude: trip.DropoffLongitude,
 		},
-		EstimatedDistance: trip.EstimatedDistance,
-		EstimatedDuration: trip.EstimatedDuration,
-		FareAmount:        trip.FareAmount,
-		PaymentMethod:     trip.PaymentMethod,
-		CreatedAt:         trip.CreatedAt.Format(time.RFC3339),
-		UpdatedAt:         trip.UpdatedAt.Format(time.RFC3339),
+		EstimatedDistance:  trip.EstimatedDistance,
+		EstimatedDuration:  trip.EstimatedDuration,
+		DistanceMethod:     trip.DistanceMethod,
+		FareAmount:         trip.FareAmount,
+		SurgeMultiplier:    trip.SurgeMultiplier,
+		PaymentMethod:      trip.PaymentMethod,
+		Discount:           discountLine(trip),
+		CancellationFee:    trip.CancellationFee,
+		CancellationReason: trip.CancellationReason,
+		Waypoints:          waypointsToDTO(trip.Waypoints, true),
+		Package:            packageToDTO(trip.Package),
+		CreatedAt:          trip.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:          trip.UpdatedAt.Format(time.RFC3339),
 	}
 
 	if trip.DriverID != nil {
@@ -249,8 +619,39 @@ func (s *tripService) tripToDTO(trip *models.Trip) *dto.TripResponse {
 		response.EstimatedArrival = &timestamp
 	}
 
+	if trip.ScheduledAt != nil {
+		scheduledAt := trip.ScheduledAt.Format(time.RFC3339)
+		response.ScheduledAt = &scheduledAt
+	}
+
+	if trip.FareTableID != nil {
+		pricingVersionID := trip.FareTableID.String()
+		response.PricingVersionID = &pricingVersionID
+	}
+
+	if trip.PaymentMethodID != nil {
+		paymentMethodID := trip.PaymentMethodID.String()
+		response.PaymentMethodID = &paymentMethodID
+	}
+
 	return response
 }
+
+func fareBreakdownToDTO(breakdown FareBreakdown) dto.FareBreakdown {
+	return dto.FareBreakdown{
+		BaseFare:         breakdown.BaseFare,
+		DistanceFare:     breakdown.DistanceFare,
+		TimeFare:         breakdown.TimeFare,
+		SurgeAmount:      breakdown.SurgeAmount,
+		NightSurcharge:   breakdown.NightSurcharge,
+		MinimumFareTopUp: breakdown.MinimumFareTopUp,
+		BookingFee:       breakdown.BookingFee,
+		AirportSurcharge: breakdown.AirportSurcharge,
+		WeightSurcharge:  breakdown.WeightSurcharge,
+		Total:            breakdown.Total,
+	}
+}
+
 // AcceptTrip allows a driver to accept a trip
 func (s *tripService) AcceptTrip(tripID uuid.UUID, driverID uuid.UUID) error {
 	trip, err := s.tripRepo.FindByID(tripID)
@@ -258,26 +659,18 @@ func (s *tripService) AcceptTrip(tripID uuid.UUID, driverID uuid.UUID) error {
 		return errors.New("trip not found")
 	}
 
-	// Check if trip is still searching
-	if trip.Status != models.TripStatusSearching {
-		return errors.New("trip is no longer available")
+	job, err := s.jobRepo.FindByTripID(trip.ID)
+	if err != nil {
+		job = nil
 	}
 
-	// Check if trip already has a driver (race condition)
-	if trip.DriverID != nil {
-		return errors.New("trip already accepted by another driver")
+	if err := s.lifecycle.accept(trip, job, nil, driverID); err != nil {
+		return err
 	}
 
-	// Update trip
-	now := time.Now()
-	trip.Status = models.TripStatusAccepted
-	trip.DriverID = &driverID
-	trip.SearchEndedAt = &now
-
-	if err := s.tripRepo.Update(trip); err != nil {
-		return errors.New("failed to accept trip")
+	if job != nil {
+		s.dispatchService.WithdrawOffers(job.ID)
 	}
-
 	return nil
 }
 
@@ -290,25 +683,67 @@ func (s *tripService) GetTripStatus(tripID uuid.UUID) (string, error) {
 	return string(trip.Status), nil
 }
 
-// ExpireSearchingTrips marks trips that have been searching for >3 minutes as expired
+// ExpireSearchingTrips is a safety net for trips the dispatcher never finished with.
+// Normally a trip expires as soon as its last dispatch wave runs out; anything still
+// searching a minute after every wave would have ended is expired here.
 func (s *tripService) ExpireSearchingTrips() error {
-	// Find all trips in searching status older than 3 minutes
-	threeMinutesAgo := time.Now().Add(-3 * time.Minute)
+	cfg := config.AppConfig.Dispatch
+	searchWindow := time.Duration(cfg.MaxWaves)*cfg.OfferTimeout + time.Minute
 
-	trips, err := s.tripRepo.FindSearchingBefore(threeMinutesAgo)
+	trips, err := s.tripRepo.FindSearchingBefore(time.Now().Add(-searchWindow))
 	if err != nil {
 		return err
 	}
 
-	now := time.Now()
-	for _, trip := range trips {
-		trip.Status = models.TripStatusExpired
-		trip.SearchEndedAt = &now
-		if err := s.tripRepo.Update(&trip); err != nil {
-			// Log error but continue with other trips
+	for i := range trips {
+		trip := &trips[i]
+		job, err := s.jobRepo.FindByTripID(trip.ID)
+		if err != nil {
+			job = nil
+		}
+		if err := s.lifecycle.apply(trip, job, tripTransition{
+			To:    models.TripStatusExpired,
+			Actor: models.TripActorSystem,
+		}); err != nil {
+			// Continue with other trips
 			continue
 		}
+		if job != nil {
+			s.dispatchService.WithdrawOffers(job.ID)
+		}
 	}
 
 	return nil
 }
+
+// GetTripTimeline returns the recorded status changes of a trip to its customer or driver
+func (s *tripService) GetTripTimeline(tripID, userID uuid.UUID) ([]dto.TripEventResponse, error) {
+	trip, err := s.tripRepo.FindByID(tripID)
+	if err != nil {
+		return nil, errors.New("trip not found")
+	}
+
+	if trip.CustomerID != userID && (trip.DriverID == nil || *trip.DriverID != userID) {
+		return nil, errors.New("unauthorized to view this trip")
+	}
+
+	return s.lifecycle.timeline(tripID)
+}
+
+// GetTripReceipt returns the metered final fare of a completed trip to its customer or driver
+func (s *tripService) GetTripReceipt(tripID, userID uuid.UUID) (*models.TripReceipt, error) {
+	trip, err := s.tripRepo.FindByID(tripID)
+	if err != nil {
+		return nil, errors.New("trip not found")
+	}
+
+	if trip.CustomerID != userID && (trip.DriverID == nil || *trip.DriverID != userID) {
+		return nil, errors.New("unauthorized to view this trip")
+	}
+
+	receipt, err := s.receiptRepo.FindByTripID(tripID)
+	if err != nil {
+		return nil, errors.New("receipt not found")
+	}
+	return receipt, nil
+}
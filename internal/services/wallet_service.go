@@ -0,0 +1,331 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/config"
+	"github.com/telemoz/backend/internal/dto"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/repositories"
+	"github.com/telemoz/backend/pkg/payment"
+)
+
+// ErrInsufficientBalance means the wallet cannot cover the amount
+var ErrInsufficientBalance = errors.New("insufficient wallet balance")
+
+type WalletService interface {
+	GetWallet(customerID uuid.UUID) (*dto.WalletResponse, error)
+	ListTransactions(customerID uuid.UUID, limit, offset int) ([]dto.WalletTransactionResponse, error)
+	TopUp(customerID uuid.UUID, req dto.TopUpRequest) (*dto.WalletTransactionResponse, error)
+	Credit(customerID, adminID uuid.UUID, req dto.WalletCreditRequest) (*dto.WalletTransactionResponse, error)
+}
+
+type walletService struct {
+	walletRepo repositories.WalletRepository
+	userRepo   repositories.UserRepository
+	payments   *paymentService
+	cfg        config.WalletConfig
+	currency   string
+}
+
+func NewWalletService() WalletService {
+	return newWalletService(defaultPaymentGateway())
+}
+
+// NewWalletServiceWithGateway creates the service with an explicit gateway for
+// top-ups, e.g. a fake in tests
+func NewWalletServiceWithGateway(gateway payment.PaymentGateway) WalletService {
+	return newWalletService(gateway)
+}
+
+func newWalletService(gateway payment.PaymentGateway) *walletService {
+	return &walletService{
+		walletRepo: repositories.NewWalletRepository(),
+		userRepo:   repositories.NewUserRepository(),
+		payments:   newPaymentService(gateway),
+		cfg:        config.AppConfig.Wallet,
+		currency:   config.AppConfig.Payment.Currency,
+	}
+}
+
+// WalletTripHold is what a wallet trip takes from the balance when a driver accepts
+// it: the fare plus the hold margin, or the whole balance if that is less but still
+// covers the fare. It returns false when the balance cannot cover the fare.
+func WalletTripHold(fare, balance, marginPercent float64) (float64, bool) {
+	if balance < fare {
+		return 0, false
+	}
+	hold := HoldAmount(fare, marginPercent)
+	if hold > balance {
+		hold = balance
+	}
+	return hold, true
+}
+
+func (s *walletService) GetWallet(customerID uuid.UUID) (*dto.WalletResponse, error) {
+	balance, err := s.walletRepo.Balance(customerID)
+	if err != nil {
+		return nil, errors.New("failed to fetch wallet")
+	}
+	return &dto.WalletResponse{Balance: balance, Currency: s.currency}, nil
+}
+
+func (s *walletService) ListTransactions(customerID uuid.UUID, limit, offset int) ([]dto.WalletTransactionResponse, error) {
+	transactions, err := s.walletRepo.FindTransactions(customerID, limit, offset)
+	if err != nil {
+		return nil, errors.New("failed to fetch wallet transactions")
+	}
+
+	responses := make([]dto.WalletTransactionResponse, len(transactions))
+	for i := range transactions {
+		responses[i] = walletTransactionToDTO(&transactions[i])
+	}
+	return responses, nil
+}
+
+// TopUp charges a saved card and credits the amount to the wallet
+func (s *walletService) TopUp(customerID uuid.UUID, req dto.TopUpRequest) (*dto.WalletTransactionResponse, error) {
+	amount := roundFare(req.Amount)
+	if amount < s.cfg.MinTopUp || amount > s.cfg.MaxTopUp {
+		return nil, fmt.Errorf("top-up must be between %.2f and %.2f", s.cfg.MinTopUp, s.cfg.MaxTopUp)
+	}
+
+	balance, err := s.walletRepo.Balance(customerID)
+	if err != nil {
+		return nil, errors.New("failed to fetch wallet")
+	}
+	if balance+amount > s.cfg.MaxBalance {
+		return nil, fmt.Errorf("wallet balance cannot exceed %.2f", s.cfg.MaxBalance)
+	}
+
+	// The transaction ID is the idempotency key of the card charge
+	transaction := &models.WalletTransaction{
+		ID:         uuid.New(),
+		CustomerID: customerID,
+		Type:       models.WalletTransactionTopUp,
+		Amount:     amount,
+	}
+	reference, err := s.payments.charge(customerID, req.PaymentMethodID, amount, "wallet-"+transaction.ID.String())
+	if err != nil {
+		return nil, err
+	}
+	transaction.Reference = &reference
+
+	if err := s.walletRepo.Post(transaction); err != nil {
+		log.Printf("Top-up %s of customer %s was charged but not credited: %v", reference, customerID, err)
+		return nil, errors.New("top-up was charged but could not be credited")
+	}
+
+	response := walletTransactionToDTO(transaction)
+	return &response, nil
+}
+
+// Credit adds a refund, referral credit or adjustment to a customer's wallet (admin)
+func (s *walletService) Credit(customerID, adminID uuid.UUID, req dto.WalletCreditRequest) (*dto.WalletTransactionResponse, error) {
+	customer, err := s.userRepo.FindByID(customerID)
+	if err != nil || customer.UserType != models.UserTypeCustomer {
+		return nil, errors.New("customer not found")
+	}
+
+	reason := req.Reason
+	transaction := &models.WalletTransaction{
+		CustomerID:  customerID,
+		Type:        models.WalletTransactionType(req.Type),
+		Amount:      roundFare(req.Amount),
+		Description: &reason,
+		CreatedBy:   &adminID,
+	}
+	if req.TripID != "" {
+		tripID, err := uuid.Parse(req.TripID)
+		if err != nil {
+			return nil, errors.New("invalid trip ID")
+		}
+		transaction.TripID = &tripID
+	}
+
+	if err := s.walletRepo.Post(transaction); err != nil {
+		return nil, errors.New("failed to credit wallet")
+	}
+
+	response := walletTransactionToDTO(transaction)
+	return &response, nil
+}
+
+// checkTrip makes sure a new wallet trip's fare is covered by the balance
+func (s *walletService) checkTrip(customerID uuid.UUID, fare float64) error {
+	balance, err := s.walletRepo.Balance(customerID)
+	if err != nil {
+		return errors.New("failed to fetch wallet")
+	}
+	if balance < fare {
+		return ErrInsufficientBalance
+	}
+	return nil
+}
+
+// hold takes a wallet trip's fare from the balance as a driver accepts it
+func (s *walletService) hold(trip *models.Trip) error {
+	// A trip dispatched again after its driver gave it up is still held
+	if held, err := s.held(trip); err != nil || held > 0 {
+		return err
+	}
+
+	var fare float64
+	if trip.FareAmount != nil {
+		fare = *trip.FareAmount
+	}
+	balance, err := s.walletRepo.Balance(trip.CustomerID)
+	if err != nil {
+		return errors.New("failed to fetch wallet")
+	}
+	amount, ok := WalletTripHold(fare, balance, s.payments.cfg.HoldMarginPercent)
+	if !ok {
+		return ErrInsufficientBalance
+	}
+	if amount <= 0 {
+		return nil
+	}
+
+	err = s.walletRepo.Post(&models.WalletTransaction{
+		CustomerID: trip.CustomerID,
+		Type:       models.WalletTransactionTripHold,
+		Amount:     -amount,
+		TripID:     &trip.ID,
+	})
+	if errors.Is(err, repositories.ErrInsufficientBalance) {
+		return ErrInsufficientBalance
+	}
+	return err
+}
+
+// raiseHold takes more from the balance once a wallet trip's fare has grown beyond
+// what is held, e.g. after a reroute
+func (s *walletService) raiseHold(trip *models.Trip) error {
+	held, err := s.held(trip)
+	if err != nil {
+		return errors.New("failed to fetch wallet")
+	}
+	var fare float64
+	if trip.FareAmount != nil {
+		fare = *trip.FareAmount
+	}
+	if fare <= held {
+		return nil
+	}
+	balance, err := s.walletRepo.Balance(trip.CustomerID)
+	if err != nil {
+		return errors.New("failed to fetch wallet")
+	}
+	// What is held already counts towards the new hold
+	total, ok := WalletTripHold(fare, balance+held, s.payments.cfg.HoldMarginPercent)
+	if !ok {
+		return ErrInsufficientBalance
+	}
+	amount := roundFare(total - held)
+	if amount <= 0 {
+		return nil
+	}
+
+	err = s.walletRepo.Post(&models.WalletTransaction{
+		CustomerID: trip.CustomerID,
+		Type:       models.WalletTransactionTripHold,
+		Amount:     -amount,
+		TripID:     &trip.ID,
+	})
+	if errors.Is(err, repositories.ErrInsufficientBalance) {
+		return ErrInsufficientBalance
+	}
+	return err
+}
+
+// settle returns to the wallet what it still holds for a trip beyond the amount
+// charged: the final fare when the trip completes, nothing otherwise
+func (s *walletService) settle(trip *models.Trip, charged float64) {
+	held, err := s.held(trip)
+	if err != nil {
+		log.Printf("Failed to fetch wallet transactions of trip %s: %v", trip.ID, err)
+		return
+	}
+
+	release := roundFare(held - CaptureAmount(charged, held))
+	if release <= 0 {
+		return
+	}
+	if err := s.walletRepo.Post(&models.WalletTransaction{
+		CustomerID: trip.CustomerID,
+		Type:       models.WalletTransactionTripRelease,
+		Amount:     release,
+		TripID:     &trip.ID,
+	}); err != nil {
+		log.Printf("Failed to release wallet hold of trip %s: %v", trip.ID, err)
+	}
+}
+
+// chargeTip takes a tip on a trip from the customer's balance
+func (s *walletService) chargeTip(trip *models.Trip, amount float64) (*models.WalletTransaction, error) {
+	transaction := &models.WalletTransaction{
+		CustomerID: trip.CustomerID,
+		Type:       models.WalletTransactionTip,
+		Amount:     -amount,
+		TripID:     &trip.ID,
+	}
+	err := s.walletRepo.Post(transaction)
+	if errors.Is(err, repositories.ErrInsufficientBalance) {
+		return nil, ErrInsufficientBalance
+	}
+	if err != nil {
+		return nil, errors.New("failed to charge tip")
+	}
+	return transaction, nil
+}
+
+// refundTip returns a tip that could not be credited to the driver
+func (s *walletService) refundTip(trip *models.Trip, tip *models.WalletTransaction) {
+	description := "Tip could not be credited to the driver"
+	if err := s.walletRepo.Post(&models.WalletTransaction{
+		CustomerID:  trip.CustomerID,
+		Type:        models.WalletTransactionRefund,
+		Amount:      -tip.Amount,
+		TripID:      &trip.ID,
+		Description: &description,
+	}); err != nil {
+		log.Printf("Failed to refund wallet tip %s of trip %s: %v", tip.ID, trip.ID, err)
+	}
+}
+
+// held is what the wallet currently holds for a trip; credits an admin made against
+// the trip don't count
+func (s *walletService) held(trip *models.Trip) (float64, error) {
+	transactions, err := s.walletRepo.FindTripTransactions(trip.ID)
+	if err != nil {
+		return 0, err
+	}
+	var held float64
+	for _, transaction := range transactions {
+		switch transaction.Type {
+		case models.WalletTransactionTripHold, models.WalletTransactionTripRelease:
+			held -= transaction.Amount
+		}
+	}
+	return roundFare(held), nil
+}
+
+func walletTransactionToDTO(transaction *models.WalletTransaction) dto.WalletTransactionResponse {
+	response := dto.WalletTransactionResponse{
+		ID:           transaction.ID.String(),
+		Type:         string(transaction.Type),
+		Amount:       transaction.Amount,
+		BalanceAfter: transaction.BalanceAfter,
+		Description:  transaction.Description,
+		CreatedAt:    transaction.CreatedAt.Format(time.RFC3339),
+	}
+	if transaction.TripID != nil {
+		tripID := transaction.TripID.String()
+		response.TripID = &tripID
+	}
+	return response
+}
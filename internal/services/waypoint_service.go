@@ -0,0 +1,428 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"mime"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/telemoz/backend/internal/config"
+	"github.com/telemoz/backend/internal/dto"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/repositories"
+	"github.com/telemoz/backend/pkg/storage"
+)
+
+var (
+	// ErrWaypointOutOfOrder means an earlier stop hasn't been completed or failed yet
+	ErrWaypointOutOfOrder = errors.New("stops must be visited in order")
+	// ErrWaypointsOutstanding means a multi-stop trip still has stops left
+	ErrWaypointsOutstanding = errors.New("every stop must be completed or failed before the trip can be completed")
+	// ErrProofRequired means a delivery stop was completed without proof of delivery
+	ErrProofRequired = errors.New("proof of delivery is required for this stop")
+	// ErrInvalidDeliveryPIN means the PIN doesn't match the one given to the recipient
+	ErrInvalidDeliveryPIN = errors.New("delivery PIN is incorrect")
+	// ErrDeliveryPINLocked means too many wrong PINs were entered at the stop
+	ErrDeliveryPINLocked = errors.New("too many incorrect PINs, complete the stop with a signature or photo")
+	// ErrInvalidProofImage means the signature or photo isn't a PNG or JPEG within the size limit
+	ErrInvalidProofImage = errors.New("proof must be a PNG or JPEG image within the upload size limit")
+)
+
+// deliveryPINDigits is the length of the PIN a recipient reads out to the driver
+const deliveryPINDigits = 4
+
+// maxDeliveryPINAttempts is how many wrong PINs lock a stop's PIN
+const maxDeliveryPINAttempts = 5
+
+// proofImageExtensions are the image types accepted as signatures and photos
+var proofImageExtensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+}
+
+// ProofImage is a signature or photo uploaded as proof of delivery
+type ProofImage struct {
+	Content io.Reader
+}
+
+// ProofFile is a stored signature or photo
+type ProofFile struct {
+	Content     []byte
+	ContentType string
+}
+
+type WaypointService interface {
+	ArriveAtWaypoint(jobID, waypointID, driverID uuid.UUID) (*dto.WaypointResponse, error)
+	CompleteWaypoint(jobID, waypointID, driverID uuid.UUID, req dto.CompleteWaypointRequest, proof *ProofImage) (*dto.WaypointResponse, error)
+	FailWaypoint(jobID, waypointID, driverID uuid.UUID, req dto.FailWaypointRequest) (*dto.WaypointResponse, error)
+	GetProof(tripID, waypointID, userID uuid.UUID) (*ProofFile, error)
+}
+
+type waypointService struct {
+	waypointRepo   repositories.TripWaypointRepository
+	jobRepo        repositories.JobRepository
+	tripRepo       repositories.TripRepository
+	store          storage.Store
+	maxUploadBytes int
+}
+
+func NewWaypointService() WaypointService {
+	return NewWaypointServiceWithStore(storage.NewStore())
+}
+
+// NewWaypointServiceWithStore creates the service with an explicit store for proof of
+// delivery, e.g. a temporary directory in tests
+func NewWaypointServiceWithStore(store storage.Store) WaypointService {
+	return &waypointService{
+		waypointRepo:   repositories.NewTripWaypointRepository(),
+		jobRepo:        repositories.NewJobRepository(),
+		tripRepo:       repositories.NewTripRepository(),
+		store:          store,
+		maxUploadBytes: config.AppConfig.Storage.MaxUploadBytes,
+	}
+}
+
+// NextWaypoint is the stop a driver is on their way to or at: the first one that
+// hasn't been completed or failed. It returns nil once every stop is done.
+func NextWaypoint(waypoints []models.TripWaypoint) *models.TripWaypoint {
+	for i := range waypoints {
+		switch waypoints[i].Status {
+		case models.WaypointStatusPending, models.WaypointStatusArrived:
+			return &waypoints[i]
+		}
+	}
+	return nil
+}
+
+// GenerateDeliveryPIN returns a random numeric PIN for a delivery stop
+func GenerateDeliveryPIN() (string, error) {
+	limit := big.NewInt(1)
+	for i := 0; i < deliveryPINDigits; i++ {
+		limit.Mul(limit, big.NewInt(10))
+	}
+	n, err := rand.Int(rand.Reader, limit)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", deliveryPINDigits, n), nil
+}
+
+// CheckDeliveryPIN reports whether the PIN a recipient read out matches the stop's
+func CheckDeliveryPIN(expected *string, given string) bool {
+	return expected != nil && given != "" && subtle.ConstantTimeCompare([]byte(*expected), []byte(given)) == 1
+}
+
+// DeliveryPINLocked reports whether a stop's PIN is locked after attempts wrong PINs,
+// so the stop can only be completed with a signature or photo
+func DeliveryPINLocked(attempts int) bool {
+	return attempts >= maxDeliveryPINAttempts
+}
+
+// ArriveAtWaypoint records the driver reaching the next stop
+func (s *waypointService) ArriveAtWaypoint(jobID, waypointID, driverID uuid.UUID) (*dto.WaypointResponse, error) {
+	trip, waypoint, err := s.nextWaypoint(jobID, waypointID, driverID)
+	if err != nil {
+		return nil, err
+	}
+	if waypoint.Status == models.WaypointStatusArrived {
+		response := waypointToDTO(waypoint, false)
+		return &response, nil
+	}
+
+	now := time.Now()
+	waypoint.Status = models.WaypointStatusArrived
+	waypoint.ArrivedAt = &now
+	return s.save(trip, waypoint, models.WaypointStatusPending)
+}
+
+// CompleteWaypoint records the next stop as done. Delivery stops need proof: the
+// recipient's signature or a photo of the handed-over package, or the PIN the
+// recipient reads out until too many wrong PINs lock it.
+func (s *waypointService) CompleteWaypoint(jobID, waypointID, driverID uuid.UUID, req dto.CompleteWaypointRequest, proof *ProofImage) (*dto.WaypointResponse, error) {
+	trip, waypoint, err := s.nextWaypoint(jobID, waypointID, driverID)
+	if err != nil {
+		return nil, err
+	}
+	from := waypoint.Status
+
+	proofType := models.ProofOfDeliveryType(req.ProofType)
+	switch proofType {
+	case "":
+		if waypoint.ProofRequired {
+			return nil, ErrProofRequired
+		}
+	case models.ProofOfDeliveryPIN:
+		// The attempt is counted before the PIN is checked so that concurrent
+		// guesses can't get past the limit
+		err := s.waypointRepo.ClaimPINAttempt(waypoint, maxDeliveryPINAttempts)
+		if errors.Is(err, repositories.ErrPINAttemptsExhausted) {
+			return nil, ErrDeliveryPINLocked
+		}
+		if err != nil {
+			return nil, errors.New("failed to update stop")
+		}
+		if !CheckDeliveryPIN(waypoint.DeliveryPIN, req.PIN) {
+			if DeliveryPINLocked(waypoint.PINAttempts) {
+				return nil, ErrDeliveryPINLocked
+			}
+			return nil, ErrInvalidDeliveryPIN
+		}
+		// Wrong PINs entered before the right one no longer count
+		if err := s.waypointRepo.ResetPINAttempts(waypoint); err != nil {
+			return nil, errors.New("failed to update stop")
+		}
+		waypoint.PINAttempts = 0
+		waypoint.ProofType = &proofType
+	case models.ProofOfDeliverySignature, models.ProofOfDeliveryPhoto:
+		if proof == nil {
+			return nil, ErrProofRequired
+		}
+		key, err := s.storeProof(waypoint, proofType, proof)
+		if err != nil {
+			return nil, err
+		}
+		waypoint.ProofType = &proofType
+		waypoint.ProofKey = &key
+	}
+
+	now := time.Now()
+	if waypoint.ArrivedAt == nil {
+		waypoint.ArrivedAt = &now
+	}
+	if req.RecipientName != "" {
+		recipientName := req.RecipientName
+		waypoint.RecipientName = &recipientName
+	}
+	waypoint.Status = models.WaypointStatusCompleted
+	waypoint.CompletedAt = &now
+	return s.save(trip, waypoint, from)
+}
+
+// FailWaypoint records that the next stop couldn't be completed; the driver moves on
+func (s *waypointService) FailWaypoint(jobID, waypointID, driverID uuid.UUID, req dto.FailWaypointRequest) (*dto.WaypointResponse, error) {
+	trip, waypoint, err := s.nextWaypoint(jobID, waypointID, driverID)
+	if err != nil {
+		return nil, err
+	}
+	from := waypoint.Status
+
+	now := time.Now()
+	reason := req.Reason
+	waypoint.Status = models.WaypointStatusFailed
+	waypoint.FailureReason = &reason
+	waypoint.CompletedAt = &now
+	return s.save(trip, waypoint, from)
+}
+
+// GetProof returns the signature or photo taken at a stop to the trip's customer or driver
+func (s *waypointService) GetProof(tripID, waypointID, userID uuid.UUID) (*ProofFile, error) {
+	trip, err := s.tripRepo.FindByID(tripID)
+	if err != nil {
+		return nil, errors.New("trip not found")
+	}
+	if trip.CustomerID != userID && (trip.DriverID == nil || *trip.DriverID != userID) {
+		return nil, errors.New("unauthorized to view this trip")
+	}
+
+	waypoint, err := s.waypointRepo.FindByID(waypointID)
+	if err != nil || waypoint.TripID != trip.ID || waypoint.ProofKey == nil {
+		return nil, errors.New("proof of delivery not found")
+	}
+
+	file, err := s.store.Open(*waypoint.ProofKey)
+	if err != nil {
+		return nil, errors.New("proof of delivery not found")
+	}
+	defer file.Close()
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return nil, errors.New("failed to read proof of delivery")
+	}
+	return &ProofFile{
+		Content:     content,
+		ContentType: mime.TypeByExtension(path.Ext(*waypoint.ProofKey)),
+	}, nil
+}
+
+// nextWaypoint loads a stop of the driver's job, making sure the trip is under way
+// and the stop is the next one to visit
+func (s *waypointService) nextWaypoint(jobID, waypointID, driverID uuid.UUID) (*models.Trip, *models.TripWaypoint, error) {
+	job, err := s.jobRepo.FindByID(jobID)
+	if err != nil {
+		return nil, nil, errors.New("job not found")
+	}
+	if job.DriverID == nil || *job.DriverID != driverID {
+		return nil, nil, errors.New("unauthorized to update this job")
+	}
+	trip := &job.Trip
+	if trip.Status != models.TripStatusInProgress {
+		return nil, nil, errors.New("stops can only be updated while the trip is in progress")
+	}
+
+	var waypoint *models.TripWaypoint
+	for i := range trip.Waypoints {
+		if trip.Waypoints[i].ID == waypointID {
+			waypoint = &trip.Waypoints[i]
+		}
+	}
+	if waypoint == nil {
+		return nil, nil, errors.New("waypoint not found")
+	}
+	switch waypoint.Status {
+	case models.WaypointStatusCompleted, models.WaypointStatusFailed:
+		return nil, nil, fmt.Errorf("stop is already %s", waypoint.Status)
+	}
+	if next := NextWaypoint(trip.Waypoints); next == nil || next.ID != waypoint.ID {
+		return nil, nil, ErrWaypointOutOfOrder
+	}
+	return trip, waypoint, nil
+}
+
+func (s *waypointService) storeProof(waypoint *models.TripWaypoint, proofType models.ProofOfDeliveryType, proof *ProofImage) (string, error) {
+	content, err := io.ReadAll(io.LimitReader(proof.Content, int64(s.maxUploadBytes)+1))
+	if err != nil {
+		return "", errors.New("failed to read proof of delivery")
+	}
+	extension, ok := proofImageExtensions[http.DetectContentType(content)]
+	if !ok || len(content) > s.maxUploadBytes {
+		return "", ErrInvalidProofImage
+	}
+
+	key := fmt.Sprintf("proofs/%s/%s-%s%s", waypoint.TripID, waypoint.ID, proofType, extension)
+	if err := s.store.Put(key, bytes.NewReader(content)); err != nil {
+		return "", errors.New("failed to store proof of delivery")
+	}
+	return key, nil
+}
+
+func (s *waypointService) save(trip *models.Trip, waypoint *models.TripWaypoint, from models.WaypointStatus) (*dto.WaypointResponse, error) {
+	if err := s.waypointRepo.UpdateProgress(waypoint, from); err != nil {
+		if errors.Is(err, repositories.ErrStatusChanged) {
+			return nil, errors.New("stop was updated by another request")
+		}
+		return nil, errors.New("failed to update stop")
+	}
+
+	publishWaypointStatus(trip, waypoint)
+	response := waypointToDTO(waypoint, false)
+	return &response, nil
+}
+
+// newTripWaypoints builds the stops of a new multi-stop trip. Delivery stops need
+// proof of delivery and get a PIN for the recipient.
+func newTripWaypoints(requests []dto.WaypointRequest, serviceType models.ServiceType) ([]models.TripWaypoint, error) {
+	waypoints := make([]models.TripWaypoint, len(requests))
+	for i, req := range requests {
+		waypoint := models.TripWaypoint{
+			Sequence:        i + 1,
+			Latitude:        req.Location.Latitude,
+			Longitude:       req.Location.Longitude,
+			ContactName:     req.ContactName,
+			ContactPhone:    req.ContactPhone,
+			PackageQuantity: 1,
+			Status:          models.WaypointStatusPending,
+		}
+		if req.Location.Address != "" {
+			address := req.Location.Address
+			waypoint.Address = &address
+		}
+		if req.PackageDescription != "" {
+			description := req.PackageDescription
+			waypoint.PackageDescription = &description
+		}
+		if req.PackageQuantity > 0 {
+			waypoint.PackageQuantity = req.PackageQuantity
+		}
+		if req.Instructions != "" {
+			instructions := req.Instructions
+			waypoint.Instructions = &instructions
+		}
+		if serviceType == models.ServiceTypeDelivery {
+			pin, err := GenerateDeliveryPIN()
+			if err != nil {
+				return nil, errors.New("failed to generate delivery PIN")
+			}
+			waypoint.ProofRequired = true
+			waypoint.DeliveryPIN = &pin
+		}
+		waypoints[i] = waypoint
+	}
+	return waypoints, nil
+}
+
+// tripRoute is the route a trip is priced on: the pickup followed by its stops, or
+// by the drop-off when it has none
+func tripRoute(trip *models.Trip) []RoutePoint {
+	points := []RoutePoint{{Latitude: trip.PickupLatitude, Longitude: trip.PickupLongitude}}
+	if len(trip.Waypoints) == 0 {
+		return append(points, RoutePoint{Latitude: trip.DropoffLatitude, Longitude: trip.DropoffLongitude})
+	}
+	for _, waypoint := range trip.Waypoints {
+		points = append(points, RoutePoint{Latitude: waypoint.Latitude, Longitude: waypoint.Longitude})
+	}
+	return points
+}
+
+// waypointsToDTO lists a trip's stops; the delivery PINs are only for the customer
+func waypointsToDTO(waypoints []models.TripWaypoint, forCustomer bool) []dto.WaypointResponse {
+	if len(waypoints) == 0 {
+		return nil
+	}
+	responses := make([]dto.WaypointResponse, len(waypoints))
+	for i := range waypoints {
+		responses[i] = waypointToDTO(&waypoints[i], forCustomer)
+	}
+	return responses
+}
+
+func waypointToDTO(waypoint *models.TripWaypoint, forCustomer bool) dto.WaypointResponse {
+	response := dto.WaypointResponse{
+		ID:       waypoint.ID.String(),
+		Sequence: waypoint.Sequence,
+		Location: dto.Location{
+			Latitude:  waypoint.Latitude,
+			Longitude: waypoint.Longitude,
+		},
+		ContactName:        waypoint.ContactName,
+		ContactPhone:       waypoint.ContactPhone,
+		PackageDescription: waypoint.PackageDescription,
+		PackageQuantity:    waypoint.PackageQuantity,
+		Instructions:       waypoint.Instructions,
+		Status:             string(waypoint.Status),
+		FailureReason:      waypoint.FailureReason,
+		ProofRequired:      waypoint.ProofRequired,
+		PINLocked:          waypoint.DeliveryPIN != nil && DeliveryPINLocked(waypoint.PINAttempts),
+		RecipientName:      waypoint.RecipientName,
+	}
+	if waypoint.Address != nil {
+		response.Location.Address = *waypoint.Address
+	}
+	if waypoint.ArrivedAt != nil {
+		arrivedAt := waypoint.ArrivedAt.Format(time.RFC3339)
+		response.ArrivedAt = &arrivedAt
+	}
+	if waypoint.CompletedAt != nil {
+		completedAt := waypoint.CompletedAt.Format(time.RFC3339)
+		response.CompletedAt = &completedAt
+	}
+	if waypoint.ProofType != nil {
+		proofType := string(*waypoint.ProofType)
+		response.ProofType = &proofType
+	}
+	if waypoint.ProofKey != nil {
+		proofURL := fmt.Sprintf("/api/trips/%s/waypoints/%s/proof", waypoint.TripID, waypoint.ID)
+		response.ProofURL = &proofURL
+	}
+	if forCustomer {
+		response.DeliveryPIN = waypoint.DeliveryPIN
+	}
+	return response
+}
@@ -48,6 +48,10 @@ func NotFound(c *gin.Context, errorMsg string) {
 	ErrorResponse(c, http.StatusNotFound, errorMsg, "NOT_FOUND", nil)
 }
 
+func Conflict(c *gin.Context, errorMsg string) {
+	ErrorResponse(c, http.StatusConflict, errorMsg, "CONFLICT", nil)
+}
+
 func InternalError(c *gin.Context, errorMsg string) {
 	ErrorResponse(c, http.StatusInternalServerError, errorMsg, "INTERNAL_ERROR", nil)
 }
@@ -0,0 +1,52 @@
+// Package geohash encodes coordinates as geohash cells, the grid surge pricing is
+// computed on
+package geohash
+
+const base32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// Encode returns the geohash of the cell containing lat, lng. Each extra character of
+// precision narrows the cell, from about 5km across at 5 to about 1.2km at 6.
+func Encode(lat, lng float64, precision int) string {
+	if precision <= 0 {
+		return ""
+	}
+	if precision > 12 {
+		precision = 12
+	}
+
+	latRange := [2]float64{-90, 90}
+	lngRange := [2]float64{-180, 180}
+	hash := make([]byte, 0, precision)
+	evenBit := true
+	bit, ch := 0, 0
+
+	for len(hash) < precision {
+		// Bits alternate between longitude and latitude, longitude first
+		if evenBit {
+			mid := (lngRange[0] + lngRange[1]) / 2
+			if lng >= mid {
+				ch = ch<<1 | 1
+				lngRange[0] = mid
+			} else {
+				ch <<= 1
+				lngRange[1] = mid
+			}
+		} else {
+			mid := (latRange[0] + latRange[1]) / 2
+			if lat >= mid {
+				ch = ch<<1 | 1
+				latRange[0] = mid
+			} else {
+				ch <<= 1
+				latRange[1] = mid
+			}
+		}
+		evenBit = !evenBit
+
+		if bit++; bit == 5 {
+			hash = append(hash, base32[ch])
+			bit, ch = 0, 0
+		}
+	}
+	return string(hash)
+}
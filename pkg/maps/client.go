@@ -1,6 +1,7 @@
 package maps
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -47,10 +48,11 @@ type DistanceMatrixResponse struct {
 }
 
 type RouteInfo struct {
-	Distance    float64 // in km
-	Duration    int     // in minutes
-	DistanceText string
-	DurationText string
+	Distance        float64 // in km
+	Duration        int     // in minutes
+	DurationSeconds int
+	DistanceText    string
+	DurationText    string
 }
 
 func NewClient() *Client {
@@ -122,6 +124,11 @@ func (c *Client) ReverseGeocode(lat, lng float64) (string, error) {
 }
 
 func (c *Client) GetDistanceAndDuration(originLat, originLng, destLat, destLng float64) (*RouteInfo, error) {
+	return c.Route(context.Background(), originLat, originLng, destLat, destLng)
+}
+
+// Route gets the driving distance and duration between two points, giving up when ctx is done
+func (c *Client) Route(ctx context.Context, originLat, originLng, destLat, destLng float64) (*RouteInfo, error) {
 	baseURL := "https://maps.googleapis.com/maps/api/distancematrix/json"
 	params := url.Values{}
 	params.Add("origins", fmt.Sprintf("%f,%f", originLat, originLng))
@@ -129,7 +136,11 @@ func (c *Client) GetDistanceAndDuration(originLat, originLng, destLat, destLng f
 	params.Add("key", c.apiKey)
 	params.Add("units", "metric")
 
-	resp, err := c.httpClient.Get(fmt.Sprintf("%s?%s", baseURL, params.Encode()))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s?%s", baseURL, params.Encode()), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -155,10 +166,10 @@ func (c *Client) GetDistanceAndDuration(originLat, originLng, destLat, destLng f
 	}
 
 	return &RouteInfo{
-		Distance:     float64(element.Distance.Value) / 1000.0, // convert to km
-		Duration:     element.Duration.Value / 60,              // convert to minutes
-		DistanceText: element.Distance.Text,
-		DurationText: element.Duration.Text,
+		Distance:        float64(element.Distance.Value) / 1000.0, // convert to km
+		Duration:        element.Duration.Value / 60,              // convert to minutes
+		DurationSeconds: element.Duration.Value,
+		DistanceText:    element.Distance.Text,
+		DurationText:    element.Duration.Text,
 	}, nil
 }
-
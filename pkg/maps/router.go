@@ -0,0 +1,78 @@
+package maps
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/telemoz/backend/internal/config"
+)
+
+// Router gets driving routes between two points
+type Router interface {
+	Route(ctx context.Context, originLat, originLng, destLat, destLng float64) (*RouteInfo, error)
+}
+
+// NewRouter returns the configured routing provider, or nil when routing is disabled
+// and distances are always estimated
+func NewRouter() Router {
+	switch config.AppConfig.Routing.Provider {
+	case "google":
+		if config.AppConfig.Maps.APIKey == "" {
+			return nil
+		}
+		return NewClient()
+	case "fake":
+		return NewFakeRouter()
+	}
+	return nil
+}
+
+// FakeRouter answers every request with the same route. Tests can make it fail with
+// FailWith and count requests with Calls.
+type FakeRouter struct {
+	mu    sync.Mutex
+	route RouteInfo
+	err   error
+	calls int
+}
+
+func NewFakeRouter() *FakeRouter {
+	return &FakeRouter{}
+}
+
+// Returns sets the route returned by later requests
+func (r *FakeRouter) Returns(route RouteInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.route = route
+	r.err = nil
+}
+
+// FailWith makes later requests fail with err; nil restores success
+func (r *FakeRouter) FailWith(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.err = err
+}
+
+// Calls returns the number of requests made so far
+func (r *FakeRouter) Calls() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.calls
+}
+
+func (r *FakeRouter) Route(ctx context.Context, originLat, originLng, destLat, destLng float64) (*RouteInfo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls++
+	if r.err != nil {
+		return nil, r.err
+	}
+	if r.route.Distance == 0 && r.route.DurationSeconds == 0 {
+		return nil, errors.New("no route")
+	}
+	route := r.route
+	return &route, nil
+}
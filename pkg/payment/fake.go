@@ -0,0 +1,203 @@
+package payment
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+)
+
+// Charge statuses kept by FakeGateway
+const (
+	FakeChargeAuthorized = "authorized"
+	FakeChargeCaptured   = "captured"
+	FakeChargeVoided     = "voided"
+)
+
+// fakeCards are the test cards FakeGateway knows. Cards ending in 0002 are declined
+// and cards ending in 9995 have insufficient funds.
+var fakeCards = map[string]Card{
+	"tok_visa":               {Token: "tok_visa", Brand: "visa", Last4: "4242", ExpMonth: 12, ExpYear: 2034},
+	"tok_mastercard":         {Token: "tok_mastercard", Brand: "mastercard", Last4: "4444", ExpMonth: 12, ExpYear: 2034},
+	"tok_amex":               {Token: "tok_amex", Brand: "amex", Last4: "8431", ExpMonth: 12, ExpYear: 2034},
+	"tok_declined":           {Token: "tok_declined", Brand: "visa", Last4: "0002", ExpMonth: 12, ExpYear: 2034},
+	"tok_insufficient_funds": {Token: "tok_insufficient_funds", Brand: "visa", Last4: "9995", ExpMonth: 12, ExpYear: 2034},
+}
+
+// FakeCharge is a charge held by FakeGateway
+type FakeCharge struct {
+	CardToken  string
+	Authorized float64
+	Captured   float64
+	Refunded   float64
+	Status     string
+}
+
+// FakeGateway charges the test cards in memory. References are derived from
+// idempotency keys, so the same requests always produce the same references.
+// Tests can make it fail with FailWith.
+type FakeGateway struct {
+	mu      sync.Mutex
+	secret  string
+	charges map[string]*FakeCharge
+	refunds map[string]float64
+	err     error
+}
+
+func NewFakeGateway(webhookSecret string) *FakeGateway {
+	return &FakeGateway{
+		secret:  webhookSecret,
+		charges: make(map[string]*FakeCharge),
+		refunds: make(map[string]float64),
+	}
+}
+
+func (g *FakeGateway) Card(token string) (*Card, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.err != nil {
+		return nil, g.err
+	}
+	card, ok := fakeCards[token]
+	if !ok {
+		return nil, ErrUnknownCard
+	}
+	return &card, nil
+}
+
+func (g *FakeGateway) Authorize(charge Charge) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.err != nil {
+		return "", g.err
+	}
+	reference := "fake_ch_" + charge.IdempotencyKey
+	if _, ok := g.charges[reference]; ok {
+		return reference, nil
+	}
+
+	card, ok := fakeCards[charge.CardToken]
+	if !ok {
+		return "", ErrUnknownCard
+	}
+	switch card.Last4 {
+	case "0002":
+		return "", ErrCardDeclined
+	case "9995":
+		return "", ErrInsufficientFunds
+	}
+	if charge.Amount <= 0 {
+		return "", errors.New("charge amount must be positive")
+	}
+
+	g.charges[reference] = &FakeCharge{
+		CardToken:  charge.CardToken,
+		Authorized: charge.Amount,
+		Status:     FakeChargeAuthorized,
+	}
+	return reference, nil
+}
+
+func (g *FakeGateway) Capture(reference string, amount float64) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.err != nil {
+		return g.err
+	}
+	charge, ok := g.charges[reference]
+	if !ok {
+		return fmt.Errorf("no such charge: %s", reference)
+	}
+	if charge.Status != FakeChargeAuthorized {
+		return fmt.Errorf("charge is %s", charge.Status)
+	}
+	if amount <= 0 || amount > charge.Authorized {
+		return errors.New("capture amount must be positive and within the authorized amount")
+	}
+	charge.Captured = amount
+	charge.Status = FakeChargeCaptured
+	return nil
+}
+
+func (g *FakeGateway) Void(reference string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.err != nil {
+		return g.err
+	}
+	charge, ok := g.charges[reference]
+	if !ok {
+		return fmt.Errorf("no such charge: %s", reference)
+	}
+	if charge.Status != FakeChargeAuthorized {
+		return fmt.Errorf("charge is %s", charge.Status)
+	}
+	charge.Status = FakeChargeVoided
+	return nil
+}
+
+func (g *FakeGateway) Refund(reference string, amount float64, idempotencyKey string) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.err != nil {
+		return "", g.err
+	}
+	refundReference := "fake_re_" + idempotencyKey
+	if _, ok := g.refunds[refundReference]; ok {
+		return refundReference, nil
+	}
+
+	charge, ok := g.charges[reference]
+	if !ok {
+		return "", fmt.Errorf("no such charge: %s", reference)
+	}
+	if charge.Status != FakeChargeCaptured {
+		return "", fmt.Errorf("charge is %s", charge.Status)
+	}
+	remaining := math.Round((charge.Captured-charge.Refunded)*100) / 100
+	if amount <= 0 || amount > remaining {
+		return "", errors.New("refund amount must be positive and within the captured amount")
+	}
+	charge.Refunded += amount
+	g.refunds[refundReference] = amount
+	return refundReference, nil
+}
+
+func (g *FakeGateway) ParseWebhook(payload []byte, signature string) (*WebhookEvent, error) {
+	if !VerifySignature(g.secret, payload, signature) {
+		return nil, ErrInvalidSignature
+	}
+	var event WebhookEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("invalid webhook payload: %w", err)
+	}
+	if event.ID == "" || event.Type == "" {
+		return nil, errors.New("invalid webhook payload: missing id or type")
+	}
+	return &event, nil
+}
+
+// FailWith makes every following call fail with err; nil restores success
+func (g *FakeGateway) FailWith(err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.err = err
+}
+
+// Charges returns the charges made so far, keyed by reference
+func (g *FakeGateway) Charges() map[string]FakeCharge {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	charges := make(map[string]FakeCharge, len(g.charges))
+	for reference, charge := range g.charges {
+		charges[reference] = *charge
+	}
+	return charges
+}
@@ -0,0 +1,106 @@
+package payment
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+
+	"github.com/telemoz/backend/internal/config"
+)
+
+var (
+	// ErrCardDeclined means the card issuer refused the charge
+	ErrCardDeclined = errors.New("card declined")
+	// ErrInsufficientFunds means the card cannot cover the amount
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	// ErrUnknownCard means the gateway has no card for the token
+	ErrUnknownCard = errors.New("unknown card token")
+	// ErrInvalidSignature means a webhook was not signed with the webhook secret
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+)
+
+// Webhook event types
+const (
+	EventChargeCaptured = "charge.captured"
+	EventChargeFailed   = "charge.failed"
+	EventChargeExpired  = "charge.expired"
+	EventRefundFailed   = "refund.failed"
+)
+
+// Card is a saved card as the gateway describes it. Card numbers never reach the
+// backend: clients tokenize cards with the gateway and send the token.
+type Card struct {
+	Token    string
+	Brand    string
+	Last4    string
+	ExpMonth int
+	ExpYear  int
+}
+
+// Charge is a request to hold an amount on a card
+type Charge struct {
+	// IdempotencyKey makes a retried authorization return the original hold
+	// instead of placing a second one
+	IdempotencyKey string
+	CardToken      string
+	Amount         float64
+	Currency       string
+}
+
+// WebhookEvent is an asynchronous update from the gateway about a charge or refund
+type WebhookEvent struct {
+	ID              string  `json:"id"`
+	Type            string  `json:"type"`
+	ChargeReference string  `json:"charge_reference"`
+	RefundReference string  `json:"refund_reference,omitempty"`
+	Amount          float64 `json:"amount,omitempty"`
+	FailureReason   string  `json:"failure_reason,omitempty"`
+}
+
+type PaymentGateway interface {
+	// Card looks up the card behind a token created by the gateway's client SDK
+	Card(token string) (*Card, error)
+	// Authorize holds the amount on the card and returns the gateway's reference for the charge
+	Authorize(charge Charge) (string, error)
+	// Capture takes up to the authorized amount of a held charge
+	Capture(reference string, amount float64) error
+	// Void releases a held charge without taking any money
+	Void(reference string) error
+	// Refund returns part or all of a captured charge and returns the gateway's
+	// reference for the refund
+	Refund(reference string, amount float64, idempotencyKey string) (string, error)
+	// ParseWebhook verifies a webhook's signature and decodes its event
+	ParseWebhook(payload []byte, signature string) (*WebhookEvent, error)
+}
+
+// NewGateway returns the configured gateway, or nil when card payments are disabled
+func NewGateway() PaymentGateway {
+	cfg := config.AppConfig.Payment
+
+	switch cfg.Gateway {
+	case "fake":
+		return NewFakeGateway(cfg.WebhookSecret)
+	}
+
+	return nil
+}
+
+// Sign returns the signature of a webhook payload: the hex HMAC-SHA256 of the
+// payload keyed with the webhook secret
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature reports whether signature is the signature of payload
+func VerifySignature(secret string, payload []byte, signature string) bool {
+	expected, err := hex.DecodeString(signature)
+	if err != nil || secret == "" {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
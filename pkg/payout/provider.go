@@ -0,0 +1,86 @@
+package payout
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/telemoz/backend/internal/config"
+)
+
+// Transfer is a single payout to a driver
+type Transfer struct {
+	// StatementID doubles as an idempotency key so a retried transfer is not paid twice
+	StatementID string
+	DriverID    string
+	Amount      float64
+	Currency    string
+}
+
+type Provider interface {
+	// Pay sends the transfer and returns the provider's reference for it
+	Pay(transfer Transfer) (string, error)
+}
+
+func NewProvider() Provider {
+	cfg := config.AppConfig.Payout
+
+	switch cfg.Provider {
+	case "fake":
+		return NewFakeProvider()
+	}
+
+	// Default: payouts are made outside the system and recorded as paid
+	return &ManualProvider{}
+}
+
+// ManualProvider marks payouts as paid without moving money, for payouts made by hand
+type ManualProvider struct{}
+
+func (p *ManualProvider) Pay(transfer Transfer) (string, error) {
+	return "manual-" + transfer.StatementID, nil
+}
+
+// FakeProvider records transfers in memory. Tests can make it fail with FailWith.
+type FakeProvider struct {
+	mu        sync.Mutex
+	transfers map[string]Transfer
+	err       error
+}
+
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{transfers: make(map[string]Transfer)}
+}
+
+func (p *FakeProvider) Pay(transfer Transfer) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.err != nil {
+		return "", p.err
+	}
+	if transfer.Amount <= 0 {
+		return "", errors.New("payout amount must be positive")
+	}
+	p.transfers[transfer.StatementID] = transfer
+	return fmt.Sprintf("fake-%s", transfer.StatementID), nil
+}
+
+// FailWith makes every following Pay call fail with err; nil restores success
+func (p *FakeProvider) FailWith(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+}
+
+// Transfers returns the transfers paid so far, keyed by statement ID
+func (p *FakeProvider) Transfers() map[string]Transfer {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	transfers := make(map[string]Transfer, len(p.transfers))
+	for id, transfer := range p.transfers {
+		transfers[id] = transfer
+	}
+	return transfers
+}
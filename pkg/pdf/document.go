@@ -0,0 +1,146 @@
+// Package pdf writes simple text-only PDF documents, enough for statements and receipts
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const (
+	pageWidth    = 595 // A4 in points
+	pageHeight   = 842
+	margin       = 50
+	leading      = 14
+	fontSize     = 10
+	headingSize  = 14
+	linesPerPage = (pageHeight - 2*margin) / leading
+)
+
+type line struct {
+	text string
+	bold bool
+	size int
+}
+
+// Document is a PDF built line by line. Lines that don't fit on a page continue on the next.
+type Document struct {
+	lines []line
+}
+
+func New() *Document {
+	return &Document{}
+}
+
+// Heading adds a line in large bold type
+func (d *Document) Heading(text string) {
+	d.lines = append(d.lines, line{text: text, bold: true, size: headingSize})
+}
+
+// Text adds a line in regular type
+func (d *Document) Text(text string) {
+	d.lines = append(d.lines, line{text: text, size: fontSize})
+}
+
+// Bold adds a line in bold type
+func (d *Document) Bold(text string) {
+	d.lines = append(d.lines, line{text: text, bold: true, size: fontSize})
+}
+
+// Blank adds an empty line
+func (d *Document) Blank() {
+	d.lines = append(d.lines, line{size: fontSize})
+}
+
+// Bytes renders the document
+func (d *Document) Bytes() []byte {
+	pages := d.paginate()
+
+	// Objects 1-4 are the catalog, page tree and the two fonts; each page then takes
+	// two objects, the page itself and its content stream
+	var objects []string
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	objects = append(objects,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+	)
+	for i, page := range pages {
+		content := pageContent(page)
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+				pageWidth, pageHeight, 6+2*i),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+func (d *Document) paginate() [][]line {
+	var pages [][]line
+	for start := 0; start < len(d.lines); start += linesPerPage {
+		end := start + linesPerPage
+		if end > len(d.lines) {
+			end = len(d.lines)
+		}
+		pages = append(pages, d.lines[start:end])
+	}
+	if len(pages) == 0 {
+		pages = append(pages, nil)
+	}
+	return pages
+}
+
+func pageContent(lines []line) string {
+	var content strings.Builder
+	y := pageHeight - margin
+	for _, l := range lines {
+		y -= leading
+		if l.text == "" {
+			continue
+		}
+		font := "F1"
+		if l.bold {
+			font = "F2"
+		}
+		fmt.Fprintf(&content, "BT /%s %d Tf %d %d Td (%s) Tj ET\n", font, l.size, margin, y, escape(l.text))
+	}
+	return content.String()
+}
+
+// escape makes text safe inside a PDF string literal. Characters outside ASCII are
+// replaced as the standard fonts are used without embedding.
+func escape(text string) string {
+	var escaped strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			escaped.WriteRune('\\')
+			escaped.WriteRune(r)
+		case r < 32 || r > 126:
+			escaped.WriteRune('?')
+		default:
+			escaped.WriteRune(r)
+		}
+	}
+	return escaped.String()
+}
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/telemoz/backend/internal/config"
+)
+
+// ErrNotFound means nothing is stored under the key
+var ErrNotFound = errors.New("file not found")
+
+// Store keeps uploaded files, such as proof-of-delivery photos, under slash-separated keys
+type Store interface {
+	// Put saves content under key, replacing anything already stored there
+	Put(key string, content io.Reader) error
+	// Open returns the content stored under key
+	Open(key string) (io.ReadCloser, error)
+}
+
+// NewStore returns the configured store
+func NewStore() Store {
+	return NewLocalStore(config.AppConfig.Storage.Dir)
+}
+
+// LocalStore keeps files on the local disk under a root directory
+type LocalStore struct {
+	root string
+}
+
+func NewLocalStore(root string) *LocalStore {
+	return &LocalStore{root: root}
+}
+
+func (s *LocalStore) Put(key string, content io.Reader) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+
+	// Written to a temporary file first so a failed upload never leaves half a file
+	file, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err := io.Copy(file, content); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+func (s *LocalStore) Open(key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+// path maps a key to a file under the root, refusing keys that would escape it
+func (s *LocalStore) path(key string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(key))
+	if key == "" || filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", errors.New("invalid storage key")
+	}
+	return filepath.Join(s.root, clean), nil
+}
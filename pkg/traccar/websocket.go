@@ -1,8 +1,12 @@
 package traccar
 
 import (
+	"encoding/base64"
 	"fmt"
 	"log"
+	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -14,7 +18,11 @@ type WebSocketClient struct {
 	url      string
 	username string
 	password string
-	handlers map[int]func(Position)
+
+	mu         sync.RWMutex
+	handlers   map[int]func(Position)
+	onPosition func(Position)
+	done       chan error
 }
 
 type WebSocketMessage struct {
@@ -31,23 +39,30 @@ type Event struct {
 
 func NewWebSocketClient() *WebSocketClient {
 	cfg := config.AppConfig.Traccar
-	url := fmt.Sprintf("ws://%s/api/socket", cfg.URL)
-	
-	// Remove http:// or https:// prefix
-	if len(url) > 7 && url[:7] == "http://" {
-		url = url[7:]
-	} else if len(url) > 8 && url[:8] == "https://" {
-		url = url[8:]
-		url = "wss://" + url
-	} else {
-		url = "ws://" + url
-	}
+	return NewWebSocketClientWithURL(SocketURL(cfg.URL), cfg.Username, cfg.Password)
+}
 
+// NewWebSocketClientWithURL creates a client for an explicit socket URL, e.g. ws://host:8082/api/socket
+func NewWebSocketClientWithURL(url, username, password string) *WebSocketClient {
 	return &WebSocketClient{
 		url:      url,
-		username: cfg.Username,
-		password: cfg.Password,
+		username: username,
+		password: password,
 		handlers: make(map[int]func(Position)),
+		done:     make(chan error, 1),
+	}
+}
+
+// SocketURL converts the Traccar HTTP base URL into its WebSocket endpoint
+func SocketURL(baseURL string) string {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	switch {
+	case strings.HasPrefix(baseURL, "https://"):
+		return "wss://" + strings.TrimPrefix(baseURL, "https://") + "/api/socket"
+	case strings.HasPrefix(baseURL, "http://"):
+		return "ws://" + strings.TrimPrefix(baseURL, "http://") + "/api/socket"
+	default:
+		return "ws://" + baseURL + "/api/socket"
 	}
 }
 
@@ -56,7 +71,11 @@ func (c *WebSocketClient) Connect() error {
 		HandshakeTimeout: 10 * time.Second,
 	}
 
-	conn, _, err := dialer.Dial(c.url, nil)
+	header := http.Header{}
+	credentials := base64.StdEncoding.EncodeToString([]byte(c.username + ":" + c.password))
+	header.Set("Authorization", "Basic "+credentials)
+
+	conn, _, err := dialer.Dial(c.url, header)
 	if err != nil {
 		return fmt.Errorf("failed to connect to Traccar WebSocket: %w", err)
 	}
@@ -86,39 +105,66 @@ func (c *WebSocketClient) readMessages() {
 		var msg WebSocketMessage
 		if err := c.conn.ReadJSON(&msg); err != nil {
 			log.Printf("WebSocket read error: %v", err)
-			break
+			c.done <- err
+			close(c.done)
+			return
 		}
 
 		// Handle positions
 		for _, pos := range msg.Positions {
-			if handler, exists := c.handlers[pos.DeviceID]; exists {
-				handler(pos)
-			}
+			c.dispatch(pos)
 		}
 
 		// Handle events
 		for _, event := range msg.Events {
 			if event.Type == "position" && event.Position.DeviceID > 0 {
-				if handler, exists := c.handlers[event.DeviceID]; exists {
-					handler(event.Position)
-				}
+				c.dispatch(event.Position)
 			}
 		}
 	}
 }
 
+func (c *WebSocketClient) dispatch(pos Position) {
+	c.mu.RLock()
+	handler, exists := c.handlers[pos.DeviceID]
+	onPosition := c.onPosition
+	c.mu.RUnlock()
+
+	if exists {
+		handler(pos)
+	}
+	if onPosition != nil {
+		onPosition(pos)
+	}
+}
+
 func (c *WebSocketClient) SubscribeToDevice(deviceID int, handler func(Position)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.handlers[deviceID] = handler
 }
 
 func (c *WebSocketClient) UnsubscribeFromDevice(deviceID int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	delete(c.handlers, deviceID)
 }
 
+// OnPosition registers a handler that receives positions from every device
+func (c *WebSocketClient) OnPosition(handler func(Position)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onPosition = handler
+}
+
+// Done is signalled with the read error once the connection drops
+func (c *WebSocketClient) Done() <-chan error {
+	return c.done
+}
+
 func (c *WebSocketClient) Close() error {
 	if c.conn != nil {
 		return c.conn.Close()
 	}
 	return nil
 }
-
@@ -0,0 +1,152 @@
+package jobs_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/telemoz/backend/internal/jobs"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/pkg/traccar"
+	"gorm.io/gorm"
+)
+
+type fakeBusLookup struct {
+	buses map[string]*models.Bus
+}
+
+func (f *fakeBusLookup) FindByTraccarDeviceID(deviceID string) (*models.Bus, error) {
+	if bus, ok := f.buses[deviceID]; ok {
+		return bus, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+type fakeRecorder struct {
+	mu        sync.Mutex
+	locations []models.BusLocation
+	received  chan struct{}
+}
+
+func (f *fakeRecorder) RecordBusLocation(location *models.BusLocation) error {
+	f.mu.Lock()
+	f.locations = append(f.locations, *location)
+	f.mu.Unlock()
+	f.received <- struct{}{}
+	return nil
+}
+
+// fakeTraccar serves one batch of messages per connection and then drops the socket
+func fakeTraccar(t *testing.T, batches [][]traccar.WebSocketMessage) (*httptest.Server, func() int) {
+	upgrader := websocket.Upgrader{}
+	connections := 0
+	var mu sync.Mutex
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		index := connections
+		connections++
+		mu.Unlock()
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var auth map[string]string
+		if err := conn.ReadJSON(&auth); err != nil || auth["type"] != "authenticate" {
+			return
+		}
+
+		if index < len(batches) {
+			for _, msg := range batches[index] {
+				conn.WriteJSON(msg)
+			}
+		}
+		if index == len(batches)-1 {
+			// Keep the last connection open until the client goes away
+			conn.ReadMessage()
+		}
+	}))
+
+	connectionCount := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return connections
+	}
+	return server, connectionCount
+}
+
+func TestTraccarIngestionPersistsAndReconnects(t *testing.T) {
+	busID := uuid.New()
+	lookup := &fakeBusLookup{buses: map[string]*models.Bus{
+		"42": {ID: busID},
+	}}
+	recorder := &fakeRecorder{received: make(chan struct{}, 10)}
+
+	server, connectionCount := fakeTraccar(t, [][]traccar.WebSocketMessage{
+		{
+			{Positions: []traccar.Position{
+				{ID: 1, DeviceID: 42, Latitude: 25.1, Longitude: 55.1, Speed: 10, Course: 90, Accuracy: 5, FixTime: "2026-01-05T07:30:00.000+00:00"},
+				{ID: 2, DeviceID: 7, Latitude: 1, Longitude: 1}, // not installed in a bus
+			}},
+		},
+		{
+			// Traccar replays the latest position on reconnect
+			{Positions: []traccar.Position{{ID: 1, DeviceID: 42, Latitude: 25.1, Longitude: 55.1}}},
+			{Events: []traccar.Event{{Type: "position", DeviceID: 42, Position: traccar.Position{ID: 3, DeviceID: 42, Latitude: 25.2, Longitude: 55.2}}}},
+		},
+	})
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+	worker := jobs.NewTraccarIngestionWorker(
+		func() *traccar.WebSocketClient { return traccar.NewWebSocketClientWithURL(url, "admin", "admin") },
+		lookup,
+		recorder,
+		10*time.Millisecond,
+		50*time.Millisecond,
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go worker.Run(ctx)
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-recorder.received:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for position %d", i+1)
+		}
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	require.Len(t, recorder.locations, 2)
+	assert.GreaterOrEqual(t, connectionCount(), 2)
+
+	first := recorder.locations[0]
+	assert.Equal(t, busID, first.BusID)
+	assert.Equal(t, 25.1, first.Latitude)
+	assert.InDelta(t, 18.52, *first.Speed, 0.001)
+	assert.Equal(t, 90.0, *first.Heading)
+	assert.Equal(t, 5.0, *first.Accuracy)
+	assert.Equal(t, time.Date(2026, 1, 5, 7, 30, 0, 0, time.UTC), first.Timestamp.UTC())
+
+	assert.Equal(t, 25.2, recorder.locations[1].Latitude)
+}
+
+func TestSocketURL(t *testing.T) {
+	assert.Equal(t, "ws://localhost:8082/api/socket", traccar.SocketURL("http://localhost:8082"))
+	assert.Equal(t, "wss://traccar.example.com/api/socket", traccar.SocketURL("https://traccar.example.com/"))
+	assert.Equal(t, "ws://localhost:8082/api/socket", traccar.SocketURL("localhost:8082"))
+}
@@ -0,0 +1,37 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/telemoz/backend/internal/middleware"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestTokenFromQueryIsNotLogged(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	core, logs := observer.New(zap.InfoLevel)
+	router := gin.New()
+	router.Use(middleware.Logger(zap.New(core)))
+
+	var authHeader string
+	router.GET("/ws", middleware.TokenFromQuery(), func(c *gin.Context) {
+		authHeader = c.GetHeader("Authorization")
+		c.Status(http.StatusOK)
+	})
+
+	req, _ := http.NewRequest("GET", "/ws?token=secret-jwt&topic=bus", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, "Bearer secret-jwt", authHeader)
+	entries := logs.All()
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "topic=bus", entries[0].ContextMap()["query"])
+	}
+}
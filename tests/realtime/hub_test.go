@@ -0,0 +1,60 @@
+package realtime_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/telemoz/backend/internal/realtime"
+)
+
+func receive(t *testing.T, sub *realtime.Subscription) (realtime.Event, bool) {
+	t.Helper()
+	select {
+	case event, ok := <-sub.Events():
+		return event, ok
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for event")
+		return realtime.Event{}, false
+	}
+}
+
+func TestHubDeliversOnlySubscribedTopics(t *testing.T) {
+	hub := realtime.NewHub()
+	customer := hub.Subscribe("user:customer")
+	parent := hub.Subscribe("user:parent", "bus:1")
+	defer customer.Close()
+	defer parent.Close()
+
+	hub.Publish("bus:1", realtime.EventBusLocation, map[string]float64{"latitude": 1})
+	hub.Publish("user:customer", realtime.EventTripStatus, "accepted")
+
+	event, ok := receive(t, parent)
+	assert.True(t, ok)
+	assert.Equal(t, realtime.EventBusLocation, event.Type)
+	assert.Equal(t, "bus:1", event.Topic)
+
+	event, ok = receive(t, customer)
+	assert.True(t, ok)
+	assert.Equal(t, realtime.EventTripStatus, event.Type)
+	assert.Equal(t, "accepted", event.Data)
+
+	select {
+	case event := <-parent.Events():
+		t.Fatalf("unexpected event for parent: %+v", event)
+	default:
+	}
+}
+
+func TestHubCloseStopsDelivery(t *testing.T) {
+	hub := realtime.NewHub()
+	sub := hub.Subscribe("trip")
+	sub.Close()
+	sub.Close()
+
+	// Publishing after close must neither panic nor block
+	hub.Publish("trip", realtime.EventTripStatus, nil)
+
+	_, ok := <-sub.Events()
+	assert.False(t, ok)
+}
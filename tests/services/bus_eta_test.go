@@ -0,0 +1,93 @@
+package services_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/services"
+)
+
+func TestEstimateStopETAs(t *testing.T) {
+	// Stops roughly 1.11 km apart due north of the bus
+	first := services.ETAStop{StopID: uuid.New(), Latitude: 25.01, Longitude: 55.0, Dwell: time.Minute}
+	second := services.ETAStop{StopID: uuid.New(), Latitude: 25.02, Longitude: 55.0, Dwell: time.Minute}
+
+	etas := services.EstimateStopETAs(25.0, 55.0, 20, 1.0, []services.ETAStop{first, second})
+
+	assert.Len(t, etas, 2)
+	assert.Equal(t, first.StopID, etas[0].StopID)
+	assert.InDelta(t, 1.11, etas[0].DistanceKm, 0.01)
+	assert.InDelta(t, 200, etas[0].Duration.Seconds(), 2) // 1.11 km at 20 km/h
+	assert.InDelta(t, 2.22, etas[1].DistanceKm, 0.01)
+	// Second leg plus the dwell at the first stop
+	assert.InDelta(t, 460, etas[1].Duration.Seconds(), 3)
+}
+
+func TestEstimateBusSpeed(t *testing.T) {
+	now := time.Now()
+	speed := func(v float64) *float64 { return &v }
+
+	locations := []models.BusLocation{
+		{Speed: speed(30), Timestamp: now.Add(-3 * time.Minute)},
+		{Speed: speed(0), Timestamp: now.Add(-2 * time.Minute)}, // waiting at a light
+		{Speed: speed(20), Timestamp: now.Add(-time.Minute)},
+	}
+	assert.InDelta(t, 25, services.EstimateBusSpeed(locations, 15), 0.001)
+
+	// Without reported speed the pace between fixes is used
+	measured := []models.BusLocation{
+		{Latitude: 25.0, Longitude: 55.0, Timestamp: now.Add(-time.Minute)},
+		{Latitude: 25.005, Longitude: 55.0, Timestamp: now},
+	}
+	assert.InDelta(t, 33.4, services.EstimateBusSpeed(measured, 15), 0.5)
+
+	assert.Equal(t, 15.0, services.EstimateBusSpeed(nil, 15))
+}
+
+func TestRemainingStops(t *testing.T) {
+	stops := []models.RouteStop{{ID: uuid.New()}, {ID: uuid.New()}, {ID: uuid.New()}, {ID: uuid.New()}}
+
+	// A skipped stop before the last visited one is not waited for
+	remaining := services.RemainingStops(stops, map[uuid.UUID]bool{stops[0].ID: true, stops[2].ID: true})
+	assert.Equal(t, stops[3:], remaining)
+
+	assert.Equal(t, stops, services.RemainingStops(stops, map[uuid.UUID]bool{}))
+}
+
+func TestActiveRun(t *testing.T) {
+	loc := time.UTC
+	weekdays := models.StringArray{"mon", "tue", "wed", "thu", "fri"}
+	stopID := uuid.New()
+	morning := models.Schedule{
+		ID: uuid.New(), RunType: models.RunTypeMorning, Weekdays: weekdays, IsActive: true,
+		DepartureTime: "07:00",
+		StopTimes:     []models.ScheduleStopTime{{RouteStopID: stopID, ArrivalTime: "07:40"}},
+	}
+	afternoon := models.Schedule{
+		ID: uuid.New(), RunType: models.RunTypeAfternoon, Weekdays: weekdays, IsActive: true,
+		DepartureTime: "14:30",
+	}
+	schedules := []models.Schedule{morning, afternoon}
+
+	// Wednesday 06:45 falls in the lead time before the morning departure
+	run, start := services.ActiveRun(schedules, time.Date(2026, 3, 4, 6, 45, 0, 0, loc), loc)
+	if assert.NotNil(t, run) {
+		assert.Equal(t, morning.ID, run.ID)
+		assert.Equal(t, time.Date(2026, 3, 4, 6, 30, 0, 0, loc), start)
+	}
+
+	run, _ = services.ActiveRun(schedules, time.Date(2026, 3, 4, 15, 0, 0, 0, loc), loc)
+	if assert.NotNil(t, run) {
+		assert.Equal(t, afternoon.ID, run.ID)
+	}
+
+	run, _ = services.ActiveRun(schedules, time.Date(2026, 3, 4, 11, 0, 0, 0, loc), loc)
+	assert.Nil(t, run)
+
+	// Saturday
+	run, _ = services.ActiveRun(schedules, time.Date(2026, 3, 7, 7, 10, 0, 0, loc), loc)
+	assert.Nil(t, run)
+}
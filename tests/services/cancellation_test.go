@@ -0,0 +1,70 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/telemoz/backend/internal/config"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/services"
+)
+
+var cancellationPolicy = config.CancellationConfig{
+	FreeWindow: 2 * time.Minute,
+	Fee:        5,
+	NoShowWait: 5 * time.Minute,
+	NoShowFee:  7,
+}
+
+func TestCancellationFee(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	justAccepted := now.Add(-time.Minute)
+	acceptedLongAgo := now.Add(-3 * time.Minute)
+	fare := 12.0
+
+	assert.Equal(t, 0.0, services.CancellationFee(cancellationPolicy, models.TripStatusSearching, nil, &fare, now))
+	assert.Equal(t, 0.0, services.CancellationFee(cancellationPolicy, models.TripStatusAccepted, &justAccepted, &fare, now))
+	assert.Equal(t, 5.0, services.CancellationFee(cancellationPolicy, models.TripStatusAccepted, &acceptedLongAgo, &fare, now))
+	// Once the driver is waiting at the pickup there is no free window
+	assert.Equal(t, 5.0, services.CancellationFee(cancellationPolicy, models.TripStatusArrived, &justAccepted, &fare, now))
+
+	// The fee never exceeds the fare
+	cheap := 3.5
+	assert.Equal(t, 3.5, services.CancellationFee(cancellationPolicy, models.TripStatusArrived, &justAccepted, &cheap, now))
+	assert.Equal(t, 3.5, services.NoShowFee(cancellationPolicy, &cheap))
+	assert.Equal(t, 7.0, services.NoShowFee(cancellationPolicy, nil))
+}
+
+func TestNoShowDue(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	arrivedRecently := now.Add(-4 * time.Minute)
+	arrivedLongAgo := now.Add(-5 * time.Minute)
+
+	assert.False(t, services.NoShowDue(cancellationPolicy, &arrivedRecently, now))
+	assert.True(t, services.NoShowDue(cancellationPolicy, &arrivedLongAgo, now))
+	assert.True(t, services.NoShowDue(cancellationPolicy, nil, now))
+}
+
+func TestFeeCollectable(t *testing.T) {
+	assert.True(t, services.FeeCollectable(models.PaymentTypeCard))
+	assert.True(t, services.FeeCollectable(models.PaymentTypeWallet))
+	// Cash trips' fees can't be collected, so drivers aren't compensated out of them
+	assert.False(t, services.FeeCollectable(models.PaymentTypeCash))
+}
+
+func TestDriverCancellationRequeuesTrip(t *testing.T) {
+	assert.NoError(t, services.ValidateTripTransition(models.TripStatusAccepted, models.TripStatusSearching, models.TripActorDriver))
+	assert.NoError(t, services.ValidateTripTransition(models.TripStatusArrived, models.TripStatusSearching, models.TripActorDriver))
+
+	// Only drivers give trips back, and only before they start
+	err := services.ValidateTripTransition(models.TripStatusAccepted, models.TripStatusSearching, models.TripActorCustomer)
+	assert.True(t, errors.Is(err, services.ErrTransitionForbidden))
+	err = services.ValidateTripTransition(models.TripStatusInProgress, models.TripStatusSearching, models.TripActorDriver)
+	assert.True(t, errors.Is(err, services.ErrIllegalTransition))
+
+	status, ok := services.JobStatusForTrip(models.TripStatusSearching)
+	assert.True(t, ok)
+	assert.Equal(t, models.JobStatusPending, status)
+}
@@ -0,0 +1,39 @@
+package services_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/services"
+)
+
+func TestChatOpen(t *testing.T) {
+	assert.True(t, services.ChatOpen(models.TripStatusAccepted))
+	assert.True(t, services.ChatOpen(models.TripStatusArrived))
+	assert.True(t, services.ChatOpen(models.TripStatusInProgress))
+
+	// Before a driver is assigned and once the trip ends the chat is read-only
+	assert.False(t, services.ChatOpen(models.TripStatusSearching))
+	assert.False(t, services.ChatOpen(models.TripStatusCompleted))
+	assert.False(t, services.ChatOpen(models.TripStatusCancelled))
+}
+
+func TestMaskPhoneNumbers(t *testing.T) {
+	assert.Equal(t, "Call me on [hidden] please", services.MaskPhoneNumbers("Call me on +971 50 123 4567 please"))
+	assert.Equal(t, "My number is [hidden]", services.MaskPhoneNumbers("My number is (555) 123-4567"))
+	assert.Equal(t, "[hidden]", services.MaskPhoneNumbers("0501234567"))
+
+	// Short numbers such as house numbers and times are left alone
+	assert.Equal(t, "Building 12, flat 304, at 5:30", services.MaskPhoneNumbers("Building 12, flat 304, at 5:30"))
+}
+
+func TestQuickReplyText(t *testing.T) {
+	text, ok := services.QuickReplyText(models.UserTypeDriver, "arrived")
+	assert.True(t, ok)
+	assert.NotEmpty(t, text)
+
+	// Each side has its own quick replies
+	_, ok = services.QuickReplyText(models.UserTypeCustomer, "arrived")
+	assert.False(t, ok)
+}
@@ -0,0 +1,52 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/telemoz/backend/internal/config"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/services"
+)
+
+func TestVehicleClassForPackage(t *testing.T) {
+	class, err := services.VehicleClassForPackage(2, 30, 20, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, models.VehicleClassBike, class)
+
+	// Dimensions fit whichever way round the package goes
+	class, err = services.VehicleClassForPackage(2, 10, 40, 50)
+	assert.NoError(t, err)
+	assert.Equal(t, models.VehicleClassBike, class)
+
+	// Too heavy for a bike, too long for a car
+	class, err = services.VehicleClassForPackage(20, 30, 20, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, models.VehicleClassCar, class)
+	class, err = services.VehicleClassForPackage(20, 180, 40, 40)
+	assert.NoError(t, err)
+	assert.Equal(t, models.VehicleClassVan, class)
+
+	_, err = services.VehicleClassForPackage(3000, 30, 20, 10)
+	assert.True(t, errors.Is(err, services.ErrPackageTooLarge))
+}
+
+func TestVehicleClassFits(t *testing.T) {
+	assert.True(t, services.VehicleClassFits(models.VehicleClassVan, models.VehicleClassCar))
+	assert.True(t, services.VehicleClassFits(models.VehicleClassCar, models.VehicleClassCar))
+	assert.False(t, services.VehicleClassFits(models.VehicleClassBike, models.VehicleClassCar))
+	// Drivers who never set a class count as cars
+	assert.True(t, services.VehicleClassFits("", models.VehicleClassBike))
+	assert.False(t, services.VehicleClassFits("", models.VehicleClassVan))
+}
+
+func TestWeightSurcharge(t *testing.T) {
+	tiers := []config.WeightTier{{OverKg: 5, Surcharge: 2}, {OverKg: 15, Surcharge: 5}, {OverKg: 30, Surcharge: 10}}
+
+	assert.Equal(t, 0.0, services.WeightSurcharge(tiers, 5))
+	assert.Equal(t, 2.0, services.WeightSurcharge(tiers, 5.5))
+	assert.Equal(t, 5.0, services.WeightSurcharge(tiers, 20))
+	assert.Equal(t, 10.0, services.WeightSurcharge(tiers, 500))
+	assert.Equal(t, 0.0, services.WeightSurcharge(nil, 500))
+}
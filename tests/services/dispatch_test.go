@@ -0,0 +1,64 @@
+package services_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/services"
+)
+
+func driverAt(lat, lng float64, seen time.Time) models.DriverAvailability {
+	return models.DriverAvailability{
+		DriverID:          uuid.New(),
+		IsAvailable:       true,
+		ServiceTypes:      models.StringArray{"taxi"},
+		Latitude:          &lat,
+		Longitude:         &lng,
+		LocationUpdatedAt: &seen,
+	}
+}
+
+func TestRankDriversByDistance(t *testing.T) {
+	now := time.Now()
+	pickupLat, pickupLng := 25.2048, 55.2708
+
+	far := driverAt(25.2500, 55.3000, now)
+	near := driverAt(25.2050, 55.2710, now)
+	middle := driverAt(25.2100, 55.2800, now)
+	stale := driverAt(25.2049, 55.2709, now.Add(-time.Hour))
+	outOfRange := driverAt(25.9000, 56.0000, now)
+	excluded := driverAt(25.2048, 55.2708, now)
+	noLocation := models.DriverAvailability{DriverID: uuid.New(), IsAvailable: true}
+
+	candidates := services.RankDriversByDistance(
+		[]models.DriverAvailability{far, near, middle, stale, outOfRange, excluded, noLocation},
+		pickupLat, pickupLng,
+		10,
+		now.Add(-5*time.Minute),
+		map[uuid.UUID]bool{excluded.DriverID: true},
+	)
+
+	if assert.Len(t, candidates, 3) {
+		assert.Equal(t, near.DriverID, candidates[0].DriverID)
+		assert.Equal(t, middle.DriverID, candidates[1].DriverID)
+		assert.Equal(t, far.DriverID, candidates[2].DriverID)
+		assert.Less(t, candidates[0].DistanceKm, candidates[1].DistanceKm)
+	}
+}
+
+func TestStringArrayRoundTrip(t *testing.T) {
+	original := models.StringArray{"taxi", "school_bus", `with "quotes"`}
+
+	value, err := original.Value()
+	assert.NoError(t, err)
+
+	var scanned models.StringArray
+	assert.NoError(t, scanned.Scan([]byte(value.(string))))
+	assert.Equal(t, original, scanned)
+
+	assert.NoError(t, scanned.Scan("{}"))
+	assert.Empty(t, scanned)
+}
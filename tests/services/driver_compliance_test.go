@@ -0,0 +1,54 @@
+package services_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/services"
+)
+
+var requiredDocuments = []string{"license", "insurance"}
+
+func driverDocument(documentType models.DocumentType, status models.DocumentStatus, createdAt, expiresAt time.Time) models.DriverDocument {
+	return models.DriverDocument{Type: documentType, Status: status, CreatedAt: createdAt, ExpiresAt: expiresAt}
+}
+
+func TestDriverComplianceIssues(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	lastYear := now.AddDate(-1, 0, 0)
+	nextYear := now.AddDate(1, 0, 0)
+	vehicle := &models.Vehicle{PlateNumber: "AB123CD"}
+
+	documents := []models.DriverDocument{
+		driverDocument(models.DocumentTypeLicense, models.DocumentStatusApproved, lastYear, nextYear),
+		driverDocument(models.DocumentTypeInsurance, models.DocumentStatusApproved, lastYear, nextYear),
+	}
+	assert.Empty(t, services.DriverComplianceIssues(vehicle, documents, requiredDocuments, now))
+
+	assert.Equal(t, []string{"no vehicle registered", "license missing", "insurance missing"},
+		services.DriverComplianceIssues(nil, nil, requiredDocuments, now))
+
+	// Each type is reported by its latest upload
+	documents = []models.DriverDocument{
+		driverDocument(models.DocumentTypeLicense, models.DocumentStatusApproved, lastYear, now),
+		driverDocument(models.DocumentTypeInsurance, models.DocumentStatusPending, now.Add(-time.Hour), nextYear),
+		driverDocument(models.DocumentTypeInsurance, models.DocumentStatusRejected, now.Add(-2*time.Hour), nextYear),
+	}
+	assert.Equal(t, []string{"license expired", "insurance awaiting review"},
+		services.DriverComplianceIssues(vehicle, documents, requiredDocuments, now))
+}
+
+func TestRenewalPendingKeepsDriverCompliant(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	documents := []models.DriverDocument{
+		driverDocument(models.DocumentTypeLicense, models.DocumentStatusPending, now, now.AddDate(5, 0, 0)),
+		driverDocument(models.DocumentTypeLicense, models.DocumentStatusApproved, now.AddDate(-1, 0, 0), now.AddDate(0, 0, 10)),
+	}
+	assert.Empty(t, services.DriverComplianceIssues(&models.Vehicle{}, documents, []string{"license"}, now))
+
+	assert.True(t, services.DocumentValid(&documents[1], now))
+	assert.False(t, services.DocumentValid(&documents[0], now))
+	assert.False(t, services.DocumentValid(&documents[1], now.AddDate(0, 0, 10)))
+}
@@ -0,0 +1,35 @@
+package services_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/telemoz/backend/internal/config"
+	"github.com/telemoz/backend/internal/services"
+)
+
+func TestCommissionPercent(t *testing.T) {
+	cfg := config.CommissionConfig{
+		PlatformFeePercent: 20,
+		ServiceFeePercent:  map[string]float64{"delivery": 15},
+	}
+
+	assert.Equal(t, 15.0, services.CommissionPercent(cfg, "delivery"))
+	assert.Equal(t, 20.0, services.CommissionPercent(cfg, "taxi"))
+}
+
+func TestSplitEarning(t *testing.T) {
+	commission, net := services.SplitEarning(23.45, 20)
+	assert.Equal(t, 4.69, commission)
+	assert.Equal(t, 18.76, net)
+
+	// Rounding never loses or invents a cent
+	commission, net = services.SplitEarning(10.01, 12.5)
+	assert.Equal(t, 1.25, commission)
+	assert.Equal(t, 8.76, net)
+
+	// Tips with no platform share go to the driver in full
+	commission, net = services.SplitEarning(5, 0)
+	assert.Equal(t, 0.0, commission)
+	assert.Equal(t, 5.0, net)
+}
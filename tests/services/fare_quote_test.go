@@ -0,0 +1,87 @@
+package services_test
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/telemoz/backend/internal/services"
+)
+
+func issueQuote(t *testing.T, signer *services.QuoteSigner, customerID uuid.UUID, now time.Time) (*services.FareQuote, string) {
+	quote := &services.FareQuote{
+		CustomerID:       customerID,
+		ServiceType:      "taxi",
+		PickupLatitude:   25.2048,
+		PickupLongitude:  55.2708,
+		DropoffLatitude:  25.2532,
+		DropoffLongitude: 55.3657,
+		Estimate: services.FareEstimate{
+			Distance:        12.5,
+			Duration:        25,
+			Fare:            31.4,
+			SurgeMultiplier: 1.2,
+			DistanceMethod:  services.DistanceMethodRoad,
+		},
+	}
+	token, err := signer.Issue(quote, now)
+	require.NoError(t, err)
+	return quote, token
+}
+
+func TestQuoteSignerRoundTrip(t *testing.T) {
+	signer := services.NewQuoteSigner("secret", 5*time.Minute)
+	now := time.Now()
+	customerID := uuid.New()
+	issued, token := issueQuote(t, signer, customerID, now)
+
+	quote, err := signer.Verify(token, now.Add(4*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, issued.ID, quote.ID)
+	assert.Equal(t, customerID, quote.CustomerID)
+	assert.Equal(t, 31.4, quote.Estimate.Fare)
+	assert.Equal(t, 1.2, quote.Estimate.SurgeMultiplier)
+	assert.Equal(t, services.DistanceMethodRoad, quote.Estimate.DistanceMethod)
+}
+
+func TestQuoteSignerRejectsExpiredAndTamperedQuotes(t *testing.T) {
+	signer := services.NewQuoteSigner("secret", 5*time.Minute)
+	now := time.Now()
+	_, token := issueQuote(t, signer, uuid.New(), now)
+
+	_, err := signer.Verify(token, now.Add(6*time.Minute))
+	assert.ErrorIs(t, err, services.ErrQuoteExpired)
+
+	// Signed with another key
+	_, err = services.NewQuoteSigner("other", 5*time.Minute).Verify(token, now)
+	assert.ErrorIs(t, err, services.ErrQuoteInvalid)
+
+	// Payload edited to lower the fare
+	parts := strings.Split(token, ".")
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+	payload = []byte(strings.Replace(string(payload), `"fare":31.4`, `"fare":1`, 1))
+	parts[1] = base64.RawURLEncoding.EncodeToString(payload)
+	_, err = signer.Verify(strings.Join(parts, "."), now)
+	assert.ErrorIs(t, err, services.ErrQuoteInvalid)
+
+	_, err = signer.Verify("not-a-quote", now)
+	assert.ErrorIs(t, err, services.ErrQuoteInvalid)
+}
+
+func TestCheckQuoteMatches(t *testing.T) {
+	signer := services.NewQuoteSigner("secret", 5*time.Minute)
+	customerID := uuid.New()
+	quote, _ := issueQuote(t, signer, customerID, time.Now())
+
+	// A few meters off the quoted points is fine
+	assert.NoError(t, services.CheckQuoteMatches(quote, customerID, "taxi", 25.20481, 55.27081, 25.2532, 55.3657, 50))
+
+	assert.ErrorIs(t, services.CheckQuoteMatches(quote, uuid.New(), "taxi", 25.2048, 55.2708, 25.2532, 55.3657, 50), services.ErrQuoteMismatch)
+	assert.ErrorIs(t, services.CheckQuoteMatches(quote, customerID, "delivery", 25.2048, 55.2708, 25.2532, 55.3657, 50), services.ErrQuoteMismatch)
+	assert.ErrorIs(t, services.CheckQuoteMatches(quote, customerID, "taxi", 25.2048, 55.2708, 25.3000, 55.3657, 50), services.ErrQuoteMismatch)
+}
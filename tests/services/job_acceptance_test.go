@@ -0,0 +1,136 @@
+package services_test
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/telemoz/backend/internal/config"
+	"github.com/telemoz/backend/internal/database"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/services"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// connectTestDB points the repositories at the Postgres database in TEST_DATABASE_DSN,
+// skipping the test when it isn't set
+func connectTestDB(t *testing.T) *gorm.DB {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN not set")
+	}
+
+	require.NoError(t, config.Load())
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	database.DB = db
+
+	require.NoError(t, database.Migrate(
+		&models.User{},
+		&models.Trip{},
+		&models.Job{},
+		&models.TripEvent{},
+		&models.JobOffer{},
+		&models.DriverAvailability{},
+		&models.TripWaypoint{},
+		&models.TripPackage{},
+	))
+	return db
+}
+
+func createTestUser(t *testing.T, db *gorm.DB, userType models.UserType) *models.User {
+	user := &models.User{
+		Email:        uuid.NewString() + "@example.com",
+		PasswordHash: "x",
+		Name:         string(userType),
+		UserType:     userType,
+	}
+	require.NoError(t, db.Create(user).Error)
+	return user
+}
+
+func TestConcurrentJobAcceptance(t *testing.T) {
+	db := connectTestDB(t)
+	const drivers = 20
+
+	customer := createTestUser(t, db, models.UserTypeCustomer)
+	now := time.Now()
+	trip := &models.Trip{
+		CustomerID:       customer.ID,
+		ServiceType:      models.ServiceTypeTaxi,
+		Status:           models.TripStatusSearching,
+		PickupLatitude:   25.2048,
+		PickupLongitude:  55.2708,
+		DropoffLatitude:  25.1972,
+		DropoffLongitude: 55.2744,
+		SearchStartedAt:  &now,
+	}
+	require.NoError(t, db.Create(trip).Error)
+	job := &models.Job{TripID: trip.ID, Status: models.JobStatusPending, DispatchWave: 1}
+	require.NoError(t, db.Create(job).Error)
+
+	driverIDs := make([]uuid.UUID, drivers)
+	for i := range driverIDs {
+		driverIDs[i] = createTestUser(t, db, models.UserTypeDriver).ID
+		require.NoError(t, db.Create(&models.JobOffer{
+			JobID:     job.ID,
+			DriverID:  driverIDs[i],
+			Wave:      1,
+			Status:    models.JobOfferStatusOffered,
+			ExpiresAt: now.Add(time.Minute),
+		}).Error)
+	}
+
+	jobService := services.NewJobService()
+	results := make([]error, drivers)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := range driverIDs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			_, results[i] = jobService.AcceptJob(job.ID, driverIDs[i])
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	var winner uuid.UUID
+	for i, err := range results {
+		if err == nil {
+			assert.Equal(t, uuid.Nil, winner, "more than one driver won")
+			winner = driverIDs[i]
+			continue
+		}
+		assert.True(t, errors.Is(err, services.ErrJobTaken), "unexpected error: %v", err)
+	}
+	require.NotEqual(t, uuid.Nil, winner, "no driver won")
+
+	var storedTrip models.Trip
+	require.NoError(t, db.First(&storedTrip, "id = ?", trip.ID).Error)
+	assert.Equal(t, models.TripStatusAccepted, storedTrip.Status)
+	assert.Equal(t, winner, *storedTrip.DriverID)
+
+	var storedJob models.Job
+	require.NoError(t, db.First(&storedJob, "id = ?", job.ID).Error)
+	assert.Equal(t, models.JobStatusAccepted, storedJob.Status)
+	assert.Equal(t, winner, *storedJob.DriverID)
+
+	var accepted int64
+	db.Model(&models.JobOffer{}).Where("job_id = ? AND status = ?", job.ID, models.JobOfferStatusAccepted).Count(&accepted)
+	assert.Equal(t, int64(1), accepted)
+
+	var events int64
+	db.Model(&models.TripEvent{}).Where("trip_id = ? AND to_status = ?", trip.ID, models.TripStatusAccepted).Count(&events)
+	assert.Equal(t, int64(1), events)
+}
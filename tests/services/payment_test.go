@@ -0,0 +1,108 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/services"
+	"github.com/telemoz/backend/pkg/payment"
+)
+
+func TestHoldAmount(t *testing.T) {
+	assert.Equal(t, 12.0, services.HoldAmount(10, 20))
+	assert.Equal(t, 15.06, services.HoldAmount(12.55, 20))
+	assert.Equal(t, 10.0, services.HoldAmount(10, 0))
+	// A negative margin never holds less than the fare
+	assert.Equal(t, 10.0, services.HoldAmount(10, -5))
+}
+
+func TestCaptureAmount(t *testing.T) {
+	assert.Equal(t, 9.5, services.CaptureAmount(9.5, 12))
+	// The gateway cannot take more than the hold
+	assert.Equal(t, 12.0, services.CaptureAmount(14, 12))
+	assert.Equal(t, 0.0, services.CaptureAmount(-1, 12))
+}
+
+func TestRefundedStatus(t *testing.T) {
+	assert.Equal(t, models.PaymentStatusCaptured, services.RefundedStatus(20, 0))
+	assert.Equal(t, models.PaymentStatusPartiallyRefunded, services.RefundedStatus(20, 5))
+	assert.Equal(t, models.PaymentStatusRefunded, services.RefundedStatus(20, 20))
+}
+
+func TestFakeGatewayChargeLifecycle(t *testing.T) {
+	gateway := payment.NewFakeGateway("secret")
+
+	card, err := gateway.Card("tok_visa")
+	require.NoError(t, err)
+	assert.Equal(t, "4242", card.Last4)
+
+	reference, err := gateway.Authorize(payment.Charge{IdempotencyKey: "trip-1", CardToken: "tok_visa", Amount: 12, Currency: "USD"})
+	require.NoError(t, err)
+	assert.Equal(t, "fake_ch_trip-1", reference)
+
+	// Retrying with the same key returns the existing hold
+	again, err := gateway.Authorize(payment.Charge{IdempotencyKey: "trip-1", CardToken: "tok_visa", Amount: 12, Currency: "USD"})
+	require.NoError(t, err)
+	assert.Equal(t, reference, again)
+	assert.Len(t, gateway.Charges(), 1)
+
+	assert.Error(t, gateway.Capture(reference, 12.01), "captures are limited to the hold")
+	require.NoError(t, gateway.Capture(reference, 9.5))
+	assert.Error(t, gateway.Void(reference), "a captured charge cannot be voided")
+
+	_, err = gateway.Refund(reference, 10, "refund-1")
+	assert.Error(t, err, "refunds are limited to the captured amount")
+	refund, err := gateway.Refund(reference, 4, "refund-1")
+	require.NoError(t, err)
+	assert.Equal(t, "fake_re_refund-1", refund)
+	_, err = gateway.Refund(reference, 4, "refund-1")
+	require.NoError(t, err, "a retried refund is not refunded twice")
+
+	charge := gateway.Charges()[reference]
+	assert.Equal(t, payment.FakeChargeCaptured, charge.Status)
+	assert.Equal(t, 9.5, charge.Captured)
+	assert.Equal(t, 4.0, charge.Refunded)
+}
+
+func TestFakeGatewayDeclines(t *testing.T) {
+	gateway := payment.NewFakeGateway("secret")
+
+	_, err := gateway.Authorize(payment.Charge{IdempotencyKey: "a", CardToken: "tok_declined", Amount: 10})
+	assert.ErrorIs(t, err, payment.ErrCardDeclined)
+	_, err = gateway.Authorize(payment.Charge{IdempotencyKey: "b", CardToken: "tok_insufficient_funds", Amount: 10})
+	assert.ErrorIs(t, err, payment.ErrInsufficientFunds)
+	_, err = gateway.Card("tok_nope")
+	assert.ErrorIs(t, err, payment.ErrUnknownCard)
+
+	outage := errors.New("gateway unavailable")
+	gateway.FailWith(outage)
+	_, err = gateway.Authorize(payment.Charge{IdempotencyKey: "c", CardToken: "tok_visa", Amount: 10})
+	assert.ErrorIs(t, err, outage)
+	gateway.FailWith(nil)
+
+	reference, err := gateway.Authorize(payment.Charge{IdempotencyKey: "c", CardToken: "tok_visa", Amount: 10})
+	require.NoError(t, err)
+	require.NoError(t, gateway.Void(reference))
+	assert.Error(t, gateway.Capture(reference, 10), "a voided charge cannot be captured")
+}
+
+func TestFakeGatewayWebhookSignature(t *testing.T) {
+	gateway := payment.NewFakeGateway("secret")
+	payload := []byte(`{"id":"evt_1","type":"charge.failed","charge_reference":"fake_ch_trip-1","failure_reason":"expired card"}`)
+
+	event, err := gateway.ParseWebhook(payload, payment.Sign("secret", payload))
+	require.NoError(t, err)
+	assert.Equal(t, "evt_1", event.ID)
+	assert.Equal(t, payment.EventChargeFailed, event.Type)
+	assert.Equal(t, "expired card", event.FailureReason)
+
+	_, err = gateway.ParseWebhook(payload, payment.Sign("other", payload))
+	assert.ErrorIs(t, err, payment.ErrInvalidSignature)
+	_, err = gateway.ParseWebhook(append(payload, ' '), payment.Sign("secret", payload))
+	assert.ErrorIs(t, err, payment.ErrInvalidSignature)
+	_, err = gateway.ParseWebhook(payload, "not-hex")
+	assert.ErrorIs(t, err, payment.ErrInvalidSignature)
+}
@@ -0,0 +1,119 @@
+package services_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/telemoz/backend/internal/dto"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/services"
+	"github.com/telemoz/backend/pkg/payout"
+)
+
+func TestSettlementPeriodFor(t *testing.T) {
+	// Thursday 2026-03-05 falls in the week of Monday 2026-03-02
+	start, end := services.SettlementPeriodFor(time.Date(2026, 3, 5, 18, 30, 0, 0, time.UTC), time.Monday)
+	assert.Equal(t, time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC), end)
+
+	// The first day of a period starts it
+	start, _ = services.SettlementPeriodFor(time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC), time.Monday)
+	assert.Equal(t, time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC), start)
+
+	// Sunday-start weeks
+	start, _ = services.SettlementPeriodFor(time.Date(2026, 3, 7, 12, 0, 0, 0, time.UTC), time.Sunday)
+	assert.Equal(t, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), start)
+}
+
+func TestDuePeriodsCatchesUpSkippedWeeks(t *testing.T) {
+	monday := func(day int) time.Time { return time.Date(2026, 3, day, 0, 0, 0, 0, time.UTC) }
+	// The week of 2026-03-02 was settled and the job didn't run the week after
+	from := monday(9)
+
+	// By Tuesday 2026-03-24, past the delay, both missed weeks are due, oldest first;
+	// the week in progress isn't
+	now := time.Date(2026, 3, 24, 10, 0, 0, 0, time.UTC)
+	assert.Equal(t, []time.Time{monday(9), monday(16)}, services.DuePeriods(from, now, time.Monday, 24*time.Hour))
+
+	// Within the delay after a week ends, only the weeks before it are due
+	now = time.Date(2026, 3, 23, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, []time.Time{monday(9)}, services.DuePeriods(from, now, time.Monday, 24*time.Hour))
+
+	// Nothing is due before the first period ends
+	assert.Empty(t, services.DuePeriods(from, monday(12), time.Monday, 24*time.Hour))
+}
+
+func TestSumStatement(t *testing.T) {
+	fare := 12.50
+	entries := []models.DriverEarning{
+		{Type: models.EarningTypeTrip, Amount: 10.00},
+		{Type: models.EarningTypeTip, Amount: 2.00},
+		{Type: models.EarningTypeAdjustment, Amount: -0.50},
+	}
+	cashTrips := []models.Trip{{FareAmount: &fare}, {}}
+
+	earnings, cash := services.SumStatement(entries, cashTrips)
+	assert.Equal(t, 11.50, earnings)
+	assert.Equal(t, 12.50, cash)
+}
+
+func testStatement() *dto.PayoutStatementDetailResponse {
+	reason := "toll refund"
+	return &dto.PayoutStatementDetailResponse{
+		PayoutStatementResponse: dto.PayoutStatementResponse{
+			PeriodStart:        "2026-03-02",
+			PeriodEnd:          "2026-03-08",
+			ExpectedPayoutDate: "2026-03-10",
+			Earnings:           21.50,
+			CashCollected:      12.50,
+			NetPayout:          9.00,
+			Currency:           "USD",
+			Status:             "pending",
+		},
+		Entries: []dto.StatementEntry{
+			{Date: "2026-03-03", Type: "trip", JobID: "job-1", GrossAmount: 25, Commission: 5, Amount: 20},
+			{Date: "2026-03-04", Type: "adjustment", JobID: "job-1", GrossAmount: 1.5, Amount: 1.5, Reason: &reason},
+		},
+		CashTrips: []dto.StatementCashTrip{{TripID: "trip-1", Date: "2026-03-03", Fare: 12.50}},
+	}
+}
+
+func TestWriteStatementCSV(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, services.WriteStatementCSV(&buf, testStatement()))
+
+	csv := buf.String()
+	assert.Contains(t, csv, "Net payout,9.00\n")
+	assert.Contains(t, csv, "2026-03-03,trip,job-1,25.00,5.00,20.00,\n")
+	assert.Contains(t, csv, "2026-03-04,adjustment,job-1,1.50,0.00,1.50,toll refund\n")
+	assert.Contains(t, csv, "2026-03-03,trip-1,12.50\n")
+}
+
+func TestRenderStatementPDF(t *testing.T) {
+	document := string(services.RenderStatementPDF(testStatement()))
+
+	assert.True(t, strings.HasPrefix(document, "%PDF-1.4\n"))
+	assert.True(t, strings.HasSuffix(document, "%%EOF\n"))
+	assert.Contains(t, document, "(Net payout: 9.00 USD)")
+	assert.Contains(t, document, "\\(toll refund\\)")
+}
+
+func TestFakePayoutProvider(t *testing.T) {
+	provider := payout.NewFakeProvider()
+
+	reference, err := provider.Pay(payout.Transfer{StatementID: "s1", DriverID: "d1", Amount: 9, Currency: "USD"})
+	require.NoError(t, err)
+	assert.Equal(t, "fake-s1", reference)
+
+	provider.FailWith(errors.New("bank unavailable"))
+	_, err = provider.Pay(payout.Transfer{StatementID: "s2", DriverID: "d1", Amount: 5, Currency: "USD"})
+	assert.Error(t, err)
+
+	assert.Len(t, provider.Transfers(), 1)
+	assert.Equal(t, 9.0, provider.Transfers()["s1"].Amount)
+}
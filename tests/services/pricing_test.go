@@ -0,0 +1,90 @@
+package services_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/services"
+)
+
+func fareTable(zone string, version int, effectiveFrom time.Time) models.FareTable {
+	return models.FareTable{
+		ServiceType:   "taxi",
+		Zone:          zone,
+		Version:       version,
+		EffectiveFrom: effectiveFrom,
+		Timezone:      "UTC",
+		BaseFare:      3,
+		PerKmRate:     2,
+		PerMinuteRate: 0.5,
+		MinimumFare:   8,
+		BookingFee:    1,
+	}
+}
+
+func TestSelectFareTable(t *testing.T) {
+	now := time.Now()
+	everywhere := fareTable("", 1, now.Add(-48*time.Hour))
+	city := fareTable("thrr", 1, now.Add(-48*time.Hour))
+	cityV2 := fareTable("thrr", 2, now.Add(-time.Hour))
+	elsewhere := fareTable("u4pr", 3, now.Add(-time.Hour))
+
+	selected := services.SelectFareTable([]models.FareTable{everywhere, city, cityV2, elsewhere}, 25.2048, 55.2708)
+	require.NotNil(t, selected)
+	assert.Equal(t, "thrr", selected.Zone)
+	assert.Equal(t, 2, selected.Version)
+
+	selected = services.SelectFareTable([]models.FareTable{everywhere, city, elsewhere}, 40.7128, -74.0060)
+	require.NotNil(t, selected)
+	assert.Equal(t, "", selected.Zone)
+
+	assert.Nil(t, services.SelectFareTable([]models.FareTable{city}, 40.7128, -74.0060))
+}
+
+func TestComputeFare(t *testing.T) {
+	table := fareTable("", 1, time.Now())
+	noon := time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)
+
+	// 3 base + 10km * 2 + 20min * 0.5 = 33, plus the booking fee
+	breakdown := services.ComputeFare(&table, 10, 20, 1, noon, false)
+	assert.Equal(t, 20.0, breakdown.DistanceFare)
+	assert.Equal(t, 10.0, breakdown.TimeFare)
+	assert.Equal(t, 34.0, breakdown.Total)
+
+	// Surge applies to the metered fare only
+	breakdown = services.ComputeFare(&table, 10, 20, 1.5, noon, false)
+	assert.Equal(t, 16.5, breakdown.SurgeAmount)
+	assert.Equal(t, 50.5, breakdown.Total)
+
+	// Short trips are topped up to the minimum fare
+	breakdown = services.ComputeFare(&table, 1, 2, 1, noon, false)
+	assert.Equal(t, 2.0, breakdown.MinimumFareTopUp)
+	assert.Equal(t, 9.0, breakdown.Total)
+}
+
+func TestComputeFareSurcharges(t *testing.T) {
+	table := fareTable("", 1, time.Now())
+	nightStart, nightEnd := "22:00", "06:00"
+	table.NightStart = &nightStart
+	table.NightEnd = &nightEnd
+	table.NightSurchargePercent = 20
+	table.AirportSurcharge = 5
+
+	// The night wraps past midnight
+	late := time.Date(2024, 3, 4, 23, 30, 0, 0, time.UTC)
+	early := time.Date(2024, 3, 5, 5, 59, 0, 0, time.UTC)
+	morning := time.Date(2024, 3, 5, 6, 0, 0, 0, time.UTC)
+	assert.Equal(t, 6.6, services.ComputeFare(&table, 10, 20, 1, late, false).NightSurcharge)
+	assert.Equal(t, 6.6, services.ComputeFare(&table, 10, 20, 1, early, false).NightSurcharge)
+	assert.Equal(t, 0.0, services.ComputeFare(&table, 10, 20, 1, morning, false).NightSurcharge)
+
+	breakdown := services.ComputeFare(&table, 10, 20, 1, morning, true)
+	assert.Equal(t, 5.0, breakdown.AirportSurcharge)
+	assert.Equal(t, 39.0, breakdown.Total)
+
+	assert.True(t, services.InFareZones([]string{"u4pr", "THRR"}, 25.2048, 55.2708))
+	assert.False(t, services.InFareZones([]string{"u4pr"}, 25.2048, 55.2708))
+}
@@ -0,0 +1,73 @@
+package services_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/services"
+)
+
+func TestNormalizePromoCode(t *testing.T) {
+	assert.Equal(t, "WELCOME10", services.NormalizePromoCode("  welcome10 "))
+}
+
+func TestPromoDiscount(t *testing.T) {
+	maxDiscount := 5.0
+	percentage := &models.PromoCode{DiscountType: models.PromoDiscountPercentage, DiscountValue: 20}
+	capped := &models.PromoCode{DiscountType: models.PromoDiscountPercentage, DiscountValue: 20, MaxDiscount: &maxDiscount}
+	fixed := &models.PromoCode{DiscountType: models.PromoDiscountFixed, DiscountValue: 8}
+
+	assert.Equal(t, 2.51, services.PromoDiscount(percentage, 12.55))
+	assert.Equal(t, 5.0, services.PromoDiscount(capped, 40))
+	assert.Equal(t, 8.0, services.PromoDiscount(fixed, 20))
+	// A discount never takes the fare below zero
+	assert.Equal(t, 6.5, services.PromoDiscount(fixed, 6.5))
+	assert.Equal(t, 0.0, services.PromoDiscount(fixed, 0))
+}
+
+func TestCheckPromo(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	endsAt := now.Add(24 * time.Hour)
+	maxRedemptions := 100
+	promo := &models.PromoCode{
+		DiscountType:   models.PromoDiscountFixed,
+		DiscountValue:  5,
+		ServiceTypes:   models.StringArray{"taxi"},
+		StartsAt:       now.Add(-time.Hour),
+		EndsAt:         &endsAt,
+		MaxRedemptions: &maxRedemptions,
+		PerUserLimit:   2,
+		Active:         true,
+	}
+
+	assert.NoError(t, services.CheckPromo(promo, "taxi", 1, 99, now))
+	assert.ErrorIs(t, services.CheckPromo(promo, "delivery", 0, 0, now), services.ErrPromoNotApplicable)
+	assert.ErrorIs(t, services.CheckPromo(promo, "taxi", 2, 50, now), services.ErrPromoLimitReached)
+	assert.ErrorIs(t, services.CheckPromo(promo, "taxi", 0, 100, now), services.ErrPromoLimitReached)
+	assert.ErrorIs(t, services.CheckPromo(promo, "taxi", 0, 0, now.Add(-2*time.Hour)), services.ErrPromoInactive)
+	assert.ErrorIs(t, services.CheckPromo(promo, "taxi", 0, 0, endsAt), services.ErrPromoInactive)
+
+	promo.Active = false
+	assert.ErrorIs(t, services.CheckPromo(promo, "taxi", 0, 0, now), services.ErrPromoInactive)
+
+	// Codes without service types apply to every service
+	promo.Active = true
+	promo.ServiceTypes = models.StringArray{}
+	assert.NoError(t, services.CheckPromo(promo, "school_bus", 0, 0, now))
+}
+
+func TestWalletTripHold(t *testing.T) {
+	hold, ok := services.WalletTripHold(10, 50, 20)
+	assert.True(t, ok)
+	assert.Equal(t, 12.0, hold)
+
+	// A balance between the fare and the margin is held in full
+	hold, ok = services.WalletTripHold(10, 11, 20)
+	assert.True(t, ok)
+	assert.Equal(t, 11.0, hold)
+
+	_, ok = services.WalletTripHold(10, 9.99, 20)
+	assert.False(t, ok)
+}
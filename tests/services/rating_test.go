@@ -0,0 +1,66 @@
+package services_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/telemoz/backend/internal/config"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/services"
+)
+
+func TestRatingWindowOpen(t *testing.T) {
+	completedAt := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	window := 72 * time.Hour
+
+	assert.True(t, services.RatingWindowOpen(completedAt, window, completedAt.Add(time.Minute)))
+	assert.True(t, services.RatingWindowOpen(completedAt, window, completedAt.Add(71*time.Hour)))
+	assert.False(t, services.RatingWindowOpen(completedAt, window, completedAt.Add(window)))
+}
+
+func TestValidateRatingTags(t *testing.T) {
+	assert.NoError(t, services.ValidateRatingTags(models.UserTypeCustomer, nil))
+	assert.NoError(t, services.ValidateRatingTags(models.UserTypeCustomer, []string{"polite", "safe_driving"}))
+	assert.NoError(t, services.ValidateRatingTags(models.UserTypeDriver, []string{"wrong_pickup"}))
+
+	// Each side has its own tags
+	assert.ErrorIs(t, services.ValidateRatingTags(models.UserTypeDriver, []string{"safe_driving"}), services.ErrInvalidRatingTag)
+	assert.ErrorIs(t, services.ValidateRatingTags(models.UserTypeCustomer, []string{"polite", "polite"}), services.ErrInvalidRatingTag)
+	assert.ErrorIs(t, services.ValidateRatingTags(models.UserTypeCustomer, []string{"great"}), services.ErrInvalidRatingTag)
+}
+
+func TestDriverFlagged(t *testing.T) {
+	cfg := config.RatingConfig{FlagThreshold: 4.2, FlagMinRatings: 10}
+
+	assert.True(t, services.DriverFlagged(4.1, 10, cfg))
+	assert.False(t, services.DriverFlagged(4.2, 50, cfg))
+	// Too few ratings to judge
+	assert.False(t, services.DriverFlagged(1, 9, cfg))
+}
+
+func TestRankDriversByRating(t *testing.T) {
+	nearLowRated := services.DriverCandidate{DriverID: uuid.New(), DistanceKm: 1}
+	fartherTopRated := services.DriverCandidate{DriverID: uuid.New(), DistanceKm: 2}
+	unrated := services.DriverCandidate{DriverID: uuid.New(), DistanceKm: 2.4}
+	farthest := services.DriverCandidate{DriverID: uuid.New(), DistanceKm: 4}
+
+	ratings := map[uuid.UUID]float64{
+		nearLowRated.DriverID:    3.5,
+		fartherTopRated.DriverID: 5,
+		farthest.DriverID:        4.9,
+	}
+
+	ranked := services.RankDriversByRating(
+		[]services.DriverCandidate{nearLowRated, fartherTopRated, unrated, farthest}, ratings, 1,
+	)
+
+	if assert.Len(t, ranked, 4) {
+		assert.Equal(t, fartherTopRated.DriverID, ranked[0].DriverID)
+		assert.Equal(t, unrated.DriverID, ranked[1].DriverID)
+		assert.Equal(t, nearLowRated.DriverID, ranked[2].DriverID)
+		assert.InDelta(t, 2.5, ranked[2].Score, 0.001)
+		assert.Equal(t, farthest.DriverID, ranked[3].DriverID)
+	}
+}
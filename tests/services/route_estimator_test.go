@@ -0,0 +1,90 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/telemoz/backend/internal/config"
+	"github.com/telemoz/backend/internal/services"
+	"github.com/telemoz/backend/pkg/maps"
+)
+
+var routingConfig = config.RoutingConfig{
+	Timeout:          time.Second,
+	CacheTTL:         time.Minute,
+	CacheSize:        100,
+	RoadFactor:       1.3,
+	FallbackSpeedKmh: 40,
+}
+
+func TestRouteEstimatorUsesAndCachesProvider(t *testing.T) {
+	router := maps.NewFakeRouter()
+	router.Returns(maps.RouteInfo{Distance: 12.5, DurationSeconds: 1500})
+	estimator := services.NewRouteEstimator(router, routingConfig)
+
+	estimate := estimator.Estimate(25.2048, 55.2708, 25.2532, 55.3657)
+	assert.Equal(t, services.DistanceMethodRoad, estimate.Method)
+	assert.Equal(t, 12.5, estimate.DistanceKm)
+	assert.Equal(t, 25.0, estimate.DurationMinutes)
+
+	// Points within a few meters of the first request are served from the cache
+	estimator.Estimate(25.20481, 55.27081, 25.25321, 55.36571)
+	assert.Equal(t, 1, router.Calls())
+}
+
+func TestRouteEstimatorFallsBackToStraightLine(t *testing.T) {
+	router := maps.NewFakeRouter()
+	router.FailWith(errors.New("quota exceeded"))
+	estimator := services.NewRouteEstimator(router, routingConfig)
+
+	estimate := estimator.Estimate(25.2048, 55.2708, 25.2532, 55.3657)
+	expected := services.EstimateStraightLineRoute(25.2048, 55.2708, 25.2532, 55.3657, 1.3, 40)
+	assert.Equal(t, services.DistanceMethodStraightLine, estimate.Method)
+	assert.Equal(t, expected, estimate)
+	assert.InDelta(t, 14.2, estimate.DistanceKm, 0.1)
+
+	// Failures are not cached
+	router.Returns(maps.RouteInfo{Distance: 12.5, DurationSeconds: 1500})
+	assert.Equal(t, services.DistanceMethodRoad, estimator.Estimate(25.2048, 55.2708, 25.2532, 55.3657).Method)
+
+	// Without a provider distances are always estimated
+	assert.Equal(t, services.DistanceMethodStraightLine, services.NewRouteEstimator(nil, routingConfig).Estimate(25.2048, 55.2708, 25.2532, 55.3657).Method)
+}
+
+// slowRouter never answers before the request's deadline
+type slowRouter struct {
+	calls atomic.Int32
+}
+
+func (r *slowRouter) Route(ctx context.Context, originLat, originLng, destLat, destLng float64) (*maps.RouteInfo, error) {
+	r.calls.Add(1)
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestRouteEstimatorSharesTimeoutAcrossLegs(t *testing.T) {
+	router := &slowRouter{}
+	cfg := routingConfig
+	cfg.Timeout = 50 * time.Millisecond
+	estimator := services.NewRouteEstimator(router, cfg)
+
+	started := time.Now()
+	legs := estimator.EstimateLegs([]services.RoutePoint{
+		{Latitude: 25.2048, Longitude: 55.2708},
+		{Latitude: 25.2532, Longitude: 55.3657},
+		{Latitude: 25.1972, Longitude: 55.2744},
+		{Latitude: 25.0657, Longitude: 55.1713},
+	})
+
+	// The first leg uses up the timeout; the others aren't sent to the provider
+	assert.Len(t, legs, 3)
+	for _, leg := range legs {
+		assert.Equal(t, services.DistanceMethodStraightLine, leg.Method)
+	}
+	assert.Equal(t, int32(1), router.calls.Load())
+	assert.Less(t, time.Since(started), 2*cfg.Timeout)
+}
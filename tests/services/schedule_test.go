@@ -0,0 +1,53 @@
+package services_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/services"
+)
+
+func TestNextScheduledArrival(t *testing.T) {
+	loc := time.FixedZone("GST", 4*60*60)
+	schedule := &models.Schedule{
+		RunType:  models.RunTypeMorning,
+		Weekdays: models.StringArray{"mon", "tue", "wed", "thu", "fri"},
+		IsActive: true,
+	}
+
+	// Wednesday 06:00 local: today's 07:10 run is next
+	now := time.Date(2026, 3, 4, 6, 0, 0, 0, loc)
+	arrival, ok := services.NextScheduledArrival(schedule, "07:10", now, loc)
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2026, 3, 4, 7, 10, 0, 0, loc), arrival)
+
+	// Friday after the run: skips the weekend to Monday
+	now = time.Date(2026, 3, 6, 8, 0, 0, 0, loc)
+	arrival, ok = services.NextScheduledArrival(schedule, "07:10", now, loc)
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2026, 3, 9, 7, 10, 0, 0, loc), arrival)
+
+	// Term ended: no upcoming arrival
+	endsOn := time.Date(2026, 3, 6, 0, 0, 0, 0, time.UTC)
+	schedule.EndsOn = &endsOn
+	_, ok = services.NextScheduledArrival(schedule, "07:10", now, loc)
+	assert.False(t, ok)
+}
+
+func TestScheduleRunsOn(t *testing.T) {
+	startsOn := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	schedule := &models.Schedule{
+		Weekdays: models.StringArray{"sat"},
+		StartsOn: &startsOn,
+		IsActive: true,
+	}
+
+	assert.False(t, schedule.RunsOn(time.Date(2026, 8, 29, 7, 0, 0, 0, time.UTC)), "before term")
+	assert.True(t, schedule.RunsOn(time.Date(2026, 9, 5, 7, 0, 0, 0, time.UTC)))
+	assert.False(t, schedule.RunsOn(time.Date(2026, 9, 6, 7, 0, 0, 0, time.UTC)), "sunday")
+
+	schedule.IsActive = false
+	assert.False(t, schedule.RunsOn(time.Date(2026, 9, 5, 7, 0, 0, 0, time.UTC)))
+}
@@ -0,0 +1,125 @@
+package services_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/telemoz/backend/internal/config"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/repositories"
+	"github.com/telemoz/backend/internal/services"
+)
+
+var schedulingPolicy = config.SchedulingConfig{
+	MinLead:        30 * time.Minute,
+	MaxLead:        7 * 24 * time.Hour,
+	DispatchBefore: 15 * time.Minute,
+	ReminderBefore: time.Hour,
+	ConflictBuffer: 15 * time.Minute,
+}
+
+func TestValidateScheduledAt(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.NoError(t, services.ValidateScheduledAt(schedulingPolicy, now.Add(30*time.Minute), now))
+	assert.NoError(t, services.ValidateScheduledAt(schedulingPolicy, now.Add(7*24*time.Hour), now))
+	assert.ErrorIs(t, services.ValidateScheduledAt(schedulingPolicy, now.Add(29*time.Minute), now), services.ErrScheduleTooSoon)
+	assert.ErrorIs(t, services.ValidateScheduledAt(schedulingPolicy, now.Add(-time.Hour), now), services.ErrScheduleTooSoon)
+	assert.ErrorIs(t, services.ValidateScheduledAt(schedulingPolicy, now.Add(8*24*time.Hour), now), services.ErrScheduleTooFar)
+}
+
+func TestScheduledTripsConflict(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	scheduled := func(at time.Time, minutes int) *models.Trip {
+		return &models.Trip{ScheduledAt: &at, EstimatedDuration: &minutes}
+	}
+	buffer := schedulingPolicy.ConflictBuffer
+
+	airport := scheduled(now.Add(2*time.Hour), 45)
+	// Ends 14:45, so the next trip can start from 15:00
+	assert.False(t, services.ScheduledTripsConflict(airport, scheduled(now.Add(3*time.Hour), 20), buffer, now))
+	assert.True(t, services.ScheduledTripsConflict(airport, scheduled(now.Add(170*time.Minute), 20), buffer, now))
+	// A trip before the airport run has to end 15 minutes ahead of it
+	assert.False(t, services.ScheduledTripsConflict(scheduled(now.Add(time.Hour), 30), airport, buffer, now))
+	assert.True(t, services.ScheduledTripsConflict(scheduled(now.Add(time.Hour), 50), airport, buffer, now))
+
+	// A trip the driver is on now occupies them from now
+	minutes := 40
+	active := &models.Trip{EstimatedDuration: &minutes}
+	assert.True(t, services.ScheduledTripsConflict(scheduled(now.Add(50*time.Minute), 20), active, buffer, now))
+	assert.False(t, services.ScheduledTripsConflict(scheduled(now.Add(55*time.Minute), 20), active, buffer, now))
+}
+
+func TestConcurrentPreAssignOverlappingTrips(t *testing.T) {
+	db := connectTestDB(t)
+	const trips = 10
+
+	customer := createTestUser(t, db, models.UserTypeCustomer)
+	driver := createTestUser(t, db, models.UserTypeDriver)
+	require.NoError(t, db.Create(&models.DriverAvailability{DriverID: driver.ID}).Error)
+
+	// Every trip overlaps every other, so the driver can hold only one of them
+	now := time.Now()
+	scheduledAt := now.Add(2 * time.Hour)
+	minutes := 45
+	jobs := make([]*models.Job, trips)
+	for i := range jobs {
+		at := scheduledAt.Add(time.Duration(i) * time.Minute)
+		trip := &models.Trip{
+			CustomerID:        customer.ID,
+			ServiceType:       models.ServiceTypeTaxi,
+			Status:            models.TripStatusPending,
+			PickupLatitude:    25.2048,
+			PickupLongitude:   55.2708,
+			DropoffLatitude:   25.1972,
+			DropoffLongitude:  55.2744,
+			ScheduledAt:       &at,
+			EstimatedDuration: &minutes,
+		}
+		require.NoError(t, db.Create(trip).Error)
+		jobs[i] = &models.Job{TripID: trip.ID, Status: models.JobStatusPending}
+		require.NoError(t, db.Create(jobs[i]).Error)
+		jobs[i].Trip = *trip
+	}
+
+	tripRepo := repositories.NewTripRepository()
+	results := make([]error, trips)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := range jobs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			trip := &jobs[i].Trip
+			results[i] = tripRepo.PreAssign(trip, jobs[i], driver.ID, func(commitments []models.Trip) bool {
+				for j := range commitments {
+					if services.ScheduledTripsConflict(trip, &commitments[j], schedulingPolicy.ConflictBuffer, now) {
+						return true
+					}
+				}
+				return false
+			})
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	assigned := 0
+	for _, err := range results {
+		if err == nil {
+			assigned++
+			continue
+		}
+		assert.True(t, errors.Is(err, repositories.ErrDriverCommitted), "unexpected error: %v", err)
+	}
+	assert.Equal(t, 1, assigned)
+
+	var held int64
+	require.NoError(t, db.Model(&models.Job{}).Where("driver_id = ?", driver.ID).Count(&held).Error)
+	assert.Equal(t, int64(1), held)
+}
@@ -0,0 +1,69 @@
+package services_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/services"
+)
+
+func TestAdvanceStopVisitFullPass(t *testing.T) {
+	visit := &models.BusStopVisit{}
+	now := time.Now()
+	const radius, nearby = 50.0, 500.0
+
+	steps := []struct {
+		distance float64
+		alerts   []services.StopAlert
+		changed  bool
+	}{
+		{900, nil, false},
+		{450, []services.StopAlert{services.StopAlertNearby}, true},
+		{300, nil, false},
+		{30, []services.StopAlert{services.StopAlertArrived}, true},
+		{10, nil, false},
+		{80, []services.StopAlert{services.StopAlertDeparted}, true},
+		// GPS jitter back into the geofence must not alert again
+		{40, nil, false},
+		{120, nil, false},
+		{700, nil, true},
+		{20, nil, false},
+	}
+
+	for i, step := range steps {
+		alerts, changed := services.AdvanceStopVisit(visit, step.distance, radius, nearby, now.Add(time.Duration(i)*time.Second))
+		assert.Equal(t, step.alerts, alerts, "step %d", i)
+		assert.Equal(t, step.changed, changed, "step %d", i)
+	}
+
+	assert.NotNil(t, visit.NearbyAlertedAt)
+	assert.NotNil(t, visit.ArrivedAt)
+	assert.NotNil(t, visit.DepartedAt)
+	assert.NotNil(t, visit.ClosedAt)
+}
+
+func TestAdvanceStopVisitFirstSeenInsideGeofence(t *testing.T) {
+	visit := &models.BusStopVisit{}
+
+	alerts, changed := services.AdvanceStopVisit(visit, 10, 50, 500, time.Now())
+
+	assert.True(t, changed)
+	assert.Equal(t, []services.StopAlert{services.StopAlertArrived}, alerts)
+	assert.NotNil(t, visit.NearbyAlertedAt)
+}
+
+func TestAdvanceStopVisitDriveBy(t *testing.T) {
+	visit := &models.BusStopVisit{}
+	now := time.Now()
+
+	alerts, _ := services.AdvanceStopVisit(visit, 200, 50, 500, now)
+	assert.Equal(t, []services.StopAlert{services.StopAlertNearby}, alerts)
+
+	alerts, changed := services.AdvanceStopVisit(visit, 600, 50, 500, now.Add(time.Minute))
+	assert.Empty(t, alerts)
+	assert.True(t, changed)
+	assert.NotNil(t, visit.ClosedAt)
+	assert.Nil(t, visit.ArrivedAt)
+}
@@ -0,0 +1,73 @@
+package services_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/telemoz/backend/internal/config"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/services"
+	"github.com/telemoz/backend/pkg/geohash"
+)
+
+var surgeConfig = config.SurgeConfig{
+	Threshold:     1,
+	Sensitivity:   0.5,
+	MinDemand:     3,
+	MaxMultiplier: 2.5,
+	Smoothing:     1,
+}
+
+func TestGeohashEncode(t *testing.T) {
+	assert.Equal(t, "u4pruydqqvj", geohash.Encode(57.64911, 10.40744, 11))
+	assert.Equal(t, "thrr", geohash.Encode(25.2048, 55.2708, 4))
+}
+
+func TestSurgeMultiplier(t *testing.T) {
+	// Demand at or below one trip per driver doesn't surge
+	assert.Equal(t, 1.0, services.SurgeMultiplier(4, 4, 1, surgeConfig))
+	// Too few trips to surge even without drivers
+	assert.Equal(t, 1.0, services.SurgeMultiplier(2, 0, 1, surgeConfig))
+	// 6 trips for 2 drivers is 2 above the threshold
+	assert.Equal(t, 2.0, services.SurgeMultiplier(6, 2, 1, surgeConfig))
+	// Capped at the maximum
+	assert.Equal(t, 2.5, services.SurgeMultiplier(20, 1, 1, surgeConfig))
+}
+
+func TestSurgeMultiplierSmoothing(t *testing.T) {
+	cfg := surgeConfig
+	cfg.Smoothing = 0.5
+
+	// Halfway from 1 towards the target of 2
+	assert.Equal(t, 1.5, services.SurgeMultiplier(6, 2, 1, cfg))
+	// Eases back towards 1 when demand drops and settles exactly at 1
+	multiplier := 2.0
+	for i := 0; i < 10; i++ {
+		multiplier = services.SurgeMultiplier(0, 5, multiplier, cfg)
+	}
+	assert.Equal(t, 1.0, multiplier)
+}
+
+func TestCountSurgeDemandAndSupply(t *testing.T) {
+	now := time.Now()
+	trips := []models.Trip{
+		{ServiceType: models.ServiceTypeTaxi, PickupLatitude: 25.2048, PickupLongitude: 55.2708},
+		{ServiceType: models.ServiceTypeTaxi, PickupLatitude: 25.2049, PickupLongitude: 55.2709},
+		{ServiceType: models.ServiceTypeDelivery, PickupLatitude: 25.2048, PickupLongitude: 55.2708},
+	}
+	cell := geohash.Encode(25.2048, 55.2708, 6)
+
+	demand := services.CountSurgeDemand(trips, 6)
+	assert.Equal(t, 2, demand[services.SurgeCell{Cell: cell, ServiceType: "taxi"}])
+	assert.Equal(t, 1, demand[services.SurgeCell{Cell: cell, ServiceType: "delivery"}])
+
+	drivers := []models.DriverAvailability{
+		driverAt(25.2048, 55.2708, now),
+		driverAt(25.2048, 55.2708, now.Add(-time.Hour)),
+		driverAt(25.9000, 56.0000, now),
+	}
+	supply := services.CountSurgeSupply(drivers, "taxi", 6, now.Add(-5*time.Minute))
+	assert.Equal(t, 1, supply[services.SurgeCell{Cell: cell, ServiceType: "taxi"}])
+	assert.Len(t, supply, 2)
+}
@@ -0,0 +1,55 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/services"
+)
+
+func TestValidateTripTransition(t *testing.T) {
+	// The happy path is driven by the driver once the trip is accepted
+	path := []models.TripStatus{
+		models.TripStatusSearching,
+		models.TripStatusAccepted,
+		models.TripStatusArrived,
+		models.TripStatusInProgress,
+		models.TripStatusCompleted,
+	}
+	for i := 0; i < len(path)-1; i++ {
+		assert.NoError(t, services.ValidateTripTransition(path[i], path[i+1], models.TripActorDriver))
+	}
+
+	assert.NoError(t, services.ValidateTripTransition(models.TripStatusArrived, models.TripStatusNoShow, models.TripActorDriver))
+	assert.NoError(t, services.ValidateTripTransition(models.TripStatusSearching, models.TripStatusExpired, models.TripActorSystem))
+	assert.NoError(t, services.ValidateTripTransition(models.TripStatusAccepted, models.TripStatusCancelled, models.TripActorCustomer))
+
+	// Skipping ahead is illegal for everyone
+	err := services.ValidateTripTransition(models.TripStatusAccepted, models.TripStatusCompleted, models.TripActorDriver)
+	assert.True(t, errors.Is(err, services.ErrIllegalTransition))
+	var transitionErr *services.TransitionError
+	assert.True(t, errors.As(err, &transitionErr))
+	assert.Equal(t, models.TripStatusAccepted, transitionErr.From)
+	assert.Equal(t, models.TripStatusCompleted, transitionErr.To)
+
+	// Finished trips stay finished
+	err = services.ValidateTripTransition(models.TripStatusCompleted, models.TripStatusCancelled, models.TripActorCustomer)
+	assert.True(t, errors.Is(err, services.ErrIllegalTransition))
+
+	// Customers can cancel but not drive the trip
+	err = services.ValidateTripTransition(models.TripStatusInProgress, models.TripStatusCompleted, models.TripActorCustomer)
+	assert.True(t, errors.Is(err, services.ErrTransitionForbidden))
+	err = services.ValidateTripTransition(models.TripStatusInProgress, models.TripStatusCancelled, models.TripActorCustomer)
+	assert.True(t, errors.Is(err, services.ErrIllegalTransition))
+}
+
+func TestJobStatusForTrip(t *testing.T) {
+	status, ok := services.JobStatusForTrip(models.TripStatusNoShow)
+	assert.True(t, ok)
+	assert.Equal(t, models.JobStatusNoShow, status)
+
+	_, ok = services.JobStatusForTrip(models.TripStatusPending)
+	assert.False(t, ok)
+}
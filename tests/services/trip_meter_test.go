@@ -0,0 +1,72 @@
+package services_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/telemoz/backend/internal/config"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/services"
+)
+
+var meterConfig = config.MeterConfig{
+	MaxAccuracyMeters: 50,
+	MaxSpeedKmh:       160,
+	MinMoveMeters:     10,
+	WaitingSpeedKmh:   5,
+}
+
+func trackPoint(lat, lng float64, at time.Time) models.TripTrackPoint {
+	return models.TripTrackPoint{Latitude: lat, Longitude: lng, RecordedAt: at}
+}
+
+func TestMeasureTrack(t *testing.T) {
+	start := time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)
+	inaccurate := trackPoint(25.3000, 55.3000, start.Add(90*time.Second))
+	accuracy := 500.0
+	inaccurate.Accuracy = &accuracy
+
+	points := []models.TripTrackPoint{
+		trackPoint(25.2000, 55.2700, start),
+		// About 1.1km north a minute later
+		trackPoint(25.2100, 55.2700, start.Add(time.Minute)),
+		inaccurate,
+		// A glitch 50km away
+		trackPoint(25.6600, 55.2700, start.Add(2*time.Minute)),
+		// Another 1.1km north
+		trackPoint(25.2200, 55.2700, start.Add(3*time.Minute)),
+		// Standing in traffic for two minutes, with a few meters of jitter
+		trackPoint(25.22002, 55.27001, start.Add(4*time.Minute)),
+		trackPoint(25.22001, 55.27002, start.Add(5*time.Minute)),
+	}
+
+	measurement := services.MeasureTrack(points, meterConfig)
+	assert.InDelta(t, 2.22, measurement.DistanceKm, 0.01)
+	assert.InDelta(t, 2.0, measurement.WaitingMinutes, 0.01)
+	assert.Equal(t, 5, measurement.Points)
+}
+
+func TestMeasureTrackCountsSlowCrawlAsWaiting(t *testing.T) {
+	start := time.Now()
+	points := []models.TripTrackPoint{
+		trackPoint(25.2000, 55.2700, start),
+		// 55m in a minute is about 3.3 km/h
+		trackPoint(25.2005, 55.2700, start.Add(time.Minute)),
+	}
+
+	measurement := services.MeasureTrack(points, meterConfig)
+	assert.InDelta(t, 0.055, measurement.DistanceKm, 0.001)
+	assert.InDelta(t, 1.0, measurement.WaitingMinutes, 0.01)
+}
+
+func TestWaitingChargeAndBoundFare(t *testing.T) {
+	table := &models.FareTable{WaitingPerMinuteRate: 0.5, FreeWaitingMinutes: 3}
+	assert.Equal(t, 0.0, services.WaitingCharge(table, 2))
+	assert.Equal(t, 3.5, services.WaitingCharge(table, 10))
+
+	assert.Equal(t, 24.0, services.BoundFare(20, 30, 20))
+	assert.Equal(t, 16.0, services.BoundFare(20, 10, 20))
+	assert.Equal(t, 21.5, services.BoundFare(20, 21.5, 20))
+	assert.Equal(t, 30.0, services.BoundFare(20, 30, -1))
+}
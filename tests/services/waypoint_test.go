@@ -0,0 +1,161 @@
+package services_test
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/telemoz/backend/internal/dto"
+	"github.com/telemoz/backend/internal/models"
+	"github.com/telemoz/backend/internal/services"
+	"github.com/telemoz/backend/pkg/storage"
+	"gorm.io/gorm"
+)
+
+func TestNextWaypoint(t *testing.T) {
+	waypoints := []models.TripWaypoint{
+		{Sequence: 1, Status: models.WaypointStatusCompleted},
+		{Sequence: 2, Status: models.WaypointStatusFailed},
+		{Sequence: 3, Status: models.WaypointStatusArrived},
+		{Sequence: 4, Status: models.WaypointStatusPending},
+	}
+	assert.Equal(t, 3, services.NextWaypoint(waypoints).Sequence)
+
+	waypoints[2].Status = models.WaypointStatusCompleted
+	assert.Equal(t, 4, services.NextWaypoint(waypoints).Sequence)
+
+	waypoints[3].Status = models.WaypointStatusCompleted
+	assert.Nil(t, services.NextWaypoint(waypoints))
+	// Single drop-off trips have no stops to finish
+	assert.Nil(t, services.NextWaypoint(nil))
+}
+
+func TestDeliveryPIN(t *testing.T) {
+	pin, err := services.GenerateDeliveryPIN()
+	assert.NoError(t, err)
+	assert.Regexp(t, regexp.MustCompile(`^\d{4}$`), pin)
+
+	assert.True(t, services.CheckDeliveryPIN(&pin, pin))
+	assert.False(t, services.CheckDeliveryPIN(&pin, pin+"0"))
+	assert.False(t, services.CheckDeliveryPIN(&pin, ""))
+	assert.False(t, services.CheckDeliveryPIN(nil, pin))
+}
+
+func TestDeliveryPINLockout(t *testing.T) {
+	assert.False(t, services.DeliveryPINLocked(0))
+	assert.False(t, services.DeliveryPINLocked(4))
+	assert.True(t, services.DeliveryPINLocked(5))
+
+	db := connectTestDB(t)
+	pin := "1234"
+	job, waypointID := createPINDelivery(t, db, pin)
+	driver := *job.DriverID
+
+	waypointService := services.NewWaypointServiceWithStore(storage.NewLocalStore(t.TempDir()))
+	wrongPIN := dto.CompleteWaypointRequest{ProofType: string(models.ProofOfDeliveryPIN), PIN: "0000"}
+	for i := 0; i < 4; i++ {
+		_, err := waypointService.CompleteWaypoint(job.ID, waypointID, driver, wrongPIN, nil)
+		assert.True(t, errors.Is(err, services.ErrInvalidDeliveryPIN), "unexpected error: %v", err)
+	}
+	_, err := waypointService.CompleteWaypoint(job.ID, waypointID, driver, wrongPIN, nil)
+	assert.True(t, errors.Is(err, services.ErrDeliveryPINLocked), "unexpected error: %v", err)
+
+	// Once locked, even the right PIN is refused
+	rightPIN := dto.CompleteWaypointRequest{ProofType: string(models.ProofOfDeliveryPIN), PIN: pin}
+	_, err = waypointService.CompleteWaypoint(job.ID, waypointID, driver, rightPIN, nil)
+	assert.True(t, errors.Is(err, services.ErrDeliveryPINLocked), "unexpected error: %v", err)
+
+	photo := &services.ProofImage{Content: bytes.NewReader([]byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"))}
+	waypoint, err := waypointService.CompleteWaypoint(job.ID, waypointID, driver,
+		dto.CompleteWaypointRequest{ProofType: string(models.ProofOfDeliveryPhoto)}, photo)
+	require.NoError(t, err)
+	assert.Equal(t, string(models.WaypointStatusCompleted), waypoint.Status)
+	assert.True(t, waypoint.PINLocked)
+}
+
+func TestDeliveryPINLockoutConcurrentAttempts(t *testing.T) {
+	db := connectTestDB(t)
+	pin := "1234"
+	job, waypointID := createPINDelivery(t, db, pin)
+
+	// Guesses sent all at once get no more attempts than guesses sent one by one
+	waypointService := services.NewWaypointServiceWithStore(storage.NewLocalStore(t.TempDir()))
+	wrongPIN := dto.CompleteWaypointRequest{ProofType: string(models.ProofOfDeliveryPIN), PIN: "0000"}
+	errs := make([]error, 20)
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = waypointService.CompleteWaypoint(job.ID, waypointID, *job.DriverID, wrongPIN, nil)
+		}(i)
+	}
+	wg.Wait()
+
+	invalid := 0
+	for _, err := range errs {
+		if errors.Is(err, services.ErrInvalidDeliveryPIN) {
+			invalid++
+		} else {
+			assert.True(t, errors.Is(err, services.ErrDeliveryPINLocked), "unexpected error: %v", err)
+		}
+	}
+	assert.Equal(t, 4, invalid)
+
+	var waypoint models.TripWaypoint
+	require.NoError(t, db.First(&waypoint, "id = ?", waypointID).Error)
+	assert.Equal(t, 5, waypoint.PINAttempts)
+}
+
+// createPINDelivery creates an in-progress delivery whose driver has arrived at a stop
+// completed with pin
+func createPINDelivery(t *testing.T, db *gorm.DB, pin string) (*models.Job, uuid.UUID) {
+	customer := createTestUser(t, db, models.UserTypeCustomer)
+	driver := createTestUser(t, db, models.UserTypeDriver)
+	trip := &models.Trip{
+		CustomerID:       customer.ID,
+		DriverID:         &driver.ID,
+		ServiceType:      models.ServiceTypeDelivery,
+		Status:           models.TripStatusInProgress,
+		PickupLatitude:   25.2048,
+		PickupLongitude:  55.2708,
+		DropoffLatitude:  25.1972,
+		DropoffLongitude: 55.2744,
+		Waypoints: []models.TripWaypoint{{
+			Sequence:      1,
+			Latitude:      25.1972,
+			Longitude:     55.2744,
+			ContactName:   "Recipient",
+			ContactPhone:  "+971500000000",
+			Status:        models.WaypointStatusArrived,
+			ProofRequired: true,
+			DeliveryPIN:   &pin,
+		}},
+	}
+	require.NoError(t, db.Create(trip).Error)
+	job := &models.Job{TripID: trip.ID, DriverID: &driver.ID, Status: models.JobStatusInProgress, DispatchWave: 1}
+	require.NoError(t, db.Create(job).Error)
+	return job, trip.Waypoints[0].ID
+}
+
+func TestCombineRouteLegs(t *testing.T) {
+	route := services.CombineRouteLegs([]services.RouteEstimate{
+		{DistanceKm: 3.5, DurationMinutes: 9, Method: services.DistanceMethodRoad},
+		{DistanceKm: 2, DurationMinutes: 6, Method: services.DistanceMethodRoad},
+	})
+	assert.Equal(t, 5.5, route.DistanceKm)
+	assert.Equal(t, 15.0, route.DurationMinutes)
+	assert.Equal(t, services.DistanceMethodRoad, route.Method)
+
+	// One estimated leg makes the whole route an estimate
+	route = services.CombineRouteLegs([]services.RouteEstimate{
+		{DistanceKm: 3.5, DurationMinutes: 9, Method: services.DistanceMethodRoad},
+		{DistanceKm: 2, DurationMinutes: 6, Method: services.DistanceMethodStraightLine},
+	})
+	assert.Equal(t, services.DistanceMethodStraightLine, route.Method)
+}